@@ -0,0 +1,257 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Apidiff reports the changes between the APIs of two versions of a
+// module, and recommends the semantic version of the newer one.
+//
+// Usage:
+//
+//	apidiff [-git repo] [-version v] [-incompatible] old new
+//
+// By default, old and new are the root directories of the two versions
+// of the module. With the -git flag, they are revisions, such as tags,
+// branches or commits, of the git repository repo, each of which is
+// checked out into a temporary directory.
+//
+// Apidiff compares each exported package of the old version, that is,
+// each package that is neither a command nor internal, with the package
+// of the same path, relative to the module root, in the new version.
+// It reports the changes of each package, the removal and addition of
+// packages, and the changes of the go.mod file that affect the users of
+// the module, as either compatible or incompatible; see
+// golang.org/x/tools/internal/apidiff for details.
+//
+// Apidiff then recommends the version of the new module: an incompatible
+// change requires a new major version (or, before v1.0.0, a new minor
+// version), a compatible change a new minor version, and otherwise a new
+// patch version suffices. The version of the old module is given by the
+// -version flag or, with the -git flag, by the old revision, if it is a
+// semantic version tag.
+//
+// The exit status is 1 if there are incompatible changes, and 2 if the
+// modules cannot be compared, so that release automation can refuse to
+// tag a version whose changes are incompatible with its predecessor.
+//
+// # Example
+//
+// Check the changes since the v0.7.0 tag of the repository in the
+// current directory:
+//
+//	apidiff -git . v0.7.0 HEAD
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/vcs"
+	"golang.org/x/tools/internal/apidiff"
+)
+
+var (
+	gitRepo          = flag.String("git", "", "compare revisions of the git `repo`sitory instead of directories")
+	oldVersion       = flag.String("version", "", "the semantic `version` of the old module")
+	incompatibleOnly = flag.Bool("incompatible", false, "report only incompatible changes")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: apidiff [-git repo] [-version v] [-incompatible] old new\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetPrefix("apidiff: ")
+	log.SetFlags(0)
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 2 {
+		usage()
+		os.Exit(2)
+	}
+	incompatible, err := run(flag.Arg(0), flag.Arg(1))
+	if err != nil {
+		log.Print(err)
+		os.Exit(2)
+	}
+	if incompatible {
+		os.Exit(1)
+	}
+}
+
+// run compares the old and new versions of the module, and reports
+// whether there are incompatible changes.
+func run(old, new string) (bool, error) {
+	version := *oldVersion
+	if *gitRepo != "" {
+		if version == "" && semver.IsValid(old) {
+			version = old
+		}
+		tmp, err := ioutil.TempDir("", "apidiff")
+		if err != nil {
+			return false, err
+		}
+		defer os.RemoveAll(tmp)
+		if old, err = checkout(*gitRepo, old, filepath.Join(tmp, "old")); err != nil {
+			return false, err
+		}
+		if new, err = checkout(*gitRepo, new, filepath.Join(tmp, "new")); err != nil {
+			return false, err
+		}
+	}
+	if version != "" && (!semver.IsValid(version) || semver.Prerelease(version)+semver.Build(version) != "") {
+		return false, fmt.Errorf("invalid version %q: must be a semantic version of a release, such as v1.2.3", version)
+	}
+
+	report, err := moduleChanges(old, new)
+	if err != nil {
+		return false, err
+	}
+	if *incompatibleOnly {
+		err = report.TextIncompatible(os.Stdout)
+	} else {
+		err = report.Text(os.Stdout)
+	}
+	if err != nil {
+		return false, err
+	}
+	incompatible := !report.Compatible()
+	compatible := len(report.Changes) > 0 || len(report.Packages) > 0
+
+	switch {
+	case version == "":
+		fmt.Printf("Suggested version: new %s version\n", increment(incompatible, compatible))
+	case incompatible && semver.Major(version) != "v0":
+		next := nextVersion(version, incompatible, compatible)
+		fmt.Printf("Suggested version: %s, with a module path ending in /%s\n", next, semver.Major(next))
+	default:
+		fmt.Printf("Suggested version: %s\n", nextVersion(version, incompatible, compatible))
+	}
+	return incompatible, nil
+}
+
+// checkout checks out the revision rev of the git repository repo into
+// dir, which must not exist, and returns dir.
+func checkout(repo, rev, dir string) (string, error) {
+	if abs, err := filepath.Abs(repo); err == nil && isDir(abs) {
+		repo = abs // a local repository
+	}
+	if err := vcs.ByCmd("git").CreateAtRev(dir, repo, rev); err != nil {
+		return "", fmt.Errorf("checking out %s of %s: %v", rev, repo, err)
+	}
+	return dir, nil
+}
+
+func isDir(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
+
+// moduleChanges returns the changes between the APIs of the exported
+// packages of the modules in the directories old and new.
+func moduleChanges(old, new string) (apidiff.ModuleReport, error) {
+	oldmod, err := loadModule(old)
+	if err != nil {
+		return apidiff.ModuleReport{}, err
+	}
+	newmod, err := loadModule(new)
+	if err != nil {
+		return apidiff.ModuleReport{}, err
+	}
+	return apidiff.ModuleChanges(oldmod, newmod), nil
+}
+
+// loadModule loads the go.mod file and the exported packages of the
+// module in dir.
+func loadModule(dir string) (*apidiff.Module, error) {
+	filename := filepath.Join(dir, "go.mod")
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.Parse(filename, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.Module == nil {
+		return nil, fmt.Errorf("%s: no module path", filename)
+	}
+	m := &apidiff.Module{Path: f.Module.Mod.Path}
+	if f.Go != nil {
+		m.Go = f.Go.Version
+	}
+	for _, r := range f.Require {
+		m.Require = append(m.Require, r.Mod)
+	}
+
+	// The packages of the module are type-checked from source.
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedTypesSizes | packages.NeedSyntax | packages.NeedImports,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("%s: errors loading packages", dir)
+	}
+	for _, pkg := range pkgs {
+		if pkg.Name != "main" && !isInternal(pkg.PkgPath) {
+			m.Packages = append(m.Packages, pkg.Types)
+		}
+	}
+	return m, nil
+}
+
+// isInternal reports whether the package path has an internal element.
+func isInternal(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// increment returns the part of the semantic version that a release
+// with the given kinds of changes must increment.
+func increment(incompatible, compatible bool) string {
+	switch {
+	case incompatible:
+		return "major"
+	case compatible:
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+// nextVersion returns the version that follows the release version v
+// for a release with the given kinds of changes. Before v1.0.0,
+// incompatible changes require only a new minor version.
+func nextVersion(v string, incompatible, compatible bool) string {
+	parts := strings.SplitN(strings.TrimPrefix(semver.Canonical(v), "v"), ".", 3)
+	var major, minor, patch int
+	for i, p := range []*int{&major, &minor, &patch} {
+		*p, _ = strconv.Atoi(parts[i])
+	}
+	switch {
+	case incompatible && major > 0:
+		major, minor, patch = major+1, 0, 0
+	case incompatible, compatible:
+		minor, patch = minor+1, 0
+	default:
+		patch++
+	}
+	return fmt.Sprintf("v%d.%d.%d", major, minor, patch)
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/internal/apidiff"
+	"golang.org/x/tools/internal/testenv"
+)
+
+// writeModule writes the files, by slash-separated path, to dir.
+func writeModule(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const (
+	goMod = "module example.com/m\n\ngo 1.18\n"
+
+	oldP = `package p
+
+func F() {}
+
+func G() {}
+`
+	newP = `package p
+
+func F(int) {}
+
+func H() {}
+`
+)
+
+// messages returns the messages of the changes of each package, and
+// those of the module as a whole under "module".
+func messages(report apidiff.ModuleReport) map[string][]string {
+	res := make(map[string][]string)
+	for _, c := range report.Changes {
+		res["module"] = append(res["module"], c.Message)
+	}
+	for _, p := range report.Packages {
+		for _, c := range p.Changes {
+			res[p.Path] = append(res[p.Path], c.Message)
+		}
+	}
+	return res
+}
+
+func TestModuleChanges(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	dir := t.TempDir()
+	old, new := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+	writeModule(t, old, map[string]string{
+		"go.mod":             goMod,
+		"p/p.go":             oldP,
+		"m.go":               "package m\n\nconst C = 1\n",
+		"internal/q/q.go":    "package q\n\nfunc Q() {}\n",
+		"cmd/tool/main.go":   "package main\n\nfunc Tool() {}\n\nfunc main() {}\n",
+		"unchanged/u/u.go":   "package u\n\nvar V int\n",
+		"removed/removed.go": "package removed\n",
+	})
+	writeModule(t, new, map[string]string{
+		"go.mod":           goMod,
+		"p/p.go":           newP,
+		"m.go":             "package m\n\nconst C = 1\n\nconst D = 2\n",
+		"internal/q/q.go":  "package q\n",
+		"cmd/tool/main.go": "package main\n\nfunc main() {}\n",
+		"unchanged/u/u.go": "package u\n\nvar V int\n",
+	})
+
+	changes, err := moduleChanges(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"example.com/m/p": {"F: changed from func() to func(int)", "G: removed", "H: added"},
+		"example.com/m":   {"D: added"},
+		"module":          {"package example.com/m/removed: removed"},
+	}
+	if got := messages(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("moduleChanges = %v, want %v", got, want)
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+	testenv.NeedsTool(t, "git")
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Gopher", "GIT_AUTHOR_EMAIL=gopher@example.com",
+			"GIT_COMMITTER_NAME=Gopher", "GIT_COMMITTER_EMAIL=gopher@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	writeModule(t, repo, map[string]string{"go.mod": goMod, "p/p.go": oldP})
+	git("add", "-A")
+	git("commit", "-q", "-m", "old")
+	git("tag", "v1.0.0")
+	writeModule(t, repo, map[string]string{"p/p.go": newP})
+	git("commit", "-q", "-a", "-m", "new")
+
+	dir := t.TempDir()
+	old, err := checkout(repo, "v1.0.0", filepath.Join(dir, "old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := checkout(repo, "HEAD", filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := moduleChanges(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"example.com/m/p": {"F: changed from func() to func(int)", "G: removed", "H: added"},
+	}
+	if got := messages(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("moduleChanges = %v, want %v", got, want)
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	for _, test := range []struct {
+		version                  string
+		incompatible, compatible bool
+		want                     string
+	}{
+		{"v1.2.3", false, false, "v1.2.4"},
+		{"v1.2.3", false, true, "v1.3.0"},
+		{"v1.2.3", true, true, "v2.0.0"},
+		{"v2.0.0", true, false, "v3.0.0"},
+		{"v0.4.1", true, false, "v0.5.0"},
+		{"v0.4.1", false, true, "v0.5.0"},
+		{"v0.4.1", false, false, "v0.4.2"},
+		{"v1.2", false, true, "v1.3.0"},
+	} {
+		if got := nextVersion(test.version, test.incompatible, test.compatible); got != test.want {
+			t.Errorf("nextVersion(%s, %t, %t) = %s, want %s", test.version, test.incompatible, test.compatible, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// This file defines the selection of a subgraph by the -focus and
+// -depth flags, and the built-in dot, json, and graphml formats.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+	"strconv"
+
+	"golang.org/x/tools/go/callgraph"
+)
+
+// A subgraph is the portion of a call graph selected for output.
+type subgraph struct {
+	nodes []*callgraph.Node                     // selected nodes, in order of ID
+	edges map[*callgraph.Node][]*callgraph.Edge // selected outgoing edges of each node
+}
+
+// wholeGraph returns the subgraph containing all of cg.
+func wholeGraph(cg *callgraph.Graph) *subgraph {
+	g := &subgraph{edges: make(map[*callgraph.Node][]*callgraph.Edge)}
+	for _, n := range cg.Nodes {
+		if n.Func == nil {
+			continue // the root of a graph without one
+		}
+		g.nodes = append(g.nodes, n)
+		g.edges[n] = n.Out
+	}
+	g.sort()
+	return g
+}
+
+// focusGraph returns the subgraph of cg reachable from the functions
+// selected by focus, which may be the name of a function, as printed
+// by ssa.Function.String, or the import path of a package.
+// If focus is empty, the graph's root is used.
+// If depth is non-negative, the subgraph includes only functions
+// reachable by a chain of at most depth calls.
+func focusGraph(cg *callgraph.Graph, focus string, depth int) (*subgraph, error) {
+	var roots []*callgraph.Node
+	if focus == "" {
+		if cg.Root == nil || cg.Root.Func == nil {
+			return nil, fmt.Errorf("-depth requires -focus for a call graph without a root")
+		}
+		roots = append(roots, cg.Root)
+	} else {
+		for fn, n := range cg.Nodes {
+			if fn == nil {
+				continue
+			}
+			if fn.String() == focus || fn.Pkg != nil && fn.Pkg.Pkg.Path() == focus {
+				roots = append(roots, n)
+			}
+		}
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("-focus=%s matches no function or package in the call graph", focus)
+		}
+	}
+
+	// Breadth-first search from the roots.
+	g := &subgraph{edges: make(map[*callgraph.Node][]*callgraph.Edge)}
+	dist := make(map[*callgraph.Node]int)
+	for _, n := range roots {
+		dist[n] = 0
+	}
+	queue := roots
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		g.nodes = append(g.nodes, n)
+		if depth >= 0 && dist[n] >= depth {
+			continue // calls from n are too deep
+		}
+		g.edges[n] = n.Out
+		for _, e := range n.Out {
+			if _, ok := dist[e.Callee]; !ok {
+				dist[e.Callee] = dist[n] + 1
+				queue = append(queue, e.Callee)
+			}
+		}
+	}
+	g.sort()
+	return g, nil
+}
+
+func (g *subgraph) sort() {
+	sort.Slice(g.nodes, func(i, j int) bool { return g.nodes[i].ID < g.nodes[j].ID })
+}
+
+// visitEdges calls f for each selected edge, in order of caller.
+func (g *subgraph) visitEdges(f func(*callgraph.Edge) error) error {
+	for _, n := range g.nodes {
+		for _, e := range g.edges[n] {
+			if err := f(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isDynamic reports whether e is a dynamic call,
+// through an interface or function value.
+func isDynamic(e *callgraph.Edge) bool {
+	return e.Site != nil && e.Site.Common().StaticCallee() == nil
+}
+
+// position returns the position of pos in "file:line:col" form,
+// or "" if it is not valid.
+func position(fset *token.FileSet, pos token.Pos) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	return fset.Position(pos).String()
+}
+
+// writeDOT writes g to w in GraphViz (.dot) format,
+// showing dynamic calls as dashed edges.
+func writeDOT(w io.Writer, fset *token.FileSet, g *subgraph) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph callgraph {")
+	for _, n := range g.nodes {
+		fmt.Fprintf(out, "\tn%d [label=%s];\n", n.ID, strconv.Quote(n.Func.String()))
+	}
+	g.visitEdges(func(e *callgraph.Edge) error {
+		fmt.Fprintf(out, "\tn%d -> n%d", e.Caller.ID, e.Callee.ID)
+		if isDynamic(e) {
+			fmt.Fprint(out, " [style=dashed]")
+		}
+		fmt.Fprintln(out, ";")
+		return nil
+	})
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// jsonNode and jsonEdge define the JSON encoding of a call graph:
+//
+//	{"nodes": [jsonNode...], "edges": [jsonEdge...]}
+type jsonNode struct {
+	ID   int    `json:"id"`
+	Func string `json:"func"`
+	Pkg  string `json:"pkg,omitempty"` // import path of package, if any
+	Pos  string `json:"pos,omitempty"` // position of declaration, if any
+}
+
+type jsonEdge struct {
+	Caller      int    `json:"caller"`
+	Callee      int    `json:"callee"`
+	Pos         string `json:"pos,omitempty"` // position of call site, if any
+	Dynamic     bool   `json:"dynamic"`
+	Description string `json:"description"`
+}
+
+// writeJSON writes g to w in JSON format.
+// Each node and edge is encoded as soon as it is visited.
+func writeJSON(w io.Writer, fset *token.FileSet, g *subgraph) error {
+	out := bufio.NewWriter(w)
+	sep := "\n"
+	write := func(v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		out.WriteString(sep)
+		out.Write(data)
+		sep = ",\n"
+		return nil
+	}
+
+	out.WriteString(`{"nodes":[`)
+	for _, n := range g.nodes {
+		node := jsonNode{
+			ID:   n.ID,
+			Func: n.Func.String(),
+			Pos:  position(fset, n.Func.Pos()),
+		}
+		if n.Func.Pkg != nil {
+			node.Pkg = n.Func.Pkg.Pkg.Path()
+		}
+		if err := write(node); err != nil {
+			return err
+		}
+	}
+	out.WriteString("\n],\n\"edges\":[")
+	sep = "\n"
+	if err := g.visitEdges(func(e *callgraph.Edge) error {
+		return write(jsonEdge{
+			Caller:      e.Caller.ID,
+			Callee:      e.Callee.ID,
+			Pos:         position(fset, e.Pos()),
+			Dynamic:     isDynamic(e),
+			Description: e.Description(),
+		})
+	}); err != nil {
+		return err
+	}
+	out.WriteString("\n]}\n")
+	return out.Flush()
+}
+
+// writeGraphML writes g to w in GraphML format.
+func writeGraphML(w io.Writer, fset *token.FileSet, g *subgraph) error {
+	out := bufio.NewWriter(w)
+	text := func(s string) string {
+		var buf bytes.Buffer
+		xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	}
+
+	fmt.Fprint(out, xml.Header)
+	fmt.Fprintln(out, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(out, `  <key id="func" for="node" attr.name="func" attr.type="string"/>`)
+	fmt.Fprintln(out, `  <key id="pos" for="all" attr.name="pos" attr.type="string"/>`)
+	fmt.Fprintln(out, `  <key id="dynamic" for="edge" attr.name="dynamic" attr.type="boolean"/>`)
+	fmt.Fprintln(out, `  <graph id="callgraph" edgedefault="directed">`)
+	for _, n := range g.nodes {
+		fmt.Fprintf(out, "    <node id=\"n%d\"><data key=\"func\">%s</data>", n.ID, text(n.Func.String()))
+		if pos := position(fset, n.Func.Pos()); pos != "" {
+			fmt.Fprintf(out, "<data key=\"pos\">%s</data>", text(pos))
+		}
+		fmt.Fprintln(out, "</node>")
+	}
+	g.visitEdges(func(e *callgraph.Edge) error {
+		fmt.Fprintf(out, "    <edge source=\"n%d\" target=\"n%d\">", e.Caller.ID, e.Callee.ID)
+		if pos := position(fset, e.Pos()); pos != "" {
+			fmt.Fprintf(out, "<data key=\"pos\">%s</data>", text(pos))
+		}
+		fmt.Fprintf(out, "<data key=\"dynamic\">%t</data></edge>\n", isDynamic(e))
+		return nil
+	})
+	fmt.Fprintln(out, "  </graph>")
+	fmt.Fprintln(out, "</graphml>")
+	return out.Flush()
+}
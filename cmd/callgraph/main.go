@@ -11,11 +11,8 @@ package main // import "golang.org/x/tools/cmd/callgraph"
 // Features:
 // - restrict graph to a single package
 // - output
-//   - functions reachable from root (use digraph tool?)
 //   - unreachable functions (use digraph tool?)
 //   - dynamic (runtime) types
-//   - indexed output (numbered nodes)
-//   - JSON output
 //   - additional template fields:
 //     callee file/line/col
 
@@ -54,7 +51,13 @@ var (
 
 	formatFlag = flag.String("format",
 		"{{.Caller}}\t--{{.Dynamic}}-{{.Line}}:{{.Column}}-->\t{{.Callee}}",
-		"A template expression specifying how to format an edge")
+		"A template expression specifying how to format an edge, or one of digraph, dot, json, graphml")
+
+	focusFlag = flag.String("focus", "",
+		"Restrict the graph to functions reachable from the named function or package")
+
+	depthFlag = flag.Int("depth", -1,
+		"Restrict the graph to functions reachable by at most this many calls, if non-negative")
 
 	ptalogFlag = flag.String("ptalog", "",
 		"Location of the points-to analysis log file, or empty to disable logging.")
@@ -68,7 +71,8 @@ const Usage = `callgraph: display the call graph of a Go program.
 
 Usage:
 
-  callgraph [-algo=static|cha|rta|vta|pta] [-test] [-format=...] package...
+  callgraph [-algo=static|cha|rta|vta|pta] [-test] [-format=...]
+            [-focus=function|package] [-depth=N] package...
 
 Flags:
 
@@ -93,6 +97,14 @@ Flags:
             digraph     output suitable for input to
                         golang.org/x/tools/cmd/digraph.
             graphviz    output in AT&T GraphViz (.dot) format.
+            dot         output in AT&T GraphViz (.dot) format,
+                        with labeled nodes and dynamic calls
+                        shown as dashed edges.
+            json        a JSON object with a list of nodes, each with
+                        its function, package and position, and a list
+                        of edges, each with its call site and whether
+                        the call is dynamic.
+            graphml     output in GraphML format.
 
            All other values are interpreted using text/template syntax.
            The default value is:
@@ -120,6 +132,14 @@ Flags:
            import path of the enclosing package.  Consult the go/ssa
            API documentation for details.
 
+-focus     Restricts the graph to the functions reachable from the
+           specified function, such as "(*bytes.Buffer).Write", or
+           from the functions of the specified package, such as "fmt".
+
+-depth     Restricts the graph to the functions reachable by a chain of
+           at most N calls from the -focus functions, or from the root
+           of the call graph if -focus is not set.
+
 Examples:
 
   Show the call graph of the trivial web server application:
@@ -138,6 +158,12 @@ Examples:
       sed -ne 's/-dynamic-/--/p' |
       sed -ne 's/-->.*fmt_test.*$//p' | sort | uniq
 
+  Render the functions called directly or indirectly by net/http's
+  ListenAndServe, up to two calls deep:
+
+    callgraph -format=dot -focus=net/http.ListenAndServe -depth=2 \
+      $GOROOT/src/net/http/triv.go | dot -Tsvg > callgraph.svg
+
   Show all functions directly called by the callgraph tool's main function:
 
     callgraph -format=digraph golang.org/x/tools/cmd/callgraph |
@@ -262,8 +288,30 @@ func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) er
 
 	cg.DeleteSyntheticNodes()
 
+	// -- subgraph selection -----------------------------------------------
+
+	var g *subgraph
+	if *focusFlag != "" || *depthFlag >= 0 {
+		g, err = focusGraph(cg, *focusFlag, *depthFlag)
+		if err != nil {
+			return err
+		}
+	} else {
+		g = wholeGraph(cg)
+	}
+
 	// -- output------------------------------------------------------------
 
+	// Built-in formats.
+	switch format {
+	case "dot":
+		return writeDOT(stdout, prog.Fset, g)
+	case "json":
+		return writeJSON(stdout, prog.Fset, g)
+	case "graphml":
+		return writeGraphML(stdout, prog.Fset, g)
+	}
+
 	var before, after string
 
 	// Pre-canned formats.
@@ -287,7 +335,7 @@ func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) er
 	data := Edge{fset: prog.Fset}
 
 	fmt.Fprint(stdout, before)
-	if err := callgraph.GraphVisitEdges(cg, func(edge *callgraph.Edge) error {
+	if err := g.visitEdges(func(edge *callgraph.Edge) error {
 		data.position.Offset = -1
 		data.edge = edge
 		data.Caller = edge.Caller.Func
@@ -346,7 +394,7 @@ func (e *Edge) Line() int        { return e.pos().Line }
 func (e *Edge) Offset() int      { return e.pos().Offset }
 
 func (e *Edge) Dynamic() string {
-	if e.edge.Site != nil && e.edge.Site.Common().StaticCallee() == nil {
+	if isDynamic(e.edge) {
 		return "dynamic"
 	}
 	return "static"
@@ -120,3 +120,72 @@ func TestCallgraph(t *testing.T) {
 		}
 	}
 }
+
+func TestCallgraphFormats(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(focus string, depth int) {
+		*focusFlag, *depthFlag = focus, depth
+	}(*focusFlag, *depthFlag)
+
+	for _, test := range []struct {
+		format string
+		focus  string
+		depth  int
+		want   []string // substrings of output
+		reject []string // substrings absent from output
+	}{
+		{"dot", "", -1, []string{
+			`digraph callgraph {`,
+			`[label="pkg.main2"];`,
+			`[style=dashed];`,
+		}, nil},
+		{"json", "", -1, []string{
+			`"func":"pkg.main2","pkg":"pkg","pos":`,
+			`"dynamic":true,"description":"dynamic method call"`,
+		}, nil},
+		{"graphml", "", -1, []string{
+			`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`,
+			`<data key="func">pkg.main2</data>`,
+			`<data key="dynamic">true</data></edge>`,
+		}, nil},
+		{"{{.Caller}} --> {{.Callee}}", "pkg.main2", -1, []string{
+			"pkg.main2 --> (pkg.D).f",
+		}, []string{
+			"pkg.main --> pkg.main2",
+		}},
+		{"{{.Caller}} --> {{.Callee}}", "pkg.main", 0, nil, []string{
+			"-->",
+		}},
+		{"{{.Caller}} --> {{.Callee}}", "pkg.main", 1, []string{
+			"pkg.main --> pkg.main2",
+		}, []string{
+			"pkg.main2 --> (pkg.D).f",
+		}},
+	} {
+		*focusFlag, *depthFlag = test.focus, test.depth
+		stdout = new(bytes.Buffer)
+		if err := doCallgraph("testdata/src", gopath, "vta", test.format, false, []string{"pkg"}); err != nil {
+			t.Error(err)
+			continue
+		}
+		got := fmt.Sprint(stdout)
+		for _, want := range test.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("callgraph(-format=%s -focus=%s -depth=%d): output lacks %q; got:\n%s",
+					test.format, test.focus, test.depth, want, got)
+			}
+		}
+		for _, reject := range test.reject {
+			if strings.Contains(got, reject) {
+				t.Errorf("callgraph(-format=%s -focus=%s -depth=%d): output contains %q; got:\n%s",
+					test.format, test.focus, test.depth, reject, got)
+			}
+		}
+	}
+}
@@ -106,7 +106,7 @@ func callees(q *Query) error {
 		}
 	}
 
-	prog := ssautil.CreateProgram(lprog, ssa.GlobalDebug)
+	prog := ssautil.CreateProgram(lprog, ssa.GlobalDebug|ssa.InstantiateGenerics)
 
 	ptaConfig, err := setupPTA(prog, lprog, q.PTALog, q.Reflection)
 	if err != nil {
@@ -36,7 +36,7 @@ func callers(q *Query) error {
 		return err
 	}
 
-	prog := ssautil.CreateProgram(lprog, 0)
+	prog := ssautil.CreateProgram(lprog, ssa.InstantiateGenerics)
 
 	ptaConfig, err := setupPTA(prog, lprog, q.PTALog, q.Reflection)
 	if err != nil {
@@ -41,7 +41,7 @@ func peers(q *Query) error {
 		return err
 	}
 
-	prog := ssautil.CreateProgram(lprog, ssa.GlobalDebug)
+	prog := ssautil.CreateProgram(lprog, ssa.GlobalDebug|ssa.InstantiateGenerics)
 
 	ptaConfig, err := setupPTA(prog, lprog, q.PTALog, q.Reflection)
 	if err != nil {
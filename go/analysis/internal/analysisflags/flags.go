@@ -26,6 +26,7 @@ import (
 // flags common to all {single,multi,unit}checkers.
 var (
 	JSON    = false // -json
+	SARIF   = false // -sarif
 	Context = -1    // -c=N: if N>0, display offending line plus N lines of context
 )
 
@@ -74,6 +75,7 @@ func Parse(analyzers []*analysis.Analyzer, multi bool) []*analysis.Analyzer {
 
 	// flags common to all checkers
 	flag.BoolVar(&JSON, "json", JSON, "emit JSON output")
+	flag.BoolVar(&SARIF, "sarif", SARIF, "emit SARIF 2.1.0 output")
 	flag.IntVar(&Context, "c", Context, `display offending line with this many lines of context`)
 
 	// Add shims for legacy vet flags to enable existing
@@ -91,6 +93,10 @@ func Parse(analyzers []*analysis.Analyzer, multi bool) []*analysis.Analyzer {
 
 	flag.Parse() // (ExitOnError)
 
+	if JSON && SARIF {
+		log.Fatalf("-json and -sarif are mutually exclusive")
+	}
+
 	// -flags: print flags so that go vet knows which ones are legitimate.
 	if *printflags {
 		printFlags()
@@ -171,7 +177,7 @@ func printFlags() {
 		// flags or fix as these have no effect on unitchecker
 		// (as invoked by 'go vet').
 		switch f.Name {
-		case "debug", "cpuprofile", "memprofile", "trace", "fix":
+		case "debug", "cpuprofile", "memprofile", "trace", "fix", "diff", "baseline", "cache":
 			return
 		}
 
@@ -0,0 +1,418 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package analysisflags
+
+// This file defines the -sarif output format, which encodes
+// diagnostics as a Static Analysis Results Interchange Format
+// (SARIF) 2.1.0 log. See
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// A SARIFLog accumulates the results of analyses in the form of a
+// SARIF log containing a single run. Each analyzer is described by a
+// reporting descriptor (a "rule") derived from its Name and Doc.
+//
+// The zero value is an empty log ready to use.
+type SARIFLog struct {
+	rules  []SARIFRule
+	index  map[string]int // maps analyzer name to index in rules
+	seen   map[sarifKey]bool
+	result []SARIFResult
+	notes  []SARIFNotification
+}
+
+// sarifKey identifies a result for the purposes of de-duplication,
+// since a source file may belong to several packages (foo and foo.test).
+type sarifKey struct {
+	rule       string
+	start, end token.Position
+	message    string
+}
+
+// The following types define the subset of the SARIF 2.1.0 object
+// model produced by SARIFLog. Field names follow the specification.
+
+type SARIFDocument struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool        SARIFTool         `json:"tool"`
+	Invocations []SARIFInvocation `json:"invocations,omitempty"`
+	Results     []SARIFResult     `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []SARIFRule `json:"rules,omitempty"`
+}
+
+type SARIFRule struct {
+	ID               string        `json:"id"`
+	ShortDescription *SARIFMessage `json:"shortDescription,omitempty"`
+	FullDescription  *SARIFMessage `json:"fullDescription,omitempty"`
+}
+
+type SARIFInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []SARIFNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type SARIFNotification struct {
+	Level   string       `json:"level"`
+	Message SARIFMessage `json:"message"`
+	// Properties records the package and analyzer that failed.
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type SARIFResult struct {
+	RuleID           string                 `json:"ruleId"`
+	RuleIndex        int                    `json:"ruleIndex"`
+	Level            string                 `json:"level"`
+	Message          SARIFMessage           `json:"message"`
+	Locations        []SARIFLocation        `json:"locations,omitempty"`
+	RelatedLocations []SARIFLocation        `json:"relatedLocations,omitempty"`
+	Fixes            []SARIFFix             `json:"fixes,omitempty"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFLocation struct {
+	ID               *int                  `json:"id,omitempty"`
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+	Message          *SARIFMessage         `json:"message,omitempty"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           SARIFRegion           `json:"region"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// A SARIFRegion describes a portion of a file.
+// Go positions are byte-oriented, so in addition to line and column
+// (which SARIF interprets as characters) the byte offset and length
+// are always given; consumers should prefer them when present.
+type SARIFRegion struct {
+	StartLine   int  `json:"startLine,omitempty"`
+	StartColumn int  `json:"startColumn,omitempty"`
+	EndLine     int  `json:"endLine,omitempty"`
+	EndColumn   int  `json:"endColumn,omitempty"`
+	ByteOffset  *int `json:"byteOffset,omitempty"`
+	ByteLength  *int `json:"byteLength,omitempty"`
+}
+
+type SARIFFix struct {
+	Description     SARIFMessage          `json:"description"`
+	ArtifactChanges []SARIFArtifactChange `json:"artifactChanges"`
+}
+
+type SARIFArtifactChange struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Replacements     []SARIFReplacement    `json:"replacements"`
+}
+
+type SARIFReplacement struct {
+	DeletedRegion   SARIFRegion           `json:"deletedRegion"`
+	InsertedContent *SARIFArtifactContent `json:"insertedContent,omitempty"`
+}
+
+type SARIFArtifactContent struct {
+	Text string `json:"text"`
+}
+
+// Add adds the result of analyzer a on package id.
+// The result is either a list of diagnostics or an error.
+func (l *SARIFLog) Add(fset *token.FileSet, id string, a *analysis.Analyzer, diags []analysis.Diagnostic, err error) {
+	ruleIndex := l.rule(a)
+	if err != nil {
+		l.notes = append(l.notes, SARIFNotification{
+			Level:   "error",
+			Message: SARIFMessage{Text: err.Error()},
+			Properties: map[string]string{
+				"package":  id,
+				"analyzer": a.Name,
+			},
+		})
+		return
+	}
+	if l.seen == nil {
+		l.seen = make(map[sarifKey]bool)
+	}
+	for _, diag := range diags {
+		start, end := fset.Position(diag.Pos), fset.Position(diag.End)
+		k := sarifKey{a.Name, start, end, diag.Message}
+		if l.seen[k] {
+			continue // duplicate
+		}
+		l.seen[k] = true
+
+		res := SARIFResult{
+			RuleID:    a.Name,
+			RuleIndex: ruleIndex,
+			Level:     "warning",
+			Message:   SARIFMessage{Text: diag.Message},
+			Locations: []SARIFLocation{sarifLocation(fset, diag.Pos, diag.End)},
+		}
+		if diag.Category != "" {
+			res.Properties = map[string]interface{}{"category": diag.Category}
+		}
+		for i, rel := range diag.Related {
+			loc := sarifLocation(fset, rel.Pos, rel.End)
+			id := i + 1 // ids are non-negative and unique within the result
+			loc.ID = &id
+			loc.Message = &SARIFMessage{Text: rel.Message}
+			res.RelatedLocations = append(res.RelatedLocations, loc)
+		}
+		for _, fix := range diag.SuggestedFixes {
+			res.Fixes = append(res.Fixes, sarifFix(fset, fix))
+		}
+		l.result = append(l.result, res)
+	}
+}
+
+// rule returns the index of the rule for analyzer a,
+// adding it to the log if necessary.
+func (l *SARIFLog) rule(a *analysis.Analyzer) int {
+	if i, ok := l.index[a.Name]; ok {
+		return i
+	}
+	if l.index == nil {
+		l.index = make(map[string]int)
+	}
+	rule := SARIFRule{ID: a.Name}
+	if doc := strings.TrimSpace(a.Doc); doc != "" {
+		title := strings.Split(doc, "\n\n")[0]
+		rule.ShortDescription = &SARIFMessage{Text: title}
+		rule.FullDescription = &SARIFMessage{Text: doc}
+	}
+	i := len(l.rules)
+	l.rules = append(l.rules, rule)
+	l.index[a.Name] = i
+	return i
+}
+
+// Document returns the SARIF document describing the accumulated results.
+// The tool is named after the current executable.
+func (l *SARIFLog) Document() *SARIFDocument {
+	results := l.result
+	if results == nil {
+		results = []SARIFResult{} // the results property is required
+	}
+	run := SARIFRun{
+		Tool: SARIFTool{Driver: SARIFDriver{
+			Name:           filepath.Base(os.Args[0]),
+			InformationURI: "https://pkg.go.dev/golang.org/x/tools/go/analysis",
+			Rules:          l.rules,
+		}},
+		Invocations: []SARIFInvocation{{
+			ExecutionSuccessful:        len(l.notes) == 0,
+			ToolExecutionNotifications: l.notes,
+		}},
+		Results: results,
+	}
+	return &SARIFDocument{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []SARIFRun{run},
+	}
+}
+
+// Print prints the SARIF document to stdout.
+func (l *SARIFLog) Print() {
+	l.Fprint(os.Stdout)
+}
+
+// Fprint writes the SARIF document to w.
+func (l *SARIFLog) Fprint(w io.Writer) {
+	l.Document().Fprint(w)
+}
+
+// Fprint writes the SARIF document to w.
+func (doc *SARIFDocument) Fprint(w io.Writer) {
+	data, err := json.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		log.Panicf("internal error: SARIF marshaling failed: %v", err)
+	}
+	fmt.Fprintf(w, "%s\n", data)
+}
+
+// MergeSARIF reads the SARIF logs printed by the separate invocations
+// of a unitchecker-based tool under "go vet -sarif", one per package,
+// and merges them into a single log with a single run. Lines starting
+// with "#", which go vet prints before the output of each package, are
+// ignored. Rules are combined by ID, and results reported for the same
+// rule, location, and message are reported only once.
+func MergeSARIF(r io.Reader) (*SARIFDocument, error) {
+	// Discard the package headers printed by go vet.
+	var buf bytes.Buffer
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<30)
+	for sc.Scan() {
+		if line := sc.Bytes(); !bytes.HasPrefix(line, []byte("#")) {
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	merged := SARIFRun{
+		Invocations: []SARIFInvocation{{ExecutionSuccessful: true}},
+		Results:     []SARIFResult{},
+	}
+	index := make(map[string]int) // maps rule ID to index in merged rules
+	seen := make(map[string]bool) // JSON-encoded results, for de-duplication
+	dec := json.NewDecoder(&buf)
+	for {
+		var doc SARIFDocument
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid SARIF log: %v", err)
+		}
+		for _, run := range doc.Runs {
+			if merged.Tool.Driver.Name == "" {
+				merged.Tool.Driver.Name = run.Tool.Driver.Name
+				merged.Tool.Driver.InformationURI = run.Tool.Driver.InformationURI
+			}
+			for _, rule := range run.Tool.Driver.Rules {
+				if _, ok := index[rule.ID]; !ok {
+					index[rule.ID] = len(merged.Tool.Driver.Rules)
+					merged.Tool.Driver.Rules = append(merged.Tool.Driver.Rules, rule)
+				}
+			}
+			for _, inv := range run.Invocations {
+				if !inv.ExecutionSuccessful {
+					merged.Invocations[0].ExecutionSuccessful = false
+				}
+				merged.Invocations[0].ToolExecutionNotifications = append(merged.Invocations[0].ToolExecutionNotifications, inv.ToolExecutionNotifications...)
+			}
+			for _, res := range run.Results {
+				res.RuleIndex = index[res.RuleID]
+				key, err := json.Marshal(res)
+				if err != nil {
+					return nil, err
+				}
+				if seen[string(key)] {
+					continue // duplicate, e.g. from foo and foo.test
+				}
+				seen[string(key)] = true
+				merged.Results = append(merged.Results, res)
+			}
+		}
+	}
+	return &SARIFDocument{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []SARIFRun{merged},
+	}, nil
+}
+
+func sarifLocation(fset *token.FileSet, pos, end token.Pos) SARIFLocation {
+	posn := fset.Position(pos)
+	return SARIFLocation{
+		PhysicalLocation: SARIFPhysicalLocation{
+			ArtifactLocation: SARIFArtifactLocation{URI: sarifURI(posn.Filename)},
+			Region:           sarifRegion(fset, pos, end),
+		},
+	}
+}
+
+// sarifRegion returns the region for the interval [pos, end).
+// If end is invalid, the region is the single point pos.
+func sarifRegion(fset *token.FileSet, pos, end token.Pos) SARIFRegion {
+	start := fset.Position(pos)
+	stop := start
+	if end.IsValid() {
+		stop = fset.Position(end)
+	}
+	region := SARIFRegion{
+		StartLine:   start.Line,
+		StartColumn: start.Column,
+		EndLine:     stop.Line,
+		EndColumn:   stop.Column,
+	}
+	if start.IsValid() {
+		offset, length := start.Offset, stop.Offset-start.Offset
+		region.ByteOffset, region.ByteLength = &offset, &length
+	}
+	return region
+}
+
+func sarifFix(fset *token.FileSet, fix analysis.SuggestedFix) SARIFFix {
+	var changes []SARIFArtifactChange
+	index := make(map[string]int) // maps URI to index in changes
+	for _, edit := range fix.TextEdits {
+		uri := sarifURI(fset.Position(edit.Pos).Filename)
+		i, ok := index[uri]
+		if !ok {
+			i = len(changes)
+			index[uri] = i
+			changes = append(changes, SARIFArtifactChange{
+				ArtifactLocation: SARIFArtifactLocation{URI: uri},
+			})
+		}
+		repl := SARIFReplacement{DeletedRegion: sarifRegion(fset, edit.Pos, edit.End)}
+		if len(edit.NewText) > 0 {
+			repl.InsertedContent = &SARIFArtifactContent{Text: string(edit.NewText)}
+		}
+		changes[i].Replacements = append(changes[i].Replacements, repl)
+	}
+	return SARIFFix{
+		Description:     SARIFMessage{Text: fix.Message},
+		ArtifactChanges: changes,
+	}
+}
+
+// sarifURI returns the URI of the named file: a file URI
+// if the name is absolute, or a relative reference otherwise.
+func sarifURI(filename string) string {
+	if filename == "" {
+		return ""
+	}
+	path := filepath.ToSlash(filename)
+	if !filepath.IsAbs(filename) {
+		return (&url.URL{Path: path}).String()
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
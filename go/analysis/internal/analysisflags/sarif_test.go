@@ -0,0 +1,145 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package analysisflags_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/token"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/internal/analysisflags"
+)
+
+func TestSARIF(t *testing.T) {
+	fset := token.NewFileSet()
+	f := fset.AddFile("/src/a/a.go", -1, 100)
+	f.SetLines([]int{0, 10, 20, 30})
+	pos := func(offset int) token.Pos { return f.Pos(offset) }
+
+	a1 := &analysis.Analyzer{Name: "a1", Doc: "check a1\n\nMore about a1."}
+	a2 := &analysis.Analyzer{Name: "a2", Doc: "check a2"}
+
+	diag := analysis.Diagnostic{
+		Pos:      pos(12),
+		End:      pos(15),
+		Category: "cat",
+		Message:  "bad thing",
+		Related:  []analysis.RelatedInformation{{Pos: pos(21), Message: "declared here"}},
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   "fix it",
+			TextEdits: []analysis.TextEdit{{Pos: pos(12), End: pos(15), NewText: []byte("good")}},
+		}},
+	}
+
+	var log analysisflags.SARIFLog
+	log.Add(fset, "a", a1, []analysis.Diagnostic{diag}, nil)
+	log.Add(fset, "a [a.test]", a1, []analysis.Diagnostic{diag}, nil) // duplicate
+	log.Add(fset, "a", a2, nil, errors.New("oops"))
+
+	// Check that the output is well-formed JSON.
+	var buf bytes.Buffer
+	log.Fprint(&buf)
+	var doc analysisflags.SARIFDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.Bytes())
+	}
+
+	if doc.Version != "2.1.0" {
+		t.Errorf("version = %q, want 2.1.0", doc.Version)
+	}
+	if len(doc.Runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(doc.Runs))
+	}
+	run := doc.Runs[0]
+
+	rules := run.Tool.Driver.Rules
+	if len(rules) != 2 || rules[0].ID != "a1" || rules[1].ID != "a2" {
+		t.Fatalf("rules = %+v, want [a1 a2]", rules)
+	}
+	if got := rules[0].ShortDescription.Text; got != "check a1" {
+		t.Errorf("a1 short description = %q, want %q", got, "check a1")
+	}
+
+	if len(run.Results) != 1 {
+		t.Fatalf("got %d results, want 1 (duplicates should be discarded)", len(run.Results))
+	}
+	res := run.Results[0]
+	if res.RuleID != "a1" || res.RuleIndex != 0 || res.Message.Text != "bad thing" {
+		t.Errorf("result = %+v", res)
+	}
+	loc := res.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "file:///src/a/a.go" {
+		t.Errorf("uri = %q", loc.ArtifactLocation.URI)
+	}
+	if r := loc.Region; r.StartLine != 2 || r.StartColumn != 3 || r.EndColumn != 6 || *r.ByteOffset != 12 || *r.ByteLength != 3 {
+		t.Errorf("region = %+v", r)
+	}
+	if len(res.RelatedLocations) != 1 || res.RelatedLocations[0].Message.Text != "declared here" {
+		t.Errorf("related = %+v", res.RelatedLocations)
+	}
+	if len(res.Fixes) != 1 {
+		t.Fatalf("got %d fixes, want 1", len(res.Fixes))
+	}
+	repl := res.Fixes[0].ArtifactChanges[0].Replacements[0]
+	if repl.InsertedContent.Text != "good" || *repl.DeletedRegion.ByteOffset != 12 {
+		t.Errorf("replacement = %+v", repl)
+	}
+
+	inv := run.Invocations[0]
+	if inv.ExecutionSuccessful || len(inv.ToolExecutionNotifications) != 1 {
+		t.Errorf("invocation = %+v, want one error notification", inv)
+	}
+}
+
+func TestMergeSARIF(t *testing.T) {
+	fset := token.NewFileSet()
+	f := fset.AddFile("/src/a/a.go", -1, 100)
+	f.SetLines([]int{0, 10, 20, 30})
+	diag := analysis.Diagnostic{Pos: f.Pos(12), End: f.Pos(15), Message: "bad thing"}
+
+	a1 := &analysis.Analyzer{Name: "a1", Doc: "check a1"}
+	a2 := &analysis.Analyzer{Name: "a2", Doc: "check a2"}
+
+	// Simulate the output of go vet, which analyzes
+	// each package, and its test variant, separately.
+	var out bytes.Buffer
+	var log1, log2, log3 analysisflags.SARIFLog
+	out.WriteString("# a\n")
+	log1.Add(fset, "a", a1, []analysis.Diagnostic{diag}, nil)
+	log1.Fprint(&out)
+	out.WriteString("# a [a.test]\n")
+	log2.Add(fset, "a [a.test]", a1, []analysis.Diagnostic{diag}, nil) // duplicate
+	log2.Fprint(&out)
+	out.WriteString("# b\n")
+	log3.Add(fset, "b", a2, []analysis.Diagnostic{diag}, nil)
+	log3.Add(fset, "b", a1, nil, errors.New("oops"))
+	log3.Fprint(&out)
+
+	doc, err := analysisflags.MergeSARIF(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(doc.Runs))
+	}
+	run := doc.Runs[0]
+	rules := run.Tool.Driver.Rules
+	if len(rules) != 2 || rules[0].ID != "a1" || rules[1].ID != "a2" {
+		t.Fatalf("rules = %+v, want [a1 a2]", rules)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2 (duplicates should be discarded)", len(run.Results))
+	}
+	if res := run.Results[1]; res.RuleID != "a2" || res.RuleIndex != 1 {
+		t.Errorf("second result = %+v, want rule a2 at index 1", res)
+	}
+	inv := run.Invocations[0]
+	if inv.ExecutionSuccessful || len(inv.ToolExecutionNotifications) != 1 {
+		t.Errorf("invocation = %+v, want one error notification", inv)
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+// This file defines the suppression of diagnostics, either by
+// //lint:ignore directives in the source or by a baseline file.
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// A baselineKey identifies a diagnostic in a way that is robust to
+// changes that merely shift line numbers: by analyzer, package path,
+// enclosing top-level declaration, and message.
+type baselineKey struct {
+	Analyzer string `json:"analyzer"`
+	Package  string `json:"package"`
+	Decl     string `json:"decl,omitempty"`
+	Message  string `json:"message"`
+}
+
+// A baselineEntry records the number of diagnostics with a given key.
+type baselineEntry struct {
+	baselineKey
+	Count int `json:"count"`
+}
+
+// baselineFile is the JSON encoding of a baseline file.
+type baselineFile struct {
+	Diagnostics []baselineEntry `json:"diagnostics"`
+}
+
+// suppressDiagnostics removes from each root action the diagnostics
+// that are suppressed by //lint:ignore directives, and then, if
+// the -baseline flag is set, those recorded in the baseline file.
+//
+// If the baseline file does not yet exist, it is created and
+// populated with the remaining diagnostics, all of which are
+// then suppressed; subsequent runs report only new diagnostics.
+func suppressDiagnostics(roots []*action) error {
+	// The same diagnostic may be reported by more than one root
+	// action for a source file that belongs to several packages,
+	// such as foo and foo.test; treat such duplicates as one.
+	type posKey struct {
+		pos, end token.Position
+		*analysis.Analyzer
+		message string
+	}
+	type item struct {
+		act  *action
+		diag analysis.Diagnostic
+		pos  posKey
+		key  baselineKey
+	}
+	var items []item
+	first := make(map[posKey]int) // index in items of first occurrence
+	for _, act := range roots {
+		ignores := lintIgnores(act.pkg.Fset, act.pkg.Syntax)
+		for _, diag := range act.diagnostics {
+			if ignores.suppresses(act.pkg.Fset, act.a.Name, diag.Pos) {
+				continue
+			}
+			k := posKey{act.pkg.Fset.Position(diag.Pos), act.pkg.Fset.Position(diag.End), act.a, diag.Message}
+			if _, ok := first[k]; !ok {
+				first[k] = len(items)
+			}
+			items = append(items, item{
+				act:  act,
+				diag: diag,
+				pos:  k,
+				key: baselineKey{
+					Analyzer: act.a.Name,
+					Package:  act.pkg.PkgPath,
+					Decl:     enclosingDecl(act.pkg.Syntax, diag.Pos),
+					Message:  diag.Message,
+				},
+			})
+		}
+	}
+
+	// Determine which diagnostics to keep.
+	keep := make([]bool, len(items))
+	for i := range keep {
+		keep[i] = true
+	}
+	if Baseline != "" {
+		baseline, err := readBaseline(Baseline)
+		if os.IsNotExist(err) {
+			// Record the current diagnostics as the baseline.
+			counts := make(map[baselineKey]int)
+			for i, it := range items {
+				if first[it.pos] == i {
+					counts[it.key]++
+				}
+			}
+			if err := writeBaseline(Baseline, counts); err != nil {
+				return err
+			}
+			baseline = counts
+		} else if err != nil {
+			return err
+		}
+
+		// Each baseline entry suppresses at most Count diagnostics
+		// with the same key; any excess is new.
+		for i, it := range items {
+			if j := first[it.pos]; j != i {
+				keep[i] = keep[j] // duplicate
+				continue
+			}
+			if baseline[it.key] > 0 {
+				baseline[it.key]--
+				keep[i] = false
+			}
+		}
+	}
+
+	// Rebuild the diagnostics of each root action.
+	for _, act := range roots {
+		act.diagnostics = nil
+	}
+	for i, it := range items {
+		if keep[i] {
+			it.act.diagnostics = append(it.act.diagnostics, it.diag)
+		}
+	}
+	return nil
+}
+
+// readBaseline reads the named baseline file and
+// returns the count of diagnostics for each key.
+func readBaseline(filename string) (map[baselineKey]int, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file baselineFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot decode baseline file %s: %v", filename, err)
+	}
+	counts := make(map[baselineKey]int)
+	for _, e := range file.Diagnostics {
+		counts[e.baselineKey] += e.Count
+	}
+	return counts, nil
+}
+
+// writeBaseline writes the specified diagnostic counts to the named
+// baseline file, in a deterministic order.
+func writeBaseline(filename string, counts map[baselineKey]int) error {
+	file := baselineFile{Diagnostics: []baselineEntry{}}
+	for k, n := range counts {
+		file.Diagnostics = append(file.Diagnostics, baselineEntry{k, n})
+	}
+	sort.Slice(file.Diagnostics, func(i, j int) bool {
+		x, y := file.Diagnostics[i], file.Diagnostics[j]
+		if x.Analyzer != y.Analyzer {
+			return x.Analyzer < y.Analyzer
+		}
+		if x.Package != y.Package {
+			return x.Package < y.Package
+		}
+		if x.Decl != y.Decl {
+			return x.Decl < y.Decl
+		}
+		return x.Message < y.Message
+	})
+	data, err := json.MarshalIndent(file, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0666)
+}
+
+// enclosingDecl returns a name for the top-level declaration
+// enclosing pos, such as "F", "T.M", or "T", or "" if there is none.
+func enclosingDecl(files []*ast.File, pos token.Pos) string {
+	for _, f := range files {
+		if !(f.Pos() <= pos && pos <= f.End()) {
+			continue
+		}
+		for _, decl := range f.Decls {
+			if !(decl.Pos() <= pos && pos <= decl.End()) {
+				continue
+			}
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv != nil && len(decl.Recv.List) > 0 {
+					return recvTypeName(decl.Recv.List[0].Type) + "." + decl.Name.Name
+				}
+				return decl.Name.Name
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if !(spec.Pos() <= pos && pos <= spec.End()) {
+						continue
+					}
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						return spec.Name.Name
+					case *ast.ValueSpec:
+						var names []string
+						for _, id := range spec.Names {
+							names = append(names, id.Name)
+						}
+						return strings.Join(names, ",")
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// recvTypeName returns the name of the named type
+// of a method receiver expression such as *T or T[P].
+func recvTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			x, _, _, _ := typeparams.UnpackIndexExpr(expr)
+			if x == nil {
+				return ""
+			}
+			expr = x
+		}
+	}
+}
+
+// A lintIgnoreSet records, for each file, the //lint:ignore and
+// //lint:file-ignore directives that it contains.
+type lintIgnoreSet map[*token.File][]lintIgnore
+
+// A lintIgnore is a single parsed directive of the form
+//
+//	//lint:ignore analyzer[,analyzer...] reason
+//	//lint:file-ignore analyzer[,analyzer...] reason
+//
+// A lint:ignore directive suppresses diagnostics on its own line and
+// on the following line; a lint:file-ignore directive suppresses
+// diagnostics throughout the file. The reason is mandatory.
+type lintIgnore struct {
+	line      int // line of directive, or 0 for file-ignore
+	analyzers []string
+}
+
+// lintIgnores returns the set of directives in the specified files.
+func lintIgnores(fset *token.FileSet, files []*ast.File) lintIgnoreSet {
+	set := make(lintIgnoreSet)
+	for _, f := range files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				var fileWide bool
+				var rest string
+				switch {
+				case strings.HasPrefix(c.Text, "//lint:ignore "):
+					rest = strings.TrimPrefix(c.Text, "//lint:ignore ")
+				case strings.HasPrefix(c.Text, "//lint:file-ignore "):
+					rest = strings.TrimPrefix(c.Text, "//lint:file-ignore ")
+					fileWide = true
+				default:
+					continue
+				}
+				fields := strings.Fields(rest)
+				if len(fields) < 2 {
+					continue // missing reason
+				}
+				tf := fset.File(c.Pos())
+				ig := lintIgnore{analyzers: strings.Split(fields[0], ",")}
+				if !fileWide {
+					ig.line = tf.Line(c.Pos())
+				}
+				set[tf] = append(set[tf], ig)
+			}
+		}
+	}
+	return set
+}
+
+// suppresses reports whether a diagnostic from the named analyzer
+// at pos is suppressed by a directive.
+func (set lintIgnoreSet) suppresses(fset *token.FileSet, analyzer string, pos token.Pos) bool {
+	tf := fset.File(pos)
+	if tf == nil {
+		return false
+	}
+	line := tf.Line(pos)
+	for _, ig := range set[tf] {
+		if ig.line != 0 && ig.line != line && ig.line != line-1 {
+			continue
+		}
+		for _, name := range ig.analyzers {
+			if name == analyzer {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checker_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/internal/checker"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestBaseline(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	files := map[string]string{
+		"baseline/test.go": `package baseline
+
+func Foo() {
+	bar := 12
+	_ = bar
+}
+`}
+	testdata, cleanup, err := analysistest.WriteFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	path := filepath.Join(testdata, "src/baseline/test.go")
+	baseline := filepath.Join(testdata, "baseline.json")
+
+	defer func(fix bool, baseline string) {
+		checker.Fix, checker.Baseline = fix, baseline
+	}(checker.Fix, checker.Baseline)
+	checker.Fix = false
+	checker.Baseline = baseline
+
+	run := func() int {
+		return checker.Run([]string{"file=" + path}, []*analysis.Analyzer{analyzer})
+	}
+
+	// The first run records the existing diagnostics.
+	if got := run(); got != 0 {
+		t.Errorf("first run: got exit code %d, want 0", got)
+	}
+	data, err := ioutil.ReadFile(baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"analyzer": "rename"`, `"decl": "Foo"`, `"count": 2`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("baseline file does not contain %s:\n%s", want, data)
+		}
+	}
+
+	// Shifting lines does not cause diagnostics to be reported.
+	shifted := `package baseline
+
+// Foo has moved.
+
+func Foo() {
+	bar := 12
+	_ = bar
+}
+`
+	if err := ioutil.WriteFile(path, []byte(shifted), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if got := run(); got != 0 {
+		t.Errorf("after shift: got exit code %d, want 0", got)
+	}
+
+	// A new diagnostic is reported.
+	if err := ioutil.WriteFile(path, []byte(shifted+"\nfunc Bar() { bar := 1; _ = bar }\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if got := run(); got != 3 {
+		t.Errorf("after addition: got exit code %d, want 3", got)
+	}
+}
+
+func TestLintIgnore(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	files := map[string]string{
+		"ignore/test.go": `package ignore
+
+func Foo() {
+	//lint:ignore rename we like this name
+	bar := 12
+	_ = bar //lint:ignore other,rename for reasons
+}
+
+func Bar() {
+	//lint:ignore rename
+	bar := 12 // not suppressed: no reason
+	_ = bar
+}
+`,
+		"fileignore/test.go": `//lint:file-ignore rename generated code
+
+package fileignore
+
+func Foo() {
+	bar := 12
+	_ = bar
+}
+`}
+	testdata, cleanup, err := analysistest.WriteFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	defer func(fix bool) { checker.Fix = fix }(checker.Fix)
+	checker.Fix = false
+
+	for _, test := range []struct {
+		dir  string
+		code int
+	}{
+		{"ignore", 3}, // diagnostics in Bar remain
+		{"fileignore", 0},
+	} {
+		path := filepath.Join(testdata, "src", test.dir, "test.go")
+		if got := checker.Run([]string{"file=" + path}, []*analysis.Analyzer{analyzer}); got != test.code {
+			t.Errorf("%s: got exit code %d, want %d", test.dir, got, test.code)
+		}
+	}
+}
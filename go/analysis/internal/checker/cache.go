@@ -0,0 +1,488 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+// This file defines a persistent cache of the outputs (diagnostics
+// and facts) of analysis actions, enabled by the -cache flag.
+//
+// Each action is identified by a content-addressed key derived from
+// the identity of its analyzer (name, flags, and executable), the
+// contents of its package and, transitively, of the packages it
+// imports, and the keys of the actions on which it depends, which
+// determine the facts it imports. So, when the sources of a package
+// change, the cached outputs of all actions on that package and on
+// the packages that import it, directly or indirectly, are invalidated.
+//
+// Only actions whose in-memory results are not required by another
+// analyzer may be satisfied from the cache, since results cannot be
+// serialized. When such an action is found in the cache, its
+// prerequisite analyzers are not run at all.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/objectpath"
+)
+
+// A cacheKey is the SHA-256 hash that identifies an action in the cache.
+type cacheKey [sha256.Size]byte
+
+func (k cacheKey) String() string { return hex.EncodeToString(k[:]) }
+
+// cacheEntry is the gob-encoded form of the outputs of an action.
+type cacheEntry struct {
+	Diagnostics  []cachedDiagnostic
+	ObjectFacts  []cachedObjectFact
+	PackageFacts []analysis.Fact
+}
+
+type cachedObjectFact struct {
+	Path objectpath.Path
+	Fact analysis.Fact
+}
+
+// A cachedPos records a token.Pos as a file name and byte offset.
+// The zero value represents token.NoPos.
+type cachedPos struct {
+	File   string
+	Offset int
+}
+
+type cachedDiagnostic struct {
+	Pos, End       cachedPos
+	Category       string
+	Message        string
+	SuggestedFixes []cachedSuggestedFix
+	Related        []cachedRelatedInformation
+}
+
+type cachedSuggestedFix struct {
+	Message   string
+	TextEdits []cachedTextEdit
+}
+
+type cachedTextEdit struct {
+	Pos, End cachedPos
+	NewText  []byte
+}
+
+type cachedRelatedInformation struct {
+	Pos, End cachedPos
+	Message  string
+}
+
+// prepareCache computes the cache key of each action
+// that may be satisfied from the cache.
+func prepareCache(actions []*action) error {
+	// Fact types must be registered with gob
+	// since they are encoded as interface values.
+	for _, act := range actions {
+		for _, f := range act.a.FactTypes {
+			gob.Register(f)
+		}
+	}
+
+	// An action whose result is consumed by
+	// another analyzer cannot be cached.
+	needed := make(map[*action]bool)
+	for _, act := range actions {
+		for _, dep := range act.deps {
+			if dep.pkg == act.pkg {
+				needed[dep] = true
+			}
+		}
+	}
+
+	exe, err := executableHash()
+	if err != nil {
+		return err
+	}
+	pkgHashes := make(map[*packages.Package]cacheKey)
+	actionKeys := make(map[*action]cacheKey)
+	var actionKey func(act *action) (cacheKey, error)
+	actionKey = func(act *action) (cacheKey, error) {
+		if k, ok := actionKeys[act]; ok {
+			return k, nil
+		}
+		h := sha256.New()
+		fmt.Fprintf(h, "analyzer %s\n", act.a.Name)
+		h.Write(exe[:])
+		act.a.Flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(h, "flag %s=%s\n", f.Name, f.Value)
+		})
+		pk, err := packageHash(act.pkg, pkgHashes)
+		if err != nil {
+			return cacheKey{}, err
+		}
+		h.Write(pk[:])
+		for _, dep := range act.deps {
+			dk, err := actionKey(dep)
+			if err != nil {
+				return cacheKey{}, err
+			}
+			h.Write(dk[:])
+		}
+		var k cacheKey
+		h.Sum(k[:0])
+		actionKeys[act] = k
+		return k, nil
+	}
+	keys := make(map[*action]*cacheKey)
+	for _, act := range actions {
+		if !needed[act] {
+			k, err := actionKey(act)
+			if err != nil {
+				return err
+			}
+			keys[act] = &k
+		}
+	}
+	files := &fileIndex{index: make(map[*token.FileSet]map[string]*token.File)}
+	for act, k := range keys {
+		act.cacheKey = k
+		act.files = files
+	}
+	return nil
+}
+
+// packageHash returns a hash of the identity and contents
+// of the package and, transitively, its imports.
+func packageHash(pkg *packages.Package, memo map[*packages.Package]cacheKey) (cacheKey, error) {
+	if k, ok := memo[pkg]; ok {
+		return k, nil
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "go %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	for _, env := range []string{"GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED"} {
+		fmt.Fprintf(h, "env %s=%s\n", env, os.Getenv(env))
+	}
+	fmt.Fprintf(h, "package %s %s %t\n", pkg.ID, pkg.PkgPath, pkg.IllTyped)
+	for _, files := range [][]string{pkg.GoFiles, pkg.CompiledGoFiles, pkg.OtherFiles, pkg.IgnoredFiles} {
+		fmt.Fprintf(h, "files %d\n", len(files))
+		for _, name := range files {
+			if err := hashFile(h, name); err != nil {
+				return cacheKey{}, err
+			}
+		}
+	}
+	paths := make([]string, 0, len(pkg.Imports))
+	for path := range pkg.Imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths) // for determinism
+	for _, path := range paths {
+		dk, err := packageHash(pkg.Imports[path], memo)
+		if err != nil {
+			return cacheKey{}, err
+		}
+		fmt.Fprintf(h, "import %s %s\n", path, dk)
+	}
+	var k cacheKey
+	h.Sum(k[:0])
+	memo[pkg] = k
+	return k, nil
+}
+
+// hashFile writes the name and contents of the named file to w.
+func hashFile(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintf(w, "file %s\n", name)
+	ch := sha256.New()
+	if _, err := io.Copy(ch, f); err != nil {
+		return err
+	}
+	w.Write(ch.Sum(nil))
+	return nil
+}
+
+var exeHash struct {
+	once sync.Once
+	hash cacheKey
+	err  error
+}
+
+// executableHash returns a hash of the contents of the current
+// executable, so that changes to the analyzers invalidate the cache.
+func executableHash() (cacheKey, error) {
+	exeHash.once.Do(func() {
+		exe, err := os.Executable()
+		if err != nil {
+			exeHash.err = err
+			return
+		}
+		f, err := os.Open(exe)
+		if err != nil {
+			exeHash.err = err
+			return
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			exeHash.err = err
+			return
+		}
+		h.Sum(exeHash.hash[:0])
+	})
+	return exeHash.hash, exeHash.err
+}
+
+// cacheFile returns the name of the file holding the entry for key.
+func cacheFile(key cacheKey) string {
+	s := key.String()
+	return filepath.Join(CacheDir, s[:2], s)
+}
+
+// loadFromCache attempts to populate the outputs of the action
+// from the cache, and reports whether it succeeded.
+// Only the action's dependencies on other packages, from which
+// it inherits facts, are executed.
+func (act *action) loadFromCache() bool {
+	if act.cacheKey == nil {
+		return false
+	}
+	data, err := ioutil.ReadFile(cacheFile(*act.cacheKey))
+	if err != nil {
+		return false // miss
+	}
+	var entry cacheEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		if dbg('v') {
+			log.Printf("%v: invalid cache entry: %v", act, err)
+		}
+		return false
+	}
+
+	var vdeps []*action
+	for _, dep := range act.deps {
+		if dep.pkg != act.pkg {
+			vdeps = append(vdeps, dep)
+		}
+	}
+	execAll(vdeps)
+	for _, dep := range vdeps {
+		if dep.err != nil {
+			return false // report the failure in the usual way
+		}
+	}
+
+	objectFacts := make(map[objectFactKey]analysis.Fact)
+	packageFacts := make(map[packageFactKey]analysis.Fact)
+	for _, f := range entry.ObjectFacts {
+		obj, err := objectpath.Object(act.pkg.Types, f.Path)
+		if err != nil {
+			return false
+		}
+		objectFacts[objectFactKey{obj, factType(f.Fact)}] = f.Fact
+	}
+	for _, f := range entry.PackageFacts {
+		packageFacts[packageFactKey{act.pkg.Types, factType(f)}] = f
+	}
+
+	var diagnostics []analysis.Diagnostic
+	ok := true
+	pos := func(p cachedPos) token.Pos {
+		if p.File == "" {
+			return token.NoPos
+		}
+		f := act.files.fileByName(act.pkg.Fset, p.File)
+		if f == nil || p.Offset > f.Size() {
+			ok = false
+			return token.NoPos
+		}
+		return f.Pos(p.Offset)
+	}
+	for _, cd := range entry.Diagnostics {
+		diag := analysis.Diagnostic{
+			Pos:      pos(cd.Pos),
+			End:      pos(cd.End),
+			Category: cd.Category,
+			Message:  cd.Message,
+		}
+		for _, cf := range cd.SuggestedFixes {
+			fix := analysis.SuggestedFix{Message: cf.Message}
+			for _, ce := range cf.TextEdits {
+				fix.TextEdits = append(fix.TextEdits, analysis.TextEdit{
+					Pos:     pos(ce.Pos),
+					End:     pos(ce.End),
+					NewText: ce.NewText,
+				})
+			}
+			diag.SuggestedFixes = append(diag.SuggestedFixes, fix)
+		}
+		for _, cr := range cd.Related {
+			diag.Related = append(diag.Related, analysis.RelatedInformation{
+				Pos:     pos(cr.Pos),
+				End:     pos(cr.End),
+				Message: cr.Message,
+			})
+		}
+		diagnostics = append(diagnostics, diag)
+	}
+	if !ok {
+		return false
+	}
+
+	// Success: inherit the facts of dependencies as usual.
+	act.objectFacts = objectFacts
+	act.packageFacts = packageFacts
+	for _, dep := range vdeps {
+		inheritFacts(act, dep)
+	}
+	act.diagnostics = diagnostics
+	if dbg('v') {
+		log.Printf("%v: cache hit", act)
+	}
+	return true
+}
+
+// saveToCache records the outputs of a successful action in the cache.
+// Failures are not fatal, since the cache is merely an optimization.
+func (act *action) saveToCache() {
+	if act.cacheKey == nil || act.err != nil {
+		return
+	}
+	if err := act.writeCacheEntry(); err != nil && dbg('v') {
+		log.Printf("%v: can't save to cache: %v", act, err)
+	}
+}
+
+func (act *action) writeCacheEntry() error {
+	fset := act.pkg.Fset
+	pos := func(p token.Pos) cachedPos {
+		if !p.IsValid() {
+			return cachedPos{}
+		}
+		f := fset.File(p)
+		return cachedPos{File: f.Name(), Offset: f.Offset(p)}
+	}
+
+	var entry cacheEntry
+	for _, diag := range act.diagnostics {
+		cd := cachedDiagnostic{
+			Pos:      pos(diag.Pos),
+			End:      pos(diag.End),
+			Category: diag.Category,
+			Message:  diag.Message,
+		}
+		for _, fix := range diag.SuggestedFixes {
+			cf := cachedSuggestedFix{Message: fix.Message}
+			for _, edit := range fix.TextEdits {
+				cf.TextEdits = append(cf.TextEdits, cachedTextEdit{
+					Pos:     pos(edit.Pos),
+					End:     pos(edit.End),
+					NewText: edit.NewText,
+				})
+			}
+			cd.SuggestedFixes = append(cd.SuggestedFixes, cf)
+		}
+		for _, rel := range diag.Related {
+			cd.Related = append(cd.Related, cachedRelatedInformation{
+				Pos:     pos(rel.Pos),
+				End:     pos(rel.End),
+				Message: rel.Message,
+			})
+		}
+		entry.Diagnostics = append(entry.Diagnostics, cd)
+	}
+
+	// Record the facts exported by this action. Facts about
+	// objects that have no path (such as local variables)
+	// cannot be observed by importing packages, so are dropped.
+	for key, fact := range act.objectFacts {
+		if key.obj.Pkg() != act.pkg.Types {
+			continue // inherited
+		}
+		path, err := objectpath.For(key.obj)
+		if err != nil {
+			continue
+		}
+		entry.ObjectFacts = append(entry.ObjectFacts, cachedObjectFact{path, fact})
+	}
+	sort.Slice(entry.ObjectFacts, func(i, j int) bool {
+		return entry.ObjectFacts[i].Path < entry.ObjectFacts[j].Path
+	})
+	for key, fact := range act.packageFacts {
+		if key.pkg == act.pkg.Types {
+			entry.PackageFacts = append(entry.PackageFacts, fact)
+		}
+	}
+	sort.Slice(entry.PackageFacts, func(i, j int) bool {
+		return factType(entry.PackageFacts[i]).String() < factType(entry.PackageFacts[j]).String()
+	})
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&entry); err != nil {
+		return err
+	}
+
+	// Write the file atomically.
+	name := cacheFile(*act.cacheKey)
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// A fileIndex maps file names to the files of the file sets of the
+// packages of a single Run, so that positions may be restored from
+// the cache. It is discarded along with the actions that refer to it.
+type fileIndex struct {
+	mu    sync.Mutex
+	index map[*token.FileSet]map[string]*token.File
+}
+
+// fileByName returns the file of the specified name in the file set.
+func (x *fileIndex) fileByName(fset *token.FileSet, name string) *token.File {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	files, ok := x.index[fset]
+	if !ok {
+		files = make(map[string]*token.File)
+		x.index[fset] = files
+	}
+	if f, ok := files[name]; ok {
+		return f
+	}
+	// Re-index the file set, which may have grown.
+	fset.Iterate(func(f *token.File) bool {
+		files[f.Name()] = f
+		return true
+	})
+	return files[name]
+}
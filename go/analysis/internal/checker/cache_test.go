@@ -0,0 +1,122 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checker_test
+
+import (
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/internal/checker"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestCache(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	files := map[string]string{
+		"a/a.go": `package a
+
+func A() {
+	bar := 1
+	_ = bar
+}
+`,
+		"b/b.go": `package b
+
+import "a"
+
+func B() { a.A() }
+`}
+	testdata, cleanup, err := analysistest.WriteFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	// The counter analyzer records the number of packages
+	// it has analyzed, and a fact about each one.
+	var runs int32
+	counter := &analysis.Analyzer{
+		Name:      "counter",
+		Doc:       "report identifiers named bar",
+		FactTypes: []analysis.Fact{new(countFact)},
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			atomic.AddInt32(&runs, 1)
+			n := 0
+			for _, imp := range pass.Pkg.Imports() {
+				var fact countFact
+				if pass.ImportPackageFact(imp, &fact) {
+					n += fact.N
+				}
+			}
+			for _, f := range pass.Files {
+				ast.Inspect(f, func(n ast.Node) bool {
+					if id, ok := n.(*ast.Ident); ok && id.Name == "bar" {
+						pass.Reportf(id.Pos(), "bar")
+					}
+					return true
+				})
+			}
+			pass.ExportPackageFact(&countFact{n + 1})
+			return nil, nil
+		},
+	}
+
+	defer func(fix bool, dir string) {
+		checker.Fix, checker.CacheDir = fix, dir
+	}(checker.Fix, checker.CacheDir)
+	checker.Fix = false
+	checker.CacheDir = t.TempDir()
+
+	// Load packages a and b from the GOPATH.
+	for k, v := range map[string]string{"GOPATH": testdata, "GO111MODULE": "off", "GOPROXY": "off"} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	run := func() (int, int32) {
+		atomic.StoreInt32(&runs, 0)
+		code := checker.Run([]string{"a", "b"}, []*analysis.Analyzer{counter})
+		return code, atomic.LoadInt32(&runs)
+	}
+
+	// The first run analyzes every package.
+	code1, runs1 := run()
+	if code1 != 3 {
+		t.Errorf("first run: got exit code %d, want 3", code1)
+	}
+	if runs1 == 0 {
+		t.Fatalf("first run: analyzer did not run")
+	}
+
+	// The second run is satisfied from the cache,
+	// yet reports the same diagnostics.
+	if code, runs := run(); code != 3 || runs != 0 {
+		t.Errorf("second run: got exit code %d and %d runs, want 3 and 0", code, runs)
+	}
+
+	// Changing package b causes only b to be re-analyzed.
+	if err := ioutil.WriteFile(filepath.Join(testdata, "src/b/b.go"), []byte(`package b
+
+import "a"
+
+func B() { a.A(); a.A() }
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if code, runs := run(); code != 3 || runs != 1 {
+		t.Errorf("after change: got exit code %d and %d runs, want 3 and 1", code, runs)
+	}
+}
+
+type countFact struct{ N int }
+
+func (*countFact) AFact() {}
@@ -25,6 +25,7 @@ import (
 	"runtime/pprof"
 	"runtime/trace"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -53,8 +54,24 @@ var (
 	// IncludeTests indicates whether test files should be analyzed too.
 	IncludeTests = true
 
-	// Fix determines whether to apply all suggested fixes.
+	// Fix determines whether to apply suggested fixes.
 	Fix bool
+
+	// FixAnalyzers, if non-empty, restricts Fix to the
+	// fixes suggested by the named analyzers.
+	FixAnalyzers []string
+
+	// Diff causes suggested fixes to be printed as
+	// unified diffs instead of being applied.
+	Diff bool
+
+	// Baseline is the name of a file of previously reported
+	// diagnostics that should be suppressed.
+	Baseline string
+
+	// CacheDir, if non-empty, is the directory of a persistent
+	// cache of analysis outputs (diagnostics and facts).
+	CacheDir string
 )
 
 // RegisterFlags registers command-line flags used by the analysis driver.
@@ -69,7 +86,10 @@ func RegisterFlags() {
 	flag.StringVar(&Trace, "trace", "", "write trace log to this file")
 	flag.BoolVar(&IncludeTests, "test", IncludeTests, "indicates whether test files should be analyzed, too")
 
-	flag.BoolVar(&Fix, "fix", false, "apply all suggested fixes")
+	flag.Var(fixFlag{}, "fix", "apply suggested fixes (of all analyzers, or of a comma-separated list)")
+	flag.BoolVar(&Diff, "diff", false, "print suggested fixes as unified diffs instead of applying them")
+	flag.StringVar(&Baseline, "baseline", "", "suppress diagnostics recorded in this file (created if absent)")
+	flag.StringVar(&CacheDir, "cache", "", "cache analysis outputs in this directory")
 }
 
 // Run loads the packages specified by args using go/packages,
@@ -145,7 +165,19 @@ func Run(args []string, analyzers []*analysis.Analyzer) (exitcode int) {
 	// Print the results.
 	roots := analyze(initial, analyzers)
 
-	if Fix {
+	// Discard suppressed diagnostics.
+	if err := suppressDiagnostics(roots); err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	if Fix || Diff {
+		for _, name := range FixAnalyzers {
+			if !containsAnalyzer(analyzers, name) {
+				log.Printf("-fix: no analyzer named %q", name)
+				return 1
+			}
+		}
 		if err := applyFixes(roots); err != nil {
 			// Fail when applying fixes failed.
 			log.Print(err)
@@ -155,6 +187,49 @@ func Run(args []string, analyzers []*analysis.Analyzer) (exitcode int) {
 	return printDiagnostics(roots)
 }
 
+// fixFlag is the flag.Value for -fix. Like a boolean flag, it may
+// be specified without a value, but it also accepts a comma-separated
+// list of analyzer names, which enables fixes only from those analyzers.
+type fixFlag struct{}
+
+func (fixFlag) IsBoolFlag() bool { return true }
+
+func (fixFlag) String() string {
+	if len(FixAnalyzers) > 0 {
+		return strings.Join(FixAnalyzers, ",")
+	}
+	return strconv.FormatBool(Fix)
+}
+
+func (fixFlag) Set(s string) error {
+	if b, err := strconv.ParseBool(s); err == nil {
+		Fix, FixAnalyzers = b, nil
+		return nil
+	}
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("want true, false, or a list of analyzers")
+	}
+	Fix, FixAnalyzers = true, names
+	return nil
+}
+
+// containsAnalyzer reports whether the graph of
+// analyzers includes one with the specified name.
+func containsAnalyzer(analyzers []*analysis.Analyzer, name string) bool {
+	for _, a := range analyzers {
+		if a.Name == name || containsAnalyzer(a.Requires, name) {
+			return true
+		}
+	}
+	return false
+}
+
 // typeParseError represents a package load error
 // that is related to typing and parsing.
 type typeParseError struct {
@@ -302,18 +377,57 @@ func analyze(pkgs []*packages.Package, analyzers []*analysis.Analyzer) []*action
 		}
 	}
 
+	// Compute the cache keys of the actions.
+	if CacheDir != "" {
+		all := make([]*action, 0, len(actions))
+		for _, act := range actions {
+			all = append(all, act)
+		}
+		if err := prepareCache(all); err != nil {
+			log.Printf("cache disabled: %v", err)
+		}
+	}
+
 	// Execute the graph in parallel.
 	execAll(roots)
 
 	return roots
 }
 
+// A suggestedFix is a SuggestedFix whose edits have been
+// validated and converted to byte offsets within each file.
+type suggestedFix struct {
+	act     *action
+	pos     token.Pos // position of the diagnostic
+	message string
+	edits   map[robustio.FileID][]diff.Edit
+}
+
+func (fix *suggestedFix) String() string {
+	return fmt.Sprintf("%s: fix %q from %s", fix.act.pkg.Fset.Position(fix.pos), fix.message, fix.act.a.Name)
+}
+
+// applyFixes applies the suggested fixes of the specified actions and
+// their dependencies, or, in -diff mode, prints them as unified diffs.
+//
+// Fixes are applied atomically: a fix is skipped, and reported as
+// such, if any of its edits overlap each other or an edit of a
+// fix from the same or another analyzer that was already accepted.
+// Fixes are considered in order of analyzer (as named by -fix, or
+// else as specified on the command line) and then in the order of
+// their diagnostics, so the result is a three-way merge of the
+// non-conflicting changes of each analyzer.
 func applyFixes(roots []*action) error {
-	// visit all of the actions and accumulate the suggested edits.
+	enabled := make(map[string]bool)
+	for _, name := range FixAnalyzers {
+		enabled[name] = true
+	}
+
+	// visit all of the actions and accumulate the suggested fixes.
 	paths := make(map[robustio.FileID]string)
-	editsByAction := make(map[robustio.FileID]map[*action][]diff.Edit)
+	var fixes []*suggestedFix
 	visited := make(map[*action]bool)
-	var apply func(*action) error
+	var gather func(*action) error
 	var visitAll func(actions []*action) error
 	visitAll = func(actions []*action) error {
 		for _, act := range actions {
@@ -322,7 +436,7 @@ func applyFixes(roots []*action) error {
 				if err := visitAll(act.deps); err != nil {
 					return err
 				}
-				if err := apply(act); err != nil {
+				if err := gather(act); err != nil {
 					return err
 				}
 			}
@@ -330,10 +444,18 @@ func applyFixes(roots []*action) error {
 		return nil
 	}
 
-	apply = func(act *action) error {
-		editsForTokenFile := make(map[*token.File][]diff.Edit)
+	gather = func(act *action) error {
+		if len(enabled) > 0 && !enabled[act.a.Name] {
+			return nil
+		}
 		for _, diag := range act.diagnostics {
 			for _, sf := range diag.SuggestedFixes {
+				fix := &suggestedFix{
+					act:     act,
+					pos:     diag.Pos,
+					message: sf.Message,
+					edits:   make(map[robustio.FileID][]diff.Edit),
+				}
 				for _, edit := range sf.TextEdits {
 					// Validate the edit.
 					// Any error here indicates a bug in the analyzer.
@@ -350,23 +472,19 @@ func applyFixes(roots []*action) error {
 						return fmt.Errorf("analysis %q suggests invalid fix: end (%v) past end of file (%v)",
 							act.a.Name, edit.End, eof)
 					}
+					id, _, err := robustio.GetFileID(file.Name())
+					if err != nil {
+						return err
+					}
+					if _, hasId := paths[id]; !hasId {
+						paths[id] = file.Name()
+					}
 					edit := diff.Edit{Start: file.Offset(edit.Pos), End: file.Offset(edit.End), New: string(edit.NewText)}
-					editsForTokenFile[file] = append(editsForTokenFile[file], edit)
+					fix.edits[id] = append(fix.edits[id], edit)
 				}
+				fixes = append(fixes, fix)
 			}
 		}
-
-		for f, edits := range editsForTokenFile {
-			id, _, err := robustio.GetFileID(f.Name())
-			if err != nil {
-				return err
-			}
-			if _, hasId := paths[id]; !hasId {
-				paths[id] = f.Name()
-				editsByAction[id] = make(map[*action][]diff.Edit)
-			}
-			editsByAction[id][act] = edits
-		}
 		return nil
 	}
 
@@ -374,51 +492,85 @@ func applyFixes(roots []*action) error {
 		return err
 	}
 
-	// Validate and group the edits to each actual file.
-	editsByPath := make(map[string][]diff.Edit)
-	for id, actToEdits := range editsByAction {
-		path := paths[id]
-		actions := make([]*action, 0, len(actToEdits))
-		for act := range actToEdits {
-			actions = append(actions, act)
+	// Sort the fixes by analyzer. The analyzers of dependencies
+	// that are not roots come last.
+	order := make(map[string]int)
+	for _, name := range FixAnalyzers {
+		if _, ok := order[name]; !ok {
+			order[name] = len(order)
 		}
-
-		// Does any action create conflicting edits?
-		for _, act := range actions {
-			edits := actToEdits[act]
-			if _, invalid := validateEdits(edits); invalid > 0 {
-				name, x, y := act.a.Name, edits[invalid-1], edits[invalid]
-				return diff3Conflict(path, name, name, []diff.Edit{x}, []diff.Edit{y})
+	}
+	if len(order) == 0 {
+		for _, act := range roots {
+			if _, ok := order[act.a.Name]; !ok {
+				order[act.a.Name] = len(order)
 			}
 		}
+	}
+	rank := func(fix *suggestedFix) int {
+		if i, ok := order[fix.act.a.Name]; ok {
+			return i
+		}
+		return len(order)
+	}
+	sort.SliceStable(fixes, func(i, j int) bool { return rank(fixes[i]) < rank(fixes[j]) })
 
-		// Does any pair of different actions create edits that conflict?
-		for j := range actions {
-			for k := range actions[:j] {
-				x, y := actions[j], actions[k]
-				if x.a.Name > y.a.Name {
-					x, y = y, x
+	// Accept each fix that conflicts neither with itself
+	// nor with any previously accepted fix.
+	type owned struct {
+		edit diff.Edit
+		fix  *suggestedFix
+	}
+	accepted := make(map[robustio.FileID][]owned)
+	skipped := make(map[string]bool) // reports of skipped fixes, for de-duplication
+	skip := func(fix *suggestedFix, reason string) {
+		msg := fmt.Sprintf("%s: skipped fix %q from %s: %s", fix.act.pkg.Fset.Position(fix.pos), fix.message, fix.act.a.Name, reason)
+		if !skipped[msg] {
+			skipped[msg] = true
+			fmt.Fprintln(os.Stderr, msg)
+		}
+	}
+fixes:
+	for _, fix := range fixes {
+		for id, edits := range fix.edits {
+			edits, _ = validateEdits(edits) // remove duplicates
+			fix.edits[id] = edits
+			for i := range edits {
+				for j := range edits[:i] {
+					if editsConflict(edits[i], edits[j]) {
+						skip(fix, "it contains overlapping edits to "+paths[id])
+						continue fixes
+					}
 				}
-				xedits, yedits := actToEdits[x], actToEdits[y]
-				combined := append(xedits, yedits...)
-				if _, invalid := validateEdits(combined); invalid > 0 {
-					// TODO: consider applying each action's consistent list of edits entirely,
-					// and then using a three-way merge (such as GNU diff3) on the resulting
-					// files to report more precisely the parts that actually conflict.
-					return diff3Conflict(path, x.a.Name, y.a.Name, xedits, yedits)
+				for _, prev := range accepted[id] {
+					if editsConflict(edits[i], prev.edit) {
+						skip(fix, fmt.Sprintf("it conflicts with %s", prev.fix))
+						continue fixes
+					}
 				}
 			}
 		}
-
-		var edits []diff.Edit
-		for act := range actToEdits {
-			edits = append(edits, actToEdits[act]...)
+		for id, edits := range fix.edits {
+			for _, edit := range edits {
+				accepted[id] = append(accepted[id], owned{edit, fix})
+			}
 		}
-		editsByPath[path], _ = validateEdits(edits) // remove duplicates. already validated.
 	}
 
 	// Now we've got a set of valid edits for each file. Apply them.
-	for path, edits := range editsByPath {
+	var ids []robustio.FileID
+	for id := range accepted {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return paths[ids[i]] < paths[ids[j]] })
+	for _, id := range ids {
+		path := paths[id]
+		var edits []diff.Edit
+		for _, o := range accepted[id] {
+			edits = append(edits, o.edit)
+		}
+		edits, _ = validateEdits(edits) // remove duplicates. already validated.
+
 		contents, err := ioutil.ReadFile(path)
 		if err != nil {
 			return err
@@ -434,6 +586,16 @@ func applyFixes(roots []*action) error {
 			out = formatted
 		}
 
+		if Diff {
+			old, new := string(contents), string(out)
+			unified, err := diff.ToUnified(path+".orig", path, old, diff.Strings(old, new))
+			if err != nil {
+				return err
+			}
+			fmt.Print(unified)
+			continue
+		}
+
 		if err := ioutil.WriteFile(path, out, 0644); err != nil {
 			return err
 		}
@@ -441,6 +603,20 @@ func applyFixes(roots []*action) error {
 	return nil
 }
 
+// editsConflict reports whether edits x and y, which apply to the
+// same file, cannot both be applied. Identical edits do not conflict;
+// nor do adjacent ones, except for two insertions at the same point,
+// whose relative order would be ambiguous.
+func editsConflict(x, y diff.Edit) bool {
+	if x == y {
+		return false
+	}
+	if x.Start == x.End && y.Start == y.End {
+		return x.Start == y.Start
+	}
+	return x.Start < y.End && y.Start < x.End
+}
+
 // validateEdits returns a list of edits that is sorted and
 // contains no duplicate edits. Returns the index of some
 // overlapping adjacent edits if there is one and <0 if the
@@ -471,36 +647,14 @@ func validateEdits(edits []diff.Edit) ([]diff.Edit, int) {
 	return unique, invalid
 }
 
-// diff3Conflict returns an error describing two conflicting sets of
-// edits on a file at path.
-func diff3Conflict(path string, xlabel, ylabel string, xedits, yedits []diff.Edit) error {
-	contents, err := ioutil.ReadFile(path)
-	if err != nil {
-		return err
-	}
-	oldlabel, old := "base", string(contents)
-
-	xdiff, err := diff.ToUnified(oldlabel, xlabel, old, xedits)
-	if err != nil {
-		return err
-	}
-	ydiff, err := diff.ToUnified(oldlabel, ylabel, old, yedits)
-	if err != nil {
-		return err
-	}
-
-	return fmt.Errorf("conflicting edits from %s and %s on %s\nfirst edits:\n%s\nsecond edits:\n%s",
-		xlabel, ylabel, path, xdiff, ydiff)
-}
-
-// printDiagnostics prints the diagnostics for the root packages in either
-// plain text or JSON format. JSON format also includes errors for any
-// dependencies.
+// printDiagnostics prints the diagnostics for the root packages in
+// plain text, JSON, or SARIF format. JSON and SARIF formats also
+// include errors for any dependencies.
 //
 // It returns the exitcode: in plain mode, 0 for success, 1 for analysis
 // errors, and 3 for diagnostics. We avoid 2 since the flag package uses
-// it. JSON mode always succeeds at printing errors and diagnostics in a
-// structured form to stdout.
+// it. JSON and SARIF modes always succeed at printing errors and
+// diagnostics in a structured form to stdout.
 func printDiagnostics(roots []*action) (exitcode int) {
 	// Print the output.
 	//
@@ -531,6 +685,18 @@ func printDiagnostics(roots []*action) (exitcode int) {
 		}
 		visitAll(roots)
 		tree.Print()
+	} else if analysisflags.SARIF {
+		// SARIF output
+		var sarif analysisflags.SARIFLog
+		print = func(act *action) {
+			var diags []analysis.Diagnostic
+			if act.isroot {
+				diags = act.diagnostics
+			}
+			sarif.Add(act.pkg.Fset, act.pkg.ID, act.a, diags, act.err)
+		}
+		visitAll(roots)
+		sarif.Print()
 	} else {
 		// plain text output
 
@@ -635,6 +801,8 @@ type action struct {
 	pass         *analysis.Pass
 	isroot       bool
 	deps         []*action
+	cacheKey     *cacheKey  // nil => not cacheable
+	files        *fileIndex // non-nil if cacheable
 	objectFacts  map[objectFactKey]analysis.Fact
 	packageFacts map[packageFactKey]analysis.Fact
 	result       interface{}
@@ -678,6 +846,11 @@ func execAll(actions []*action) {
 func (act *action) exec() { act.once.Do(act.execOnce) }
 
 func (act *action) execOnce() {
+	// Use the cached outputs, if any.
+	if act.loadFromCache() {
+		return
+	}
+
 	// Analyze dependencies.
 	execAll(act.deps)
 
@@ -773,6 +946,8 @@ func (act *action) execOnce() {
 	// disallow calls after Run
 	pass.ExportObjectFact = nil
 	pass.ExportPackageFact = nil
+
+	act.saveToCache()
 }
 
 // inheritFacts populates act.facts with
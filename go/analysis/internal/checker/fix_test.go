@@ -10,8 +10,10 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
 	"testing"
 
 	"golang.org/x/tools/go/analysis"
@@ -208,12 +210,12 @@ func Foo() {
 	if err, ok := err.(*exec.ExitError); ok {
 		exitcode = err.ExitCode() // requires go1.12
 	}
-	const errExitCode = 1
-	if exitcode != errExitCode {
-		t.Errorf("%s: exited %d, want %d", args, exitcode, errExitCode)
+	const diagnosticsExitCode = 3
+	if exitcode != diagnosticsExitCode {
+		t.Errorf("%s: exited %d, want %d", args, exitcode, diagnosticsExitCode)
 	}
 
-	pattern := `conflicting edits from rename and rename on /.*/conflict/foo.go`
+	pattern := `skipped fix .* from rename: it contains overlapping edits to /.*/conflict/foo.go`
 	matched, err := regexp.Match(pattern, out)
 	if err != nil {
 		t.Errorf("error matching pattern %s: %v", pattern, err)
@@ -234,8 +236,8 @@ func Foo() {
 	}
 }
 
-// TestOther ensures that checker.Run reports conflicts from
-// distinct actions correctly.
+// TestOther ensures that checker.Run merges the fixes of
+// distinct actions and reports those that conflict.
 // This test fork/execs the main function above.
 func TestOther(t *testing.T) {
 	oses := map[string]bool{"darwin": true, "linux": true}
@@ -282,12 +284,12 @@ func Foo() {
 	if err, ok := err.(*exec.ExitError); ok {
 		exitcode = err.ExitCode() // requires go1.12
 	}
-	const errExitCode = 1
-	if exitcode != errExitCode {
-		t.Errorf("%s: exited %d, want %d", args, exitcode, errExitCode)
+	const diagnosticsExitCode = 3
+	if exitcode != diagnosticsExitCode {
+		t.Errorf("%s: exited %d, want %d", args, exitcode, diagnosticsExitCode)
 	}
 
-	pattern := `conflicting edits from other and rename on /.*/other/foo.go`
+	pattern := `other/foo.go:4:2: skipped fix .* from other: it conflicts with .*/other/foo.go:4:2: fix .* from rename`
 	matched, err := regexp.Match(pattern, out)
 	if err != nil {
 		t.Errorf("error matching pattern %s: %v", pattern, err)
@@ -295,8 +297,19 @@ func Foo() {
 		t.Errorf("%s: output was=<<%s>>. Expected it to match <<%s>>", args, out, pattern)
 	}
 
-	// No files updated
-	for name, want := range files {
+	// Only the fixes of the first analyzer are applied.
+	fixed := map[string]string{
+		"other/foo.go": `package other
+
+func Foo() {
+	baz := 12
+	_ = baz
+}
+
+// the end
+`,
+	}
+	for name, want := range fixed {
 		path := path.Join(dir, "src", name)
 		contents, err := ioutil.ReadFile(path)
 		if err != nil {
@@ -307,3 +320,150 @@ func Foo() {
 		}
 	}
 }
+
+// TestFixSelection ensures that -fix=name applies
+// only the fixes of the named analyzers.
+func TestFixSelection(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	files := map[string]string{
+		"other/foo.go": `package other
+
+func Foo() {
+	bar := 12
+	_ = bar
+}
+`}
+	want := `package other
+
+func Foo() {
+	bbaz := 12
+	_ = bbaz
+}
+`
+	testdata, cleanup, err := analysistest.WriteFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	defer func(fix bool, names []string) {
+		checker.Fix, checker.FixAnalyzers = fix, names
+	}(checker.Fix, checker.FixAnalyzers)
+	checker.Fix, checker.FixAnalyzers = true, []string{"other"}
+
+	path := filepath.Join(testdata, "src/other/foo.go")
+	checker.Run([]string{"file=" + path}, []*analysis.Analyzer{analyzer, other})
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(contents); got != want {
+		t.Errorf("contents of rewritten file\ngot: %s\nwant: %s", got, want)
+	}
+}
+
+// TestFixOrder ensures that the fixes of the analyzers
+// are applied in the order named by -fix.
+func TestFixOrder(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	files := map[string]string{
+		"other/foo.go": `package other
+
+func Foo() {
+	bar := 12
+	_ = bar
+}
+`}
+	want := `package other
+
+func Foo() {
+	bbaz := 12
+	_ = bbaz
+}
+`
+	testdata, cleanup, err := analysistest.WriteFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	defer func(fix bool, names []string) {
+		checker.Fix, checker.FixAnalyzers = fix, names
+	}(checker.Fix, checker.FixAnalyzers)
+	checker.Fix, checker.FixAnalyzers = true, []string{"other", "rename"}
+
+	// The fix of other, named first by -fix, is applied, and the
+	// conflicting fix of rename, named second, is skipped, even
+	// though rename comes first in the list of analyzers.
+	path := filepath.Join(testdata, "src/other/foo.go")
+	checker.Run([]string{"file=" + path}, []*analysis.Analyzer{analyzer, other})
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(contents); got != want {
+		t.Errorf("contents of rewritten file\ngot: %s\nwant: %s", got, want)
+	}
+}
+
+// TestFixDiff ensures that -diff prints fixes
+// as a unified diff without changing any files.
+func TestFixDiff(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	files := map[string]string{
+		"rename/foo.go": `package rename
+
+func Foo() {
+	bar := 12
+	_ = bar
+}
+`}
+	testdata, cleanup, err := analysistest.WriteFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	defer func(fix, diff bool) { checker.Fix, checker.Diff = fix, diff }(checker.Fix, checker.Diff)
+	checker.Fix, checker.Diff = false, true
+
+	// Capture the standard output.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	path := filepath.Join(testdata, "src/rename/foo.go")
+	checker.Run([]string{"file=" + path}, []*analysis.Analyzer{analyzer})
+	os.Stdout = stdout
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"--- " + path + ".orig\n",
+		"+++ " + path + "\n",
+		"-\tbar := 12\n",
+		"+\tbaz := 12\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("diff does not contain %q:\n%s", want, out)
+		}
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(contents); got != files["rename/foo.go"] {
+		t.Errorf("file was modified in -diff mode:\n%s", got)
+	}
+}
@@ -0,0 +1,421 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package deferclose defines an Analyzer that checks for deferred
+// calls to Close on files opened for writing, which discard the
+// error that reports whether the written data reached the file.
+package deferclose
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/cfg"
+	"golang.org/x/tools/go/ssa"
+)
+
+const Doc = `check for deferred Close calls that discard write errors
+
+The deferclose analysis reports deferred calls to the Close method of
+an *os.File that was opened for writing, for example:
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+On many file systems, errors that occur while writing are not reported
+until the file is closed, so discarding the result of Close may silently
+lose data.
+
+Files are considered open for writing if they were created by os.Create,
+os.CreateTemp, or ioutil.TempFile, or opened by os.OpenFile with a
+constant flag that includes O_WRONLY or O_RDWR. A deferred Close is not
+reported if the function also calls Close on the same file and uses the
+result, a common idiom for handling the error on the normal path.
+
+When the enclosing function's last result is an error, a suggested fix
+replaces the deferred call by a closure that reports the error of Close
+through a named result:
+
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "deferclose",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{buildssa.Analyzer, ctrlflow.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+	for _, fn := range ssainput.SrcFuncs {
+		runFunc(pass, cfgs, fn)
+	}
+	return nil, nil
+}
+
+func runFunc(pass *analysis.Pass, cfgs *ctrlflow.CFGs, fn *ssa.Function) {
+	syntax := funcSyntax(pass, fn)
+
+	// Deferred calls run only if the function returns.
+	var g *cfg.CFG
+	switch syntax := syntax.(type) {
+	case *ast.FuncDecl:
+		g = cfgs.FuncDecl(syntax)
+	case *ast.FuncLit:
+		g = cfgs.FuncLit(syntax)
+	}
+	if g == nil || !mayReturn(g) {
+		return
+	}
+
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok || !opensForWriting(call.Common()) {
+				continue
+			}
+			file := fileResult(call)
+			if file == nil {
+				continue
+			}
+			var defers []*ssa.Defer
+			checked := false
+			for _, ref := range *file.Referrers() {
+				switch ref := ref.(type) {
+				case *ssa.Defer:
+					if isClose(ref.Common(), file) {
+						defers = append(defers, ref)
+					}
+				case *ssa.Call:
+					if isClose(ref.Common(), file) && len(*ref.Referrers()) > 0 {
+						checked = true
+					}
+				}
+			}
+			if checked {
+				continue // the error is handled by an explicit call to Close
+			}
+			for _, d := range defers {
+				report(pass, syntax, d)
+			}
+		}
+	}
+}
+
+// mayReturn reports whether the function whose control-flow graph
+// is g may return normally.
+func mayReturn(g *cfg.CFG) bool {
+	for _, b := range g.Blocks {
+		if b.Live && b.Return() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// opensForWriting reports whether call opens a file for writing.
+func opensForWriting(call *ssa.CallCommon) bool {
+	callee := call.StaticCallee()
+	if callee == nil {
+		return false
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok {
+		return false
+	}
+	switch obj.FullName() {
+	case "os.Create", "os.CreateTemp", "io/ioutil.TempFile":
+		return true
+	case "os.OpenFile":
+		if len(call.Args) != 3 {
+			return false
+		}
+		flag, ok := call.Args[1].(*ssa.Const)
+		if !ok || flag.Value == nil {
+			return false // unknown flags
+		}
+		mode := constant.BinaryOp(
+			constantOf(obj.Pkg(), "O_WRONLY"), token.OR, constantOf(obj.Pkg(), "O_RDWR"))
+		if mode == nil || mode.Kind() != constant.Int {
+			return false
+		}
+		bits := constant.BinaryOp(flag.Value, token.AND, mode)
+		return constant.Sign(bits) != 0
+	}
+	return false
+}
+
+// constantOf returns the value of the named constant in package pkg,
+// or an unknown value if there is none.
+func constantOf(pkg *types.Package, name string) constant.Value {
+	if c, ok := pkg.Scope().Lookup(name).(*types.Const); ok {
+		return c.Val()
+	}
+	return constant.MakeUnknown()
+}
+
+// fileResult returns the value of the *os.File result of call.
+func fileResult(call *ssa.Call) ssa.Value {
+	for _, ref := range *call.Referrers() {
+		if ext, ok := ref.(*ssa.Extract); ok && ext.Index == 0 {
+			return ext
+		}
+	}
+	return nil
+}
+
+// isClose reports whether call is a static call of
+// (*os.File).Close with the specified receiver.
+func isClose(call *ssa.CallCommon, file ssa.Value) bool {
+	callee := call.StaticCallee()
+	if callee == nil || len(call.Args) != 1 || call.Args[0] != file {
+		return false
+	}
+	obj, ok := callee.Object().(*types.Func)
+	return ok && obj.FullName() == "(*os.File).Close"
+}
+
+// funcSyntax returns the *ast.FuncDecl or *ast.FuncLit of fn,
+// or nil if it has none.
+func funcSyntax(pass *analysis.Pass, fn *ssa.Function) ast.Node {
+	// Without debug information, fn.Syntax
+	// records only the extent of the function.
+	extent := fn.Syntax()
+	if extent == nil {
+		return nil
+	}
+	file := findFile(pass, extent.Pos())
+	if file == nil {
+		return nil
+	}
+	path, _ := astutil.PathEnclosingInterval(file, extent.Pos(), extent.End())
+	for _, n := range path {
+		switch n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			if n.Pos() == extent.Pos() && n.End() == extent.End() {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+// report reports the deferred call d within the
+// function whose syntax is node, suggesting a fix if possible.
+func report(pass *analysis.Pass, node ast.Node, d *ssa.Defer) {
+	diag := analysis.Diagnostic{
+		Pos:     d.Pos(),
+		Message: "deferred Close of a file opened for writing discards its error, which may indicate lost data",
+	}
+	if fix, ok := suggestFix(pass, node, d.Pos()); ok {
+		diag.SuggestedFixes = []analysis.SuggestedFix{fix}
+	}
+	pass.Report(diag)
+}
+
+// suggestFix returns a fix that replaces the defer statement at pos,
+// within the function whose syntax is node, by a deferred closure
+// that assigns the error of Close to the function's error result,
+// naming the results if necessary.
+func suggestFix(pass *analysis.Pass, node ast.Node, pos token.Pos) (analysis.SuggestedFix, bool) {
+	var ftype *ast.FuncType
+	var body *ast.BlockStmt
+	switch node := node.(type) {
+	case *ast.FuncDecl:
+		ftype, body = node.Type, node.Body
+	case *ast.FuncLit:
+		ftype, body = node.Type, node.Body
+	}
+	if ftype == nil || body == nil || ftype.Results == nil {
+		return analysis.SuggestedFix{}, false
+	}
+
+	// Find the defer statement.
+	file := findFile(pass, pos)
+	if file == nil {
+		return analysis.SuggestedFix{}, false
+	}
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	var stmt *ast.DeferStmt
+	for _, n := range path {
+		if s, ok := n.(*ast.DeferStmt); ok && s.Defer == pos {
+			stmt = s
+			break
+		}
+	}
+	if stmt == nil {
+		return analysis.SuggestedFix{}, false
+	}
+
+	funcScope := pass.TypesInfo.Scopes[ftype]
+	if funcScope == nil {
+		return analysis.SuggestedFix{}, false
+	}
+	results := ftype.Results.List
+	last := results[len(results)-1]
+	if t := pass.TypesInfo.TypeOf(last.Type); t == nil || !types.Identical(t, types.Universe.Lookup("error").Type()) {
+		return analysis.SuggestedFix{}, false
+	}
+
+	var edits []analysis.TextEdit
+	var name string
+	if len(last.Names) > 0 {
+		// Use the existing error result, provided it
+		// is not shadowed at the defer statement.
+		id := last.Names[len(last.Names)-1]
+		name = id.Name
+		obj := pass.TypesInfo.Defs[id]
+		if name == "_" || obj == nil {
+			return analysis.SuggestedFix{}, false
+		}
+		if _, found := funcScope.Innermost(pos).LookupParent(name, pos); found != obj {
+			return analysis.SuggestedFix{}, false
+		}
+	} else {
+		// Name the results, provided the new name
+		// would neither conflict with nor shadow another.
+		name = "err"
+		if s, obj := funcScope.Innermost(pos).LookupParent(name, pos); s != nil && within(s, funcScope) {
+			// A local err variable declared at the top level of the body
+			// may become the result, if its declaration is still valid.
+			if s != funcScope || !redeclarable(pass, body, obj) {
+				return analysis.SuggestedFix{}, false
+			}
+		}
+		if usesOuter(pass, body, name, funcScope) {
+			return analysis.SuggestedFix{}, false
+		}
+		var buf bytes.Buffer
+		buf.WriteString("(")
+		for i, field := range results {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if i < len(results)-1 {
+				buf.WriteString("_ ")
+			} else {
+				buf.WriteString(name + " ")
+			}
+			buf.WriteString(analysisutil.Format(pass.Fset, field.Type))
+		}
+		buf.WriteString(")")
+		edits = append(edits, analysis.TextEdit{
+			Pos:     ftype.Results.Pos(),
+			End:     ftype.Results.End(),
+			NewText: buf.Bytes(),
+		})
+	}
+
+	indent := indentation(pass, stmt.Pos())
+	call := analysisutil.Format(pass.Fset, stmt.Call)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "defer func() {\n")
+	fmt.Fprintf(&buf, "%s\tif cerr := %s; cerr != nil && %s == nil {\n", indent, call, name)
+	fmt.Fprintf(&buf, "%s\t\t%s = cerr\n", indent, name)
+	fmt.Fprintf(&buf, "%s\t}\n", indent)
+	fmt.Fprintf(&buf, "%s}()", indent)
+	edits = append(edits, analysis.TextEdit{
+		Pos:     stmt.Pos(),
+		End:     stmt.End(),
+		NewText: buf.Bytes(),
+	})
+
+	return analysis.SuggestedFix{
+		Message:   fmt.Sprintf("Report the error of Close through the %s result", name),
+		TextEdits: edits,
+	}, true
+}
+
+// findFile returns the syntax tree of the file containing pos.
+func findFile(pass *analysis.Pass, pos token.Pos) *ast.File {
+	for _, f := range pass.Files {
+		if f.Pos() <= pos && pos <= f.End() {
+			return f
+		}
+	}
+	return nil
+}
+
+// within reports whether scope s is outer or one of its descendants.
+func within(s, outer *types.Scope) bool {
+	for ; s != nil; s = s.Parent() {
+		if s == outer {
+			return true
+		}
+	}
+	return false
+}
+
+// redeclarable reports whether obj is an error variable declared
+// by a top-level short variable declaration in body that also
+// declares another variable, such as "f, err := os.Create(name)",
+// and thus remains valid if a result of the same name is declared.
+func redeclarable(pass *analysis.Pass, body *ast.BlockStmt, obj types.Object) bool {
+	if _, ok := obj.(*types.Var); !ok || !types.Identical(obj.Type(), types.Universe.Lookup("error").Type()) {
+		return false
+	}
+	for _, stmt := range body.List {
+		assign, ok := stmt.(*ast.AssignStmt)
+		if !ok || assign.Tok != token.DEFINE {
+			continue
+		}
+		declares, others := false, false
+		for _, lhs := range assign.Lhs {
+			if id, ok := lhs.(*ast.Ident); ok {
+				if def := pass.TypesInfo.Defs[id]; def == obj {
+					declares = true
+				} else if def != nil {
+					others = true
+				}
+			}
+		}
+		if declares {
+			return others
+		}
+	}
+	return false // declared some other way
+}
+
+// usesOuter reports whether body refers to an object with
+// the specified name that is declared outside funcScope.
+func usesOuter(pass *analysis.Pass, body *ast.BlockStmt, name string, funcScope *types.Scope) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == name {
+			if obj := pass.TypesInfo.Uses[id]; obj != nil && !within(obj.Parent(), funcScope) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// indentation returns the indentation of the statement at pos,
+// assuming that the file is formatted by gofmt.
+func indentation(pass *analysis.Pass, pos token.Pos) string {
+	col := pass.Fset.Position(pos).Column
+	if col < 1 {
+		return ""
+	}
+	return strings.Repeat("\t", col-1)
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+func create(name string, data []byte) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // want `deferred Close of a file opened for writing discards its error`
+	_, err = f.Write(data)
+	return err
+}
+
+func named(name string) (n int, err error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close() // want `deferred Close`
+	return f.Write(nil)
+}
+
+func multiple(name string) (int, error) {
+	f, err := ioutil.TempFile("", name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close() // want `deferred Close`
+	return f.Write(nil)
+}
+
+func noError(name string) {
+	f, _ := os.Create(name)
+	defer f.Close() // want `deferred Close`
+	f.Write(nil)
+}
+
+func shadowed(name string) (err error) {
+	if f, err := os.Create(name); err == nil {
+		defer f.Close() // want `deferred Close`
+		f.Write(nil)
+	}
+	return nil
+}
+
+func lit() {
+	_ = func() error {
+		f, err := os.Create("x")
+		if err != nil {
+			return err
+		}
+		defer f.Close() // want `deferred Close`
+		return nil
+	}
+}
+
+func readOnly(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	g, err := os.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+	return nil
+}
+
+func unknownFlags(name string, flag int) error {
+	f, err := os.OpenFile(name, flag, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return nil
+}
+
+func checked(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(nil); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func noReturn(name string) {
+	f, _ := os.Create(name)
+	defer f.Close()
+	for {
+		f.Write(nil)
+	}
+}
+
+func fatal(name string) {
+	f, _ := os.Create(name)
+	defer f.Close()
+	log.Fatal(f.Name())
+}
@@ -0,0 +1,491 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package errwrap defines an Analyzer that checks for fmt.Errorf calls
+// that lose the error chain, and for errors.Is and errors.As calls
+// that are unlikely to succeed because their operand wraps no other
+// error.
+package errwrap
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/analysis/passes/printf"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+	"golang.org/x/tools/internal/fmtstr"
+)
+
+const Doc = `check that fmt.Errorf calls preserve the error chain
+
+The errwrap analysis reports calls to fmt.Errorf, and to functions
+that the printf analysis has identified as wrappers of fmt.Errorf,
+that format an error operand using %v or %s instead of %w:
+
+	return fmt.Errorf("reading config: %v", err) // should be %w
+
+Such a call discards the error chain, so errors.Is and errors.As can
+no longer find err within the result. A suggested fix rewrites the
+verb as %w.
+
+It also reports calls that use more than one %w verb when the Go
+version, specified by the -go flag, is older than 1.20, the first
+release to support them. The flag defaults to the version of the Go
+toolchain running the analysis, not the go version declared in the
+module's go.mod file, so to check a module that declares an older
+version, set the flag to that version, such as -go=1.19.
+
+Finally, it reports calls to errors.Is and errors.As whose first
+operand is the result of a function that returns only newly
+created errors that wrap no other error, such as those returned by
+errors.New or by fmt.Errorf without %w. Such calls succeed only if
+the target is the error value being tested, or is matched by an Is or
+As method of its type, so they usually indicate a mistake.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:      "errwrap",
+	Doc:       Doc,
+	Requires:  []*analysis.Analyzer{inspect.Analyzer, printf.Analyzer},
+	Run:       run,
+	FactTypes: []analysis.Fact{new(neverWraps)},
+}
+
+// goVersion is the Go language version assumed by the -go flag.
+var goVersion = defaultGoVersion()
+
+func init() {
+	Analyzer.Flags.StringVar(&goVersion, "go", goVersion, "Go version of the code under analysis, such as 1.19")
+}
+
+// defaultGoVersion returns the version of the Go toolchain,
+// such as "1.20", according to its release tags.
+func defaultGoVersion() string {
+	tags := build.Default.ReleaseTags
+	if len(tags) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(tags[len(tags)-1], "go")
+}
+
+// neverWraps is a fact indicating that every non-nil error returned
+// by a function is newly created and wraps no other error.
+type neverWraps struct{}
+
+func (*neverWraps) AFact()         {}
+func (*neverWraps) String() string { return "neverWraps" }
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	kinds := pass.ResultOf[printf.Analyzer].(*printf.Result)
+
+	computeNeverWraps(pass)
+	single := singleAssignments(pass)
+
+	nodeFilter := []ast.Node{
+		(*ast.CallExpr)(nil),
+	}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn := typeutil.StaticCallee(pass.TypesInfo, call)
+		if fn == nil {
+			return // not a static call
+		}
+		switch fn.FullName() {
+		case "errors.Is", "errors.As":
+			checkIsAs(pass, call, fn, single)
+			return
+		}
+		if idx := errorfFormatIndex(fn, kinds); idx >= 0 {
+			checkErrorf(pass, call, fn, idx)
+		}
+	})
+	return nil, nil
+}
+
+// errorfFormatIndex returns the index of the format parameter of
+// fn if it is fmt.Errorf or a wrapper of it, or -1 otherwise.
+func errorfFormatIndex(fn *types.Func, kinds *printf.Result) int {
+	if fn.FullName() == "fmt.Errorf" {
+		return 0
+	}
+	if kinds.Kind(fn) != printf.KindErrorf {
+		return -1
+	}
+	// A wrapper's format is the string parameter preceding the final
+	// ...interface{} parameter.
+	sig := fn.Type().(*types.Signature)
+	params := sig.Params()
+	if !sig.Variadic() || params.Len() < 2 {
+		return -1
+	}
+	idx := params.Len() - 2
+	if t, ok := params.At(idx).Type().Underlying().(*types.Basic); !ok || t.Kind() != types.String {
+		return -1
+	}
+	return idx
+}
+
+// checkErrorf checks a call to an Errorf-like function fn whose
+// format is the idx'th argument.
+func checkErrorf(pass *analysis.Pass, call *ast.CallExpr, fn *types.Func, idx int) {
+	if call.Ellipsis.IsValid() || len(call.Args) <= idx {
+		return // operands are unknown
+	}
+	format, ok := constantString(pass, call.Args[idx])
+	if !ok {
+		return
+	}
+	args := call.Args[idx+1:]
+	dirs, err := fmtstr.Parse(format, len(args))
+	if err != nil {
+		return // malformed format; the printf checker reports these
+	}
+
+	// Find the source positions of the directives, if the format
+	// is a literal whose text we can edit without unquoting.
+	var litDirs []*fmtstr.Directive
+	lit, _ := analysisutil.Unparen(call.Args[idx]).(*ast.BasicLit)
+	if lit != nil && len(lit.Value) >= 2 {
+		litDirs, _ = fmtstr.Parse(lit.Value[1:len(lit.Value)-1], len(args))
+		if !sameDirectives(dirs, litDirs) {
+			litDirs = nil
+		}
+	}
+
+	wraps := 0
+	for _, d := range dirs {
+		if d.Verb == 'w' {
+			wraps++
+		}
+	}
+	multi := multipleWrapsAllowed()
+	if wraps > 1 && !multi {
+		pass.ReportRangef(call, "%s call has more than one error-wrapping directive %%w, which requires go1.20", fn.FullName())
+	}
+
+	fixed := false
+	for i, d := range dirs {
+		if d.Verb != 'v' && d.Verb != 's' || d.ArgNum >= len(args) {
+			continue
+		}
+		arg := args[d.ArgNum]
+		if !isError(pass.TypesInfo.TypeOf(arg)) {
+			continue
+		}
+		diag := analysis.Diagnostic{
+			Pos:     arg.Pos(),
+			End:     arg.End(),
+			Message: fmt.Sprintf("%s call formats error %s with %%%c, losing its error chain; use %%w to wrap it", fn.FullName(), analysisutil.Format(pass.Fset, arg), d.Verb),
+		}
+		// Before go1.20, a call may wrap only one error.
+		if litDirs != nil && (multi || wraps == 0 && !fixed) {
+			verb := lit.Pos() + 1 + token.Pos(litDirs[i].End-1)
+			diag.SuggestedFixes = []analysis.SuggestedFix{{
+				Message: fmt.Sprintf("Replace %%%c with %%w", d.Verb),
+				TextEdits: []analysis.TextEdit{{
+					Pos:     verb,
+					End:     verb + 1,
+					NewText: []byte("w"),
+				}},
+			}}
+			fixed = true
+		}
+		pass.Report(diag)
+	}
+}
+
+// multipleWrapsAllowed reports whether the Go version specified
+// by the -go flag permits more than one %w per call.
+func multipleWrapsAllowed() bool {
+	v := strings.TrimPrefix(goVersion, "go")
+	if v == "" {
+		return true
+	}
+	major, minor := v, ""
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		major, minor = v[:i], v[i+1:]
+	}
+	if i := strings.IndexFunc(minor, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minor = minor[:i] // e.g. "20rc1", "19.3"
+	}
+	x, err1 := strconv.Atoi(major)
+	y, err2 := strconv.Atoi(minor)
+	if err1 != nil || err2 != nil {
+		return true // unknown version
+	}
+	return x > 1 || x == 1 && y >= 20
+}
+
+// constantString returns the value of e if it is a string constant.
+func constantString(pass *analysis.Pass, e ast.Expr) (string, bool) {
+	tv := pass.TypesInfo.Types[e]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// isError reports whether t is a non-interface type that implements
+// error, or is the error interface itself.
+func isError(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	if isErrorInterface(t) {
+		return true
+	}
+	if types.IsInterface(t) {
+		return false // may or may not hold an error
+	}
+	return types.Implements(t, errorType.Underlying().(*types.Interface))
+}
+
+// sameDirectives reports whether x and y have the same
+// verbs and operands, ignoring their positions.
+func sameDirectives(x, y []*fmtstr.Directive) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i].Verb != y[i].Verb || x[i].ArgNum != y[i].ArgNum {
+			return false
+		}
+	}
+	return true
+}
+
+// checkIsAs checks a call to errors.Is or errors.As.
+func checkIsAs(pass *analysis.Pass, call *ast.CallExpr, fn *types.Func, single map[*types.Var]*ast.CallExpr) {
+	if len(call.Args) != 2 {
+		return
+	}
+	if fn.Name() == "Is" && pass.TypesInfo.Types[call.Args[1]].IsNil() {
+		return // errors.Is(err, nil) is merely err == nil
+	}
+	if fn.Name() == "As" {
+		// The target of errors.As may be an interface
+		// that the error itself implements.
+		ptr, ok := pass.TypesInfo.TypeOf(call.Args[1]).Underlying().(*types.Pointer)
+		if !ok || types.IsInterface(ptr.Elem()) {
+			return
+		}
+	}
+
+	x := analysisutil.Unparen(call.Args[0])
+	var src *ast.CallExpr
+	switch x := x.(type) {
+	case *ast.CallExpr:
+		src = x
+	case *ast.Ident:
+		if v, ok := pass.TypesInfo.Uses[x].(*types.Var); ok {
+			src = single[v]
+		}
+	}
+	if src == nil {
+		return
+	}
+	callee := typeutil.StaticCallee(pass.TypesInfo, src)
+	if callee == nil || !pass.ImportObjectFact(callee, new(neverWraps)) {
+		return
+	}
+	pass.ReportRangef(call, "%s is likely false: %s returns only new errors that wrap no other error",
+		fn.FullName(), callee.Name())
+}
+
+// singleAssignments returns, for each local variable of type error
+// that is assigned exactly once, from the last result of a call,
+// that call. Variables whose address is taken are excluded.
+func singleAssignments(pass *analysis.Pass) map[*types.Var]*ast.CallExpr {
+	count := make(map[*types.Var]int)
+	calls := make(map[*types.Var]*ast.CallExpr)
+	escapes := make(map[*types.Var]bool) // address taken
+	record := func(lhs []ast.Expr, rhs []ast.Expr) {
+		for i, e := range lhs {
+			id, ok := analysisutil.Unparen(e).(*ast.Ident)
+			if !ok {
+				continue
+			}
+			v, ok := pass.TypesInfo.ObjectOf(id).(*types.Var)
+			if !ok {
+				continue
+			}
+			count[v]++
+			if len(rhs) == 1 && i == len(lhs)-1 {
+				if call, ok := analysisutil.Unparen(rhs[0]).(*ast.CallExpr); ok {
+					calls[v] = call
+				}
+			}
+		}
+	}
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				record(n.Lhs, n.Rhs)
+			case *ast.ValueSpec:
+				lhs := make([]ast.Expr, len(n.Names))
+				for i, id := range n.Names {
+					lhs[i] = id
+				}
+				record(lhs, n.Values)
+			case *ast.RangeStmt:
+				record([]ast.Expr{n.Key, n.Value}, nil)
+			case *ast.UnaryExpr:
+				if n.Op == token.AND {
+					if id, ok := analysisutil.Unparen(n.X).(*ast.Ident); ok {
+						if v, ok := pass.TypesInfo.Uses[id].(*types.Var); ok {
+							escapes[v] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	single := make(map[*types.Var]*ast.CallExpr)
+	for v, call := range calls {
+		if count[v] == 1 && !escapes[v] && v.Parent() != v.Pkg().Scope() {
+			single[v] = call
+		}
+	}
+	return single
+}
+
+// computeNeverWraps exports a neverWraps fact for each function
+// declared in this package all of whose non-nil error results are
+// newly created and wrap no other error.
+//
+// Since such functions may call one another, even recursively, it
+// computes the greatest fixed point: it starts by assuming that every
+// candidate function qualifies and repeatedly discards those with a
+// return statement that cannot be shown to yield a fresh error.
+func computeNeverWraps(pass *analysis.Pass) {
+	type candidate struct {
+		fn      *types.Func
+		results []ast.Expr // error operands of return statements
+	}
+	cands := make(map[*types.Func]*candidate)
+	for _, f := range pass.Files {
+		for _, decl := range f.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || decl.Body == nil {
+				continue
+			}
+			fn, ok := pass.TypesInfo.Defs[decl.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			res := fn.Type().(*types.Signature).Results()
+			if res.Len() == 0 || !isErrorInterface(res.At(res.Len()-1).Type()) {
+				continue
+			}
+			c := &candidate{fn: fn}
+			ok = true
+			ast.Inspect(decl.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.ReturnStmt:
+					if len(n.Results) == res.Len() {
+						c.results = append(c.results, n.Results[len(n.Results)-1])
+					} else if len(n.Results) == 1 && res.Len() > 1 {
+						c.results = append(c.results, n.Results[0]) // return f()
+					} else {
+						ok = false // bare return of named results
+					}
+				}
+				return true
+			})
+			if ok {
+				cands[fn] = c
+			}
+		}
+	}
+
+	// fresh reports whether e denotes nil or a newly created error,
+	// and whether it is non-nil.
+	fresh := func(e ast.Expr) (ok, nonNil bool) {
+		e = analysisutil.Unparen(e)
+		if pass.TypesInfo.Types[e].IsNil() {
+			return true, false
+		}
+		call, isCall := e.(*ast.CallExpr)
+		if !isCall {
+			return false, false
+		}
+		callee := typeutil.StaticCallee(pass.TypesInfo, call)
+		if callee == nil {
+			return false, false
+		}
+		switch callee.FullName() {
+		case "errors.New":
+			return true, true
+		case "fmt.Errorf":
+			if len(call.Args) == 0 || call.Ellipsis.IsValid() {
+				return false, false
+			}
+			format, ok := constantString(pass, call.Args[0])
+			if !ok {
+				return false, false
+			}
+			dirs, err := fmtstr.Parse(format, len(call.Args)-1)
+			if err != nil {
+				return false, false
+			}
+			for _, d := range dirs {
+				if d.Verb == 'w' {
+					return false, false
+				}
+			}
+			return true, true
+		}
+		if callee.Pkg() == pass.Pkg {
+			_, ok := cands[callee]
+			return ok, ok
+		}
+		ok = pass.ImportObjectFact(callee, new(neverWraps))
+		return ok, ok
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for fn, c := range cands {
+			constructs := false
+			for _, e := range c.results {
+				ok, nonNil := fresh(e)
+				if !ok {
+					delete(cands, fn)
+					changed = true
+					break
+				}
+				constructs = constructs || nonNil
+			}
+			if _, ok := cands[fn]; ok && !constructs {
+				delete(cands, fn) // never returns a non-nil error
+				changed = true
+			}
+		}
+	}
+
+	for fn := range cands {
+		pass.ExportObjectFact(fn, new(neverWraps))
+	}
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// isErrorInterface reports whether t is the error type.
+func isErrorInterface(t types.Type) bool {
+	return types.Identical(t, errorType)
+}
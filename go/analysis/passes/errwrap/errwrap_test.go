@@ -0,0 +1,25 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errwrap_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/errwrap"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, errwrap.Analyzer, "a", "b")
+}
+
+func TestGo119(t *testing.T) {
+	defer errwrap.Analyzer.Flags.Set("go", errwrap.Analyzer.Flags.Lookup("go").Value.String())
+	errwrap.Analyzer.Flags.Set("go", "1.19")
+
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, errwrap.Analyzer, "c")
+}
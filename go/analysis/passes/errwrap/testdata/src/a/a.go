@@ -0,0 +1,92 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the errwrap checker.
+
+package a
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+type myError struct{}
+
+func (myError) Error() string { return "my error" }
+
+func Errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(format, args...)
+}
+
+func formatting(err error, path string) {
+	_ = fmt.Errorf("open %s: %v", path, err)  // want `fmt.Errorf call formats error err with %v, losing its error chain; use %w to wrap it`
+	_ = fmt.Errorf("open %s: %s", path, err)  // want `fmt.Errorf call formats error err with %s`
+	_ = fmt.Errorf("%[2]v: %[1]s", path, err) // want `fmt.Errorf call formats error err with %v`
+	_ = fmt.Errorf("%v", myError{})           // want `fmt.Errorf call formats error myError{} with %v`
+	_ = Errorf("open: %v", err)               // want `a.Errorf call formats error err with %v`
+	_ = fmt.Errorf("open %s: %w", path, err)
+	_ = fmt.Errorf("open %s: %q", path, err)
+	_ = fmt.Errorf("open %v: %d", path, 1)
+	_ = fmt.Errorf("open: %v", err.Error())
+	_ = fmt.Errorf("%v: %v", err, err) // want `formats error err with %v` `formats error err with %v`
+	_ = fmt.Errorf("%w: %w", err, os.ErrNotExist)
+	_ = fmt.Errorf(`raw %v`, err) // want `formats error err with %v`
+	const format = "constant %v"
+	_ = fmt.Errorf(format, err) // want `formats error err with %v`
+	_ = fmt.Errorf("bad %!", err)
+}
+
+func ErrNotFound() error { return errors.New("not found") } // want ErrNotFound:"neverWraps"
+
+func find(name string) (int, error) { // want find:"neverWraps"
+	if name == "" {
+		return 0, ErrNotFound()
+	}
+	if name == "x" {
+		return 0, fmt.Errorf("no %s", name)
+	}
+	return 1, nil
+}
+
+func recursive(n int) error { // want recursive:"neverWraps"
+	if n > 0 {
+		return recursive(n - 1)
+	}
+	return errors.New("done")
+}
+
+func wraps(name string) error {
+	_, err := find(name)
+	if err != nil {
+		return fmt.Errorf("wrapped: %w", err)
+	}
+	return nil
+}
+
+func passthrough() error {
+	_, err := os.Open("x")
+	return err
+}
+
+func nilOnly() error { return nil }
+
+func comparisons(target error) {
+	_ = errors.Is(recursive(1), os.ErrNotExist) // want `errors.Is is likely false: recursive returns only new errors that wrap no other error`
+	_, err := find("x")
+	_ = errors.Is(err, target) // want `errors.Is is likely false: find returns only new errors`
+	var me myError
+	_ = errors.As(err, &me) // want `errors.As is likely false: find returns only new errors`
+	var iface interface{ Timeout() bool }
+	_ = errors.As(err, &iface)
+	_ = errors.Is(err, nil)
+
+	_ = errors.Is(wraps("x"), os.ErrNotExist)
+	_ = errors.Is(passthrough(), os.ErrNotExist)
+	_ = errors.Is(nilOnly(), os.ErrNotExist)
+
+	_, err2 := find("y")
+	err2 = wraps("y")
+	_ = errors.Is(err2, os.ErrNotExist)
+}
@@ -0,0 +1,20 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package b
+
+import (
+	"errors"
+	"os"
+
+	"a"
+)
+
+func NotFound() error { return a.ErrNotFound() } // want NotFound:"neverWraps"
+
+func _() {
+	_ = errors.Is(a.ErrNotFound(), os.ErrNotExist) // want `errors.Is is likely false: ErrNotFound returns only new errors`
+	err := NotFound()
+	_ = errors.Is(err, os.ErrNotExist) // want `errors.Is is likely false: NotFound returns only new errors`
+}
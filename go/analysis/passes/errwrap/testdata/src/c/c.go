@@ -0,0 +1,18 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file is checked with -go=1.19.
+
+package c
+
+import (
+	"fmt"
+	"os"
+)
+
+func _(err error) {
+	_ = fmt.Errorf("%w: %w", err, os.ErrNotExist) // want `fmt.Errorf call has more than one error-wrapping directive %w, which requires go1.20`
+	_ = fmt.Errorf("%v: %v", err, err)            // want `formats error err with %v` `formats error err with %v`
+	_ = fmt.Errorf("%w: %v", os.ErrNotExist, err) // want `formats error err with %v`
+}
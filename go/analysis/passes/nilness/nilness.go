@@ -52,25 +52,63 @@ and:
 	if p == nil {
 		panic(p)
 	}
+
+The checker is interprocedural: it summarizes, for each function,
+which pointer results may be nil (on paths that return a nil error or
+a true ok result, if any) and which parameters are dereferenced unconditionally, and uses
+these summaries, even across packages, to report conditions such as:
+
+	p := lookup() // lookup may return nil
+	print(p.x)    // possible nil dereference
+
+and:
+
+	deref(nil) // deref dereferences its parameter
 `
 
 var Analyzer = &analysis.Analyzer{
-	Name:     "nilness",
-	Doc:      Doc,
-	Run:      run,
-	Requires: []*analysis.Analyzer{buildssa.Analyzer},
+	Name:      "nilness",
+	Doc:       Doc,
+	Run:       run,
+	Requires:  []*analysis.Analyzer{buildssa.Analyzer},
+	FactTypes: []analysis.Fact{new(summary)},
 }
 
 func run(pass *analysis.Pass) (interface{}, error) {
 	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	sums := &summaries{pass: pass, local: make(map[*types.Func]*summary)}
+
+	// Summarize the functions of this package. Since they may
+	// call one another, iterate until the summaries are stable;
+	// this terminates because a summary only ever gains facts.
+	for changed := true; changed; {
+		changed = false
+		for _, fn := range ssainput.SrcFuncs {
+			if runFunc(pass, fn, sums, false) {
+				changed = true
+			}
+		}
+	}
+	for obj, sum := range sums.local {
+		if !sum.empty() {
+			pass.ExportObjectFact(obj, sum)
+		}
+	}
+
 	for _, fn := range ssainput.SrcFuncs {
-		runFunc(pass, fn)
+		runFunc(pass, fn, sums, true)
 	}
 	return nil, nil
 }
 
-func runFunc(pass *analysis.Pass, fn *ssa.Function) {
+// runFunc analyzes the function fn. If reporting is set, it reports
+// diagnostics; otherwise it updates the summary of fn and reports
+// whether it changed.
+func runFunc(pass *analysis.Pass, fn *ssa.Function, sums *summaries, reporting bool) (changed bool) {
 	reportf := func(category string, pos token.Pos, format string, args ...interface{}) {
+		if !reporting {
+			return
+		}
 		pass.Report(analysis.Diagnostic{
 			Pos:      pos,
 			Category: category,
@@ -78,10 +116,28 @@ func runFunc(pass *analysis.Pass, fn *ssa.Function) {
 		})
 	}
 
-	// notNil reports an error if v is provably nil.
+	var sum *summary
+	if !reporting {
+		sum = sums.forFunc(fn)
+		if sum == nil {
+			return false
+		}
+		changed = summarizeParams(fn, sums, sum)
+	}
+
+	// notNil reports an error if v is provably nil,
+	// or is the unchecked result of a call to a function
+	// that may return nil.
+	reported := make(map[ssa.Value]bool)
 	notNil := func(stack []fact, instr ssa.Instruction, v ssa.Value, descr string) {
-		if nilnessOf(stack, v) == isnil {
+		switch nilnessOf(stack, v) {
+		case isnil:
 			reportf("nilderef", instr.Pos(), "nil dereference in "+descr)
+		case unknown:
+			if callee := sums.nilResult(v); callee != nil && !reported[v] {
+				reported[v] = true // report only the first dereference
+				reportf("nilderef", instr.Pos(), "possible nil dereference in %s: %s may return nil", descr, callee.Name())
+			}
 		}
 	}
 
@@ -108,6 +164,16 @@ func runFunc(pass *analysis.Pass, fn *ssa.Function) {
 				if !(cc.IsInvoke() && typeparams.IsTypeParam(cc.Value.Type())) {
 					notNil(stack, instr, cc.Value, cc.Description())
 				}
+				// Report nil arguments that the callee dereferences.
+				if callee := staticFunc(cc); callee != nil {
+					if csum := sums.get(callee); csum != nil {
+						for i, arg := range cc.Args {
+							if i < len(csum.DerefParams) && csum.DerefParams[i] {
+								notNil(stack, instr, arg, "call to "+callee.Name())
+							}
+						}
+					}
+				}
 			case *ssa.FieldAddr:
 				notNil(stack, instr, instr.X, "field selection")
 			case *ssa.IndexAddr:
@@ -132,9 +198,13 @@ func runFunc(pass *analysis.Pass, fn *ssa.Function) {
 			}
 		}
 
-		// Look for panics with nil value
+		// Look for panics with nil value, and summarize returned values.
 		for _, instr := range b.Instrs {
 			switch instr := instr.(type) {
+			case *ssa.Return:
+				if sum != nil && sum.updateResults(sums, stack, instr) {
+					changed = true
+				}
 			case *ssa.Panic:
 				if nilnessOf(stack, instr.X) == isnil {
 					reportf("nilpanic", instr.Pos(), "panic with nil value")
@@ -227,6 +297,7 @@ func runFunc(pass *analysis.Pass, fn *ssa.Function) {
 	if fn.Blocks != nil {
 		visit(fn.Blocks[0], make([]fact, 0, 20)) // 20 is plenty
 	}
+	return changed
 }
 
 // A fact records that a block is dominated
@@ -14,7 +14,7 @@ import (
 
 func Test(t *testing.T) {
 	testdata := analysistest.TestData()
-	analysistest.Run(t, testdata, nilness.Analyzer, "a")
+	analysistest.Run(t, testdata, nilness.Analyzer, "a", "e", "f")
 }
 
 func TestInstantiated(t *testing.T) {
@@ -0,0 +1,304 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nilness
+
+// This file defines the function summaries that make the
+// analysis interprocedural.
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ssa"
+)
+
+// A summary is a fact that records the nilness behavior
+// of a function, as observed by its callers.
+//
+// Parameter indices include the receiver, if any, as in
+// ssa.Function.Params and ssa.CallCommon.Args.
+type summary struct {
+	// NilResults[i] records that result i, of pointer type, is nil
+	// along some path on which the function's final error or bool
+	// result, if any, reports success: that is, the error is nil or
+	// the bool is true.
+	NilResults []bool
+
+	// DerefParams[i] records that parameter i is dereferenced
+	// along every path through the function.
+	DerefParams []bool
+}
+
+func (*summary) AFact() {}
+
+func (s *summary) String() string {
+	indices := func(bits []bool) string {
+		var buf strings.Builder
+		buf.WriteByte('[')
+		for i, b := range bits {
+			if b {
+				if buf.Len() > 1 {
+					buf.WriteByte(' ')
+				}
+				fmt.Fprint(&buf, i)
+			}
+		}
+		buf.WriteByte(']')
+		return buf.String()
+	}
+	return fmt.Sprintf("nilResults=%s derefParams=%s", indices(s.NilResults), indices(s.DerefParams))
+}
+
+// empty reports whether the summary records nothing.
+func (s *summary) empty() bool {
+	for _, b := range s.NilResults {
+		if b {
+			return false
+		}
+	}
+	for _, b := range s.DerefParams {
+		if b {
+			return false
+		}
+	}
+	return true
+}
+
+// summaries provides access to the summaries of functions, both
+// those of the current package, which are computed by the pass,
+// and those imported from dependencies as facts.
+type summaries struct {
+	pass     *analysis.Pass
+	local    map[*types.Func]*summary
+	imported map[*types.Func]*summary // nil entries record absent facts
+}
+
+// forFunc returns the summary of the package-level function or
+// method fn, creating it if necessary, or nil if fn has no object.
+func (s *summaries) forFunc(fn *ssa.Function) *summary {
+	obj, ok := fn.Object().(*types.Func)
+	if !ok || obj.Pkg() != s.pass.Pkg {
+		return nil // e.g. an anonymous function
+	}
+	sum := s.local[obj]
+	if sum == nil {
+		sig := fn.Signature
+		sum = &summary{
+			NilResults:  make([]bool, sig.Results().Len()),
+			DerefParams: make([]bool, len(fn.Params)),
+		}
+		s.local[obj] = sum
+	}
+	return sum
+}
+
+// get returns the summary of the specified function, or nil if none.
+func (s *summaries) get(fn *types.Func) *summary {
+	if fn.Pkg() == s.pass.Pkg {
+		return s.local[fn]
+	}
+	sum, ok := s.imported[fn]
+	if !ok {
+		sum = new(summary)
+		if !s.pass.ImportObjectFact(fn, sum) {
+			sum = nil
+		}
+		if s.imported == nil {
+			s.imported = make(map[*types.Func]*summary)
+		}
+		s.imported[fn] = sum
+	}
+	return sum
+}
+
+// nilResult returns the called function if v is
+// a result of a call to it that may be nil.
+func (s *summaries) nilResult(v ssa.Value) *types.Func {
+	var call *ssa.Call
+	index := 0
+	switch v := v.(type) {
+	case *ssa.Call:
+		call = v
+	case *ssa.Extract:
+		call, _ = v.Tuple.(*ssa.Call)
+		index = v.Index
+	}
+	if call == nil {
+		return nil
+	}
+	callee := staticFunc(call.Common())
+	if callee == nil {
+		return nil
+	}
+	if sum := s.get(callee); sum != nil && index < len(sum.NilResults) && sum.NilResults[index] {
+		return callee
+	}
+	return nil
+}
+
+// staticFunc returns the declared function or method
+// called by a static call, or nil if there is none.
+func staticFunc(call *ssa.CallCommon) *types.Func {
+	if fn := call.StaticCallee(); fn != nil {
+		obj, _ := fn.Object().(*types.Func)
+		return obj
+	}
+	return nil
+}
+
+// updateResults records in s the results of the return instruction
+// ret that are nil given the dominating facts, and reports whether
+// it changed s.
+func (s *summary) updateResults(sums *summaries, stack []fact, ret *ssa.Return) bool {
+	results := ret.Results
+	if n := len(results); n > 0 {
+		switch last := results[n-1]; {
+		case isError(last.Type()):
+			// Consider only paths that return a nil error.
+			if nilnessOf(stack, last) != isnil {
+				return false
+			}
+			results = results[:n-1]
+		case isBool(last.Type()):
+			// Consider only paths that return true,
+			// as in the comma-ok idiom.
+			if c, ok := last.(*ssa.Const); !ok || c.Value == nil || !constant.BoolVal(c.Value) {
+				return false
+			}
+			results = results[:n-1]
+		}
+	}
+	changed := false
+	for i, v := range results {
+		if _, ok := v.Type().Underlying().(*types.Pointer); !ok || s.NilResults[i] {
+			continue
+		}
+		nn := nilnessOf(stack, v)
+		if nn == isnil || nn == unknown && sums.nilResult(v) != nil {
+			s.NilResults[i] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// summarizeParams records in sum the parameters of fn that are
+// dereferenced unconditionally, and reports whether it changed sum.
+//
+// It considers only the straight-line sequence of blocks starting at
+// the entry block, whose instructions execute on every path. A
+// function that may recover from a panic is assumed to dereference
+// nothing, since a nil dereference need not reach its caller.
+func summarizeParams(fn *ssa.Function, sums *summaries, sum *summary) bool {
+	index := make(map[ssa.Value]int)
+	for i, p := range fn.Params {
+		index[p] = i
+	}
+	changed := false
+	deref := func(v ssa.Value) {
+		if i, ok := index[v]; ok && !sum.DerefParams[i] {
+			sum.DerefParams[i] = true
+			changed = true
+		}
+	}
+	if len(fn.Blocks) == 0 || mayRecover(fn) {
+		return false
+	}
+	for b := fn.Blocks[0]; ; b = b.Succs[0] {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.FieldAddr:
+				deref(instr.X)
+			case *ssa.Store:
+				deref(instr.Addr)
+			case *ssa.MapUpdate:
+				deref(instr.Map)
+			case *ssa.UnOp:
+				if instr.Op == token.MUL {
+					deref(instr.X)
+				}
+			case ssa.CallInstruction:
+				if _, ok := instr.(*ssa.Call); !ok {
+					continue // go and defer statements
+				}
+				cc := instr.Common()
+				if cc.IsInvoke() {
+					if _, ok := cc.Value.Type().Underlying().(*types.Interface); ok {
+						deref(cc.Value)
+					}
+					continue
+				}
+				if callee := staticFunc(cc); callee != nil && callee != fn.Object() {
+					if csum := sums.get(callee); csum != nil {
+						for i, arg := range cc.Args {
+							if i < len(csum.DerefParams) && csum.DerefParams[i] {
+								deref(arg)
+							}
+						}
+					}
+				}
+			}
+		}
+		if len(b.Succs) != 1 || len(b.Succs[0].Preds) != 1 {
+			break
+		}
+	}
+	return changed
+}
+
+// mayRecover reports whether fn defers a call to a function that
+// may call recover, or to a function that is not known statically.
+func mayRecover(fn *ssa.Function) bool {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			d, ok := instr.(*ssa.Defer)
+			if !ok {
+				continue
+			}
+			callee := d.Call.StaticCallee()
+			if callee == nil {
+				if _, ok := d.Call.Value.(*ssa.Builtin); ok {
+					continue // e.g. defer close(ch)
+				}
+				return true
+			}
+			if callsRecover(callee) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callsRecover reports whether fn calls the recover built-in.
+func callsRecover(fn *ssa.Function) bool {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				if b, ok := call.Call.Value.(*ssa.Builtin); ok && b.Name() == "recover" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// isError reports whether t is the error type.
+func isError(t types.Type) bool {
+	return types.Identical(t, errorType)
+}
+
+// isBool reports whether t is a boolean type.
+func isBool(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsBoolean != 0
+}
@@ -115,7 +115,7 @@ func f7() {
 	}
 }
 
-func bad() (*X, error) {
+func bad() (*X, error) { // want bad:"nilResults=.0. derefParams=.."
 	return nil, nil
 }
 
@@ -125,7 +125,7 @@ func f8() {
 	print(v)
 }
 
-func f9(x interface {
+func f9(x interface { // want f9:"nilResults=.. derefParams=.0."
 	a()
 	b()
 	c()
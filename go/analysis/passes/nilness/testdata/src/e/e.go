@@ -0,0 +1,110 @@
+package e
+
+type T struct{ x int }
+
+type myError struct{}
+
+func (myError) Error() string { return "error" }
+
+var errEmpty, errNotFound error = myError{}, myError{}
+
+var table map[string]*T
+
+func Lookup(name string) *T { // want Lookup:"nilResults=.0. derefParams=.."
+	if t, ok := table[name]; ok {
+		return t
+	}
+	return nil
+}
+
+func Find(name string) (*T, error) { // want Find:"nilResults=.0. derefParams=.."
+	if name == "" {
+		return nil, errEmpty
+	}
+	return Lookup(name), nil
+}
+
+func Get(name string) (*T, error) {
+	if t := Lookup(name); t != nil {
+		return t, nil
+	}
+	return nil, errNotFound
+}
+
+func New() *T { return &T{} }
+
+func X(t *T) int { return t.x } // want X:"nilResults=.. derefParams=.0."
+
+func Y(t *T, cond bool) int { // want Y:"nilResults=.. derefParams=.0."
+	n := X(t)
+	if cond {
+		return 0
+	}
+	return n
+}
+
+func Z(t *T, cond bool) int {
+	if t == nil {
+		return 0
+	}
+	return t.x
+}
+
+func (t *T) M() int { return t.x } // want M:"nilResults=.. derefParams=.0."
+
+func use() {
+	p := Lookup("a")
+	_ = p.x // want "possible nil dereference in field selection: Lookup may return nil"
+	_ = p.x // reported once
+
+	q := Lookup("b")
+	if q != nil {
+		_ = q.x
+	}
+
+	r, err := Get("c")
+	if err != nil {
+		return
+	}
+	_ = r.x
+
+	_ = New().x
+
+	_ = X(nil) // want "nil dereference in call to X"
+	_ = Z(nil, true)
+}
+
+func Load(name string) (*T, bool) { // comma-ok: nil only when not ok
+	t, ok := table[name]
+	if !ok {
+		return nil, false
+	}
+	return t, true
+}
+
+func Must(name string) (*T, bool) { // want Must:"nilResults=.0. derefParams=.."
+	if name == "" {
+		return nil, true
+	}
+	return Load(name)
+}
+
+func Safe(t *T) (x int) {
+	defer func() {
+		if recover() != nil {
+			x = -1
+		}
+	}()
+	return t.x
+}
+
+func useCommaOk() {
+	if t, ok := Load("d"); ok {
+		_ = t.x
+	}
+
+	t, _ := Must("e")
+	_ = t.x // want "possible nil dereference in field selection: Must may return nil"
+
+	_ = Safe(nil)
+}
@@ -0,0 +1,16 @@
+package f
+
+import "e"
+
+func use() {
+	t, err := e.Find("a")
+	if err != nil {
+		return
+	}
+	_ = t.M() // want "possible nil dereference in call to M: Find may return nil"
+
+	_ = e.Y(nil, false) // want "nil dereference in call to Y"
+
+	var u *e.T
+	_ = u.M() // want "nil dereference in call to M"
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// A Config specifies the functions of interest to the taint analysis.
+//
+// Functions and methods are named as by types.Func.FullName, for
+// example "os/exec.Command" or "(*net/http.Request).FormValue".
+// Interface methods, such as "(io.Writer).Write", match dynamic
+// calls through the interface.
+type Config struct {
+	// Sources are functions whose results are tainted.
+	Sources []string `json:"sources"`
+
+	// Sinks are functions that must not receive tainted arguments.
+	Sinks []Sink `json:"sinks"`
+
+	// Sanitizers are functions whose results are never
+	// tainted, regardless of their arguments.
+	Sanitizers []string `json:"sanitizers"`
+}
+
+// A Sink is a function that must not receive tainted arguments.
+type Sink struct {
+	Func string `json:"func"`
+
+	// Args holds the indices of the sensitive parameters, not
+	// counting the receiver of a method. If empty, all are.
+	Args []int `json:"args,omitempty"`
+}
+
+// DefaultConfig is the configuration used by Analyzer when
+// no configuration file is specified.
+var DefaultConfig = &Config{
+	Sources: []string{
+		"(*net/http.Request).FormValue",
+		"(*net/http.Request).PostFormValue",
+		"(*net/http.Request).Cookie",
+		"(net/url.Values).Get",
+		"os.Getenv",
+	},
+	Sinks: []Sink{
+		{Func: "(*database/sql.DB).Query", Args: []int{0}},
+		{Func: "(*database/sql.DB).QueryRow", Args: []int{0}},
+		{Func: "(*database/sql.DB).Exec", Args: []int{0}},
+		{Func: "(*database/sql.DB).QueryContext", Args: []int{1}},
+		{Func: "(*database/sql.DB).ExecContext", Args: []int{1}},
+		{Func: "os/exec.Command"},
+		{Func: "os/exec.CommandContext", Args: []int{1, 2}},
+	},
+	Sanitizers: []string{
+		"strconv.Atoi",
+		"strconv.ParseInt",
+		"strconv.Quote",
+		"html.EscapeString",
+		"net/url.QueryEscape",
+	},
+}
+
+// ReadConfig reads a configuration from the named JSON file,
+// such as:
+//
+//	{
+//		"sources": ["(*net/http.Request).FormValue"],
+//		"sinks": [{"func": "(*database/sql.DB).Query", "args": [0]}],
+//		"sanitizers": ["strconv.Atoi"]
+//	}
+func ReadConfig(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg := new(Config)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("cannot decode taint configuration %s: %v", filename, err)
+	}
+	for _, sink := range cfg.Sinks {
+		if sink.Func == "" {
+			return nil, fmt.Errorf("%s: sink has no function name", filename)
+		}
+	}
+	return cfg, nil
+}
+
+// sink returns the sink of the named function, if any.
+func (cfg *Config) sink(name string) (*Sink, bool) {
+	for i := range cfg.Sinks {
+		if cfg.Sinks[i].Func == name {
+			return &cfg.Sinks[i], true
+		}
+	}
+	return nil, false
+}
+
+// sensitive reports whether the sink's parameter
+// of the specified index is sensitive.
+func (sink *Sink) sensitive(index int) bool {
+	if len(sink.Args) == 0 {
+		return true
+	}
+	for _, i := range sink.Args {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,391 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+// This file defines the propagation of taint through SSA values.
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// A Flow is a path along which tainted data
+// flows from the result of a source to a sink.
+type Flow struct {
+	Source     ssa.CallInstruction // call to source function
+	SourceFunc string              // name of source function
+	Sink       ssa.CallInstruction // call to sink function
+	SinkFunc   string              // name of sink function
+	Steps      []Step              // intermediate steps, in order
+}
+
+// A Step is an intermediate step of a Flow.
+type Step struct {
+	Pos     token.Pos
+	Message string
+}
+
+// A node is a location that may hold tainted data:
+// an ssa.Value, or a *types.Var denoting a struct field,
+// which is tainted in all structs of its type.
+//
+// A tainted value of pointer (or reference) type indicates
+// that the memory to which it points is tainted.
+type node interface{}
+
+// A cause records why a node is tainted.
+type cause struct {
+	from node      // predecessor, or nil for the result of a source
+	pos  token.Pos // position of propagation, if any
+	msg  string    // description of propagation, if any
+}
+
+// Analyze returns the flows of tainted data within the specified
+// functions, according to cfg. Calls are resolved using the call
+// graph cg, which should include all edges from funcs, for example
+// one computed by the vta package.
+//
+// Data flows from callers to callees through parameters and free
+// variables, from callees to callers through results, and through
+// values, the memory to which they point, and struct fields.
+// Calls to functions outside funcs are assumed to propagate taint
+// from their arguments to their results and to the memory
+// referenced by their pointer arguments.
+func Analyze(funcs []*ssa.Function, cg *callgraph.Graph, cfg *Config) []*Flow {
+	p := &propagator{
+		cfg:    cfg,
+		cg:     cg,
+		funcs:  make(map[*ssa.Function]bool),
+		causes: make(map[node]cause),
+		fields: make(map[*types.Var][]ssa.Value),
+		seen:   make(map[flowKey]bool),
+	}
+	for _, fn := range funcs {
+		p.funcs[fn] = true
+	}
+
+	// Index the field selections, and taint the results of sources.
+	for _, fn := range funcs {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.FieldAddr:
+					if field := fieldOf(instr.X.Type(), instr.Field, true); field != nil {
+						p.fields[field] = append(p.fields[field], instr)
+					}
+				case *ssa.Field:
+					if field := fieldOf(instr.X.Type(), instr.Field, false); field != nil {
+						p.fields[field] = append(p.fields[field], instr)
+					}
+				case *ssa.Call:
+					if name := calleeName(instr.Common()); contains(cfg.Sources, name) {
+						p.taint(instr, cause{pos: instr.Pos(), msg: "source " + name})
+					}
+				}
+			}
+		}
+	}
+
+	for len(p.queue) > 0 {
+		n := p.queue[0]
+		p.queue = p.queue[1:]
+		p.propagate(n)
+	}
+
+	sort.Slice(p.flows, func(i, j int) bool {
+		return p.flows[i].Sink.Pos() < p.flows[j].Sink.Pos()
+	})
+	return p.flows
+}
+
+type propagator struct {
+	cfg    *Config
+	cg     *callgraph.Graph
+	funcs  map[*ssa.Function]bool
+	causes map[node]cause // tainted nodes
+	queue  []node
+	fields map[*types.Var][]ssa.Value // selections of each field
+	flows  []*Flow
+	seen   map[flowKey]bool
+}
+
+type flowKey struct {
+	source, sink ssa.CallInstruction
+}
+
+// taint marks n as tainted for the specified cause,
+// if it is not already tainted.
+func (p *propagator) taint(n node, c cause) {
+	if _, ok := p.causes[n]; ok {
+		return
+	}
+	p.causes[n] = c
+	p.queue = append(p.queue, n)
+}
+
+// propagate taints the successors of the tainted node n.
+func (p *propagator) propagate(n node) {
+	switch n := n.(type) {
+	case *types.Var:
+		for _, sel := range p.fields[n] {
+			p.taint(sel, cause{from: n, pos: sel.Pos(), msg: "load of field " + n.Name()})
+		}
+		return
+
+	case *ssa.Function:
+		return // not a data value
+	}
+
+	v := n.(ssa.Value)
+	refs := v.Referrers()
+	if refs == nil {
+		return
+	}
+	for _, instr := range *refs {
+		switch instr := instr.(type) {
+		case *ssa.Store:
+			if instr.Val == v {
+				p.taintAddr(instr.Addr, cause{from: v, pos: instr.Pos(), msg: "store"})
+			}
+
+		case *ssa.MapUpdate:
+			if instr.Key == v || instr.Value == v {
+				p.taint(instr.Map, cause{from: v, pos: instr.Pos(), msg: "map update"})
+			}
+
+		case *ssa.Send:
+			if instr.X == v {
+				p.taint(instr.Chan, cause{from: v, pos: instr.Pos(), msg: "channel send"})
+			}
+
+		case *ssa.Return:
+			p.taintReturn(instr, v)
+
+		case *ssa.MakeClosure:
+			fn := instr.Fn.(*ssa.Function)
+			for i, b := range instr.Bindings {
+				if b == v && i < len(fn.FreeVars) {
+					p.taint(fn.FreeVars[i], cause{from: v, pos: instr.Pos(), msg: "captured by " + fn.Name()})
+				}
+			}
+
+		case ssa.CallInstruction:
+			p.call(instr, v)
+
+		case ssa.Value:
+			// Taint selections from tainted aggregates,
+			// but not those using tainted indices.
+			if x := aggregate(instr); x != nil && x != v {
+				continue
+			}
+			p.taint(instr, cause{from: v})
+		}
+	}
+}
+
+// aggregate returns the operand from which v selects
+// a field or element, or nil if v is not a selection.
+func aggregate(v ssa.Value) ssa.Value {
+	switch v := v.(type) {
+	case *ssa.FieldAddr:
+		return v.X
+	case *ssa.Field:
+		return v.X
+	case *ssa.IndexAddr:
+		return v.X
+	case *ssa.Index:
+		return v.X
+	case *ssa.Lookup:
+		return v.X
+	}
+	return nil
+}
+
+// taintAddr taints the memory at the address addr.
+func (p *propagator) taintAddr(addr ssa.Value, c cause) {
+	p.taint(addr, c)
+	switch addr := addr.(type) {
+	case *ssa.FieldAddr:
+		if field := fieldOf(addr.X.Type(), addr.Field, true); field != nil {
+			p.taint(field, cause{from: addr, pos: addr.Pos(), msg: "store to field " + field.Name()})
+		}
+	case *ssa.IndexAddr:
+		p.taint(addr.X, cause{from: addr, pos: addr.Pos(), msg: "store to element"})
+	}
+}
+
+// taintReturn taints the results of the calls to the function
+// containing ret, which returns the tainted value v.
+func (p *propagator) taintReturn(ret *ssa.Return, v ssa.Value) {
+	fn := ret.Parent()
+	node := p.cg.Nodes[fn]
+	if node == nil {
+		return
+	}
+	for _, edge := range node.In {
+		if res := edge.Site.Value(); res != nil {
+			p.taint(res, cause{from: v, pos: ret.Pos(), msg: "returned from " + fn.Name()})
+		}
+	}
+}
+
+// call propagates the tainted value v through the call instr.
+func (p *propagator) call(instr ssa.CallInstruction, v ssa.Value) {
+	cc := instr.Common()
+	name := calleeName(cc)
+	if contains(p.cfg.Sanitizers, name) {
+		return
+	}
+
+	// args holds the arguments, including any receiver.
+	args := cc.Args
+	if cc.IsInvoke() {
+		args = append([]ssa.Value{cc.Value}, cc.Args...)
+	}
+
+	if sink, ok := p.cfg.sink(name); ok {
+		recv := 0
+		if cc.IsInvoke() || isMethod(cc.StaticCallee()) {
+			recv = 1
+		}
+		for i, arg := range args {
+			if arg == v && i >= recv && sink.sensitive(i-recv) {
+				p.reportFlow(instr, name, v)
+				break
+			}
+		}
+		return
+	}
+
+	// Propagate to callees defined within funcs.
+	propagated := false
+	if node := p.cg.Nodes[instr.Parent()]; node != nil {
+		for _, edge := range node.Out {
+			if edge.Site != instr || !p.funcs[edge.Callee.Func] {
+				continue
+			}
+			callee := edge.Callee.Func
+			propagated = true
+			for i, arg := range args {
+				if arg == v && i < len(callee.Params) {
+					p.taint(callee.Params[i], cause{from: v, pos: instr.Pos(), msg: fmt.Sprintf("passed to %s parameter %s", callee.Name(), callee.Params[i].Name())})
+				}
+			}
+		}
+	}
+	if propagated {
+		return
+	}
+
+	// Otherwise, assume that the callee propagates taint
+	// to its results and through its pointer arguments.
+	if !cc.IsInvoke() && cc.Value == v {
+		return // call of a tainted function value
+	}
+	msg := "call of " + name
+	if res := instr.Value(); res != nil {
+		p.taint(res, cause{from: v, pos: instr.Pos(), msg: msg})
+	}
+	for _, arg := range args {
+		if arg != v && isReference(arg.Type()) {
+			p.taint(arg, cause{from: v, pos: instr.Pos(), msg: msg})
+		}
+	}
+}
+
+// isMethod reports whether fn is a method called with
+// its receiver as the first argument.
+func isMethod(fn *ssa.Function) bool {
+	return fn != nil && fn.Signature.Recv() != nil
+}
+
+// isReference reports whether values of type t refer to memory
+// that a callee might update.
+func isReference(t types.Type) bool {
+	switch typeparams.CoreType(t).(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan:
+		return true
+	}
+	return false
+}
+
+// reportFlow records the flow of the tainted value v to the sink call.
+func (p *propagator) reportFlow(sink ssa.CallInstruction, sinkName string, v ssa.Value) {
+	var steps []Step
+	var source ssa.CallInstruction
+	var sourceName string
+	var n node = v
+	for i := 0; n != nil && i < len(p.causes); i++ {
+		c := p.causes[n]
+		if c.from == nil {
+			source, _ = n.(ssa.CallInstruction)
+			sourceName = calleeName(source.Common())
+			break
+		}
+		if c.msg != "" && c.pos.IsValid() {
+			steps = append(steps, Step{Pos: c.pos, Message: c.msg})
+		}
+		n = c.from
+	}
+	if source == nil {
+		return // cycle; can't happen
+	}
+	key := flowKey{source, sink}
+	if p.seen[key] {
+		return
+	}
+	p.seen[key] = true
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+	p.flows = append(p.flows, &Flow{
+		Source:     source,
+		SourceFunc: sourceName,
+		Sink:       sink,
+		SinkFunc:   sinkName,
+		Steps:      steps,
+	})
+}
+
+// calleeName returns the name of the function or interface
+// method called by cc, or "" for a dynamic function call.
+func calleeName(cc *ssa.CallCommon) string {
+	if cc.IsInvoke() {
+		return cc.Method.FullName()
+	}
+	switch fn := cc.Value.(type) {
+	case *ssa.Function:
+		if obj, ok := fn.Object().(*types.Func); ok {
+			return obj.FullName()
+		}
+		return fn.String()
+	case *ssa.Builtin:
+		return fn.Name()
+	}
+	return ""
+}
+
+// fieldOf returns the field of the specified index of the struct type
+// t, or of the struct type to which t points if isPtr.
+func fieldOf(t types.Type, index int, isPtr bool) *types.Var {
+	if isPtr {
+		ptr, ok := typeparams.CoreType(t).(*types.Pointer)
+		if !ok {
+			return nil
+		}
+		t = ptr.Elem()
+	}
+	s, ok := typeparams.CoreType(t).(*types.Struct)
+	if !ok || index >= s.NumFields() {
+		return nil
+	}
+	return s.Field(index)
+}
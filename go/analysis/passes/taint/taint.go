@@ -0,0 +1,213 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package taint defines an Analyzer that reports flows of untrusted
+// data from sources, such as HTTP request parameters, to sensitive
+// sinks, such as SQL queries, that do not pass through a sanitizer.
+//
+// The underlying analysis is also available to other tools through
+// the Analyze function, and NewAnalyzer constructs analyzers for
+// specific configurations.
+package taint
+
+import (
+	"go/types"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/ssa"
+)
+
+const Doc = `report flows of untrusted data to sensitive functions
+
+The taint analysis reports calls to sink functions, such as
+(*database/sql.DB).Query or os/exec.Command, with arguments that
+are derived from the results of source functions, such as
+(*net/http.Request).FormValue, without passing through a sanitizer
+function, such as strconv.Atoi. Each report includes the steps by
+which the data flowed from the source to the sink.
+
+The -config flag names a JSON file that specifies the sources, sinks,
+and sanitizers, replacing the built-in configuration:
+
+	{
+		"sources": ["(*net/http.Request).FormValue"],
+		"sinks": [{"func": "(*database/sql.DB).Query", "args": [0]}],
+		"sanitizers": ["strconv.Atoi"]
+	}
+
+Functions are named as by types.Func.FullName. The optional args of a
+sink are the indices of its sensitive parameters, not counting any
+receiver.
+
+Taint is propagated through values, the memory to which they point,
+and struct fields, and through calls within the package, which are
+resolved using a VTA call graph. Calls to other functions are assumed
+to propagate taint from their arguments to their results.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "taint",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{buildssa.Analyzer},
+	Run:      run,
+}
+
+var configFile string // -config flag
+
+func init() {
+	Analyzer.Flags.StringVar(&configFile, "config", "", "name of JSON file specifying sources, sinks, and sanitizers")
+}
+
+// configs caches the configurations read from files.
+var configs sync.Map // map[string]*configResult
+
+type configResult struct {
+	once sync.Once
+	cfg  *Config
+	err  error
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	cfg := DefaultConfig
+	if configFile != "" {
+		v, _ := configs.LoadOrStore(configFile, new(configResult))
+		res := v.(*configResult)
+		res.once.Do(func() { res.cfg, res.err = ReadConfig(configFile) })
+		if res.err != nil {
+			return nil, res.err
+		}
+		cfg = res.cfg
+	}
+	return runConfig(pass, cfg)
+}
+
+// NewAnalyzer returns a new taint analyzer with the specified
+// name and documentation that uses the configuration cfg.
+func NewAnalyzer(name, doc string, cfg *Config) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name:     name,
+		Doc:      doc,
+		Requires: []*analysis.Analyzer{buildssa.Analyzer},
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			return runConfig(pass, cfg)
+		},
+	}
+}
+
+func runConfig(pass *analysis.Pass, cfg *Config) (interface{}, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+
+	// Only calls among the source functions propagate taint, so the
+	// call graph need not cover the rest of the program, whose
+	// method sets may be large.
+	funcs := reachable(ssainput.SrcFuncs)
+	cg := vta.CallGraph(funcs, initialCallGraph(funcs))
+
+	for _, flow := range Analyze(ssainput.SrcFuncs, cg, cfg) {
+		diag := analysis.Diagnostic{
+			Pos: flow.Sink.Pos(),
+			Message: "tainted data from " + flow.SourceFunc +
+				" reaches " + flow.SinkFunc,
+			Related: []analysis.RelatedInformation{{
+				Pos:     flow.Source.Pos(),
+				Message: "tainted by " + flow.SourceFunc,
+			}},
+		}
+		for _, step := range flow.Steps {
+			diag.Related = append(diag.Related, analysis.RelatedInformation{
+				Pos:     step.Pos,
+				Message: step.Message,
+			})
+		}
+		pass.Report(diag)
+	}
+	return nil, nil
+}
+
+// reachable returns the set of the functions srcFuncs and the
+// functions they may call: those they reference, and the methods of
+// the types they convert to interfaces.
+func reachable(srcFuncs []*ssa.Function) map[*ssa.Function]bool {
+	funcs := make(map[*ssa.Function]bool)
+	var queue []*ssa.Function
+	add := func(fn *ssa.Function) {
+		if !funcs[fn] {
+			funcs[fn] = true
+			queue = append(queue, fn)
+		}
+	}
+	for _, fn := range srcFuncs {
+		add(fn)
+	}
+	for len(queue) > 0 {
+		fn := queue[0]
+		queue = queue[1:]
+		var buf [10]*ssa.Value
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				for _, op := range instr.Operands(buf[:0]) {
+					if g, ok := (*op).(*ssa.Function); ok {
+						add(g)
+					}
+				}
+				if mi, ok := instr.(*ssa.MakeInterface); ok {
+					prog := fn.Prog
+					mset := prog.MethodSets.MethodSet(mi.X.Type())
+					for i := 0; i < mset.Len(); i++ {
+						if g := prog.MethodValue(mset.At(i)); g != nil {
+							add(g)
+						}
+					}
+				}
+			}
+		}
+	}
+	return funcs
+}
+
+// initialCallGraph returns a conservative call graph of funcs, in the
+// manner of the cha package but restricted to funcs, for refinement
+// by VTA: a dynamic call may call any function in funcs of the same
+// signature, or any method of the same name whose receiver type
+// implements the interface.
+func initialCallGraph(funcs map[*ssa.Function]bool) *callgraph.Graph {
+	cg := callgraph.New(nil)
+	for fn := range funcs {
+		fnode := cg.CreateNode(fn)
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				site, ok := instr.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				if g := site.Common().StaticCallee(); g != nil {
+					callgraph.AddEdge(fnode, site, cg.CreateNode(g))
+					continue
+				}
+				for g := range funcs {
+					if mayCall(site.Common(), g) {
+						callgraph.AddEdge(fnode, site, cg.CreateNode(g))
+					}
+				}
+			}
+		}
+	}
+	return cg
+}
+
+// mayCall reports whether the dynamic call cc may call fn.
+func mayCall(cc *ssa.CallCommon, fn *ssa.Function) bool {
+	recv := fn.Signature.Recv()
+	if !cc.IsInvoke() {
+		return recv == nil && types.Identical(cc.Value.Type().Underlying(), fn.Signature)
+	}
+	if recv == nil || fn.Name() != cc.Method.Name() {
+		return false
+	}
+	iface, ok := cc.Value.Type().Underlying().(*types.Interface)
+	return ok && types.Implements(recv.Type(), iface)
+}
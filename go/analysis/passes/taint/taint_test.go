@@ -0,0 +1,49 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/taint"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	config := filepath.Join(testdata, "config.json")
+	defer taint.Analyzer.Flags.Set("config", "")
+	taint.Analyzer.Flags.Set("config", config)
+	analysistest.Run(t, testdata, taint.Analyzer, "a")
+}
+
+// TestCrossPackage checks flows through the functions and interfaces
+// of other packages.
+func TestCrossPackage(t *testing.T) {
+	testdata := analysistest.TestData()
+	config := filepath.Join(testdata, "config.json")
+	defer taint.Analyzer.Flags.Set("config", "")
+	taint.Analyzer.Flags.Set("config", config)
+	analysistest.Run(t, testdata, taint.Analyzer, "cross")
+}
+
+// TestDefaultConfig runs the analyzer with the built-in configuration.
+func TestDefaultConfig(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), taint.Analyzer, "defaults")
+}
+
+func TestReadConfig(t *testing.T) {
+	cfg, err := taint.ReadConfig(filepath.Join(analysistest.TestData(), "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Sources) != 2 || len(cfg.Sinks) != 3 || len(cfg.Sanitizers) != 1 {
+		t.Errorf("ReadConfig returned %+v", cfg)
+	}
+	if sink := cfg.Sinks[0]; sink.Func != "(*db.DB).Query" || len(sink.Args) != 1 || sink.Args[0] != 0 {
+		t.Errorf("first sink = %+v", sink)
+	}
+}
@@ -0,0 +1,79 @@
+package a
+
+import (
+	"db"
+	"run"
+	"web"
+)
+
+func direct(r *web.Request, d *db.DB) {
+	name := r.FormValue("name")
+	d.Query("SELECT * FROM users WHERE name = '" + name + "'") // want `tainted data from \(\*web.Request\).FormValue reaches \(\*db.DB\).Query`
+	d.Query("SELECT * FROM users WHERE name = ?", name)        // ok: not a sensitive argument
+	d.Query(web.Escape(name))                                  // ok: sanitized
+}
+
+func variadic() {
+	run.Command("ls", "-l", web.Param("dir")) // want `tainted data from web.Param reaches run.Command`
+}
+
+type query struct {
+	text string
+	n    int
+}
+
+func fields(r *web.Request, d *db.DB) {
+	q := &query{}
+	q.text = r.FormValue("q")
+	q.n = 1
+	exec(d, q)
+}
+
+func exec(d *db.DB, q *query) {
+	d.Query(q.text) // want `tainted data from \(\*web.Request\).FormValue reaches \(\*db.DB\).Query`
+}
+
+func untaintedField(d *db.DB, q *query) {
+	d.Query(string(rune(q.n)))
+}
+
+func calls(e db.Execer) {
+	s := build(web.Param("x"))
+	e.Exec(s) // want `tainted data from web.Param reaches \(db.Execer\).Exec`
+}
+
+func build(s string) string {
+	return "prefix " + s
+}
+
+func closures() {
+	p := web.Param("x")
+	f := func() {
+		run.Command(p) // want `tainted data from web.Param reaches run.Command`
+	}
+	f()
+}
+
+func channels() {
+	ch := make(chan string, 1)
+	ch <- web.Param("x")
+	run.Command(<-ch) // want `tainted data from web.Param reaches run.Command`
+}
+
+type executor interface {
+	Do(string)
+}
+
+type runner struct{}
+
+func (runner) Do(cmd string) {
+	run.Command(cmd) // want `tainted data from web.Param reaches run.Command`
+}
+
+func dynamic(x executor) {
+	x.Do(web.Param("x"))
+}
+
+func useDynamic() {
+	dynamic(runner{})
+}
@@ -0,0 +1,32 @@
+package cross
+
+import (
+	"lib"
+	"run"
+	"web"
+)
+
+// Calls to functions of other packages propagate taint to their results.
+func wrapped() {
+	run.Command(lib.Wrap(web.Param("x"))) // want `tainted data from web.Param reaches run.Command`
+}
+
+func boxed() {
+	b := lib.NewBox(web.Param("x"))
+	run.Command(b.Text) // want `tainted data from web.Param reaches run.Command`
+}
+
+// Calls through interfaces of other packages reach the methods of this one.
+type local struct{}
+
+func (local) Do(s string) {
+	run.Command(s) // want `tainted data from web.Param reaches run.Command`
+}
+
+func viaInterface(d lib.Doer) {
+	d.Do(web.Param("x"))
+}
+
+func useViaInterface() {
+	viaInterface(local{})
+}
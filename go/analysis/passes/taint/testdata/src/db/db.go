@@ -0,0 +1,9 @@
+package db
+
+type DB struct{}
+
+func (db *DB) Query(query string, args ...interface{}) error { return nil }
+
+type Execer interface {
+	Exec(query string) error
+}
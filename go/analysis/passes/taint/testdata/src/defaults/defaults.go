@@ -0,0 +1,27 @@
+package defaults
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os/exec"
+	"strconv"
+)
+
+func queries(ctx context.Context, r *http.Request, db *sql.DB) {
+	name := r.FormValue("name")
+	db.Query("SELECT * FROM users WHERE name = '" + name + "'")    // want `tainted data from \(\*net/http.Request\).FormValue reaches \(\*database/sql.DB\).Query`
+	db.Query("SELECT * FROM users WHERE name = ?", name)           // ok: a bind argument
+	db.QueryRow("SELECT * FROM users WHERE name = '" + name + "'") // want `reaches \(\*database/sql.DB\).QueryRow`
+	db.Exec("DELETE FROM users WHERE name = ?", name)              // ok: a bind argument
+	db.QueryContext(ctx, "SELECT "+name)                           // want `reaches \(\*database/sql.DB\).QueryContext`
+	db.ExecContext(ctx, "DELETE FROM users WHERE name = ?", name)  // ok: a bind argument
+
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	db.Exec("DELETE FROM users WHERE id = " + strconv.Itoa(id)) // ok: sanitized
+}
+
+func commands(ctx context.Context, r *http.Request) {
+	exec.Command(r.FormValue("cmd"))                   // want `reaches os/exec.Command`
+	exec.CommandContext(ctx, "ls", r.FormValue("dir")) // want `reaches os/exec.CommandContext`
+}
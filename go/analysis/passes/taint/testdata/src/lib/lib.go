@@ -0,0 +1,11 @@
+package lib
+
+func Wrap(s string) string { return "(" + s + ")" }
+
+type Doer interface {
+	Do(string)
+}
+
+type Box struct{ Text string }
+
+func NewBox(s string) *Box { return &Box{Text: s} }
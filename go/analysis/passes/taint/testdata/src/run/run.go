@@ -0,0 +1,3 @@
+package run
+
+func Command(name string, args ...string) {}
@@ -0,0 +1,9 @@
+package web
+
+type Request struct{ form map[string]string }
+
+func (r *Request) FormValue(key string) string { return r.form[key] }
+
+func Param(name string) string { return name }
+
+func Escape(s string) string { return s }
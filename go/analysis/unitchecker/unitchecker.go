@@ -14,6 +14,13 @@
 //	-flags          describe flags                    (to the build tool)
 //	foo.cfg         description of compilation unit (from the build tool)
 //
+// With the -sarif flag, each compilation unit is reported as a separate
+// SARIF log, so the output of "go vet -sarif" is a sequence of logs.
+// To combine them into the single log expected by most SARIF consumers,
+// pass that output to the tool's merge-sarif subcommand:
+//
+//	$ go vet -vettool=$(which vet) -sarif ./... 2>&1 | vet merge-sarif > vet.sarif
+//
 // This package does not depend on go/packages.
 // If you need a standalone tool, use multichecker,
 // which supports this mode but can also load packages
@@ -99,6 +106,7 @@ Usage of %[1]s:
 	%.16[1]s unit.cfg	# execute analysis specified by config file
 	%.16[1]s help    	# general help, including listing analyzers and flags
 	%.16[1]s help name	# help on specific analyzer and its flags
+	%.16[1]s merge-sarif [file...]	# merge the SARIF logs printed by go vet -sarif
 `, progname)
 		os.Exit(1)
 	}
@@ -113,12 +121,42 @@ Usage of %[1]s:
 		analysisflags.Help(progname, analyzers, args[1:])
 		os.Exit(0)
 	}
+	if args[0] == "merge-sarif" {
+		if err := mergeSARIF(os.Stdout, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
 	if len(args) != 1 || !strings.HasSuffix(args[0], ".cfg") {
 		log.Fatalf(`invoking "go tool vet" directly is unsupported; use "go vet"`)
 	}
 	Run(args[0], analyzers)
 }
 
+// mergeSARIF writes to w the single SARIF log that combines the logs
+// of the named files, or of the standard input if there are none.
+func mergeSARIF(w io.Writer, files []string) error {
+	var r io.Reader = os.Stdin
+	if len(files) > 0 {
+		var readers []io.Reader
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			readers = append(readers, f)
+		}
+		r = io.MultiReader(readers...)
+	}
+	doc, err := analysisflags.MergeSARIF(r)
+	if err != nil {
+		return err
+	}
+	doc.Fprint(w)
+	return nil
+}
+
 // Run reads the *.cfg file, runs the analysis,
 // and calls os.Exit with an appropriate error code.
 // It assumes flags have already been set.
@@ -143,6 +181,13 @@ func Run(configFile string, analyzers []*analysis.Analyzer) {
 				tree.Add(fset, cfg.ID, res.a.Name, res.diagnostics, res.err)
 			}
 			tree.Print()
+		} else if analysisflags.SARIF {
+			// SARIF output
+			var sarif analysisflags.SARIFLog
+			for _, res := range results {
+				sarif.Add(fset, cfg.ID, res.a, res.diagnostics, res.err)
+			}
+			sarif.Print()
 		} else {
 			// plain text
 			exit := 0
@@ -12,6 +12,8 @@ package unitchecker_test
 // the (*os.ProcessState).ExitCode method (1.12).
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"os"
 	"os/exec"
@@ -187,3 +189,78 @@ func _() {
 		}
 	}
 }
+
+// TestIntegrationSARIF checks that the SARIF logs printed by go vet
+// for each package can be merged into a single valid log.
+func TestIntegrationSARIF(t *testing.T) { packagestest.TestAll(t, testIntegrationSARIF) }
+func testIntegrationSARIF(t *testing.T, exporter packagestest.Exporter) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skipf("skipping fork/exec test on this platform")
+	}
+
+	exported := packagestest.Export(t, exporter, []packagestest.Module{{
+		Name: "golang.org/fake",
+		Files: map[string]interface{}{
+			"a/a.go": `package a
+
+func _() {
+	MyFunc123()
+}
+
+func MyFunc123() {}
+`,
+			"c/c.go": `package c
+
+func _() {
+    i := 5
+    i = i
+}
+`,
+		}}})
+	defer exported.Cleanup()
+
+	cmd := exec.Command("go", "vet", "-vettool="+os.Args[0], "-findcall.name=MyFunc123", "-sarif", "golang.org/fake/a", "golang.org/fake/c")
+	cmd.Env = append(exported.Config.Env, "UNITCHECKER_CHILD=1")
+	cmd.Dir = exported.Config.Dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go vet failed: %v\n%s", err, out)
+	}
+
+	merge := exec.Command(os.Args[0], "merge-sarif")
+	merge.Env = append(os.Environ(), "UNITCHECKER_CHILD=1")
+	merge.Stdin = bytes.NewReader(out)
+	merged, err := merge.Output()
+	if err != nil {
+		t.Fatalf("merge-sarif failed: %v\ninput:\n%s", err, out)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct{ ID string }
+				}
+			}
+			Results []struct {
+				RuleID  string
+				Message struct{ Text string }
+			}
+		}
+	}
+	if err := json.Unmarshal(merged, &log); err != nil {
+		t.Fatalf("invalid SARIF log: %v\n%s", err, merged)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("got version %q with %d runs, want 2.1.0 with 1 run", log.Version, len(log.Runs))
+	}
+	var got []string
+	for _, res := range log.Runs[0].Results {
+		got = append(got, res.RuleID+": "+res.Message.Text)
+	}
+	want := []string{"findcall: call of MyFunc123(...)", "assign: self-assignment of i to i"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("results = %q, want %q", got, want)
+	}
+}
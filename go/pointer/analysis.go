@@ -128,6 +128,10 @@ type analysis struct {
 	track       track                       // pointerlike types whose aliasing we track
 	deltaSpace  []int                       // working space for iterating over PTS deltas
 
+	// Queries on values within generic function bodies:
+	genericQueries  map[*ssa.Function]bool                    // generic bodies containing queried values
+	instanceQueries map[*ssa.Function]map[ssa.Value]ssa.Value // maps values of each instance to queried values of its generic body
+
 	// Reflection & intrinsics:
 	hasher              typeutil.Hasher // cache of type hashes
 	reflectValueObj     types.Object    // type symbol for reflect.Value (if present)
@@ -286,6 +290,7 @@ func Analyze(config *Config) (result *Result, err error) {
 		a.runtimeSetFinalizer = runtime.Func("SetFinalizer")
 	}
 	a.computeTrackBits()
+	a.indexGenericQueries()
 
 	a.generate()
 	a.showCounts()
@@ -389,7 +394,7 @@ func (a *analysis) callEdge(caller *cgnode, site *callsite, calleeid nodeid) {
 	}
 
 	// Warn about calls to generic function bodies.
-	if fn.TypeParams().Len() > 0 && len(fn.TypeArgs()) == 0 {
+	if isGenericBody(fn) {
 		a.warnf(site.pos(), "unsound call to generic function body: %s (build with ssa.InstantiateGenerics)", fn)
 		a.warnf(fn.Pos(), " (declared here)")
 	}
@@ -366,6 +366,15 @@ Users of the package are expected to use the ssa.InstantiateGenerics
 builder mode when building code that uses or depends on code
 containing generics.
 
+In that mode, each instance of a generic function or method is
+analyzed like an ordinary function, and instances of generic types
+flow through interfaces and reflection like other named types.
+A query on a value within the body of a generic function is
+answered by the union of the points-to sets of the corresponding
+values in all its instances. Calls to instances of generic
+intrinsics, such as the methods of sync/atomic.Pointer and
+reflect.TypeFor, are treated like calls to the intrinsic.
+
 reflect.Value:
 
 A reflect.Value is modelled very similar to an interface{}, i.e. as
@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"go/token"
 	"go/types"
+	"reflect"
 	"strings"
 
 	"golang.org/x/tools/go/callgraph"
@@ -83,29 +84,42 @@ func (a *analysis) setValueNode(v ssa.Value, id nodeid, cgn *cgnode) {
 	// in many contexts. We merge them to a canonical node, since
 	// that's what all clients want.
 
+	// Likewise, generic function bodies are not analyzed, so a
+	// query on a value within one is answered by merging the
+	// corresponding values of all instances.
+	q := v
+	if orig := a.genericQuery(v); orig != nil {
+		q = orig
+	}
+
 	// Record the (v, id) relation if the client has queried pts(v).
-	if _, ok := a.config.Queries[v]; ok {
+	if _, ok := a.config.Queries[q]; ok {
 		t := v.Type()
-		ptr, ok := a.result.Queries[v]
+		ptr, ok := a.result.Queries[q]
 		if !ok {
 			// First time?  Create the canonical query node.
-			ptr = Pointer{a, a.addNodes(t, "query")}
-			a.result.Queries[v] = ptr
+			ptr = Pointer{a, a.addQueryNodes(q, t, "query")}
+			a.result.Queries[q] = ptr
+		}
+		if q == v {
+			a.copy(ptr.n, id, a.sizeof(t))
+		} else if a.isPointerShaped(t) {
+			a.copy(ptr.n, id, 1)
 		}
-		a.result.Queries[v] = ptr
-		a.copy(ptr.n, id, a.sizeof(t))
 	}
 
 	// Record the (*v, id) relation if the client has queried pts(*v).
-	if _, ok := a.config.IndirectQueries[v]; ok {
+	if _, ok := a.config.IndirectQueries[q]; ok {
 		t := v.Type()
-		ptr, ok := a.result.IndirectQueries[v]
+		ptr, ok := a.result.IndirectQueries[q]
 		if !ok {
 			// First time? Create the canonical indirect query node.
-			ptr = Pointer{a, a.addNodes(v.Type(), "query.indirect")}
-			a.result.IndirectQueries[v] = ptr
+			ptr = Pointer{a, a.addQueryNodes(q, t, "query.indirect")}
+			a.result.IndirectQueries[q] = ptr
+		}
+		if q == v || a.isPointerShaped(mustDeref(t)) {
+			a.genLoad(cgn, ptr.n, v, 0, a.sizeof(t))
 		}
-		a.genLoad(cgn, ptr.n, v, 0, a.sizeof(t))
 	}
 
 	for _, query := range a.config.extendedQueries[v] {
@@ -119,6 +133,111 @@ func (a *analysis) setValueNode(v ssa.Value, id nodeid, cgn *cgnode) {
 	}
 }
 
+// addQueryNodes creates the canonical node for a query on q, whose
+// value in the current context has type t.
+//
+// A value within a generic body stands for the values of all its
+// instances, whose types may have different shapes, so its query node
+// is a single node, into which only the instances whose values are
+// represented by a single pointer-like node are merged.
+func (a *analysis) addQueryNodes(q ssa.Value, t types.Type, comment string) nodeid {
+	if fn := q.Parent(); fn != nil && isGenericBody(fn) {
+		return a.addOneNode(q.Type(), comment, nil)
+	}
+	return a.addNodes(t, comment)
+}
+
+// isPointerShaped reports whether a value of type t is represented by
+// a single pointer-like node.
+func (a *analysis) isPointerShaped(t types.Type) bool {
+	return CanPoint(t) && a.sizeof(t) == 1
+}
+
+// indexGenericQueries records the generic function bodies that
+// contain values queried by the client.
+func (a *analysis) indexGenericQueries() {
+	for _, queries := range []map[ssa.Value]struct{}{a.config.Queries, a.config.IndirectQueries} {
+		for v := range queries {
+			if fn := v.Parent(); fn != nil && isGenericBody(fn) {
+				if a.genericQueries == nil {
+					a.genericQueries = make(map[*ssa.Function]bool)
+					a.instanceQueries = make(map[*ssa.Function]map[ssa.Value]ssa.Value)
+				}
+				a.genericQueries[fn] = true
+			}
+		}
+	}
+}
+
+// genericQuery returns the queried value within a generic function
+// body that corresponds to the value v of one of its instances, or
+// nil if there is none.
+func (a *analysis) genericQuery(v ssa.Value) ssa.Value {
+	fn := v.Parent()
+	if fn == nil || a.genericQueries == nil {
+		return nil
+	}
+	orig := fn.Origin()
+	if orig == nil || !a.genericQueries[orig] {
+		return nil
+	}
+	m, ok := a.instanceQueries[fn]
+	if !ok {
+		m = a.correspondingQueries(fn, orig)
+		a.instanceQueries[fn] = m
+	}
+	return m[v]
+}
+
+// correspondingQueries returns a mapping from the values of the
+// instance fn to the queried values of its generic body, orig.
+//
+// An instance is built from the same syntax as its generic body, so
+// corresponding parameters and free variables have the same index, and
+// corresponding instructions usually have the same block and index
+// within it. Blocks whose lengths differ, and instructions whose kinds
+// differ, as when an instance needs an extra conversion, are not
+// paired, so that a value is never merged with one it does not
+// correspond to.
+func (a *analysis) correspondingQueries(fn, orig *ssa.Function) map[ssa.Value]ssa.Value {
+	m := make(map[ssa.Value]ssa.Value)
+	record := func(v, q ssa.Value) {
+		_, direct := a.config.Queries[q]
+		_, indirect := a.config.IndirectQueries[q]
+		if direct || indirect {
+			m[v] = q
+		}
+	}
+	for i, p := range fn.Params {
+		if i < len(orig.Params) {
+			record(p, orig.Params[i])
+		}
+	}
+	for i, fv := range fn.FreeVars {
+		if i < len(orig.FreeVars) {
+			record(fv, orig.FreeVars[i])
+		}
+	}
+	if len(fn.Blocks) != len(orig.Blocks) {
+		return m
+	}
+	for i, b := range fn.Blocks {
+		ob := orig.Blocks[i]
+		if len(b.Instrs) != len(ob.Instrs) {
+			continue
+		}
+		for j, instr := range b.Instrs {
+			if reflect.TypeOf(instr) != reflect.TypeOf(ob.Instrs[j]) {
+				continue
+			}
+			if v, ok := instr.(ssa.Value); ok {
+				record(v, ob.Instrs[j].(ssa.Value))
+			}
+		}
+	}
+	return m
+}
+
 // endObject marks the end of a sequence of calls to addNodes denoting
 // a single object allocation.
 //
@@ -1207,7 +1326,7 @@ func (a *analysis) genFunc(cgn *cgnode) {
 		return
 	}
 
-	if fn.TypeParams().Len() > 0 && len(fn.TypeArgs()) == 0 {
+	if isGenericBody(fn) {
 		// Body of generic function.
 		// We'll warn about calls to such functions at the end.
 		return
@@ -155,6 +155,17 @@ func init() {
 	} {
 		intrinsicsByName[name] = fn
 	}
+
+	// Methods of generic types are keyed by the
+	// String of the origin of their instances.
+	for name, fn := range map[string]intrinsic{
+		"(*sync/atomic.Pointer[T]).CompareAndSwap": ext۰atomic۰Pointer۰CompareAndSwap,
+		"(*sync/atomic.Pointer[T]).Load":           ext۰atomic۰Pointer۰Load,
+		"(*sync/atomic.Pointer[T]).Store":          ext۰atomic۰Pointer۰Store,
+		"(*sync/atomic.Pointer[T]).Swap":           ext۰atomic۰Pointer۰Swap,
+	} {
+		intrinsicsByName[name] = fn
+	}
 }
 
 // findIntrinsic returns the constraint generation function for an
@@ -165,6 +176,10 @@ func (a *analysis) findIntrinsic(fn *ssa.Function) intrinsic {
 	impl, ok := a.intrinsics[fn]
 	if !ok {
 		impl = intrinsicsByName[fn.String()] // may be nil
+		if impl == nil && fn.Origin() != nil {
+			// An instance of a generic intrinsic.
+			impl = intrinsicsByName[fn.Origin().String()]
+		}
 
 		if a.isReflect(fn) {
 			if !a.config.Reflection {
@@ -357,3 +372,57 @@ func ext۰time۰startTimer(a *analysis, cgn *cgnode) {
 		t:       params,
 	})
 }
+
+// ---------- sync/atomic.Pointer[T] ----------
+
+// The methods of sync/atomic.Pointer[T] are implemented using
+// unsafe.Pointer operations, whose effects are ignored, so we
+// model them as loads and stores of its unexported v field.
+
+// atomicPointerField returns the receiver node of a call to a method
+// of sync/atomic.Pointer[T], and the offset of its v field. If the
+// type has no such field, as its representation is a detail of the
+// standard library, it reports false, and warns that the call is
+// treated as having no effect, like the functions of sync/atomic
+// that operate on unsafe.Pointers.
+func atomicPointerField(a *analysis, cgn *cgnode) (recv nodeid, offset uint32, ok bool) {
+	T := mustDeref(cgn.fn.Signature.Recv().Type())
+	if s, ok := T.Underlying().(*types.Struct); ok {
+		for i := 0; i < s.NumFields(); i++ {
+			if s.Field(i).Name() == "v" {
+				return a.funcParams(cgn.obj), a.offsetOf(T, i), true
+			}
+		}
+	}
+	a.warnf(cgn.fn.Pos(), "unsound: %s has no field v; ignoring the effects of %s", T, cgn.fn)
+	return 0, 0, false
+}
+
+// result = p.v
+func ext۰atomic۰Pointer۰Load(a *analysis, cgn *cgnode) {
+	if recv, offset, ok := atomicPointerField(a, cgn); ok {
+		a.load(a.funcResults(cgn.obj), recv, offset, 1)
+	}
+}
+
+// p.v = val
+func ext۰atomic۰Pointer۰Store(a *analysis, cgn *cgnode) {
+	if recv, offset, ok := atomicPointerField(a, cgn); ok {
+		a.store(recv, recv+1, offset, 1)
+	}
+}
+
+// result = p.v; p.v = new
+func ext۰atomic۰Pointer۰Swap(a *analysis, cgn *cgnode) {
+	if recv, offset, ok := atomicPointerField(a, cgn); ok {
+		a.load(a.funcResults(cgn.obj), recv, offset, 1)
+		a.store(recv, recv+1, offset, 1)
+	}
+}
+
+// p.v = new
+func ext۰atomic۰Pointer۰CompareAndSwap(a *analysis, cgn *cgnode) {
+	if recv, offset, ok := atomicPointerField(a, cgn); ok {
+		a.store(recv, recv+2, offset, 1)
+	}
+}
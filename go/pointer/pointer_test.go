@@ -23,12 +23,14 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
 	"unsafe"
 
 	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/loader"
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/pointer"
 	"golang.org/x/tools/go/ssa"
@@ -725,3 +727,92 @@ func TestTypeParam(t *testing.T) {
 		t.Fail()
 	}
 }
+
+// TestGenericBodyQuery checks that a query on a value within a generic
+// body merges the corresponding values of those of its instances whose
+// values are represented by a single pointer, but not of instances of
+// other shapes.
+func TestGenericBodyQuery(t *testing.T) {
+	if !typeparams.Enabled {
+		t.Skip("TestGenericBodyQuery requires type parameters")
+	}
+	const src = `package main
+
+type T struct{ x int }
+
+type pair struct{ a, b *T }
+
+var g1, g2, g3 T
+
+func first[X any](xs []X) (X, []X) {
+	x := xs[0]
+	return x, xs[1:]
+}
+
+func main() {
+	first([]*T{&g1})
+	first([]pair{{&g2, &g3}})
+	first([]int{0})
+}
+`
+	var conf loader.Config
+	f, err := conf.ParseFile("main.go", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.CreateFromFiles("main", f)
+	lprog, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := ssautil.CreateProgram(lprog, ssa.InstantiateGenerics|ssa.SanityCheckFunctions)
+	mainPkg := prog.Package(lprog.Created[0].Pkg)
+	prog.Build()
+
+	// Query, within the generic body, the element x, its address
+	// &xs[0], and the slice xs[1:], whose nodes follow that of x.
+	first := mainPkg.Func("first")
+	var addr, x, rest ssa.Value
+	for _, instr := range first.Blocks[0].Instrs {
+		switch instr := instr.(type) {
+		case *ssa.IndexAddr:
+			addr = instr
+		case *ssa.UnOp:
+			x = instr
+		case *ssa.Slice:
+			rest = instr
+		}
+	}
+	if addr == nil || x == nil || rest == nil {
+		t.Fatalf("missing instructions in %s", first)
+	}
+	config := &pointer.Config{Mains: []*ssa.Package{mainPkg}}
+	config.AddQuery(x)
+	config.AddIndirectQuery(addr)
+	config.AddQuery(rest)
+	result, err := pointer.Analyze(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	globals := func(ptr pointer.Pointer) string {
+		var names []string
+		for _, l := range ptr.PointsTo().Labels() {
+			if g, ok := l.Value().(*ssa.Global); ok {
+				names = append(names, g.Name())
+			}
+		}
+		sort.Strings(names)
+		return strings.Join(names, " ")
+	}
+	if got, want := globals(result.Queries[x]), "g1"; got != want {
+		t.Errorf("pts(%s) = {%s}, want {%s}", x, got, want)
+	}
+	if got, want := globals(result.IndirectQueries[addr]), "g1"; got != want {
+		t.Errorf("pts(*%s) = {%s}, want {%s}", addr, got, want)
+	}
+	// The instance for pair must not spill into the nodes of rest.
+	if got := globals(result.Queries[rest]); got != "" {
+		t.Errorf("pts(%s) includes globals {%s}, want none", rest, got)
+	}
+}
@@ -116,6 +116,7 @@ func init() {
 		"reflect.Select":      ext۰reflect۰Select,
 		"reflect.SliceOf":     ext۰reflect۰SliceOf,
 		"reflect.TypeOf":      ext۰reflect۰TypeOf,
+		"reflect.TypeFor":     ext۰reflect۰TypeFor,
 		"reflect.ValueOf":     ext۰reflect۰ValueOf,
 		"reflect.Zero":        ext۰reflect۰Zero,
 		"reflect.init":        ext۰NoEffect,
@@ -1480,6 +1481,16 @@ func ext۰reflect۰TypeOf(a *analysis, cgn *cgnode) {
 	})
 }
 
+// ---------- func TypeFor[T any]() Type ----------
+
+func ext۰reflect۰TypeFor(a *analysis, cgn *cgnode) {
+	// The result is the rtype of the type argument
+	// of this instance of the generic function.
+	if targs := cgn.fn.TypeArgs(); len(targs) == 1 {
+		a.addressOf(a.reflectType, a.funcResults(cgn.obj), a.makeRtype(targs[0]))
+	}
+}
+
 // ---------- func ValueOf(interface{}) Value ----------
 
 func ext۰reflect۰ValueOf(a *analysis, cgn *cgnode) {
@@ -6,6 +6,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"reflect"
 )
 
 type S[T any] struct{ t T }
@@ -43,6 +44,23 @@ func fn[T any](a *T) {
 	print(x) // @pointstoquery <-(*x[i].a)[key] command-line-arguments.a | command-line-arguments.b
 }
 
+type Box[T any] struct{ p *T }
+
+func (b Box[T]) Get() *T {
+	print(b.p) // @pointsto command-line-arguments.c
+	return b.p
+}
+
+type getter interface{ Get() *int }
+
+var c int
+
+func viaInterface() {
+	var g getter = Box[int]{&c}
+	print(g.Get())                        // @pointsto command-line-arguments.c
+	print(reflect.ValueOf(g).Interface()) // @types Box[int]
+}
+
 func main() {
 	// os.Args is considered intrinsically allocated,
 	// but may also be set explicitly (e.g. on Windows), hence '...'.
@@ -60,9 +78,12 @@ func main() {
 
 	fn(&a)
 	fn(&b)
+
+	viaInterface()
 }
 
 // @calls (*fmt.pp).handleMethods -> (*command-line-arguments.S[int]).String[int]
 // @calls (*fmt.pp).handleMethods -> (*command-line-arguments.S[bool]).String[bool]
 // @calls command-line-arguments.Caller[int] -> (*command-line-arguments.S[int]).String[int]
 // @calls command-line-arguments.Caller[bool] -> (*command-line-arguments.S[bool]).String[bool]
+// @calls command-line-arguments.viaInterface -> (command-line-arguments.Box[int]).Get[int]
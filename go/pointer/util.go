@@ -16,6 +16,8 @@ import (
 	exec "golang.org/x/sys/execabs"
 
 	"golang.org/x/tools/container/intsets"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/internal/typeparams"
 )
 
 // CanPoint reports whether the type T is pointerlike,
@@ -29,6 +31,19 @@ func CanPoint(T types.Type) bool {
 		return CanPoint(T.Underlying())
 	case *types.Pointer, *types.Interface, *types.Map, *types.Chan, *types.Signature, *types.Slice:
 		return true
+	case *typeparams.TypeParam:
+		// A type parameter is pointerlike if
+		// any type in its type set may be.
+		terms, err := typeparams.StructuralTerms(T)
+		if err != nil || len(terms) == 0 {
+			return true // unrestricted
+		}
+		for _, term := range terms {
+			if CanPoint(term.Type().Underlying()) {
+				return true
+			}
+		}
+		return false
 	}
 
 	return false // array struct tuple builtin basic
@@ -248,6 +263,12 @@ func (a *analysis) offsetOf(typ types.Type, index int) uint32 {
 	return offset
 }
 
+// isGenericBody reports whether fn is the body of a generic
+// function, as opposed to one of its instances.
+func isGenericBody(fn *ssa.Function) bool {
+	return fn.TypeParams().Len() > 0 && len(fn.TypeArgs()) == 0
+}
+
 // sliceToArray returns the type representing the arrays to which
 // slice type slice points.
 func sliceToArray(slice types.Type) *types.Array {
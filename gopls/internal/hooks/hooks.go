@@ -20,7 +20,15 @@ func Options(options *source.Options) {
 		case "old":
 			options.ComputeEdits = ComputeEdits
 		case "new":
-			options.ComputeEdits = diff.Strings
+			options.ComputeEdits = func(before, after string) []diff.Edit {
+				return diff.Strings(before, after)
+			}
+		case "histogram":
+			options.ComputeEdits = func(before, after string) []diff.Edit {
+				return diff.Strings(before, after, diff.Histogram)
+			}
+		case "tokens":
+			options.ComputeEdits = diff.GoTokens
 		default:
 			options.ComputeEdits = BothDiffs
 		}
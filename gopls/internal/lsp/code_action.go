@@ -7,6 +7,10 @@ package lsp
 import (
 	"context"
 	"fmt"
+	"go/build"
+	"go/types"
+	"io"
+	"path/filepath"
 	"sort"
 	"strings"
 
@@ -66,6 +70,10 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 		return nil, fmt.Errorf("no supported code action to execute for %s, wanted %v", uri, params.Context.Only)
 	}
 
+	// If the client can resolve the edits of code actions lazily, leave
+	// the computation of those of Go files to resolveCodeAction.
+	deferEdits := snapshot.View().Options().CodeActionResolveEdits && kind == source.Go
+
 	var codeActions []protocol.CodeAction
 	switch kind {
 	case source.Mod:
@@ -81,7 +89,7 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 			if err != nil {
 				return nil, err
 			}
-			quickFixes, err := codeActionsMatchingDiagnostics(ctx, snapshot, diagnostics, append(diags, udiags...))
+			quickFixes, err := codeActionsMatchingDiagnostics(ctx, snapshot, diagnostics, append(diags, udiags...), false)
 			if err != nil {
 				return nil, err
 			}
@@ -98,7 +106,7 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 				m[v.Range] = append(m[v.Range], v)
 			}
 			for _, sdiags := range m {
-				quickFixes, err = codeActionsMatchingDiagnostics(ctx, snapshot, diagnostics, sdiags)
+				quickFixes, err = codeActionsMatchingDiagnostics(ctx, snapshot, diagnostics, sdiags, false)
 				if err != nil {
 					return nil, err
 				}
@@ -117,61 +125,53 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 		// First, process any missing imports and pair them with the
 		// diagnostics they fix.
 		if wantQuickFixes := wanted[protocol.QuickFix] && len(diagnostics) > 0; wantQuickFixes || wanted[protocol.SourceOrganizeImports] {
-			importEdits, importEditsPerFix, err := source.AllImportsFixes(ctx, snapshot, fh)
-			if err != nil {
-				event.Error(ctx, "imports fixes", err, tag.File.Of(fh.URI().Filename()))
-			}
-			// Separate this into a set of codeActions per diagnostic, where
-			// each action is the addition, removal, or renaming of one import.
-			if wantQuickFixes {
-				for _, importFix := range importEditsPerFix {
-					fixes := importDiagnostics(importFix.Fix, diagnostics)
-					if len(fixes) == 0 {
-						continue
+			if deferEdits {
+				codeActions = append(codeActions, importCodeActions(ctx, snapshot, fh, wanted, diagnostics)...)
+			} else {
+				importEdits, importEditsPerFix, err := source.AllImportsFixes(ctx, snapshot, fh)
+				if err != nil {
+					event.Error(ctx, "imports fixes", err, tag.File.Of(fh.URI().Filename()))
+				}
+				// Separate this into a set of codeActions per diagnostic, where
+				// each action is the addition, removal, or renaming of one import.
+				if wantQuickFixes {
+					for _, importFix := range importEditsPerFix {
+						fixes := importDiagnostics(importFix.Fix, diagnostics)
+						if len(fixes) == 0 {
+							continue
+						}
+						codeActions = append(codeActions, protocol.CodeAction{
+							Title: importFixTitle(importFix.Fix),
+							Kind:  protocol.QuickFix,
+							Edit: &protocol.WorkspaceEdit{
+								DocumentChanges: documentChanges(fh, importFix.Edits),
+							},
+							Diagnostics: fixes,
+						})
 					}
+				}
+
+				// Send all of the import edits as one code action if the file is
+				// being organized.
+				if wanted[protocol.SourceOrganizeImports] && len(importEdits) > 0 {
 					codeActions = append(codeActions, protocol.CodeAction{
-						Title: importFixTitle(importFix.Fix),
-						Kind:  protocol.QuickFix,
+						Title: "Organize Imports",
+						Kind:  protocol.SourceOrganizeImports,
 						Edit: &protocol.WorkspaceEdit{
-							DocumentChanges: documentChanges(fh, importFix.Edits),
+							DocumentChanges: documentChanges(fh, importEdits),
 						},
-						Diagnostics: fixes,
 					})
 				}
 			}
-
-			// Send all of the import edits as one code action if the file is
-			// being organized.
-			if wanted[protocol.SourceOrganizeImports] && len(importEdits) > 0 {
-				codeActions = append(codeActions, protocol.CodeAction{
-					Title: "Organize Imports",
-					Kind:  protocol.SourceOrganizeImports,
-					Edit: &protocol.WorkspaceEdit{
-						DocumentChanges: documentChanges(fh, importEdits),
-					},
-				})
-			}
 		}
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
 
-		// Type-check the package and also run analysis,
-		// then combine their diagnostics.
-		pkg, _, err := source.PackageForFile(ctx, snapshot, fh.URI(), source.NarrowestPackage)
+		fileDiags, err := fileDiagnostics(ctx, snapshot, uri)
 		if err != nil {
 			return nil, err
 		}
-		pkgDiags, err := pkg.DiagnosticsForFile(ctx, snapshot, uri)
-		if err != nil {
-			return nil, err
-		}
-		analysisDiags, err := source.Analyze(ctx, snapshot, pkg.Metadata().ID, true)
-		if err != nil {
-			return nil, err
-		}
-		var fileDiags []*source.Diagnostic
-		source.CombineDiagnostics(pkgDiags, analysisDiags[uri], &fileDiags, &fileDiags)
 
 		// Split diagnostics into fixes, which must match incoming diagnostics,
 		// and non-fixes, which must match the requested range. Build actions
@@ -195,7 +195,7 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 			}
 		}
 
-		fixActions, err := codeActionsMatchingDiagnostics(ctx, snapshot, diagnostics, fixDiags)
+		fixActions, err := codeActionsMatchingDiagnostics(ctx, snapshot, diagnostics, fixDiags, deferEdits)
 		if err != nil {
 			return nil, err
 		}
@@ -207,7 +207,7 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 			if !protocol.Intersect(nonfix.Range, params.Range) {
 				continue
 			}
-			actions, err := codeActionsForDiagnostic(ctx, snapshot, nonfix, nil)
+			actions, err := codeActionsForDiagnostic(ctx, snapshot, nonfix, nil, deferEdits)
 			if err != nil {
 				return nil, err
 			}
@@ -222,6 +222,28 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 			codeActions = append(codeActions, fixes...)
 		}
 
+		if wanted[protocol.RefactorInline] {
+			fixes, err := inliningFixes(ctx, snapshot, uri, params.Range)
+			if err != nil {
+				return nil, err
+			}
+			codeActions = append(codeActions, fixes...)
+		}
+
+		if wanted[protocol.RefactorRewrite] {
+			fixes, err := moveFixes(ctx, snapshot, uri, params.Range)
+			if err != nil {
+				return nil, err
+			}
+			codeActions = append(codeActions, fixes...)
+
+			fixes, err = changeSignatureFixes(ctx, snapshot, uri, params.Range)
+			if err != nil {
+				return nil, err
+			}
+			codeActions = append(codeActions, fixes...)
+		}
+
 		if wanted[protocol.GoTest] {
 			fixes, err := goTest(ctx, snapshot, uri, params.Range)
 			if err != nil {
@@ -244,6 +266,145 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 	return filtered, nil
 }
 
+// codeActionData is the data of a code action whose edit is left to
+// codeAction/resolve. It identifies the fix that the action performs.
+type codeActionData struct {
+	URI protocol.DocumentURI
+
+	// ImportFixes, if set, are the fixes to the imports of the file
+	// that the action performs.
+	ImportFixes []*imports.ImportFix `json:",omitempty"`
+
+	// Diagnostic and Fix, if set, identify the diagnostic of the file
+	// and the title of its suggested fix that the action performs.
+	Diagnostic *protocol.Diagnostic `json:",omitempty"`
+	Fix        string               `json:",omitempty"`
+}
+
+func (s *Server) resolveCodeAction(ctx context.Context, action *protocol.CodeAction) (*protocol.CodeAction, error) {
+	if action.Data == nil {
+		return action, nil
+	}
+	var data codeActionData
+	if err := unmarshalData(action.Data, &data); err != nil {
+		return nil, fmt.Errorf("invalid code action data: %v", err)
+	}
+	snapshot, fh, ok, release, err := s.beginFileRequest(ctx, data.URI, source.Go)
+	defer release()
+	if !ok {
+		return nil, err
+	}
+
+	var changes []protocol.DocumentChanges
+	switch {
+	case len(data.ImportFixes) > 0:
+		edits, err := source.ImportFixEdits(ctx, snapshot, fh, data.ImportFixes)
+		if err != nil {
+			return nil, err
+		}
+		changes = documentChanges(fh, edits)
+
+	case data.Diagnostic != nil:
+		diags, err := fileDiagnostics(ctx, snapshot, fh.URI())
+		if err != nil {
+			return nil, err
+		}
+	search:
+		for _, sd := range diags {
+			if !sameDiagnostic(*data.Diagnostic, sd) {
+				continue
+			}
+			for _, fix := range sd.SuggestedFixes {
+				if fix.Title == data.Fix {
+					if changes, err = fixDocumentChanges(ctx, snapshot, fix); err != nil {
+						return nil, err
+					}
+					break search
+				}
+			}
+		}
+		if changes == nil {
+			return nil, fmt.Errorf("fix %q no longer applies", data.Fix)
+		}
+	}
+	action.Edit = &protocol.WorkspaceEdit{
+		DocumentChanges: changes,
+	}
+	return action, nil
+}
+
+// importCodeActions returns the wanted code actions that fix the
+// imports of fh, leaving their edits to resolveCodeAction. An action
+// that only sorts the imports has no fixes and carries its edit.
+func importCodeActions(ctx context.Context, snapshot source.Snapshot, fh source.FileHandle, wanted map[protocol.CodeActionKind]bool, diagnostics []protocol.Diagnostic) []protocol.CodeAction {
+	fixes, err := source.ImportFixes(ctx, snapshot, fh)
+	if err != nil {
+		event.Error(ctx, "imports fixes", err, tag.File.Of(fh.URI().Filename()))
+		return nil
+	}
+	uri := protocol.URIFromSpanURI(fh.URI())
+	var actions []protocol.CodeAction
+	if wanted[protocol.QuickFix] {
+		for _, fix := range fixes {
+			diags := importDiagnostics(fix, diagnostics)
+			if len(diags) == 0 {
+				continue
+			}
+			actions = append(actions, protocol.CodeAction{
+				Title:       importFixTitle(fix),
+				Kind:        protocol.QuickFix,
+				Diagnostics: diags,
+				Data:        &codeActionData{URI: uri, ImportFixes: []*imports.ImportFix{fix}},
+			})
+		}
+	}
+	if wanted[protocol.SourceOrganizeImports] {
+		action := protocol.CodeAction{
+			Title: "Organize Imports",
+			Kind:  protocol.SourceOrganizeImports,
+		}
+		if len(fixes) > 0 {
+			action.Data = &codeActionData{URI: uri, ImportFixes: fixes}
+		} else {
+			// Organizing the imports may still sort and group them.
+			// Without fixes to apply, that edit is cheap to compute.
+			edits, err := source.ImportFixEdits(ctx, snapshot, fh, nil)
+			if err != nil {
+				event.Error(ctx, "imports edits", err, tag.File.Of(fh.URI().Filename()))
+				return actions
+			}
+			if len(edits) == 0 {
+				return actions
+			}
+			action.Edit = &protocol.WorkspaceEdit{DocumentChanges: documentChanges(fh, edits)}
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// fileDiagnostics returns the type-checking and analysis diagnostics of
+// the Go file uri.
+func fileDiagnostics(ctx context.Context, snapshot source.Snapshot, uri span.URI) ([]*source.Diagnostic, error) {
+	// Type-check the package and also run analysis,
+	// then combine their diagnostics.
+	pkg, _, err := source.PackageForFile(ctx, snapshot, uri, source.NarrowestPackage)
+	if err != nil {
+		return nil, err
+	}
+	pkgDiags, err := pkg.DiagnosticsForFile(ctx, snapshot, uri)
+	if err != nil {
+		return nil, err
+	}
+	analysisDiags, err := source.Analyze(ctx, snapshot, pkg.Metadata().ID, true)
+	if err != nil {
+		return nil, err
+	}
+	var fileDiags []*source.Diagnostic
+	source.CombineDiagnostics(pkgDiags, analysisDiags[uri], &fileDiags, &fileDiags)
+	return fileDiags, nil
+}
+
 func (s *Server) getSupportedCodeActions() []protocol.CodeActionKind {
 	allCodeActionKinds := make(map[protocol.CodeActionKind]struct{})
 	for _, kinds := range s.session.Options().SupportedCodeActions {
@@ -370,6 +531,206 @@ func extractionFixes(ctx context.Context, snapshot source.Snapshot, uri span.URI
 	return actions, nil
 }
 
+// inliningFixes returns the code actions that inline the call
+// or the local variable at the selected range.
+func inliningFixes(ctx context.Context, snapshot source.Snapshot, uri span.URI, rng protocol.Range) ([]protocol.CodeAction, error) {
+	pkg, pgf, err := source.PackageForFile(ctx, snapshot, uri, source.NarrowestPackage)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	puri := protocol.URIFromSpanURI(uri)
+	var commands []protocol.Command
+	if call, _, err := source.CanInlineCall(start, end, pgf.File, pkg.GetTypesInfo()); err == nil && !source.IllTypedCallStatement(ctx, snapshot, pkg, pgf, call) {
+		cmd, err := command.NewApplyFixCommand("Inline call", command.ApplyFixArgs{
+			URI:   puri,
+			Fix:   source.InlineCall,
+			Range: rng,
+		})
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
+	if _, err := source.CanInlineVariable(start, end, pgf.File, pkg.GetTypesInfo()); err == nil {
+		cmd, err := command.NewApplyFixCommand("Inline variable", command.ApplyFixArgs{
+			URI:   puri,
+			Fix:   source.InlineVariable,
+			Range: rng,
+		})
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
+	var actions []protocol.CodeAction
+	for i := range commands {
+		actions = append(actions, protocol.CodeAction{
+			Title:   commands[i].Title,
+			Kind:    protocol.RefactorInline,
+			Command: &commands[i],
+		})
+	}
+	return actions, nil
+}
+
+// moveFixes offers to move the top-level declaration whose name is
+// selected to a new file of the same package, named after it. Moves to
+// existing files or other packages are available through the
+// MoveDeclaration command.
+func moveFixes(ctx context.Context, snapshot source.Snapshot, uri span.URI, rng protocol.Range) ([]protocol.CodeAction, error) {
+	if !supportsResourceOperation(snapshot, protocol.Create) {
+		return nil, nil
+	}
+	fh, err := snapshot.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	pgf, err := snapshot.ParseGo(ctx, fh, source.ParseFull)
+	if err != nil {
+		return nil, err
+	}
+	start, _, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	id := source.MovableDecl(pgf.File, start)
+	if id == nil {
+		return nil, nil
+	}
+	base := strings.ToLower(id.Name) + ".go"
+	if strings.HasSuffix(base, "_test.go") || constrainedByName(base) {
+		return nil, nil // the file would be a test, or not always built
+	}
+	dest := span.URIFromPath(filepath.Join(filepath.Dir(uri.Filename()), base))
+	if dest == uri {
+		return nil, nil
+	}
+	destFH, err := snapshot.GetFile(ctx, dest)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := destFH.Read(); err == nil {
+		return nil, nil // the destination file exists
+	}
+	loc, err := pgf.PosLocation(id.Pos(), id.End())
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := command.NewMoveDeclarationCommand(fmt.Sprintf("Move %s to file %s", id.Name, base), command.MoveDeclarationArgs{
+		Location:    loc,
+		Destination: protocol.URIFromSpanURI(dest),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []protocol.CodeAction{{
+		Title:   cmd.Title,
+		Kind:    protocol.RefactorRewrite,
+		Command: &cmd,
+	}}, nil
+}
+
+// constrainedByName reports whether the go command would not build a
+// file of the given base name on every platform, as when its name ends
+// in a GOOS or GOARCH suffix, such as _linux, or begins with _ or a dot.
+func constrainedByName(base string) bool {
+	// A file constrained by its name fails to match
+	// in a context that matches no GOOS or GOARCH.
+	ctxt := build.Default
+	ctxt.GOOS, ctxt.GOARCH = "none", "none"
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("package p\n")), nil
+	}
+	ok, err := ctxt.MatchFile(".", base)
+	return err != nil || !ok
+}
+
+// changeSignatureFixes offers to remove the selected parameter of a
+// function declaration, if it is unused, or to swap it with one of its
+// neighbors. Other changes are available through the ChangeSignature
+// command.
+func changeSignatureFixes(ctx context.Context, snapshot source.Snapshot, uri span.URI, rng protocol.Range) ([]protocol.CodeAction, error) {
+	pkg, pgf, err := source.PackageForFile(ctx, snapshot, uri, source.NarrowestPackage)
+	if err != nil {
+		return nil, err
+	}
+	start, _, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	id, index, unused := source.ParamAt(pkg.GetTypesInfo(), pgf.File, start)
+	if id == nil {
+		return nil, nil
+	}
+	fn, ok := pkg.GetTypesInfo().Defs[id].(*types.Func)
+	if !ok {
+		return nil, nil
+	}
+	sig := fn.Type().(*types.Signature)
+	n := sig.Params().Len()
+	name := sig.Params().At(index).Name()
+	loc, err := pgf.PosLocation(id.Pos(), id.End())
+	if err != nil {
+		return nil, err
+	}
+
+	// params returns the parameters of the original signature,
+	// with those at indices i and j swapped, or with i removed if j < 0.
+	params := func(i, j int) []command.SignatureParam {
+		var params []command.SignatureParam
+		for k := 0; k < n; k++ {
+			if k != i || j >= 0 {
+				params = append(params, command.SignatureParam{Index: k})
+			}
+		}
+		if j >= 0 {
+			params[i], params[j] = params[j], params[i]
+		}
+		return params
+	}
+	var commands []protocol.Command
+	add := func(title string, params []command.SignatureParam) error {
+		cmd, err := command.NewChangeSignatureCommand(title, command.ChangeSignatureArgs{
+			Location: loc,
+			Params:   params,
+		})
+		if err == nil {
+			commands = append(commands, cmd)
+		}
+		return err
+	}
+	if unused {
+		if err := add(fmt.Sprintf("Remove unused parameter %s", name), params(index, -1)); err != nil {
+			return nil, err
+		}
+	}
+	if !sig.Variadic() || index < n-1 {
+		if index > 0 {
+			if err := add(fmt.Sprintf("Move parameter %s left", name), params(index, index-1)); err != nil {
+				return nil, err
+			}
+		}
+		if index < n-1 && !(sig.Variadic() && index+1 == n-1) {
+			if err := add(fmt.Sprintf("Move parameter %s right", name), params(index, index+1)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var actions []protocol.CodeAction
+	for i := range commands {
+		actions = append(actions, protocol.CodeAction{
+			Title:   commands[i].Title,
+			Kind:    protocol.RefactorRewrite,
+			Command: &commands[i],
+		})
+	}
+	return actions, nil
+}
+
 func documentChanges(fh source.FileHandle, edits []protocol.TextEdit) []protocol.DocumentChanges {
 	return []protocol.DocumentChanges{
 		{
@@ -386,7 +747,7 @@ func documentChanges(fh source.FileHandle, edits []protocol.TextEdit) []protocol
 	}
 }
 
-func codeActionsMatchingDiagnostics(ctx context.Context, snapshot source.Snapshot, pdiags []protocol.Diagnostic, sdiags []*source.Diagnostic) ([]protocol.CodeAction, error) {
+func codeActionsMatchingDiagnostics(ctx context.Context, snapshot source.Snapshot, pdiags []protocol.Diagnostic, sdiags []*source.Diagnostic, deferEdits bool) ([]protocol.CodeAction, error) {
 	var actions []protocol.CodeAction
 	for _, sd := range sdiags {
 		var diag *protocol.Diagnostic
@@ -399,7 +760,7 @@ func codeActionsMatchingDiagnostics(ctx context.Context, snapshot source.Snapsho
 		if diag == nil {
 			continue
 		}
-		diagActions, err := codeActionsForDiagnostic(ctx, snapshot, sd, diag)
+		diagActions, err := codeActionsForDiagnostic(ctx, snapshot, sd, diag, deferEdits)
 		if err != nil {
 			return nil, err
 		}
@@ -409,34 +770,36 @@ func codeActionsMatchingDiagnostics(ctx context.Context, snapshot source.Snapsho
 	return actions, nil
 }
 
-func codeActionsForDiagnostic(ctx context.Context, snapshot source.Snapshot, sd *source.Diagnostic, pd *protocol.Diagnostic) ([]protocol.CodeAction, error) {
+// codeActionsForDiagnostic returns the code actions that apply the
+// suggested fixes of sd. If deferEdits is set, the edits of the fixes
+// are left to resolveCodeAction, which finds them again from the data
+// of the actions.
+func codeActionsForDiagnostic(ctx context.Context, snapshot source.Snapshot, sd *source.Diagnostic, pd *protocol.Diagnostic, deferEdits bool) ([]protocol.CodeAction, error) {
 	var actions []protocol.CodeAction
 	for _, fix := range sd.SuggestedFixes {
-		var changes []protocol.DocumentChanges
-		for uri, edits := range fix.Edits {
-			fh, err := snapshot.GetFile(ctx, uri)
+		action := protocol.CodeAction{
+			Title:   fix.Title,
+			Kind:    fix.ActionKind,
+			Command: fix.Command,
+		}
+		if deferEdits && len(fix.Edits) > 0 {
+			action.Data = &codeActionData{
+				URI: protocol.URIFromSpanURI(sd.URI),
+				Diagnostic: &protocol.Diagnostic{
+					Range:   sd.Range,
+					Message: strings.TrimSpace(sd.Message),
+					Source:  string(sd.Source),
+				},
+				Fix: fix.Title,
+			}
+		} else {
+			changes, err := fixDocumentChanges(ctx, snapshot, fix)
 			if err != nil {
 				return nil, err
 			}
-			changes = append(changes, protocol.DocumentChanges{
-				TextDocumentEdit: &protocol.TextDocumentEdit{
-					TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
-						Version: fh.Version(),
-						TextDocumentIdentifier: protocol.TextDocumentIdentifier{
-							URI: protocol.URIFromSpanURI(fh.URI()),
-						},
-					},
-					Edits: edits,
-				},
-			})
-		}
-		action := protocol.CodeAction{
-			Title: fix.Title,
-			Kind:  fix.ActionKind,
-			Edit: &protocol.WorkspaceEdit{
+			action.Edit = &protocol.WorkspaceEdit{
 				DocumentChanges: changes,
-			},
-			Command: fix.Command,
+			}
 		}
 		if pd != nil {
 			action.Diagnostics = []protocol.Diagnostic{*pd}
@@ -446,6 +809,20 @@ func codeActionsForDiagnostic(ctx context.Context, snapshot source.Snapshot, sd
 	return actions, nil
 }
 
+// fixDocumentChanges returns the document changes that apply the edits
+// of fix to the current versions of the files.
+func fixDocumentChanges(ctx context.Context, snapshot source.Snapshot, fix source.SuggestedFix) ([]protocol.DocumentChanges, error) {
+	var changes []protocol.DocumentChanges
+	for uri, edits := range fix.Edits {
+		fh, err := snapshot.GetFile(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, documentChanges(fh, edits)...)
+	}
+	return changes, nil
+}
+
 func sameDiagnostic(pd protocol.Diagnostic, sd *source.Diagnostic) bool {
 	return pd.Message == strings.TrimSpace(sd.Message) && // extra space may have been trimmed when converting to protocol.Diagnostic
 		protocol.CompareRange(pd.Range, sd.Range) == 0 && pd.Source == string(sd.Source)
@@ -799,6 +799,105 @@ func (c *commandHandler) AddImport(ctx context.Context, args command.AddImportAr
 	})
 }
 
+func (c *commandHandler) MoveDeclaration(ctx context.Context, args command.MoveDeclarationArgs) error {
+	return c.run(ctx, commandConfig{
+		progress: "Moving declaration",
+		forURI:   args.Location.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		edits, err := source.MoveDeclaration(ctx, deps.snapshot, deps.fh, args.Location.Range.Start, args.Destination.SpanURI())
+		if err != nil {
+			return fmt.Errorf("could not move declaration: %v", err)
+		}
+		var uris []span.URI
+		for uri := range edits {
+			uris = append(uris, uri)
+		}
+		sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+
+		var changes []protocol.DocumentChanges
+		for _, uri := range uris {
+			fh, err := deps.snapshot.GetFile(ctx, uri)
+			if err != nil {
+				return err
+			}
+			if _, err := fh.Read(); err != nil {
+				// The destination file does not yet exist.
+				if !supportsResourceOperation(deps.snapshot, protocol.Create) {
+					return fmt.Errorf("could not create %s: LSP client does not support file creation", uri.Filename())
+				}
+				changes = append(changes, protocol.DocumentChanges{
+					CreateFile: &protocol.CreateFile{
+						Kind: string(protocol.Create),
+						URI:  protocol.URIFromSpanURI(uri),
+					},
+				})
+			}
+			changes = append(changes, documentChanges(fh, edits[uri])...)
+		}
+		r, err := c.s.client.ApplyEdit(ctx, &protocol.ApplyWorkspaceEditParams{
+			Edit: protocol.WorkspaceEdit{
+				DocumentChanges: changes,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not apply edits: %v", err)
+		}
+		if !r.Applied {
+			return errors.New(r.FailureReason)
+		}
+		return nil
+	})
+}
+
+func (c *commandHandler) ChangeSignature(ctx context.Context, args command.ChangeSignatureArgs) error {
+	return c.run(ctx, commandConfig{
+		progress: "Changing signature",
+		forURI:   args.Location.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		edits, err := source.ChangeSignature(ctx, deps.snapshot, deps.fh, args.Location.Range.Start, args.Params)
+		if err != nil {
+			return fmt.Errorf("could not change signature: %v", err)
+		}
+		var uris []span.URI
+		for uri := range edits {
+			uris = append(uris, uri)
+		}
+		sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+
+		var changes []protocol.DocumentChanges
+		for _, uri := range uris {
+			fh, err := deps.snapshot.GetFile(ctx, uri)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, documentChanges(fh, edits[uri])...)
+		}
+		r, err := c.s.client.ApplyEdit(ctx, &protocol.ApplyWorkspaceEditParams{
+			Edit: protocol.WorkspaceEdit{
+				DocumentChanges: changes,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("could not apply edits: %v", err)
+		}
+		if !r.Applied {
+			return errors.New(r.FailureReason)
+		}
+		return nil
+	})
+}
+
+// supportsResourceOperation reports whether the client
+// supports the specified kind of resource operation.
+func supportsResourceOperation(snapshot source.Snapshot, kind protocol.ResourceOperationKind) bool {
+	for _, op := range snapshot.View().Options().SupportedResourceOperations {
+		if op == kind {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *commandHandler) StartDebugging(ctx context.Context, args command.DebuggingArgs) (result command.DebuggingResult, _ error) {
 	addr := args.Addr
 	if addr == "" {
@@ -22,6 +22,7 @@ const (
 	AddDependency         Command = "add_dependency"
 	AddImport             Command = "add_import"
 	ApplyFix              Command = "apply_fix"
+	ChangeSignature       Command = "change_signature"
 	CheckUpgrades         Command = "check_upgrades"
 	EditGoDirective       Command = "edit_go_directive"
 	FetchVulncheckResult  Command = "fetch_vulncheck_result"
@@ -31,6 +32,7 @@ const (
 	ListImports           Command = "list_imports"
 	ListKnownPackages     Command = "list_known_packages"
 	MemStats              Command = "mem_stats"
+	MoveDeclaration       Command = "move_declaration"
 	RegenerateCgo         Command = "regenerate_cgo"
 	RemoveDependency      Command = "remove_dependency"
 	ResetGoModDiagnostics Command = "reset_go_mod_diagnostics"
@@ -49,6 +51,7 @@ var Commands = []Command{
 	AddDependency,
 	AddImport,
 	ApplyFix,
+	ChangeSignature,
 	CheckUpgrades,
 	EditGoDirective,
 	FetchVulncheckResult,
@@ -58,6 +61,7 @@ var Commands = []Command{
 	ListImports,
 	ListKnownPackages,
 	MemStats,
+	MoveDeclaration,
 	RegenerateCgo,
 	RemoveDependency,
 	ResetGoModDiagnostics,
@@ -92,6 +96,12 @@ func Dispatch(ctx context.Context, params *protocol.ExecuteCommandParams, s Inte
 			return nil, err
 		}
 		return nil, s.ApplyFix(ctx, a0)
+	case "gopls.change_signature":
+		var a0 ChangeSignatureArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return nil, s.ChangeSignature(ctx, a0)
 	case "gopls.check_upgrades":
 		var a0 CheckUpgradesArgs
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
@@ -142,6 +152,12 @@ func Dispatch(ctx context.Context, params *protocol.ExecuteCommandParams, s Inte
 		return s.ListKnownPackages(ctx, a0)
 	case "gopls.mem_stats":
 		return s.MemStats(ctx)
+	case "gopls.move_declaration":
+		var a0 MoveDeclarationArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return nil, s.MoveDeclaration(ctx, a0)
 	case "gopls.regenerate_cgo":
 		var a0 URIArg
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
@@ -256,6 +272,18 @@ func NewApplyFixCommand(title string, a0 ApplyFixArgs) (protocol.Command, error)
 	}, nil
 }
 
+func NewChangeSignatureCommand(title string, a0 ChangeSignatureArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.change_signature",
+		Arguments: args,
+	}, nil
+}
+
 func NewCheckUpgradesCommand(title string, a0 CheckUpgradesArgs) (protocol.Command, error) {
 	args, err := MarshalArgs(a0)
 	if err != nil {
@@ -364,6 +392,18 @@ func NewMemStatsCommand(title string) (protocol.Command, error) {
 	}, nil
 }
 
+func NewMoveDeclarationCommand(title string, a0 MoveDeclarationArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.move_declaration",
+		Arguments: args,
+	}, nil
+}
+
 func NewRegenerateCgoCommand(title string, a0 URIArg) (protocol.Command, error) {
 	args, err := MarshalArgs(a0)
 	if err != nil {
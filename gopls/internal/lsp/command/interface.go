@@ -139,6 +139,22 @@ type Interface interface {
 	// themselves.
 	AddImport(context.Context, AddImportArgs) error
 
+	// MoveDeclaration: Move a declaration
+	//
+	// Moves the top-level declaration at the given location, along with
+	// the methods of a type, to another file, which may belong to another
+	// package of the workspace. References to the declaration are updated
+	// throughout the workspace. The method calls applyEdit on the client.
+	MoveDeclaration(context.Context, MoveDeclarationArgs) error
+
+	// ChangeSignature: Change the parameters of a function
+	//
+	// Removes, adds, and reorders the parameters of the function or
+	// method at the given location, updating every call throughout the
+	// workspace and every method coupled to it by an interface. The
+	// method calls applyEdit on the client.
+	ChangeSignature(context.Context, ChangeSignatureArgs) error
+
 	// StartDebugging: Start the gopls debug server
 	//
 	// Start the gopls debug server if it isn't running, and return the debug
@@ -252,6 +268,32 @@ type AddImportArgs struct {
 	URI protocol.DocumentURI
 }
 
+type MoveDeclarationArgs struct {
+	// The location of the name of the declaration to move.
+	Location protocol.Location
+	// The file to which the declaration is moved. If the file does not
+	// exist, it is created in the package of its directory.
+	Destination protocol.DocumentURI
+}
+
+type ChangeSignatureArgs struct {
+	// The location of the name of the function or method to change.
+	Location protocol.Location
+	// The parameters of the new signature, in order.
+	Params []SignatureParam
+}
+
+// A SignatureParam is a parameter of a changed signature.
+type SignatureParam struct {
+	// The index of the parameter in the original signature,
+	// or -1 for a new parameter.
+	Index int
+	// The name and type of a new parameter. The name may be empty.
+	Name, Type string
+	// The argument passed to a new parameter at each existing call.
+	Default string
+}
+
 type ListKnownPackagesResult struct {
 	// Packages is a list of packages relative
 	// to the URIArg passed by the command request.
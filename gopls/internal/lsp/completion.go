@@ -19,6 +19,8 @@ import (
 )
 
 func (s *Server) completion(ctx context.Context, params *protocol.CompletionParams) (*protocol.CompletionList, error) {
+	cell := params.TextDocument.URI
+	layout := s.toNotebookFile(&params.TextDocumentPositionParams)
 	snapshot, fh, ok, release, err := s.beginFileRequest(ctx, params.TextDocument.URI, source.UnknownKind)
 	defer release()
 	if !ok {
@@ -65,7 +67,24 @@ func (s *Server) completion(ctx context.Context, params *protocol.CompletionPara
 	options := snapshot.View().Options()
 	incompleteResults := options.DeepCompletion || options.Matcher == source.Fuzzy
 
-	items := toProtocolCompletionItems(candidates, rng, options)
+	// If the client can resolve the deferred properties of the items,
+	// keep the candidates for resolveCompletionItem.
+	var id uint64
+	if options.CompletionResolveDocumentation || options.CompletionResolveEdits {
+		s.lastCompletionMu.Lock()
+		s.lastCompletion.id++
+		id = s.lastCompletion.id
+		s.lastCompletion.uri = params.TextDocument.URI
+		s.lastCompletion.candidates = candidates
+		s.lastCompletion.cell = cell
+		s.lastCompletion.layout = layout
+		s.lastCompletionMu.Unlock()
+	}
+
+	items := toProtocolCompletionItems(candidates, rng, options, id)
+	if layout != nil {
+		items = toCellCompletionItems(layout, cell, items)
+	}
 
 	return &protocol.CompletionList{
 		IsIncomplete: incompleteResults,
@@ -73,7 +92,10 @@ func (s *Server) completion(ctx context.Context, params *protocol.CompletionPara
 	}, nil
 }
 
-func toProtocolCompletionItems(candidates []completion.CompletionItem, rng protocol.Range, options *source.Options) []protocol.CompletionItem {
+// toProtocolCompletionItems converts the candidates of a completion to
+// protocol items. If id is nonzero, the items record the id and the
+// index of their candidate for resolveCompletionItem.
+func toProtocolCompletionItems(candidates []completion.CompletionItem, rng protocol.Range, options *source.Options, id uint64) []protocol.CompletionItem {
 	var (
 		items                  = make([]protocol.CompletionItem, 0, len(candidates))
 		numDeepCompletionsSeen int
@@ -101,15 +123,6 @@ func toProtocolCompletionItems(candidates []completion.CompletionItem, rng proto
 			continue
 		}
 
-		doc := &protocol.Or_CompletionItem_documentation{
-			Value: protocol.MarkupContent{
-				Kind:  protocol.Markdown,
-				Value: source.CommentToMarkdown(candidate.Documentation, options),
-			},
-		}
-		if options.PreferredContentFormat != protocol.Markdown {
-			doc.Value = candidate.Documentation
-		}
 		item := protocol.CompletionItem{
 			Label:  candidate.Label,
 			Detail: candidate.Detail,
@@ -130,11 +143,97 @@ func toProtocolCompletionItems(candidates []completion.CompletionItem, rng proto
 			FilterText: strings.TrimLeft(candidate.InsertText, "&*"),
 
 			Preselect:     i == 0,
-			Documentation: doc,
+			Documentation: completionDocumentation(candidate.Documentation, options),
 			Tags:          candidate.Tags,
 			Deprecated:    candidate.Deprecated,
 		}
+		if id != 0 {
+			item.Data = &completionData{ID: id, Index: i}
+		}
 		items = append(items, item)
 	}
 	return items
 }
+
+func completionDocumentation(doc string, options *source.Options) *protocol.Or_CompletionItem_documentation {
+	if options.PreferredContentFormat != protocol.Markdown {
+		return &protocol.Or_CompletionItem_documentation{Value: doc}
+	}
+	return &protocol.Or_CompletionItem_documentation{
+		Value: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: source.CommentToMarkdown(doc, options),
+		},
+	}
+}
+
+// toCellCompletionItems translates the edits of the completion items
+// of the synthetic Go file of a notebook to the cell. Edits of other
+// cells, such as the addition of imports, are dropped.
+func toCellCompletionItems(layout *notebookLayout, cell protocol.DocumentURI, items []protocol.CompletionItem) []protocol.CompletionItem {
+	res := items[:0]
+	for _, item := range items {
+		edits := layout.toCellEdits(cell, []protocol.TextEdit{*item.TextEdit})
+		if len(edits) == 0 {
+			continue
+		}
+		item.TextEdit = &edits[0]
+		item.AdditionalTextEdits = layout.toCellEdits(cell, item.AdditionalTextEdits)
+		res = append(res, item)
+	}
+	return res
+}
+
+// completionCache holds the candidates of a completion request.
+type completionCache struct {
+	id         uint64 // sequence number of the request
+	uri        protocol.DocumentURI
+	candidates []completion.CompletionItem
+
+	// For a notebook cell, uri is the synthetic Go file of the notebook.
+	cell   protocol.DocumentURI
+	layout *notebookLayout
+}
+
+// completionData is the data of a completion item, which identifies
+// its candidate.
+type completionData struct {
+	ID    uint64 // id of the completion request
+	Index int    // index of the candidate
+}
+
+func (s *Server) resolveCompletionItem(ctx context.Context, item *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+	if item.Data == nil {
+		return item, nil
+	}
+	var data completionData
+	if err := unmarshalData(item.Data, &data); err != nil {
+		return nil, fmt.Errorf("invalid completion item data: %v", err)
+	}
+
+	s.lastCompletionMu.Lock()
+	last := s.lastCompletion
+	s.lastCompletionMu.Unlock()
+	if data.ID != last.id || data.Index < 0 || data.Index >= len(last.candidates) {
+		return item, nil // the item belongs to an earlier completion
+	}
+	candidate := last.candidates[data.Index]
+
+	snapshot, _, ok, release, err := s.beginFileRequest(ctx, last.uri, source.Go)
+	defer release()
+	if !ok {
+		return nil, err
+	}
+	if err := candidate.Resolve(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	options := snapshot.View().Options()
+	item.Documentation = completionDocumentation(candidate.Documentation, options)
+	item.AdditionalTextEdits = candidate.AdditionalTextEdits
+	if last.layout != nil {
+		item.AdditionalTextEdits = last.layout.toCellEdits(last.cell, item.AdditionalTextEdits)
+	}
+	item.Tags = candidate.Tags
+	item.Deprecated = candidate.Deprecated
+	return item, nil
+}
@@ -15,6 +15,10 @@ import (
 )
 
 func (s *Server) definition(ctx context.Context, params *protocol.DefinitionParams) ([]protocol.Location, error) {
+	if layout := s.toNotebookFile(&params.TextDocumentPositionParams); layout != nil {
+		locs, err := s.definition(ctx, params)
+		return layout.toCellLocations(locs), err
+	}
 	// TODO(rfindley): definition requests should be multiplexed across all views.
 	snapshot, fh, ok, release, err := s.beginFileRequest(ctx, params.TextDocument.URI, source.UnknownKind)
 	defer release()
@@ -606,6 +606,13 @@ func (s *Server) publishDiagnostics(ctx context.Context, final bool, snapshot so
 	ctx, done := event.Start(ctx, "Server.publishDiagnostics", source.SnapshotLabels(snapshot)...)
 	defer done()
 
+	// A client that pulls diagnostics, and asked not to have them
+	// published, receives those of its files on request, so only the
+	// diagnostics of notebook cells, which cannot be pulled, are
+	// published to it.
+	options := s.session.Options()
+	pull := options.PullDiagnostics && options.PullDiagnosticsSupported
+
 	s.diagnosticsMu.Lock()
 	defer s.diagnosticsMu.Unlock()
 
@@ -663,11 +670,24 @@ func (s *Server) publishDiagnostics(ctx context.Context, final bool, snapshot so
 		if fh := snapshot.FindFile(uri); fh != nil { // file may have been deleted
 			version = fh.Version()
 		}
-		if err := s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
-			Diagnostics: toProtocolDiagnostics(diags),
-			URI:         protocol.URIFromSpanURI(uri),
-			Version:     version,
-		}); err == nil {
+		var err error
+		if layout := s.notebookLayout(uri); layout != nil {
+			// The diagnostics of the synthetic file of a notebook are
+			// those of its cells, provided that the cells are unchanged.
+			if layout.version != version {
+				continue
+			}
+			err = s.publishCellDiagnostics(ctx, layout, toProtocolDiagnostics(diags))
+		} else if pull {
+			continue
+		} else {
+			err = s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+				Diagnostics: toProtocolDiagnostics(diags),
+				URI:         protocol.URIFromSpanURI(uri),
+				Version:     version,
+			})
+		}
+		if err == nil {
 			r.publishedHash = hash
 			r.mustPublish = false // diagnostics have been successfully published
 			r.publishedSnapshotID = snapshot.GlobalID()
@@ -43,6 +43,7 @@ type Editor struct {
 	mu                 sync.Mutex
 	config             EditorConfig                // editor configuration
 	buffers            map[string]buffer           // open buffers (relative path -> buffer content)
+	notebooks          map[string]int32            // open notebooks (relative path -> version)
 	serverCapabilities protocol.ServerCapabilities // capabilities / options
 	watchPatterns      []*glob.Glob                // glob patterns to watch
 
@@ -104,12 +105,22 @@ type EditorConfig struct {
 
 	// Settings holds user-provided configuration for the LSP server.
 	Settings map[string]interface{}
+
+	// ResolveLazily, if set, makes the editor declare that it resolves
+	// the documentation and additional text edits of completion items
+	// and the edits of code actions lazily, using the resolve requests.
+	ResolveLazily bool
+
+	// PullDiagnostics, if set, makes the editor declare that it pulls
+	// diagnostics, using the textDocument/diagnostic request.
+	PullDiagnostics bool
 }
 
 // NewEditor Creates a new Editor.
 func NewEditor(sandbox *Sandbox, config EditorConfig) *Editor {
 	return &Editor{
 		buffers:    make(map[string]buffer),
+		notebooks:  make(map[string]int32),
 		sandbox:    sandbox,
 		defaultEnv: sandbox.GoEnv(),
 		config:     config,
@@ -256,6 +267,18 @@ func (e *Editor) initialize(ctx context.Context) error {
 	params.Capabilities.TextDocument.Completion.CompletionItem.TagSupport.ValueSet = []protocol.CompletionItemTag{protocol.ComplDeprecated}
 
 	params.Capabilities.TextDocument.Completion.CompletionItem.SnippetSupport = true
+	if e.config.ResolveLazily {
+		params.Capabilities.TextDocument.Completion.CompletionItem.ResolveSupport = &protocol.FResolveSupportPCompletionItem{
+			Properties: []string{"documentation", "additionalTextEdits"},
+		}
+		params.Capabilities.TextDocument.CodeAction.DataSupport = true
+		params.Capabilities.TextDocument.CodeAction.ResolveSupport = &protocol.PResolveSupportPCodeAction{
+			Properties: []string{"edit"},
+		}
+	}
+	if e.config.PullDiagnostics {
+		params.Capabilities.TextDocument.Diagnostic = &protocol.DiagnosticClientCapabilities{}
+	}
 	params.Capabilities.TextDocument.SemanticTokens.Requests.Full.Value = true
 	// copied from lsp/semantic.go to avoid import cycle in tests
 	params.Capabilities.TextDocument.SemanticTokens.TokenTypes = []string{
@@ -277,6 +300,7 @@ func (e *Editor) initialize(ctx context.Context) error {
 	params.Capabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
 		ResourceOperations: []protocol.ResourceOperationKind{
 			"rename",
+			"create",
 		},
 	}
 
@@ -529,6 +553,128 @@ func (e *Editor) sendDidClose(ctx context.Context, doc protocol.TextDocumentIden
 	return nil
 }
 
+// notebookCellScheme is the URI scheme of the cells of notebooks, as
+// in VS Code.
+const notebookCellScheme = "vscode-notebook-cell"
+
+// isNotebookCell reports whether uri is that of a notebook cell.
+func isNotebookCell(uri protocol.DocumentURI) bool {
+	return strings.HasPrefix(string(uri), notebookCellScheme+":")
+}
+
+// NotebookCellURI returns the URI of the cell of the given index of the
+// notebook at the workdir-relative path.
+func (e *Editor) NotebookCellURI(path string, cell int) protocol.DocumentURI {
+	return protocol.DocumentURI(fmt.Sprintf("%s:%s#%d", notebookCellScheme, filepath.ToSlash(e.sandbox.Workdir.AbsPath(path)), cell))
+}
+
+// OpenNotebook opens a notebook document whose Go code cells have the
+// given contents.
+func (e *Editor) OpenNotebook(ctx context.Context, path string, cells ...string) error {
+	e.mu.Lock()
+	if _, ok := e.notebooks[path]; ok {
+		e.mu.Unlock()
+		return fmt.Errorf("notebook %q already open", path)
+	}
+	e.notebooks[path] = 1
+	e.mu.Unlock()
+
+	params := &protocol.DidOpenNotebookDocumentParams{
+		NotebookDocument: protocol.NotebookDocument{
+			URI:          string(e.sandbox.Workdir.URI(path)),
+			NotebookType: "jupyter-notebook",
+			Version:      1,
+		},
+	}
+	for i, content := range cells {
+		uri := e.NotebookCellURI(path, i)
+		params.NotebookDocument.Cells = append(params.NotebookDocument.Cells, protocol.NotebookCell{
+			Kind:     protocol.Code,
+			Document: uri,
+		})
+		params.CellTextDocuments = append(params.CellTextDocuments, protocol.TextDocumentItem{
+			URI:        uri,
+			LanguageID: "go",
+			Version:    1,
+			Text:       content,
+		})
+	}
+	if e.Server != nil {
+		if err := e.Server.DidOpenNotebookDocument(ctx, params); err != nil {
+			return fmt.Errorf("DidOpenNotebookDocument: %w", err)
+		}
+		// The server processes notebook notifications as those of
+		// its synthetic Go file.
+		e.callsMu.Lock()
+		e.calls.DidOpen++
+		e.callsMu.Unlock()
+	}
+	return nil
+}
+
+// SetNotebookCellContent replaces the content of a cell of an open
+// notebook.
+func (e *Editor) SetNotebookCellContent(ctx context.Context, path string, cell int, content string) error {
+	e.mu.Lock()
+	version, ok := e.notebooks[path]
+	if !ok {
+		e.mu.Unlock()
+		return fmt.Errorf("notebook %q is not open", path)
+	}
+	version++
+	e.notebooks[path] = version
+	e.mu.Unlock()
+
+	params := &protocol.DidChangeNotebookDocumentParams{
+		NotebookDocument: protocol.VersionedNotebookDocumentIdentifier{
+			Version: version,
+			URI:     string(e.sandbox.Workdir.URI(path)),
+		},
+		Change: protocol.NotebookDocumentChangeEvent{
+			Cells: &protocol.PCellsPChange{
+				TextContent: []protocol.Lit_NotebookDocumentChangeEvent_cells_textContent_Elem{{
+					Document: protocol.VersionedTextDocumentIdentifier{
+						Version:                version,
+						TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: e.NotebookCellURI(path, cell)},
+					},
+					Changes: []protocol.TextDocumentContentChangeEvent{{Text: content}},
+				}},
+			},
+		},
+	}
+	if e.Server != nil {
+		if err := e.Server.DidChangeNotebookDocument(ctx, params); err != nil {
+			return fmt.Errorf("DidChangeNotebookDocument: %w", err)
+		}
+		e.callsMu.Lock()
+		e.calls.DidChange++
+		e.callsMu.Unlock()
+	}
+	return nil
+}
+
+// CloseNotebook closes an open notebook.
+func (e *Editor) CloseNotebook(ctx context.Context, path string) error {
+	e.mu.Lock()
+	_, ok := e.notebooks[path]
+	delete(e.notebooks, path)
+	e.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("notebook %q is not open", path)
+	}
+	if e.Server != nil {
+		if err := e.Server.DidCloseNotebookDocument(ctx, &protocol.DidCloseNotebookDocumentParams{
+			NotebookDocument: protocol.NotebookDocumentIdentifier{URI: string(e.sandbox.Workdir.URI(path))},
+		}); err != nil {
+			return fmt.Errorf("DidCloseNotebookDocument: %w", err)
+		}
+		e.callsMu.Lock()
+		e.calls.DidClose++
+		e.callsMu.Unlock()
+	}
+	return nil
+}
+
 func (e *Editor) TextDocumentIdentifier(path string) protocol.TextDocumentIdentifier {
 	return protocol.TextDocumentIdentifier{
 		URI: e.sandbox.Workdir.URI(path),
@@ -815,6 +961,9 @@ func (e *Editor) extractFirstLocation(ctx context.Context, locs []protocol.Locat
 		return protocol.Location{}, nil
 	}
 
+	if isNotebookCell(locs[0].URI) {
+		return locs[0], nil
+	}
 	newPath := e.sandbox.Workdir.URIToPath(locs[0].URI)
 	if !e.HasBuffer(newPath) {
 		if err := e.OpenFile(ctx, newPath); err != nil {
@@ -860,6 +1009,13 @@ func (e *Editor) ApplyQuickFixes(ctx context.Context, loc protocol.Location, dia
 
 // ApplyCodeAction applies the given code action.
 func (e *Editor) ApplyCodeAction(ctx context.Context, action protocol.CodeAction) error {
+	if action.Edit == nil && action.Data != nil {
+		resolved, err := e.Server.ResolveCodeAction(ctx, &action)
+		if err != nil {
+			return fmt.Errorf("resolving code action: %w", err)
+		}
+		action = *resolved
+	}
 	if action.Edit != nil {
 		for _, change := range action.Edit.DocumentChanges {
 			if change.TextDocumentEdit != nil {
@@ -989,7 +1145,51 @@ func (e *Editor) FormatBuffer(ctx context.Context, path string) error {
 	return e.editBufferLocked(ctx, path, edits)
 }
 
+// FormatRange formats the statements of a Go file that overlap the
+// given location.
+func (e *Editor) FormatRange(ctx context.Context, loc protocol.Location) error {
+	if e.Server == nil {
+		return nil
+	}
+	params := &protocol.DocumentRangeFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: loc.URI},
+		Range:        loc.Range,
+	}
+	edits, err := e.Server.RangeFormatting(ctx, params)
+	if err != nil {
+		return fmt.Errorf("textDocument/rangeFormatting: %w", err)
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+	return e.EditBuffer(ctx, e.sandbox.Workdir.URIToPath(loc.URI), edits)
+}
+
+// FormatOnType formats a Go file as if the character ch had just been
+// typed at the given location.
+func (e *Editor) FormatOnType(ctx context.Context, loc protocol.Location, ch string) error {
+	if e.Server == nil {
+		return nil
+	}
+	params := &protocol.DocumentOnTypeFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: loc.URI},
+		Position:     loc.Range.Start,
+		Ch:           ch,
+	}
+	edits, err := e.Server.OnTypeFormatting(ctx, params)
+	if err != nil {
+		return fmt.Errorf("textDocument/onTypeFormatting: %w", err)
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+	return e.EditBuffer(ctx, e.sandbox.Workdir.URIToPath(loc.URI), edits)
+}
+
 func (e *Editor) checkBufferLocation(loc protocol.Location) error {
+	if isNotebookCell(loc.URI) {
+		return nil // the editor does not track the content of cells
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	path := e.sandbox.Workdir.URIToPath(loc.URI)
@@ -1059,12 +1259,8 @@ func (e *Editor) Completion(ctx context.Context, loc protocol.Location) (*protoc
 	if e.Server == nil {
 		return nil, nil
 	}
-	path := e.sandbox.Workdir.URIToPath(loc.URI)
-	e.mu.Lock()
-	_, ok := e.buffers[path]
-	e.mu.Unlock()
-	if !ok {
-		return nil, fmt.Errorf("buffer %q is not open", path)
+	if err := e.checkBufferLocation(loc); err != nil {
+		return nil, err
 	}
 	params := &protocol.CompletionParams{
 		TextDocumentPositionParams: protocol.LocationTextDocumentPositionParams(loc),
@@ -1076,12 +1272,31 @@ func (e *Editor) Completion(ctx context.Context, loc protocol.Location) (*protoc
 	return completions, nil
 }
 
+// ResolveCompletionItem executes a completionItem/resolve request on the
+// server.
+func (e *Editor) ResolveCompletionItem(ctx context.Context, item protocol.CompletionItem) (protocol.CompletionItem, error) {
+	if e.Server == nil {
+		return item, nil
+	}
+	resolved, err := e.Server.ResolveCompletionItem(ctx, &item)
+	if err != nil {
+		return item, err
+	}
+	return *resolved, nil
+}
+
 // AcceptCompletion accepts a completion for the given item at the given
-// position.
+// position, first resolving it if it has data for the server.
 func (e *Editor) AcceptCompletion(ctx context.Context, loc protocol.Location, item protocol.CompletionItem) error {
 	if e.Server == nil {
 		return nil
 	}
+	if item.Data != nil {
+		var err error
+		if item, err = e.ResolveCompletionItem(ctx, item); err != nil {
+			return fmt.Errorf("resolving completion item: %w", err)
+		}
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	path := e.sandbox.Workdir.URIToPath(loc.URI)
@@ -1221,7 +1436,52 @@ func (e *Editor) SignatureHelp(ctx context.Context, loc protocol.Location) (*pro
 	return e.Server.SignatureHelp(ctx, params)
 }
 
+// RenameFile renames the file or directory at oldPath to newPath, as a
+// user would.
 func (e *Editor) RenameFile(ctx context.Context, oldPath, newPath string) error {
+	// Notify the server of renamed directories, applying any edits it
+	// returns beforehand. The editor does not evaluate the server's
+	// file operation filters, assuming interest in all directories.
+	var (
+		params *protocol.RenameFilesParams
+		ops    *protocol.FileOperationOptions
+	)
+	if ws := e.serverCapabilities.Workspace; ws != nil {
+		ops = ws.FileOperations
+	}
+	if fi, err := os.Stat(e.sandbox.Workdir.AbsPath(oldPath)); err == nil && fi.IsDir() && e.Server != nil && ops != nil {
+		params = &protocol.RenameFilesParams{
+			Files: []protocol.FileRename{{
+				OldURI: string(e.sandbox.Workdir.URI(oldPath)),
+				NewURI: string(e.sandbox.Workdir.URI(newPath)),
+			}},
+		}
+	}
+	if params != nil && ops.WillRename != nil {
+		wsEdit, err := e.Server.WillRenameFiles(ctx, params)
+		if err != nil {
+			return fmt.Errorf("willRenameFiles: %w", err)
+		}
+		if wsEdit != nil {
+			for _, change := range wsEdit.DocumentChanges {
+				if err := e.applyDocumentChange(ctx, change); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := e.renameFile(ctx, oldPath, newPath); err != nil {
+		return err
+	}
+	if params != nil && ops.DidRename != nil {
+		return e.Server.DidRenameFiles(ctx, params)
+	}
+	return nil
+}
+
+// renameFile renames the file or directory at oldPath to newPath,
+// without notifying the server of the renaming.
+func (e *Editor) renameFile(ctx context.Context, oldPath, newPath string) error {
 	closed, opened, err := e.renameBuffers(ctx, oldPath, newPath)
 	if err != nil {
 		return err
@@ -1293,12 +1553,16 @@ func (e *Editor) applyDocumentChange(ctx context.Context, change protocol.Docume
 		oldPath := e.sandbox.Workdir.URIToPath(change.RenameFile.OldURI)
 		newPath := e.sandbox.Workdir.URIToPath(change.RenameFile.NewURI)
 
-		return e.RenameFile(ctx, oldPath, newPath)
+		return e.renameFile(ctx, oldPath, newPath)
+	}
+	if change.CreateFile != nil {
+		path := e.sandbox.Workdir.URIToPath(change.CreateFile.URI)
+		return e.sandbox.Workdir.WriteFile(ctx, path, "")
 	}
 	if change.TextDocumentEdit != nil {
 		return e.applyTextDocumentEdit(ctx, *change.TextDocumentEdit)
 	}
-	panic("Internal error: one of RenameFile or TextDocumentEdit must be set")
+	panic("Internal error: one of RenameFile, CreateFile, or TextDocumentEdit must be set")
 }
 
 func (e *Editor) applyTextDocumentEdit(ctx context.Context, change protocol.TextDocumentEdit) error {
@@ -1462,3 +1726,19 @@ func (e *Editor) DocumentHighlight(ctx context.Context, loc protocol.Location) (
 
 	return e.Server.DocumentHighlight(ctx, params)
 }
+
+// LinkedEditingRange executes a textDocument/linkedEditingRange request
+// at the given location.
+func (e *Editor) LinkedEditingRange(ctx context.Context, loc protocol.Location) (*protocol.LinkedEditingRanges, error) {
+	if e.Server == nil {
+		return nil, nil
+	}
+	if err := e.checkBufferLocation(loc); err != nil {
+		return nil, err
+	}
+	params := &protocol.LinkedEditingRangeParams{}
+	params.TextDocument.URI = loc.URI
+	params.Position = loc.Range.Start
+
+	return e.Server.LinkedEditingRange(ctx, params)
+}
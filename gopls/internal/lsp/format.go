@@ -29,3 +29,21 @@ func (s *Server) formatting(ctx context.Context, params *protocol.DocumentFormat
 	}
 	return nil, nil
 }
+
+func (s *Server) rangeFormatting(ctx context.Context, params *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
+	snapshot, fh, ok, release, err := s.beginFileRequest(ctx, params.TextDocument.URI, source.Go)
+	defer release()
+	if !ok {
+		return nil, err
+	}
+	return source.FormatRange(ctx, snapshot, fh, params.Range)
+}
+
+func (s *Server) onTypeFormatting(ctx context.Context, params *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
+	snapshot, fh, ok, release, err := s.beginFileRequest(ctx, params.TextDocument.URI, source.Go)
+	defer release()
+	if !ok {
+		return nil, err
+	}
+	return source.FormatOnType(ctx, snapshot, fh, params.Position, params.Ch)
+}
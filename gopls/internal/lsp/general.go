@@ -103,6 +103,7 @@ func (s *Server) initialize(ctx context.Context, params *protocol.ParamInitializ
 		// Using CodeActionOptions is only valid if codeActionLiteralSupport is set.
 		codeActionProvider = &protocol.CodeActionOptions{
 			CodeActionKinds: s.getSupportedCodeActions(),
+			ResolveProvider: true,
 		}
 	}
 	var renameOpts interface{} = true
@@ -112,6 +113,29 @@ func (s *Server) initialize(ctx context.Context, params *protocol.ParamInitializ
 		}
 	}
 
+	// Offer pull diagnostics only to clients that support them.
+	// Diagnostics are still published to such clients unless the
+	// pullDiagnostics setting is enabled (see publishDiagnostics).
+	var diagnosticProvider *protocol.Or_ServerCapabilities_diagnosticProvider
+	if options.PullDiagnosticsSupported {
+		diagnosticProvider = &protocol.Or_ServerCapabilities_diagnosticProvider{
+			Value: protocol.DiagnosticOptions{
+				InterFileDependencies: true,
+				WorkspaceDiagnostics:  true,
+			},
+		}
+	}
+
+	// Moving a directory may change import paths,
+	// so ask to be told of renamed folders.
+	folder := protocol.FolderPattern
+	renameFilters := &protocol.FileOperationRegistrationOptions{
+		Filters: []protocol.FileOperationFilter{{
+			Scheme:  "file",
+			Pattern: protocol.FileOperationPattern{Glob: "**", Matches: &folder},
+		}},
+	}
+
 	versionInfo := debug.VersionInfo()
 
 	// golang/go#45732: Warn users who've installed sergi/go-diff@v1.2.0, since
@@ -137,28 +161,45 @@ See https://github.com/golang/go/issues/45732 for more information.`,
 	return &protocol.InitializeResult{
 		Capabilities: protocol.ServerCapabilities{
 			CallHierarchyProvider: &protocol.Or_ServerCapabilities_callHierarchyProvider{Value: true},
+			TypeHierarchyProvider: &protocol.Or_ServerCapabilities_typeHierarchyProvider{Value: true},
 			CodeActionProvider:    codeActionProvider,
+			DiagnosticProvider:    diagnosticProvider,
 			CodeLensProvider:      &protocol.CodeLensOptions{}, // must be non-nil to enable the code lens capability
 			CompletionProvider: &protocol.CompletionOptions{
 				TriggerCharacters: []string{"."},
+				ResolveProvider:   true,
+			},
+			DefinitionProvider:              &protocol.Or_ServerCapabilities_definitionProvider{Value: true},
+			TypeDefinitionProvider:          &protocol.Or_ServerCapabilities_typeDefinitionProvider{Value: true},
+			ImplementationProvider:          &protocol.Or_ServerCapabilities_implementationProvider{Value: true},
+			DocumentFormattingProvider:      &protocol.Or_ServerCapabilities_documentFormattingProvider{Value: true},
+			DocumentRangeFormattingProvider: &protocol.Or_ServerCapabilities_documentRangeFormattingProvider{Value: true},
+			DocumentOnTypeFormattingProvider: &protocol.DocumentOnTypeFormattingOptions{
+				FirstTriggerCharacter: "}",
+				MoreTriggerCharacter:  []string{"\n"},
 			},
-			DefinitionProvider:         &protocol.Or_ServerCapabilities_definitionProvider{Value: true},
-			TypeDefinitionProvider:     &protocol.Or_ServerCapabilities_typeDefinitionProvider{Value: true},
-			ImplementationProvider:     &protocol.Or_ServerCapabilities_implementationProvider{Value: true},
-			DocumentFormattingProvider: &protocol.Or_ServerCapabilities_documentFormattingProvider{Value: true},
-			DocumentSymbolProvider:     &protocol.Or_ServerCapabilities_documentSymbolProvider{Value: true},
-			WorkspaceSymbolProvider:    &protocol.Or_ServerCapabilities_workspaceSymbolProvider{Value: true},
+			DocumentSymbolProvider:  &protocol.Or_ServerCapabilities_documentSymbolProvider{Value: true},
+			WorkspaceSymbolProvider: &protocol.Or_ServerCapabilities_workspaceSymbolProvider{Value: true},
 			ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
 				Commands: options.SupportedCommands,
 			},
-			FoldingRangeProvider:      &protocol.Or_ServerCapabilities_foldingRangeProvider{Value: true},
-			HoverProvider:             &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
-			DocumentHighlightProvider: &protocol.Or_ServerCapabilities_documentHighlightProvider{Value: true},
-			DocumentLinkProvider:      &protocol.DocumentLinkOptions{},
-			InlayHintProvider:         protocol.InlayHintOptions{},
-			ReferencesProvider:        &protocol.Or_ServerCapabilities_referencesProvider{Value: true},
-			RenameProvider:            renameOpts,
-			SelectionRangeProvider:    &protocol.Or_ServerCapabilities_selectionRangeProvider{Value: true},
+			FoldingRangeProvider:       &protocol.Or_ServerCapabilities_foldingRangeProvider{Value: true},
+			HoverProvider:              &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+			DocumentHighlightProvider:  &protocol.Or_ServerCapabilities_documentHighlightProvider{Value: true},
+			DocumentLinkProvider:       &protocol.DocumentLinkOptions{},
+			InlayHintProvider:          protocol.InlayHintOptions{},
+			LinkedEditingRangeProvider: &protocol.Or_ServerCapabilities_linkedEditingRangeProvider{Value: true},
+			NotebookDocumentSync: &protocol.Or_ServerCapabilities_notebookDocumentSync{
+				Value: protocol.NotebookDocumentSyncOptions{
+					NotebookSelector: []protocol.PNotebookSelectorPNotebookDocumentSync{{
+						Notebook: protocol.OrFNotebookPNotebookSelector{Value: "*"},
+						Cells:    []protocol.Lit_NotebookDocumentSyncOptions_notebookSelector_Elem_Item0_cells_Elem{{Language: "go"}},
+					}},
+				},
+			},
+			ReferencesProvider:     &protocol.Or_ServerCapabilities_referencesProvider{Value: true},
+			RenameProvider:         renameOpts,
+			SelectionRangeProvider: &protocol.Or_ServerCapabilities_selectionRangeProvider{Value: true},
 			SemanticTokensProvider: protocol.SemanticTokensOptions{
 				Range: &protocol.Or_SemanticTokensOptions_range{Value: true},
 				Full:  &protocol.Or_SemanticTokensOptions_full{Value: true},
@@ -182,6 +223,10 @@ See https://github.com/golang/go/issues/45732 for more information.`,
 					Supported:           true,
 					ChangeNotifications: "workspace/didChangeWorkspaceFolders",
 				},
+				FileOperations: &protocol.FileOperationOptions{
+					DidRename:  renameFilters,
+					WillRename: renameFilters,
+				},
 			},
 		},
 		ServerInfo: &protocol.PServerInfoMsg_initialize{
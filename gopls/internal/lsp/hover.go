@@ -15,6 +15,21 @@ import (
 )
 
 func (s *Server) hover(ctx context.Context, params *protocol.HoverParams) (*protocol.Hover, error) {
+	cell, pos := params.TextDocument.URI, params.Position
+	if layout := s.toNotebookFile(&params.TextDocumentPositionParams); layout != nil {
+		hover, err := s.hover(ctx, params)
+		if hover != nil {
+			// The Range of a Hover cannot be omitted, so a range
+			// that is not within the cell is replaced by an empty
+			// one at the position of the request.
+			if loc, ok := layout.toCell(hover.Range); ok && loc.URI == cell {
+				hover.Range = loc.Range
+			} else {
+				hover.Range = protocol.Range{Start: pos, End: pos}
+			}
+		}
+		return hover, err
+	}
 	snapshot, fh, ok, release, err := s.beginFileRequest(ctx, params.TextDocument.URI, source.UnknownKind)
 	defer release()
 	if !ok {
@@ -0,0 +1,42 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/lsp/source"
+	"golang.org/x/tools/gopls/internal/lsp/template"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/event/tag"
+)
+
+// linkedEditingRange implements the textDocument/linkedEditingRange
+// request, which reports the ranges of the identifiers that the client
+// renames together as the user edits one of them.
+func (s *Server) linkedEditingRange(ctx context.Context, params *protocol.LinkedEditingRangeParams) (*protocol.LinkedEditingRanges, error) {
+	snapshot, fh, ok, release, err := s.beginFileRequest(ctx, params.TextDocument.URI, source.UnknownKind)
+	defer release()
+	if !ok {
+		return nil, err
+	}
+
+	var ranges []protocol.Range
+	switch snapshot.View().FileKind(fh) {
+	case source.Go:
+		ranges, err = source.LinkedEditingRange(ctx, snapshot, fh, params.Position)
+	case source.Tmpl:
+		ranges, err = template.LinkedEditingRange(ctx, snapshot, fh, params.Position)
+	}
+	if err != nil {
+		event.Error(ctx, "no linked editing range", err, tag.URI.Of(params.TextDocument.URI))
+		return nil, nil
+	}
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+	return &protocol.LinkedEditingRanges{Ranges: ranges}, nil
+}
@@ -0,0 +1,470 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/lsp/source"
+	"golang.org/x/tools/gopls/internal/span"
+	"golang.org/x/tools/internal/jsonrpc2"
+)
+
+// Go notebooks, such as those of the gonb Jupyter kernel, are analyzed
+// as a single synthetic Go file, which is an overlay next to the
+// notebook: the code cells of nb.ipynb make up the file nb.ipynb.go.
+// Requests about the cells are translated to requests about the file,
+// and the positions in their results, and the diagnostics of the file,
+// are translated back to the cells.
+//
+// The file is formed as the gonb kernel runs the cells: it declares
+// package main, the cells that import packages come first, and the
+// lines of a cell that follow a "%%" line form the body of function
+// main. As the kernel runs the body of only one cell at a time, the
+// bodies of later cells form those of blank functions, which may be
+// declared any number of times. Other special lines, such as
+// %-commands and !-commands, are commented out.
+
+// A notebook is an open notebook document.
+type notebook struct {
+	uri     protocol.URI // of the notebook
+	file    span.URI     // of the synthetic Go file
+	version int32        // of the synthetic Go file
+	cells   []*notebookCell
+	layout  *notebookLayout // of the cells in the synthetic file
+}
+
+// A notebookCell is a cell of a notebook.
+type notebookCell struct {
+	uri        protocol.DocumentURI
+	kind       protocol.NotebookCellKind
+	languageID string
+	text       []byte
+}
+
+// isGo reports whether the cell is a Go code cell.
+func (c *notebookCell) isGo() bool {
+	return c.kind == protocol.Code && (c.languageID == "go" || c.languageID == "")
+}
+
+// A notebookLayout records where the lines of the code cells of a
+// notebook are in its synthetic Go file. It is immutable.
+type notebookLayout struct {
+	file    span.URI
+	version int32 // of the synthetic file
+	cells   []cellLayout
+}
+
+// A cellLayout records where the lines of a cell are in the synthetic
+// Go file of its notebook.
+type cellLayout struct {
+	uri    protocol.DocumentURI
+	line   uint32            // line of the file at which the cell starts
+	lines  uint32            // number of lines of the cell
+	prefix map[uint32]uint32 // UTF-16 length of the text inserted before a line of the cell, by line
+	main   int               // line of the cell replaced by the declaration of a function, or -1
+}
+
+// synthesize returns the text of the synthetic Go file of the notebook
+// and the layout of its cells in it.
+func (nb *notebook) synthesize() ([]byte, *notebookLayout) {
+	// Imports must precede other declarations.
+	var cells []*notebookCell
+	for _, importing := range []bool{true, false} {
+		for _, c := range nb.cells {
+			if c.isGo() && hasImports(c.text) == importing {
+				cells = append(cells, c)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("package main\n")
+	line := uint32(1)
+	layout := &notebookLayout{file: nb.file}
+	hasMain := false
+	for _, c := range cells {
+		cl := cellLayout{uri: c.uri, line: line, prefix: make(map[uint32]uint32), main: -1}
+		lines := strings.Split(string(c.text), "\n")
+		for i, l := range lines {
+			switch {
+			case strings.TrimSpace(l) == "%%" && cl.main < 0:
+				cl.main = i
+				l = "func _() {"
+				if !hasMain {
+					hasMain = true
+					l = "func main() {"
+				}
+			case strings.HasPrefix(l, "%") || strings.HasPrefix(l, "!"):
+				cl.prefix[uint32(i)] = 2
+				l = "//" + l
+			}
+			buf.WriteString(l)
+			buf.WriteByte('\n')
+		}
+		cl.lines = uint32(len(lines))
+		line += cl.lines
+		if cl.main >= 0 {
+			buf.WriteString("}\n")
+			line++
+		}
+		layout.cells = append(layout.cells, cl)
+	}
+	return buf.Bytes(), layout
+}
+
+// hasImports reports whether the Go text contains an import declaration.
+func hasImports(text []byte) bool {
+	for _, l := range strings.Split(string(text), "\n") {
+		if strings.HasPrefix(l, "import ") || strings.HasPrefix(l, "import(") {
+			return true
+		}
+	}
+	return false
+}
+
+// cell returns the layout of the cell of the given URI.
+func (l *notebookLayout) cell(uri protocol.DocumentURI) *cellLayout {
+	for i := range l.cells {
+		if l.cells[i].uri == uri {
+			return &l.cells[i]
+		}
+	}
+	return nil
+}
+
+// toFile returns the position in the synthetic file of the position
+// in the cell.
+func (c *cellLayout) toFile(pos protocol.Position) protocol.Position {
+	if int(pos.Line) == c.main {
+		pos.Character = 0
+	} else {
+		pos.Character += c.prefix[pos.Line]
+	}
+	pos.Line += c.line
+	return pos
+}
+
+// toCell returns the position in the cell of the position in the
+// synthetic file, which must be within the cell.
+func (c *cellLayout) toCell(pos protocol.Position) protocol.Position {
+	pos.Line -= c.line
+	if int(pos.Line) == c.main {
+		pos.Character = 0
+	} else if n := c.prefix[pos.Line]; pos.Character >= n {
+		pos.Character -= n
+	} else {
+		pos.Character = 0
+	}
+	return pos
+}
+
+// contains reports whether the line of the synthetic file belongs to
+// the cell.
+func (c *cellLayout) contains(line uint32) bool {
+	return c.line <= line && line < c.line+c.lines
+}
+
+// toCell returns the location in a cell of the range of the synthetic
+// file. It reports false if the range is not within a single cell.
+func (l *notebookLayout) toCell(rng protocol.Range) (protocol.Location, bool) {
+	for _, c := range l.cells {
+		if c.contains(rng.Start.Line) && c.contains(rng.End.Line) {
+			return protocol.Location{
+				URI:   c.uri,
+				Range: protocol.Range{Start: c.toCell(rng.Start), End: c.toCell(rng.End)},
+			}, true
+		}
+	}
+	return protocol.Location{}, false
+}
+
+// toCellLocations translates the locations in the synthetic file to
+// the cells, dropping those that are in no cell.
+func (l *notebookLayout) toCellLocations(locs []protocol.Location) []protocol.Location {
+	res := locs[:0]
+	for _, loc := range locs {
+		if loc.URI.SpanURI() == l.file {
+			var ok bool
+			if loc, ok = l.toCell(loc.Range); !ok {
+				continue
+			}
+		}
+		res = append(res, loc)
+	}
+	return res
+}
+
+// toCellEdits translates the edits of the synthetic file to the cell,
+// dropping those that are not within it.
+func (l *notebookLayout) toCellEdits(cell protocol.DocumentURI, edits []protocol.TextEdit) []protocol.TextEdit {
+	var res []protocol.TextEdit
+	for _, edit := range edits {
+		if loc, ok := l.toCell(edit.Range); ok && loc.URI == cell {
+			res = append(res, protocol.TextEdit{Range: loc.Range, NewText: edit.NewText})
+		}
+	}
+	return res
+}
+
+// toNotebookFile rewrites the document position params of a position
+// in a notebook cell to the corresponding position in the synthetic Go
+// file of the notebook, whose layout it returns. If the document is
+// not a Go cell of an open notebook, it returns nil.
+func (s *Server) toNotebookFile(params *protocol.TextDocumentPositionParams) *notebookLayout {
+	s.notebooksMu.Lock()
+	defer s.notebooksMu.Unlock()
+	for _, nb := range s.notebooks {
+		if c := nb.layout.cell(params.TextDocument.URI); c != nil {
+			params.TextDocument.URI = protocol.URIFromSpanURI(nb.file)
+			params.Position = c.toFile(params.Position)
+			return nb.layout
+		}
+	}
+	return nil
+}
+
+// notebookLayout returns the layout of the notebook whose synthetic Go
+// file is uri, or nil if there is none.
+func (s *Server) notebookLayout(uri span.URI) *notebookLayout {
+	s.notebooksMu.Lock()
+	defer s.notebooksMu.Unlock()
+	for _, nb := range s.notebooks {
+		if nb.file == uri {
+			return nb.layout
+		}
+	}
+	return nil
+}
+
+func (s *Server) didOpenNotebookDocument(ctx context.Context, params *protocol.DidOpenNotebookDocumentParams) error {
+	uri := span.URIFromURI(params.NotebookDocument.URI)
+	if !uri.IsFile() {
+		return nil
+	}
+	nb := &notebook{
+		uri:  params.NotebookDocument.URI,
+		file: span.URIFromPath(uri.Filename() + ".go"),
+	}
+	nb.cells = openCells(params.NotebookDocument.Cells, params.CellTextDocuments, nil)
+	text := nb.update()
+
+	s.notebooksMu.Lock()
+	if s.notebooks == nil {
+		s.notebooks = make(map[protocol.URI]*notebook)
+	}
+	s.notebooks[nb.uri] = nb
+	s.notebooksMu.Unlock()
+
+	// As in didOpen, create a view for the notebook if there is none.
+	if _, err := s.session.ViewOf(nb.file); err != nil {
+		dir := filepath.Dir(nb.file.Filename())
+		if err := s.addFolders(ctx, []protocol.WorkspaceFolder{{
+			URI:  string(protocol.URIFromPath(dir)),
+			Name: filepath.Base(dir),
+		}}); err != nil {
+			return err
+		}
+	}
+	return s.didModifyFiles(ctx, []source.FileModification{{
+		URI:        nb.file,
+		Action:     source.Open,
+		Version:    nb.version,
+		Text:       text,
+		LanguageID: "go",
+	}}, FromDidOpen)
+}
+
+// openCells returns the cells of a notebook, whose text is that of the
+// opened documents or, for the others, that of the existing cells.
+func openCells(cells []protocol.NotebookCell, opened []protocol.TextDocumentItem, existing []*notebookCell) []*notebookCell {
+	res := make([]*notebookCell, 0, len(cells))
+	for _, c := range cells {
+		cell := &notebookCell{uri: c.Document, kind: c.Kind}
+		for _, e := range existing {
+			if e.uri == c.Document {
+				cell.languageID, cell.text = e.languageID, e.text
+			}
+		}
+		for _, doc := range opened {
+			if doc.URI == c.Document {
+				cell.languageID, cell.text = doc.LanguageID, []byte(doc.Text)
+			}
+		}
+		res = append(res, cell)
+	}
+	return res
+}
+
+// update increments the version of the synthetic file of the notebook,
+// recomputes its layout, and returns its text.
+func (nb *notebook) update() []byte {
+	text, layout := nb.synthesize()
+	nb.version++
+	layout.version = nb.version
+	nb.layout = layout
+	return text
+}
+
+func (s *Server) didChangeNotebookDocument(ctx context.Context, params *protocol.DidChangeNotebookDocumentParams) error {
+	s.notebooksMu.Lock()
+	nb, ok := s.notebooks[params.NotebookDocument.URI]
+	if !ok {
+		s.notebooksMu.Unlock()
+		return fmt.Errorf("%w: notebook %s is not open", jsonrpc2.ErrInternal, params.NotebookDocument.URI)
+	}
+	text, closed, err := nb.change(params.Change)
+	version := nb.version
+	s.notebooksMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := s.clearCellDiagnostics(ctx, closed); err != nil {
+		return err
+	}
+	return s.didModifyFiles(ctx, []source.FileModification{{
+		URI:     nb.file,
+		Action:  source.Change,
+		Version: version,
+		Text:    text,
+	}}, FromDidChange)
+}
+
+// change applies the changes to the notebook and returns the new text
+// of its synthetic Go file and the cells that were removed.
+func (nb *notebook) change(change protocol.NotebookDocumentChangeEvent) ([]byte, []protocol.DocumentURI, error) {
+	var closed []protocol.DocumentURI
+	if change.Cells == nil {
+		return nb.update(), nil, nil
+	}
+	if structure := change.Cells.Structure; structure != nil {
+		start, end := int(structure.Array.Start), int(structure.Array.Start+structure.Array.DeleteCount)
+		if start > len(nb.cells) || end > len(nb.cells) {
+			return nil, nil, fmt.Errorf("%w: invalid notebook cell array change", jsonrpc2.ErrInternal)
+		}
+		for _, c := range nb.cells[start:end] {
+			closed = append(closed, c.uri)
+		}
+		inserted := openCells(structure.Array.Cells, structure.DidOpen, nb.cells)
+		cells := append([]*notebookCell(nil), nb.cells[:start]...)
+		cells = append(cells, inserted...)
+		nb.cells = append(cells, nb.cells[end:]...)
+	}
+	for _, data := range change.Cells.Data {
+		for _, c := range nb.cells {
+			if c.uri == data.Document {
+				c.kind = data.Kind
+			}
+		}
+	}
+	for _, content := range change.Cells.TextContent {
+		for _, c := range nb.cells {
+			if c.uri != content.Document.URI {
+				continue
+			}
+			if len(content.Changes) == 1 && content.Changes[0].Range == nil {
+				c.text = []byte(content.Changes[0].Text)
+				continue
+			}
+			text, err := applyChanges(content.Document.URI.SpanURI(), c.text, content.Changes)
+			if err != nil {
+				return nil, nil, err
+			}
+			c.text = text
+		}
+	}
+	// Cells moved within the notebook are closed and reopened.
+	live := closed[:0]
+	for _, uri := range closed {
+		found := false
+		for _, c := range nb.cells {
+			found = found || c.uri == uri
+		}
+		if !found {
+			live = append(live, uri)
+		}
+	}
+	return nb.update(), live, nil
+}
+
+func (s *Server) didSaveNotebookDocument(ctx context.Context, params *protocol.DidSaveNotebookDocumentParams) error {
+	// The synthetic file is never saved.
+	return nil
+}
+
+func (s *Server) didCloseNotebookDocument(ctx context.Context, params *protocol.DidCloseNotebookDocumentParams) error {
+	s.notebooksMu.Lock()
+	nb, ok := s.notebooks[params.NotebookDocument.URI]
+	delete(s.notebooks, params.NotebookDocument.URI)
+	s.notebooksMu.Unlock()
+	if !ok {
+		return nil
+	}
+	var cells []protocol.DocumentURI
+	for _, c := range nb.cells {
+		cells = append(cells, c.uri)
+	}
+	if err := s.clearCellDiagnostics(ctx, cells); err != nil {
+		return err
+	}
+	return s.didModifyFiles(ctx, []source.FileModification{{
+		URI:     nb.file,
+		Action:  source.Close,
+		Version: -1,
+	}}, FromDidClose)
+}
+
+// clearCellDiagnostics publishes empty diagnostics for the cells.
+func (s *Server) clearCellDiagnostics(ctx context.Context, cells []protocol.DocumentURI) error {
+	for _, uri := range cells {
+		if err := s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+			URI:         uri,
+			Diagnostics: []protocol.Diagnostic{},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishCellDiagnostics publishes the diagnostics of the synthetic Go
+// file of a notebook as those of its cells. Diagnostics outside the
+// cells, such as those of the package clause, are dropped.
+func (s *Server) publishCellDiagnostics(ctx context.Context, layout *notebookLayout, diags []protocol.Diagnostic) error {
+	byCell := make(map[protocol.DocumentURI][]protocol.Diagnostic)
+	for _, c := range layout.cells {
+		byCell[c.uri] = []protocol.Diagnostic{}
+	}
+	for _, diag := range diags {
+		loc, ok := layout.toCell(diag.Range)
+		if !ok {
+			continue
+		}
+		diag.Range = loc.Range
+		var related []protocol.DiagnosticRelatedInformation
+		for _, info := range diag.RelatedInformation {
+			if locs := layout.toCellLocations([]protocol.Location{info.Location}); len(locs) > 0 {
+				info.Location = locs[0]
+				related = append(related, info)
+			}
+		}
+		diag.RelatedInformation = related
+		byCell[loc.URI] = append(byCell[loc.URI], diag)
+	}
+	for _, c := range layout.cells {
+		if err := s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+			URI:         c.uri,
+			Diagnostics: byCell[c.uri],
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
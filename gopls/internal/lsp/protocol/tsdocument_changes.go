@@ -9,11 +9,13 @@ import (
 	"fmt"
 )
 
-// DocumentChanges is a union of a file edit and directory rename operations
-// for package renaming feature. At most one field of this struct is non-nil.
+// DocumentChanges is a union of a file edit, a directory rename operation
+// for the package renaming feature, and a file creation operation for the
+// declaration moving feature. At most one field of this struct is non-nil.
 type DocumentChanges struct {
 	TextDocumentEdit *TextDocumentEdit
 	RenameFile       *RenameFile
+	CreateFile       *CreateFile
 }
 
 func (d *DocumentChanges) UnmarshalJSON(data []byte) error {
@@ -28,6 +30,11 @@ func (d *DocumentChanges) UnmarshalJSON(data []byte) error {
 		return json.Unmarshal(data, d.TextDocumentEdit)
 	}
 
+	if m["kind"] == "create" {
+		d.CreateFile = new(CreateFile)
+		return json.Unmarshal(data, d.CreateFile)
+	}
+
 	d.RenameFile = new(RenameFile)
 	return json.Unmarshal(data, d.RenameFile)
 }
@@ -37,6 +44,8 @@ func (d *DocumentChanges) MarshalJSON() ([]byte, error) {
 		return json.Marshal(d.TextDocumentEdit)
 	} else if d.RenameFile != nil {
 		return json.Marshal(d.RenameFile)
+	} else if d.CreateFile != nil {
+		return json.Marshal(d.CreateFile)
 	}
 	return nil, fmt.Errorf("Empty DocumentChanges union value")
 }
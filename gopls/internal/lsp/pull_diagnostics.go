@@ -0,0 +1,263 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+// This file defines the textDocument/diagnostic and workspace/diagnostic
+// requests, with which a client pulls diagnostics on demand rather than
+// waiting for them to be published.
+//
+// Each report carries a result ID, the hash of its diagnostics. If a
+// client presents the ID of the previous report for a file, and the
+// diagnostics have not changed since, the server responds that the
+// report is unchanged instead of sending the diagnostics again.
+//
+// Unlike the diagnostics published after each change, which include
+// analysis results only for packages with open files, pulled
+// diagnostics always include analysis results.
+
+import (
+	"context"
+
+	"golang.org/x/tools/gopls/internal/lsp/mod"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/lsp/source"
+	"golang.org/x/tools/gopls/internal/lsp/template"
+	"golang.org/x/tools/gopls/internal/lsp/work"
+	"golang.org/x/tools/gopls/internal/span"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/event/tag"
+)
+
+func (s *Server) diagnostic(ctx context.Context, params *protocol.DocumentDiagnosticParams) (*protocol.DocumentDiagnosticReport, error) {
+	ctx, done := event.Start(ctx, "lsp.Server.diagnostic", tag.URI.Of(params.TextDocument.URI))
+	defer done()
+
+	snapshot, fh, ok, release, err := s.beginFileRequest(ctx, params.TextDocument.URI, source.UnknownKind)
+	defer release()
+	if !ok {
+		return nil, err
+	}
+	diags, err := s.fileDiagnostics(ctx, snapshot, fh)
+	if err != nil {
+		return nil, err
+	}
+
+	resultID := hashDiagnostics(diags...)
+	if resultID == params.PreviousResultID {
+		return &protocol.DocumentDiagnosticReport{
+			Value: protocol.RelatedUnchangedDocumentDiagnosticReport{
+				UnchangedDocumentDiagnosticReport: protocol.UnchangedDocumentDiagnosticReport{
+					Kind:     string(protocol.DiagnosticUnchanged),
+					ResultID: resultID,
+				},
+			},
+		}, nil
+	}
+	return &protocol.DocumentDiagnosticReport{
+		Value: protocol.RelatedFullDocumentDiagnosticReport{
+			FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
+				Kind:     string(protocol.DiagnosticFull),
+				ResultID: resultID,
+				Items:    toProtocolDiagnostics(diags),
+			},
+		},
+	}, nil
+}
+
+func (s *Server) diagnosticWorkspace(ctx context.Context, params *protocol.WorkspaceDiagnosticParams) (*protocol.WorkspaceDiagnosticReport, error) {
+	ctx, done := event.Start(ctx, "lsp.Server.diagnosticWorkspace")
+	defer done()
+
+	previous := make(map[span.URI]string)
+	for _, id := range params.PreviousResultIds {
+		previous[id.URI.SpanURI()] = id.Value
+	}
+
+	report := &protocol.WorkspaceDiagnosticReport{
+		Items: []protocol.WorkspaceDocumentDiagnosticReport{},
+	}
+	for _, view := range s.session.Views() {
+		snapshot, release, err := view.Snapshot()
+		if err != nil {
+			continue // view is shut down
+		}
+		byFile, err := s.workspaceDiagnostics(ctx, snapshot)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		for uri, diags := range byFile {
+			var version int32
+			if fh := snapshot.FindFile(uri); fh != nil {
+				version = fh.Version()
+			}
+			resultID := hashDiagnostics(diags...)
+			var item protocol.WorkspaceDocumentDiagnosticReport
+			if resultID == previous[uri] {
+				item.Value = protocol.WorkspaceUnchangedDocumentDiagnosticReport{
+					URI:     protocol.URIFromSpanURI(uri),
+					Version: version,
+					UnchangedDocumentDiagnosticReport: protocol.UnchangedDocumentDiagnosticReport{
+						Kind:     string(protocol.DiagnosticUnchanged),
+						ResultID: resultID,
+					},
+				}
+			} else {
+				item.Value = protocol.WorkspaceFullDocumentDiagnosticReport{
+					URI:     protocol.URIFromSpanURI(uri),
+					Version: version,
+					FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
+						Kind:     string(protocol.DiagnosticFull),
+						ResultID: resultID,
+						Items:    toProtocolDiagnostics(diags),
+					},
+				}
+			}
+			report.Items = append(report.Items, item)
+		}
+		release()
+	}
+	return report, nil
+}
+
+// fileDiagnostics computes the diagnostics for a single file.
+func (s *Server) fileDiagnostics(ctx context.Context, snapshot source.Snapshot, fh source.FileHandle) ([]*source.Diagnostic, error) {
+	uri := fh.URI()
+	if snapshot.IgnoredFile(uri) || snapshot.IsBuiltin(ctx, uri) {
+		return nil, nil
+	}
+
+	var (
+		byFile map[span.URI][]*source.Diagnostic
+		err    error
+	)
+	switch snapshot.View().FileKind(fh) {
+	case source.Go:
+		metas, err := snapshot.MetadataForFile(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		if len(metas) == 0 {
+			if d := s.checkForOrphanedFile(ctx, snapshot, fh); d != nil {
+				return []*source.Diagnostic{d}, nil
+			}
+			return nil, nil
+		}
+		byFile, err = packageDiagnostics(ctx, snapshot, metas)
+		if err != nil {
+			return nil, err
+		}
+	case source.Mod:
+		byFile, err = modDiagnostics(ctx, snapshot)
+	case source.Work:
+		byFile, err = work.Diagnostics(ctx, snapshot)
+	case source.Tmpl:
+		return template.Diagnose(fh), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return byFile[uri], nil
+}
+
+// workspaceDiagnostics computes the diagnostics for all files of the
+// workspace packages and modules of the snapshot. The result has an
+// entry, possibly empty, for each such file.
+func (s *Server) workspaceDiagnostics(ctx context.Context, snapshot source.Snapshot) (map[span.URI][]*source.Diagnostic, error) {
+	metas, err := snapshot.ActiveMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byFile, err := packageDiagnostics(ctx, snapshot, metas)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range metas {
+		for _, uri := range m.CompiledGoFiles {
+			if _, ok := byFile[uri]; !ok && !snapshot.IgnoredFile(uri) && !snapshot.IsBuiltin(ctx, uri) {
+				byFile[uri] = nil
+			}
+		}
+	}
+
+	modDiags, err := modDiagnostics(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	workDiags, err := work.Diagnostics(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	for _, diags := range []map[span.URI][]*source.Diagnostic{modDiags, workDiags} {
+		for uri, diags := range diags {
+			byFile[uri] = append(byFile[uri], diags...)
+		}
+	}
+	for _, fh := range snapshot.Templates() {
+		byFile[fh.URI()] = template.Diagnose(fh)
+	}
+	return byFile, nil
+}
+
+// packageDiagnostics returns the type-checking and analysis
+// diagnostics of the specified packages, grouped by file.
+// A file belonging to several packages, such as a package and
+// its test variant, reports each distinct diagnostic once.
+func packageDiagnostics(ctx context.Context, snapshot source.Snapshot, metas []*source.Metadata) (map[span.URI][]*source.Diagnostic, error) {
+	byFile := make(map[span.URI][]*source.Diagnostic)
+	seen := make(map[span.URI]map[string]bool)
+	add := func(uri span.URI, diags []*source.Diagnostic) {
+		if seen[uri] == nil {
+			seen[uri] = make(map[string]bool)
+		}
+		for _, d := range diags {
+			if hash := hashDiagnostics(d); !seen[uri][hash] {
+				seen[uri][hash] = true
+				byFile[uri] = append(byFile[uri], d)
+			}
+		}
+	}
+
+	for _, m := range metas {
+		tdiags, err := snapshot.PackageDiagnostics(ctx, m.ID)
+		if err != nil {
+			return nil, err
+		}
+		adiags, err := source.Analyze(ctx, snapshot, m.ID, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, uri := range m.CompiledGoFiles {
+			if snapshot.IgnoredFile(uri) || snapshot.IsBuiltin(ctx, uri) {
+				continue
+			}
+			var t, a []*source.Diagnostic
+			source.CombineDiagnostics(tdiags[uri], adiags[uri], &t, &a)
+			add(uri, t)
+			add(uri, a)
+		}
+	}
+	return byFile, nil
+}
+
+// modDiagnostics returns the diagnostics
+// for the go.mod files of the workspace.
+func modDiagnostics(ctx context.Context, snapshot source.Snapshot) (map[span.URI][]*source.Diagnostic, error) {
+	byFile := make(map[span.URI][]*source.Diagnostic)
+	for _, diagnose := range []func(context.Context, source.Snapshot) (map[span.URI][]*source.Diagnostic, error){
+		mod.Diagnostics,
+		mod.UpgradeDiagnostics,
+		mod.VulnerabilityDiagnostics,
+	} {
+		diags, err := diagnose(ctx, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		for uri, diags := range diags {
+			byFile[uri] = append(byFile[uri], diags...)
+		}
+	}
+	return byFile, nil
+}
@@ -183,7 +183,11 @@ func (a *Awaiter) onDiagnostics(_ context.Context, d *protocol.PublishDiagnostic
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	pth := a.workdir.URIToPath(d.URI)
+	// The diagnostics of notebook cells are keyed by URI.
+	pth := string(d.URI)
+	if d.URI.SpanURI().IsFile() {
+		pth = a.workdir.URIToPath(d.URI)
+	}
 	a.state.diagnostics[pth] = d
 	a.checkConditionsLocked()
 	return nil
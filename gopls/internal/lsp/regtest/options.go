@@ -52,6 +52,23 @@ func WindowsLineEndings() RunOption {
 	})
 }
 
+// ResolveLazily configures the editor to resolve the documentation and
+// additional text edits of completion items, and the edits of code
+// actions, lazily.
+func ResolveLazily() RunOption {
+	return optionSetter(func(opts *runConfig) {
+		opts.editor.ResolveLazily = true
+	})
+}
+
+// PullDiagnostics configures the editor to pull diagnostics, rather than
+// to have them published.
+func PullDiagnostics() RunOption {
+	return optionSetter(func(opts *runConfig) {
+		opts.editor.PullDiagnostics = true
+	})
+}
+
 // Settings is a RunOption that sets user-provided configuration for the LSP
 // server.
 //
@@ -99,6 +99,33 @@ func (e *Env) CloseBuffer(name string) {
 	}
 }
 
+// OpenNotebook opens a notebook document of Go code cells in the editor,
+// calling t.Fatal on any error.
+func (e *Env) OpenNotebook(name string, cells ...string) {
+	e.T.Helper()
+	if err := e.Editor.OpenNotebook(e.Ctx, name, cells...); err != nil {
+		e.T.Fatal(err)
+	}
+}
+
+// SetNotebookCellContent replaces the content of a notebook cell,
+// calling t.Fatal on any error.
+func (e *Env) SetNotebookCellContent(name string, cell int, content string) {
+	e.T.Helper()
+	if err := e.Editor.SetNotebookCellContent(e.Ctx, name, cell, content); err != nil {
+		e.T.Fatal(err)
+	}
+}
+
+// CloseNotebook closes a notebook document in the editor, calling
+// t.Fatal on any error.
+func (e *Env) CloseNotebook(name string) {
+	e.T.Helper()
+	if err := e.Editor.CloseNotebook(e.Ctx, name); err != nil {
+		e.T.Fatal(err)
+	}
+}
+
 // EditBuffer applies edits to an editor buffer, calling t.Fatal on any error.
 func (e *Env) EditBuffer(name string, edits ...protocol.TextEdit) {
 	e.T.Helper()
@@ -172,6 +199,24 @@ func (e *Env) FormatBuffer(name string) {
 	}
 }
 
+// FormatRange formats the statements overlapping the given location,
+// calling t.Fatal on any error.
+func (e *Env) FormatRange(loc protocol.Location) {
+	e.T.Helper()
+	if err := e.Editor.FormatRange(e.Ctx, loc); err != nil {
+		e.T.Fatal(err)
+	}
+}
+
+// FormatOnType formats the buffer as if ch had just been typed at the
+// given location, calling t.Fatal on any error.
+func (e *Env) FormatOnType(loc protocol.Location, ch string) {
+	e.T.Helper()
+	if err := e.Editor.FormatOnType(e.Ctx, loc, ch); err != nil {
+		e.T.Fatal(err)
+	}
+}
+
 // OrganizeImports processes the source.organizeImports codeAction, calling
 // t.Fatal on any error.
 func (e *Env) OrganizeImports(name string) {
@@ -237,6 +282,17 @@ func (e *Env) DocumentHighlight(loc protocol.Location) []protocol.DocumentHighli
 	return highlights
 }
 
+// LinkedEditingRange requests the linked editing ranges at the given
+// location, calling t.Fatal on any error.
+func (e *Env) LinkedEditingRange(loc protocol.Location) *protocol.LinkedEditingRanges {
+	e.T.Helper()
+	ranges, err := e.Editor.LinkedEditingRange(e.Ctx, loc)
+	if err != nil {
+		e.T.Fatal(err)
+	}
+	return ranges
+}
+
 // RunGenerate runs "go generate" in the given dir, calling t.Fatal on any error.
 // It waits for the generate command to complete and checks for file changes
 // before returning.
@@ -438,6 +494,17 @@ func (e *Env) Completion(loc protocol.Location) *protocol.CompletionList {
 	return completions
 }
 
+// ResolveCompletionItem resolves the given completion item, calling
+// t.Fatal on any error.
+func (e *Env) ResolveCompletionItem(item protocol.CompletionItem) protocol.CompletionItem {
+	e.T.Helper()
+	resolved, err := e.Editor.ResolveCompletionItem(e.Ctx, item)
+	if err != nil {
+		e.T.Fatal(err)
+	}
+	return resolved
+}
+
 // AcceptCompletion accepts a completion for the given item at the given
 // position.
 func (e *Env) AcceptCompletion(loc protocol.Location, item protocol.CompletionItem) {
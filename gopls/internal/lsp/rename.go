@@ -7,9 +7,12 @@ package lsp
 import (
 	"context"
 	"path/filepath"
+	"sort"
 
 	"golang.org/x/tools/gopls/internal/lsp/protocol"
 	"golang.org/x/tools/gopls/internal/lsp/source"
+	"golang.org/x/tools/gopls/internal/span"
+	"golang.org/x/tools/internal/event"
 )
 
 func (s *Server) rename(ctx context.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
@@ -51,6 +54,59 @@ func (s *Server) rename(ctx context.Context, params *protocol.RenameParams) (*pr
 	}, nil
 }
 
+// willRenameFiles implements the workspace/willRenameFiles handler,
+// which updates the import paths of packages in renamed directories,
+// and the go.mod and go.work directives that refer to them, before the
+// client moves the files.
+func (s *Server) willRenameFiles(ctx context.Context, params *protocol.RenameFilesParams) (*protocol.WorkspaceEdit, error) {
+	ctx, done := event.Start(ctx, "lsp.Server.willRenameFiles")
+	defer done()
+
+	// Group the renamings by the view of the renamed file or directory.
+	byView := make(map[source.View][]protocol.FileRename)
+	var views []source.View
+	for _, r := range params.Files {
+		view, err := s.session.ViewOf(span.URIFromURI(r.OldURI))
+		if err != nil {
+			continue // no view for this file
+		}
+		if _, ok := byView[view]; !ok {
+			views = append(views, view)
+		}
+		byView[view] = append(byView[view], r)
+	}
+
+	var docChanges []protocol.DocumentChanges
+	for _, view := range views {
+		snapshot, release, err := view.Snapshot()
+		if err != nil {
+			continue // view is shut down
+		}
+		edits, err := source.RenameFiles(ctx, snapshot, byView[view])
+		if err != nil {
+			release()
+			return nil, err
+		}
+		uris := make([]span.URI, 0, len(edits))
+		for uri := range edits {
+			uris = append(uris, uri)
+		}
+		sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+		for _, uri := range uris {
+			fh, err := snapshot.GetFile(ctx, uri)
+			if err != nil {
+				release()
+				return nil, err
+			}
+			docChanges = append(docChanges, documentChanges(fh, edits[uri])...)
+		}
+		release()
+	}
+	return &protocol.WorkspaceEdit{
+		DocumentChanges: docChanges,
+	}, nil
+}
+
 // prepareRename implements the textDocument/prepareRename handler. It may
 // return (nil, nil) if there is no rename at the cursor position, but it is
 // not desirable to display an error to the user.
@@ -9,6 +9,7 @@ package lsp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 
@@ -111,6 +112,15 @@ type Server struct {
 	// report with an error message.
 	criticalErrorStatusMu sync.Mutex
 	criticalErrorStatus   *progress.WorkDone
+
+	// lastCompletion holds the candidates of the last completion request,
+	// whose deferred properties are computed by completionItem/resolve.
+	lastCompletionMu sync.Mutex
+	lastCompletion   completionCache
+
+	// notebooks holds the open notebook documents, by URI.
+	notebooksMu sync.Mutex
+	notebooks   map[protocol.URI]*notebook
 }
 
 func (s *Server) workDoneProgressCancel(ctx context.Context, params *protocol.WorkDoneProgressCancelParams) error {
@@ -156,3 +166,13 @@ func (s *Server) nonstandardRequest(ctx context.Context, method string, params i
 func notImplemented(method string) error {
 	return fmt.Errorf("%w: %q not yet implemented", jsonrpc2.ErrMethodNotFound, method)
 }
+
+// unmarshalData decodes the data field of a protocol value, which the
+// client returns as it was decoded from JSON, into v.
+func unmarshalData(data interface{}, v interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -303,7 +303,7 @@ var GeneratedAPIJSON = &APIJSON{
 						},
 						{
 							Name:    "\"nilness\"",
-							Doc:     "check for redundant or impossible nil comparisons\n\nThe nilness checker inspects the control-flow graph of each function in\na package and reports nil pointer dereferences, degenerate nil\npointers, and panics with nil values. A degenerate comparison is of the form\nx==nil or x!=nil where x is statically known to be nil or non-nil. These are\noften a mistake, especially in control flow related to errors. Panics with nil\nvalues are checked because they are not detectable by\n\n\tif r := recover(); r != nil {\n\nThis check reports conditions such as:\n\n\tif f == nil { // impossible condition (f is a function)\n\t}\n\nand:\n\n\tp := &v\n\t...\n\tif p != nil { // tautological condition\n\t}\n\nand:\n\n\tif p == nil {\n\t\tprint(*p) // nil dereference\n\t}\n\nand:\n\n\tif p == nil {\n\t\tpanic(p)\n\t}\n",
+							Doc:     "check for redundant or impossible nil comparisons\n\nThe nilness checker inspects the control-flow graph of each function in\na package and reports nil pointer dereferences, degenerate nil\npointers, and panics with nil values. A degenerate comparison is of the form\nx==nil or x!=nil where x is statically known to be nil or non-nil. These are\noften a mistake, especially in control flow related to errors. Panics with nil\nvalues are checked because they are not detectable by\n\n\tif r := recover(); r != nil {\n\nThis check reports conditions such as:\n\n\tif f == nil { // impossible condition (f is a function)\n\t}\n\nand:\n\n\tp := &v\n\t...\n\tif p != nil { // tautological condition\n\t}\n\nand:\n\n\tif p == nil {\n\t\tprint(*p) // nil dereference\n\t}\n\nand:\n\n\tif p == nil {\n\t\tpanic(p)\n\t}\n\nThe checker is interprocedural: it summarizes, for each function,\nwhich pointer results may be nil (on paths that return a nil error or\na true ok result, if any) and which parameters are dereferenced unconditionally, and uses\nthese summaries, even across packages, to report conditions such as:\n\n\tp := lookup() // lookup may return nil\n\tprint(p.x)    // possible nil dereference\n\nand:\n\n\tderef(nil) // deref dereferences its parameter\n",
 							Default: "false",
 						},
 						{
@@ -513,6 +513,14 @@ var GeneratedAPIJSON = &APIJSON{
 				Status:    "advanced",
 				Hierarchy: "ui.diagnostic",
 			},
+			{
+				Name:      "pullDiagnostics",
+				Type:      "bool",
+				Doc:       "pullDiagnostics stops gopls from publishing diagnostics to clients\nthat pull them, using the textDocument/diagnostic and\nworkspace/diagnostic requests. Such clients must then pull the\ndiagnostics of every file they show, as gopls does not ask them to\npull again when diagnostics change.\n",
+				Default:   "false",
+				Status:    "experimental",
+				Hierarchy: "ui.diagnostic",
+			},
 			{
 				Name: "hints",
 				Type: "map[string]bool",
@@ -676,6 +684,12 @@ var GeneratedAPIJSON = &APIJSON{
 			Doc:     "Applies a fix to a region of source code.",
 			ArgDoc:  "{\n\t// The fix to apply.\n\t\"Fix\": string,\n\t// The file URI for the document to fix.\n\t\"URI\": string,\n\t// The document range to scan for fixes.\n\t\"Range\": {\n\t\t\"start\": {\n\t\t\t\"line\": uint32,\n\t\t\t\"character\": uint32,\n\t\t},\n\t\t\"end\": {\n\t\t\t\"line\": uint32,\n\t\t\t\"character\": uint32,\n\t\t},\n\t},\n}",
 		},
+		{
+			Command: "gopls.change_signature",
+			Title:   "Change the parameters of a function",
+			Doc:     "Removes, adds, and reorders the parameters of the function or\nmethod at the given location, updating every call throughout the\nworkspace and every method coupled to it by an interface. The\nmethod calls applyEdit on the client.",
+			ArgDoc:  "{\n\t// The location of the name of the function or method to change.\n\t\"Location\": {\n\t\t\"uri\": string,\n\t\t\"range\": {\n\t\t\t\"start\": { ... },\n\t\t\t\"end\": { ... },\n\t\t},\n\t},\n\t// The parameters of the new signature, in order.\n\t\"Params\": []{\n\t\t\"Index\": int,\n\t\t\"Name\": string,\n\t\t\"Type\": string,\n\t\t\"Default\": string,\n\t},\n}",
+		},
 		{
 			Command: "gopls.check_upgrades",
 			Title:   "Check for upgrades",
@@ -733,6 +747,12 @@ var GeneratedAPIJSON = &APIJSON{
 			Doc:       "Call runtime.GC multiple times and return memory statistics as reported by\nruntime.MemStats.\n\nThis command is used for benchmarking, and may change in the future.",
 			ResultDoc: "{\n\t\"HeapAlloc\": uint64,\n\t\"HeapInUse\": uint64,\n}",
 		},
+		{
+			Command: "gopls.move_declaration",
+			Title:   "Move a declaration",
+			Doc:     "Moves the top-level declaration at the given location, along with\nthe methods of a type, to another file, which may belong to another\npackage of the workspace. References to the declaration are updated\nthroughout the workspace. The method calls applyEdit on the client.",
+			ArgDoc:  "{\n\t// The location of the name of the declaration to move.\n\t\"Location\": {\n\t\t\"uri\": string,\n\t\t\"range\": {\n\t\t\t\"start\": { ... },\n\t\t\t\"end\": { ... },\n\t\t},\n\t},\n\t// The file to which the declaration is moved. If the file does not\n\t// exist, it is created in the package of its directory.\n\t\"Destination\": string,\n}",
+		},
 		{
 			Command: "gopls.regenerate_cgo",
 			Title:   "Regenerate cgo",
@@ -952,7 +972,7 @@ var GeneratedAPIJSON = &APIJSON{
 		},
 		{
 			Name: "nilness",
-			Doc:  "check for redundant or impossible nil comparisons\n\nThe nilness checker inspects the control-flow graph of each function in\na package and reports nil pointer dereferences, degenerate nil\npointers, and panics with nil values. A degenerate comparison is of the form\nx==nil or x!=nil where x is statically known to be nil or non-nil. These are\noften a mistake, especially in control flow related to errors. Panics with nil\nvalues are checked because they are not detectable by\n\n\tif r := recover(); r != nil {\n\nThis check reports conditions such as:\n\n\tif f == nil { // impossible condition (f is a function)\n\t}\n\nand:\n\n\tp := &v\n\t...\n\tif p != nil { // tautological condition\n\t}\n\nand:\n\n\tif p == nil {\n\t\tprint(*p) // nil dereference\n\t}\n\nand:\n\n\tif p == nil {\n\t\tpanic(p)\n\t}\n",
+			Doc:  "check for redundant or impossible nil comparisons\n\nThe nilness checker inspects the control-flow graph of each function in\na package and reports nil pointer dereferences, degenerate nil\npointers, and panics with nil values. A degenerate comparison is of the form\nx==nil or x!=nil where x is statically known to be nil or non-nil. These are\noften a mistake, especially in control flow related to errors. Panics with nil\nvalues are checked because they are not detectable by\n\n\tif r := recover(); r != nil {\n\nThis check reports conditions such as:\n\n\tif f == nil { // impossible condition (f is a function)\n\t}\n\nand:\n\n\tp := &v\n\t...\n\tif p != nil { // tautological condition\n\t}\n\nand:\n\n\tif p == nil {\n\t\tprint(*p) // nil dereference\n\t}\n\nand:\n\n\tif p == nil {\n\t\tpanic(p)\n\t}\n\nThe checker is interprocedural: it summarizes, for each function,\nwhich pointer results may be nil (on paths that return a nil error or\na true ok result, if any) and which parameters are dereferenced unconditionally, and uses\nthese summaries, even across packages, to report conditions such as:\n\n\tp := lookup() // lookup may return nil\n\tprint(p.x)    // possible nil dereference\n\nand:\n\n\tderef(nil) // deref dereferences its parameter\n",
 		},
 		{
 			Name:    "printf",
@@ -0,0 +1,823 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+// This file defines the change-signature refactoring, which removes,
+// adds, and reorders the parameters of a function or method.
+//
+// The declaration of the function and every call to it throughout the
+// workspace are updated. Changing the signature of a method changes
+// that of every method coupled to it by an interface satisfaction
+// constraint (as found by the 'satisfy' package), transitively, since
+// otherwise some type would no longer implement some interface.
+//
+// Before making any change, it reports an error if a removed
+// parameter is still used, if the function is used other than by a
+// call (for example, as a function value assigned to a variable,
+// whose type would no longer match), or if a new parameter or its
+// default argument cannot be expressed at some place it is needed.
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/types/typeutil"
+	"golang.org/x/tools/gopls/internal/lsp/command"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/lsp/safetoken"
+	"golang.org/x/tools/gopls/internal/span"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/typeparams"
+	"golang.org/x/tools/refactor/satisfy"
+)
+
+// ChangeSignature returns the edits that change the parameters of the
+// function or method whose name is at position pp of the file fh.
+//
+// The parameters of the new signature are given in order. Each is
+// either a parameter of the original signature, identified by its
+// index, or a new parameter, whose default value is passed as its
+// argument at each existing call.
+func ChangeSignature(ctx context.Context, snapshot Snapshot, fh FileHandle, pp protocol.Position, params []command.SignatureParam) (map[span.URI][]protocol.TextEdit, error) {
+	ctx, done := event.Start(ctx, "source.ChangeSignature")
+	defer done()
+
+	pkg, pgf, err := PackageForFile(ctx, snapshot, fh.URI(), NarrowestPackage)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := pgf.PositionPos(pp)
+	if err != nil {
+		return nil, err
+	}
+	targets, _, err := objectsAt(pkg.GetTypesInfo(), pgf.File, pos)
+	if err != nil {
+		return nil, err
+	}
+	var fn *types.Func
+	for obj := range targets {
+		if f, ok := obj.(*types.Func); ok {
+			fn = f
+			break
+		}
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("no function or method at this position")
+	}
+	if fn.Pkg() == nil {
+		return nil, fmt.Errorf("cannot change the signature of built-in %s", fn.Name())
+	}
+
+	c := &signatureChanger{
+		snapshot:    snapshot,
+		fn:          fn,
+		key:         keyOf(pkg.FileSet(), fn),
+		sig:         fn.Type().(*types.Signature),
+		params:      params,
+		targets:     make(map[objKey]bool),
+		declared:    make(map[objKey]bool),
+		constraints: make(map[PackageID]map[satisfy.Constraint]bool),
+		edits:       make(map[span.URI][]diff.Edit),
+	}
+	c.targets[c.key] = true
+	if err := c.checkParams(); err != nil {
+		return nil, err
+	}
+	pkgs, err := c.packages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkNewParams(pkgs); err != nil {
+		return nil, err
+	}
+	for _, pkg := range pkgs {
+		if err := c.rewrite(pkg); err != nil {
+			return nil, err
+		}
+	}
+	for key := range c.targets {
+		if !c.declared[key] {
+			return nil, fmt.Errorf("cannot change the signature of %s: a method coupled to it is declared outside the workspace", fn.Name())
+		}
+	}
+	return toProtocolEditMap(ctx, snapshot, c.edits)
+}
+
+// ParamAt returns the name of the function declaration, and the index
+// of the parameter, whose parameter name encloses pos, and reports
+// whether the parameter is unused by the body of the function. The
+// name is nil if there is no such parameter.
+func ParamAt(info *types.Info, file *ast.File, pos token.Pos) (fn *ast.Ident, index int, unused bool) {
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.FuncDecl)
+		if !ok || !(decl.Pos() <= pos && pos <= decl.Type.End()) {
+			continue
+		}
+		index := 0
+		for _, field := range decl.Type.Params.List {
+			if len(field.Names) == 0 {
+				index++
+			}
+			for _, name := range field.Names {
+				if name.Pos() <= pos && pos <= name.End() {
+					v, _ := info.Defs[name].(*types.Var)
+					unused := decl.Body != nil && (v == nil || useOf(info, decl.Body, v) == nil)
+					return decl.Name, index, unused
+				}
+				index++
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// An objKey identifies a function or method independent of the
+// types.Package through which it is seen, by the position of its name.
+type objKey struct {
+	file   string
+	offset int
+}
+
+func keyOf(fset *token.FileSet, obj types.Object) objKey {
+	posn := safetoken.StartPosition(fset, obj.Pos())
+	return objKey{posn.Filename, posn.Offset}
+}
+
+// A signatureChanger holds the state of a change-signature operation.
+type signatureChanger struct {
+	snapshot  Snapshot
+	fn        *types.Func      // the function initially selected
+	key       objKey           // the key of fn
+	sig       *types.Signature // its original signature
+	params    []command.SignatureParam
+	newParams map[int]*newParam // by index into params
+	declFset  *token.FileSet    // the file set of the package declaring fn
+
+	targets     map[objKey]bool // functions whose signature changes
+	declared    map[objKey]bool // targets whose declaration has been rewritten
+	constraints map[PackageID]map[satisfy.Constraint]bool
+	msets       typeutil.MethodSetCache
+
+	edits map[span.URI][]diff.Edit
+}
+
+// A newParam records the names referred to by the type and the
+// default value of a new parameter.
+type newParam struct {
+	typeRefs, defaultRefs refs
+}
+
+// refs maps each free name of an expression to the object it denotes
+// at the declaration of the function.
+type refs map[string]types.Object
+
+// checkParams checks the well-formedness of the new parameter list.
+func (c *signatureChanger) checkParams() error {
+	n := c.sig.Params().Len()
+	seen := make(map[int]bool)
+	names := make(map[string]bool)
+	unchanged := len(c.params) == n
+	for i, p := range c.params {
+		if p.Index < 0 {
+			unchanged = false
+			if p.Name != "" && !token.IsIdentifier(p.Name) {
+				return fmt.Errorf("invalid parameter name %q", p.Name)
+			}
+			if p.Name != "" && p.Name != "_" {
+				if names[p.Name] {
+					return fmt.Errorf("parameter %s appears more than once", p.Name)
+				}
+				names[p.Name] = true
+			}
+			if strings.HasPrefix(strings.TrimSpace(p.Type), "...") {
+				return fmt.Errorf("cannot add a variadic parameter")
+			}
+			if p.Type == "" || p.Default == "" {
+				return fmt.Errorf("a new parameter needs a type and a default value")
+			}
+			continue
+		}
+		if p.Index >= n {
+			return fmt.Errorf("no parameter %d in signature of %s", p.Index, c.fn.Name())
+		}
+		if seen[p.Index] {
+			return fmt.Errorf("parameter %d appears more than once", p.Index)
+		}
+		seen[p.Index] = true
+		if p.Index != i {
+			unchanged = false
+		}
+		if c.sig.Variadic() && p.Index == n-1 && i != len(c.params)-1 {
+			return fmt.Errorf("the variadic parameter must be last")
+		}
+	}
+	if unchanged {
+		return fmt.Errorf("the signature of %s is unchanged", c.fn.Name())
+	}
+	return nil
+}
+
+// packages type-checks the packages that may refer to the targets,
+// adding to the targets the methods coupled to them, whose own
+// declarations may in turn bring more packages into scope.
+func (c *signatureChanger) packages(ctx context.Context) ([]Package, error) {
+	active, err := c.snapshot.ActiveMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	workspace := make(map[PackageID]bool)
+	for _, m := range active {
+		workspace[m.ID] = true
+	}
+
+	isMethod := c.sig.Recv() != nil
+	var (
+		pkgs    []Package
+		seen    = make(map[PackageID]bool)
+		scanned = map[string]bool{c.key.file: true}
+		pending = []string{c.key.file}
+	)
+	for len(pending) > 0 {
+		for _, filename := range pending {
+			uri := span.URIFromPath(filename)
+			metas, err := c.snapshot.MetadataForFile(ctx, uri)
+			if err != nil {
+				return nil, err
+			}
+			inWorkspace := false
+			for _, m := range metas {
+				inWorkspace = inWorkspace || workspace[m.ID]
+			}
+			if !inWorkspace {
+				return nil, fmt.Errorf("cannot change the signature of %s: %s is not in the workspace", c.fn.Name(), uri.Filename())
+			}
+			rdeps, err := typeCheckReverseDependencies(ctx, c.snapshot, uri, isMethod)
+			if err != nil {
+				return nil, err
+			}
+			for _, pkg := range rdeps {
+				if !seen[pkg.Metadata().ID] {
+					seen[pkg.Metadata().ID] = true
+					pkgs = append(pkgs, pkg)
+				}
+			}
+		}
+		pending = nil
+		if !isMethod {
+			break
+		}
+
+		// Find the methods coupled to the targets.
+		for grown := true; grown; {
+			grown = false
+			for _, pkg := range pkgs {
+				g, err := c.couple(pkg)
+				if err != nil {
+					return nil, err
+				}
+				grown = grown || g
+			}
+		}
+		for key := range c.targets {
+			if !scanned[key.file] {
+				scanned[key.file] = true
+				pending = append(pending, key.file)
+			}
+		}
+	}
+	return pkgs, nil
+}
+
+// couple adds to the targets each method that is coupled to a target
+// by an interface satisfaction constraint of the package, and reports
+// whether the set of targets grew.
+func (c *signatureChanger) couple(pkg Package) (bool, error) {
+	constraints, ok := c.constraints[pkg.Metadata().ID]
+	if !ok {
+		// The 'satisfy' pass requires a well-typed package.
+		if pkg.HasParseErrors() || pkg.HasTypeErrors() {
+			return false, fmt.Errorf("cannot change the signature of %s: package %s has errors", c.fn.Name(), pkg.Metadata().PkgPath)
+		}
+		var f satisfy.Finder
+		f.Find(pkg.GetTypesInfo(), pkg.GetSyntax())
+		constraints = f.Result
+		c.constraints[pkg.Metadata().ID] = constraints
+	}
+
+	// An unexported method name is qualified by its package.
+	mpkg := pkg.DependencyTypes(PackagePath(c.fn.Pkg().Path()))
+	if mpkg == nil {
+		return false, nil // no reference to the package of the method
+	}
+	grown := false
+	for key := range constraints {
+		lsel := c.msets.MethodSet(key.LHS).Lookup(mpkg, c.fn.Name())
+		rsel := c.msets.MethodSet(key.RHS).Lookup(mpkg, c.fn.Name())
+		if lsel == nil || rsel == nil {
+			continue
+		}
+		lkey, rkey := keyOf(pkg.FileSet(), lsel.Obj()), keyOf(pkg.FileSet(), rsel.Obj())
+		if c.targets[lkey] != c.targets[rkey] {
+			c.targets[lkey], c.targets[rkey] = true, true
+			grown = true
+		}
+	}
+	return grown, nil
+}
+
+// checkNewParams checks the type and default value of each new
+// parameter in the context of the declaration of the function, and
+// records the names to which they refer.
+func (c *signatureChanger) checkNewParams(pkgs []Package) error {
+	var (
+		pkg Package
+		pos token.Pos
+	)
+	for _, p := range pkgs {
+		if p.Metadata().PkgPath != PackagePath(c.fn.Pkg().Path()) {
+			continue
+		}
+		for _, pgf := range p.CompiledGoFiles() {
+			if pgf.Tok.Name() == c.key.file {
+				var err error
+				if pos, err = safetoken.Pos(pgf.Tok, c.key.offset); err != nil {
+					return err
+				}
+				pkg = p
+			}
+		}
+	}
+	if pkg == nil {
+		return fmt.Errorf("cannot find the declaration of %s", c.fn.Name())
+	}
+	c.declFset = pkg.FileSet()
+
+	c.newParams = make(map[int]*newParam)
+	for i, p := range c.params {
+		if p.Index >= 0 {
+			continue
+		}
+		tv, err := types.Eval(pkg.FileSet(), pkg.GetTypes(), pos, p.Type)
+		if err != nil {
+			return fmt.Errorf("invalid parameter type %s: %v", p.Type, err)
+		}
+		if !tv.IsType() {
+			return fmt.Errorf("%s is not a type", p.Type)
+		}
+		dv, err := types.Eval(pkg.FileSet(), pkg.GetTypes(), pos, p.Default)
+		if err != nil {
+			return fmt.Errorf("invalid default value %s: %v", p.Default, err)
+		}
+		if !dv.IsValue() || !types.AssignableTo(types.Default(dv.Type), tv.Type) &&
+			!(dv.Value != nil && types.ConvertibleTo(dv.Type, tv.Type)) {
+			return fmt.Errorf("default value %s is not assignable to %s", p.Default, p.Type)
+		}
+		np := new(newParam)
+		if np.typeRefs, err = exprRefs(pkg, pos, p.Type); err != nil {
+			return err
+		}
+		if np.defaultRefs, err = exprRefs(pkg, pos, p.Default); err != nil {
+			return err
+		}
+		c.newParams[i] = np
+	}
+	return nil
+}
+
+// exprRefs returns the free names of the expression text, mapped to
+// the objects they denote at position pos of the package.
+func exprRefs(pkg Package, pos token.Pos, text string) (refs, error) {
+	expr, err := parser.ParseExpr(text)
+	if err != nil {
+		return nil, err
+	}
+	scope := pkg.GetTypes().Scope().Innermost(pos)
+	refs := make(refs)
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(n.X, visit)
+			return false
+		case *ast.KeyValueExpr:
+			if _, ok := n.Key.(*ast.Ident); ok {
+				ast.Inspect(n.Value, visit) // the key may be a field name
+				return false
+			}
+		case *ast.FuncLit:
+			return false
+		case *ast.Ident:
+			if _, obj := scope.LookupParent(n.Name, pos); obj != nil {
+				refs[n.Name] = obj
+			}
+		}
+		return true
+	}
+	ast.Inspect(expr, visit)
+	return refs, nil
+}
+
+// checkRefs reports an error if a name referred to by the expression
+// text would not denote the same object at position pos of the
+// package as at the declaration of the function.
+func (c *signatureChanger) checkRefs(pkg Package, pos token.Pos, text string, refs refs) error {
+	scope := pkg.GetTypes().Scope().Innermost(pos)
+	for name, want := range refs {
+		var got types.Object
+		if scope != nil {
+			_, got = scope.LookupParent(name, pos)
+		}
+		if !sameObject(c.declFset, want, pkg.FileSet(), got) {
+			return fmt.Errorf("cannot use %s at %s: %s does not refer to the same declaration there",
+				text, safetoken.StartPosition(pkg.FileSet(), pos), name)
+		}
+	}
+	return nil
+}
+
+// sameObject reports whether x and y, which may belong to different
+// type-checked packages, denote the same object.
+func sameObject(xfset *token.FileSet, x types.Object, yfset *token.FileSet, y types.Object) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	if x, ok := x.(*types.PkgName); ok {
+		y, ok := y.(*types.PkgName)
+		return ok && x.Imported().Path() == y.Imported().Path()
+	}
+	if x.Pkg() == nil || y.Pkg() == nil {
+		return x == y // universe
+	}
+	return x.Name() == y.Name() && keyOf(xfset, x) == keyOf(yfset, y)
+}
+
+// A callSite is a call of a target. For a call of a method expression
+// T.f(recv, ...), skip is the number of arguments before those of the
+// signature.
+type callSite struct {
+	call *ast.CallExpr
+	skip int
+}
+
+// rewrite computes the edits to the declarations and calls of the
+// targets in the files of the package.
+func (c *signatureChanger) rewrite(pkg Package) error {
+	if pkg.HasParseErrors() || pkg.HasTypeErrors() {
+		return fmt.Errorf("cannot change the signature of %s: package %s has errors", c.fn.Name(), pkg.Metadata().PkgPath)
+	}
+	info := pkg.GetTypesInfo()
+	fset := pkg.FileSet()
+	isTarget := func(obj types.Object) bool {
+		fn, ok := obj.(*types.Func)
+		return ok && c.targets[keyOf(fset, fn)]
+	}
+	for _, pgf := range pkg.CompiledGoFiles() {
+		var (
+			edits []diff.Edit
+			calls []callSite
+			stack []ast.Node
+			err   error
+		)
+		ast.Inspect(pgf.File, func(n ast.Node) bool {
+			if n == nil {
+				stack = stack[:len(stack)-1]
+				return true
+			}
+			if err != nil {
+				return false
+			}
+			stack = append(stack, n)
+			var edit diff.Edit
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if obj := info.Defs[n.Name]; isTarget(obj) {
+					edit, err = c.declEdit(pkg, pgf, obj.(*types.Func), n.Type, n.Body)
+					edits = append(edits, edit)
+				}
+			case *ast.InterfaceType:
+				for _, field := range n.Methods.List {
+					ftype, ok := field.Type.(*ast.FuncType)
+					if !ok || len(field.Names) != 1 {
+						continue // embedded interface
+					}
+					if obj := info.Defs[field.Names[0]]; isTarget(obj) && err == nil {
+						edit, err = c.declEdit(pkg, pgf, obj.(*types.Func), ftype, nil)
+						edits = append(edits, edit)
+					}
+				}
+			case *ast.Ident:
+				if isTarget(info.Uses[n]) {
+					var site callSite
+					site, err = callOf(fset, info, stack)
+					calls = append(calls, site)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		// Rewrite inner calls first, so that the text of each
+		// argument includes the changes to the calls within it.
+		sort.Slice(calls, func(i, j int) bool {
+			return calls[i].call.End()-calls[i].call.Pos() < calls[j].call.End()-calls[j].call.Pos()
+		})
+		for _, site := range calls {
+			edit, err := c.callEdit(pkg, pgf, site, &edits)
+			if err != nil {
+				return err
+			}
+			edits = append(edits, edit)
+		}
+		c.edits[pgf.URI] = append(c.edits[pgf.URI], edits...)
+	}
+	return nil
+}
+
+// callOf returns the call of the function denoted by the identifier
+// at the top of the stack of enclosing nodes, or an error if the
+// identifier is not the callee of a call.
+func callOf(fset *token.FileSet, info *types.Info, stack []ast.Node) (callSite, error) {
+	id := stack[len(stack)-1].(*ast.Ident)
+	child := ast.Node(id)
+	skip := 0
+loop:
+	for i := len(stack) - 2; i >= 0; i-- {
+		switch parent := stack[i].(type) {
+		case *ast.SelectorExpr:
+			if parent.Sel != child {
+				break loop
+			}
+			if sel, ok := info.Selections[parent]; ok && sel.Kind() == types.MethodExpr {
+				skip = 1
+			}
+		case *ast.ParenExpr:
+		case *ast.CallExpr:
+			if parent.Fun == child {
+				return callSite{parent, skip}, nil
+			}
+			break loop
+		default:
+			// An instantiation, as in f[T](...).
+			if x, _, _, _ := typeparams.UnpackIndexExpr(parent); x != child {
+				break loop
+			}
+		}
+		child = stack[i]
+	}
+	return callSite{}, fmt.Errorf("cannot change the signature of %s: it is used as a value at %s",
+		id.Name, safetoken.StartPosition(fset, id.Pos()))
+}
+
+// declEdit returns the edit to the parameter list of the declaration
+// of the function fn, which has the body body, if any.
+func (c *signatureChanger) declEdit(pkg Package, pgf *ParsedGoFile, fn *types.Func, ftype *ast.FuncType, body *ast.BlockStmt) (diff.Edit, error) {
+	c.declared[keyOf(pkg.FileSet(), fn)] = true
+	info := pkg.GetTypesInfo()
+	sig := fn.Type().(*types.Signature)
+	posn := func(pos token.Pos) token.Position { return safetoken.StartPosition(pkg.FileSet(), pos) }
+
+	// Check that the removed parameters are unused.
+	kept := make(map[int]bool)
+	for _, p := range c.params {
+		if p.Index >= 0 {
+			kept[p.Index] = true
+		}
+	}
+	if body != nil {
+		for i := 0; i < sig.Params().Len(); i++ {
+			if kept[i] {
+				continue
+			}
+			if id := useOf(info, body, sig.Params().At(i)); id != nil {
+				return diff.Edit{}, fmt.Errorf("cannot remove parameter %s of %s: it is used at %s", id.Name, fn.Name(), posn(id.Pos()))
+			}
+		}
+	}
+
+	// Check the new parameters.
+	for i, p := range c.params {
+		if p.Index >= 0 {
+			continue
+		}
+		if err := c.checkRefs(pkg, ftype.Params.Opening, p.Type, c.newParams[i].typeRefs); err != nil {
+			return diff.Edit{}, err
+		}
+		if p.Name == "" || p.Name == "_" {
+			continue
+		}
+		var vars []*types.Var
+		if sig.Recv() != nil {
+			vars = append(vars, sig.Recv())
+		}
+		for j := 0; j < sig.Params().Len(); j++ {
+			if kept[j] {
+				vars = append(vars, sig.Params().At(j))
+			}
+		}
+		for j := 0; j < sig.Results().Len(); j++ {
+			vars = append(vars, sig.Results().At(j))
+		}
+		for _, v := range vars {
+			if v.Name() == p.Name {
+				return diff.Edit{}, fmt.Errorf("new parameter %s conflicts with the declaration at %s", p.Name, posn(v.Pos()))
+			}
+		}
+		if body == nil {
+			continue
+		}
+		var shadowed *ast.Ident
+		ast.Inspect(body, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && shadowed == nil && id.Name == p.Name {
+				// Does the name refer to a declaration outside the function?
+				if obj := info.Uses[id]; obj != nil && !(ftype.Pos() <= obj.Pos() && obj.Pos() < body.End()) {
+					shadowed = id
+				}
+			}
+			return shadowed == nil
+		})
+		if shadowed != nil {
+			return diff.Edit{}, fmt.Errorf("new parameter %s would shadow the reference at %s", p.Name, posn(shadowed.Pos()))
+		}
+	}
+
+	return posEdit(pgf.Tok, ftype.Params.Opening+1, ftype.Params.Closing, c.paramsText(pgf, ftype))
+}
+
+// paramsText returns the text of the new parameter list of the
+// function type. Parameters of the original list that shared a type
+// continue to do so while they remain adjacent.
+func (c *signatureChanger) paramsText(pgf *ParsedGoFile, ftype *ast.FuncType) string {
+	type param struct {
+		name, typ string
+		field     int // index of the original field, or -1 if new
+	}
+	var old []param
+	for i, field := range ftype.Params.List {
+		typ := FormatNodeFile(pgf.Tok, field.Type)
+		if len(field.Names) == 0 {
+			old = append(old, param{"", typ, i})
+		}
+		for _, name := range field.Names {
+			old = append(old, param{name.Name, typ, i})
+		}
+	}
+	var params []param
+	named := false
+	for _, p := range c.params {
+		if p.Index >= 0 {
+			params = append(params, old[p.Index])
+			named = named || old[p.Index].name != ""
+		} else {
+			params = append(params, param{p.Name, p.Type, -1})
+			named = named || p.Name != ""
+		}
+	}
+
+	// Either all parameters are named or none are.
+	var parts []string
+	for i := 0; i < len(params); {
+		if !named {
+			parts = append(parts, params[i].typ)
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(params) && params[i].field >= 0 && params[j].field == params[i].field {
+			j++
+		}
+		var names []string
+		for _, p := range params[i:j] {
+			if p.name == "" {
+				p.name = "_"
+			}
+			names = append(names, p.name)
+		}
+		parts = append(parts, strings.Join(names, ", ")+" "+params[i].typ)
+		i = j
+	}
+	return strings.Join(parts, ", ")
+}
+
+// callEdit returns the edit to the arguments of a call of a target.
+// The pending edits within the arguments are applied to their text
+// and removed from the list.
+func (c *signatureChanger) callEdit(pkg Package, pgf *ParsedGoFile, site callSite, pending *[]diff.Edit) (diff.Edit, error) {
+	info := pkg.GetTypesInfo()
+	call := site.call
+	posn := safetoken.StartPosition(pkg.FileSet(), call.Lparen)
+	n := c.sig.Params().Len()
+	args := call.Args[site.skip:]
+	if len(args) == 1 {
+		if _, ok := info.TypeOf(args[0]).(*types.Tuple); ok {
+			return diff.Edit{}, fmt.Errorf("cannot change the call at %s: its argument is a multi-valued call", posn)
+		}
+	}
+
+	// Group the arguments by parameter.
+	groups := make([][]ast.Expr, n)
+	for i, arg := range args {
+		j := i
+		if c.sig.Variadic() && j >= n-1 {
+			j = n - 1
+		}
+		groups[j] = append(groups[j], arg)
+	}
+
+	var texts []string
+	for _, arg := range call.Args[:site.skip] {
+		text, err := argText(pgf, arg, pending)
+		if err != nil {
+			return diff.Edit{}, err
+		}
+		texts = append(texts, text)
+	}
+	kept := make(map[int]bool)
+	reordered := false
+	effects := 0
+	for i, p := range c.params {
+		if p.Index < 0 {
+			if err := c.checkRefs(pkg, call.Lparen, p.Default, c.newParams[i].defaultRefs); err != nil {
+				return diff.Edit{}, err
+			}
+			texts = append(texts, p.Default)
+			continue
+		}
+		for j := range kept {
+			reordered = reordered || j > p.Index
+		}
+		kept[p.Index] = true
+		for _, arg := range groups[p.Index] {
+			if hasEffects(info, arg) {
+				effects++
+			}
+			text, err := argText(pgf, arg, pending)
+			if err != nil {
+				return diff.Edit{}, err
+			}
+			texts = append(texts, text)
+		}
+	}
+	for i, group := range groups {
+		if kept[i] {
+			continue
+		}
+		for _, arg := range group {
+			if hasEffects(info, arg) {
+				return diff.Edit{}, fmt.Errorf("cannot remove the argument %s at %s: it may have side effects",
+					FormatNodeFile(pgf.Tok, arg), posn)
+			}
+		}
+	}
+	if reordered && effects > 1 {
+		return diff.Edit{}, fmt.Errorf("cannot reorder the arguments at %s: they may have side effects", posn)
+	}
+
+	text := strings.Join(texts, ", ")
+	if call.Ellipsis.IsValid() && kept[n-1] {
+		text += "..."
+	}
+	return posEdit(pgf.Tok, call.Lparen+1, call.Rparen, text)
+}
+
+// argText returns the text of the argument e, with the pending edits
+// within it applied. Those edits are removed from the list.
+func argText(pgf *ParsedGoFile, e ast.Expr, pending *[]diff.Edit) (string, error) {
+	start, end, err := safetoken.Offsets(pgf.Tok, e.Pos(), e.End())
+	if err != nil {
+		return "", err
+	}
+	var inner []diff.Edit
+	rest := (*pending)[:0]
+	for _, edit := range *pending {
+		if start <= edit.Start && edit.End <= end {
+			inner = append(inner, diff.Edit{Start: edit.Start - start, End: edit.End - start, New: edit.New})
+		} else {
+			rest = append(rest, edit)
+		}
+	}
+	*pending = rest
+	return diff.Apply(string(pgf.Src[start:end]), inner)
+}
+
+// useOf returns an identifier within n that refers to v, or nil.
+func useOf(info *types.Info, n ast.Node, v *types.Var) *ast.Ident {
+	var use *ast.Ident
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && info.Uses[id] == v {
+			use = id
+		}
+		return use == nil
+	})
+	return use
+}
@@ -88,19 +88,57 @@ type CompletionItem struct {
 	// obj is the object from which this candidate was derived, if any.
 	// obj is for internal use only.
 	obj types.Object
+
+	// deferred, if non-nil, records the parts of the item whose
+	// computation was deferred until the item is resolved.
+	deferred *deferred
+}
+
+// deferred holds the information needed to compute the documentation
+// and additional text edits of a completion item when it is resolved,
+// for clients that can resolve these properties lazily.
+type deferred struct {
+	fset              *token.FileSet       // file set of obj, if documentation is deferred
+	fullDocumentation bool                 // whether to compute the full documentation
+	imp               *importInfo          // import to add, if its edits are deferred
+	pgf               *source.ParsedGoFile // file to which imp is added
+}
+
+// Resolve computes the documentation and additional text edits of the
+// item whose computation was deferred by Completion because the client
+// can resolve them lazily. It is a no-op for other items.
+func (item *CompletionItem) Resolve(ctx context.Context, snapshot source.Snapshot) error {
+	d := item.deferred
+	if d == nil {
+		return nil
+	}
+	item.deferred = nil
+	if d.fset != nil {
+		setDocumentation(ctx, snapshot, d.fset, item, d.fullDocumentation)
+	}
+	if d.imp != nil {
+		edits, err := importEdits(snapshot, d.pgf, d.imp)
+		if err != nil {
+			return err
+		}
+		item.AdditionalTextEdits = append(edits, item.AdditionalTextEdits...)
+	}
+	return nil
 }
 
 // completionOptions holds completion specific configuration.
 type completionOptions struct {
-	unimported        bool
-	documentation     bool
-	fullDocumentation bool
-	placeholders      bool
-	literal           bool
-	snippets          bool
-	postfix           bool
-	matcher           source.Matcher
-	budget            time.Duration
+	unimported           bool
+	documentation        bool
+	fullDocumentation    bool
+	resolveDocumentation bool
+	resolveEdits         bool
+	placeholders         bool
+	literal              bool
+	snippets             bool
+	postfix              bool
+	matcher              source.Matcher
+	budget               time.Duration
 }
 
 // Snippet is a convenience returns the snippet if available, otherwise
@@ -529,15 +567,17 @@ func Completion(ctx context.Context, snapshot source.Snapshot, fh source.FileHan
 			enabled: opts.DeepCompletion,
 		},
 		opts: &completionOptions{
-			matcher:           opts.Matcher,
-			unimported:        opts.CompleteUnimported,
-			documentation:     opts.CompletionDocumentation && opts.HoverKind != source.NoDocumentation,
-			fullDocumentation: opts.HoverKind == source.FullDocumentation,
-			placeholders:      opts.UsePlaceholders,
-			literal:           opts.LiteralCompletions && opts.InsertTextFormat == protocol.SnippetTextFormat,
-			budget:            opts.CompletionBudget,
-			snippets:          opts.InsertTextFormat == protocol.SnippetTextFormat,
-			postfix:           opts.ExperimentalPostfixCompletions,
+			matcher:              opts.Matcher,
+			unimported:           opts.CompleteUnimported,
+			documentation:        opts.CompletionDocumentation && opts.HoverKind != source.NoDocumentation,
+			fullDocumentation:    opts.HoverKind == source.FullDocumentation,
+			resolveDocumentation: opts.CompletionResolveDocumentation,
+			resolveEdits:         opts.CompletionResolveEdits,
+			placeholders:         opts.UsePlaceholders,
+			literal:              opts.LiteralCompletions && opts.InsertTextFormat == protocol.SnippetTextFormat,
+			budget:               opts.CompletionBudget,
+			snippets:             opts.InsertTextFormat == protocol.SnippetTextFormat,
+			postfix:              opts.ExperimentalPostfixCompletions,
 		},
 		// default to a matcher that always matches
 		matcher:        prefixMatcher(""),
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/doc"
+	"go/token"
 	"go/types"
 	"strings"
 
@@ -164,14 +165,19 @@ Suffixes:
 
 	// If this candidate needs an additional import statement,
 	// add the additional text edits needed.
+	var deferredImport *importInfo
 	if cand.imp != nil {
-		addlEdits, err := c.importEdits(cand.imp)
-
-		if err != nil {
-			return CompletionItem{}, err
+		if c.opts.resolveEdits {
+			// Computing the edits requires formatting the import
+			// declaration, so leave it to Resolve.
+			deferredImport = cand.imp
+		} else {
+			addlEdits, err := c.importEdits(cand.imp)
+			if err != nil {
+				return CompletionItem{}, err
+			}
+			protocolEdits = append(protocolEdits, addlEdits...)
 		}
-
-		protocolEdits = append(protocolEdits, addlEdits...)
 		if kind != protocol.ModuleCompletion {
 			if detail != "" {
 				detail += " "
@@ -230,6 +236,13 @@ Suffixes:
 		snippet:             &snip,
 		obj:                 obj,
 	}
+	if deferredImport != nil {
+		pgf, err := c.pkg.File(span.URIFromPath(c.filename))
+		if err != nil {
+			return CompletionItem{}, err
+		}
+		item.deferred = &deferred{imp: deferredImport, pgf: pgf}
+	}
 	// If the user doesn't want documentation for completion items.
 	if !c.opts.documentation {
 		return item, nil
@@ -242,12 +255,32 @@ Suffixes:
 		return item, nil
 	}
 
-	comment, err := source.HoverDocForObject(ctx, c.snapshot, c.pkg.FileSet(), obj)
+	// Finding the documentation requires parsing the declaring file,
+	// which is expensive for long lists of candidates, so leave it to
+	// Resolve if the client allows.
+	if c.opts.resolveDocumentation {
+		if item.deferred == nil {
+			item.deferred = new(deferred)
+		}
+		item.deferred.fset = c.pkg.FileSet()
+		item.deferred.fullDocumentation = c.opts.fullDocumentation
+		return item, nil
+	}
+	setDocumentation(ctx, c.snapshot, c.pkg.FileSet(), &item, c.opts.fullDocumentation)
+	return item, nil
+}
+
+// setDocumentation sets the documentation of item, derived from the
+// object item.obj whose position is in fset, and marks the item as
+// deprecated if the documentation says so.
+func setDocumentation(ctx context.Context, snapshot source.Snapshot, fset *token.FileSet, item *CompletionItem, fullDocumentation bool) {
+	obj := item.obj
+	comment, err := source.HoverDocForObject(ctx, snapshot, fset, obj)
 	if err != nil {
 		event.Error(ctx, fmt.Sprintf("failed to find Hover for %q", obj.Name()), err)
-		return item, nil
+		return
 	}
-	if c.opts.fullDocumentation {
+	if fullDocumentation {
 		item.Documentation = comment.Text()
 	} else {
 		item.Documentation = doc.Synopsis(comment.Text())
@@ -256,14 +289,12 @@ Suffixes:
 	// TODO(rfindley): It doesn't look like this does the right thing for
 	// multi-line comments.
 	if strings.HasPrefix(comment.Text(), "Deprecated") {
-		if c.snapshot.View().Options().CompletionTags {
+		if snapshot.View().Options().CompletionTags {
 			item.Tags = []protocol.CompletionItemTag{protocol.ComplDeprecated}
-		} else if c.snapshot.View().Options().CompletionDeprecated {
+		} else if snapshot.View().Options().CompletionDeprecated {
 			item.Deprecated = true
 		}
 	}
-
-	return item, nil
 }
 
 // importEdits produces the text edits necessary to add the given import to the current file.
@@ -277,7 +308,12 @@ func (c *completer) importEdits(imp *importInfo) ([]protocol.TextEdit, error) {
 		return nil, err
 	}
 
-	return source.ComputeOneImportFixEdits(c.snapshot, pgf, &imports.ImportFix{
+	return importEdits(c.snapshot, pgf, imp)
+}
+
+// importEdits produces the text edits necessary to add the given import to pgf.
+func importEdits(snapshot source.Snapshot, pgf *source.ParsedGoFile, imp *importInfo) ([]protocol.TextEdit, error) {
+	return source.ComputeOneImportFixEdits(snapshot, pgf, &imports.ImportFix{
 		StmtInfo: imports.ImportInfo{
 			ImportPath: imp.importPath,
 			Name:       imp.name,
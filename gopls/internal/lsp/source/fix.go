@@ -40,6 +40,8 @@ const (
 	ExtractVariable = "extract_variable"
 	ExtractFunction = "extract_function"
 	ExtractMethod   = "extract_method"
+	InlineCall      = "inline_call"
+	InlineVariable  = "inline_variable"
 )
 
 // suggestedFixes maps a suggested fix command id to its handler.
@@ -49,6 +51,8 @@ var suggestedFixes = map[string]SuggestedFixFunc{
 	ExtractVariable: singleFile(extractVariable),
 	ExtractFunction: singleFile(extractFunction),
 	ExtractMethod:   singleFile(extractMethod),
+	InlineCall:      inlineCall,
+	InlineVariable:  singleFile(inlineVariable),
 	StubMethods:     stubSuggestedFixFunc,
 }
 
@@ -37,15 +37,24 @@ func Format(ctx context.Context, snapshot Snapshot, fh FileHandle) ([]protocol.T
 	if err != nil {
 		return nil, err
 	}
+	formatted, err := formatFile(ctx, snapshot, fh, pgf)
+	if err != nil {
+		return nil, err
+	}
+	return computeTextEdits(ctx, snapshot, pgf, formatted)
+}
+
+// formatFile returns the formatted content of the parsed file.
+func formatFile(ctx context.Context, snapshot Snapshot, fh FileHandle, pgf *ParsedGoFile) (string, error) {
 	// Even if this file has parse errors, it might still be possible to format it.
 	// Using format.Node on an AST with errors may result in code being modified.
 	// Attempt to format the source of this file instead.
 	if pgf.ParseErr != nil {
 		formatted, err := formatSource(ctx, fh)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		return computeTextEdits(ctx, snapshot, pgf, string(formatted))
+		return string(formatted), nil
 	}
 
 	// format.Node changes slightly from one release to another, so the version
@@ -55,7 +64,7 @@ func Format(ctx context.Context, snapshot Snapshot, fh FileHandle) ([]protocol.T
 	buf := &bytes.Buffer{}
 	fset := FileSetFor(pgf.Tok)
 	if err := format.Node(buf, fset, pgf.File); err != nil {
-		return nil, err
+		return "", err
 	}
 	formatted := buf.String()
 
@@ -80,11 +89,11 @@ func Format(ctx context.Context, snapshot Snapshot, fh FileHandle) ([]protocol.T
 		}
 		b, err := format(ctx, langVersion, modulePath, buf.Bytes())
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 		formatted = string(b)
 	}
-	return computeTextEdits(ctx, snapshot, pgf, formatted)
+	return formatted, nil
 }
 
 func formatSource(ctx context.Context, fh FileHandle) ([]byte, error) {
@@ -124,6 +133,45 @@ func AllImportsFixes(ctx context.Context, snapshot Snapshot, fh FileHandle) (all
 	return allFixEdits, editsPerFix, nil
 }
 
+// ImportFixes returns the fixes to the imports of fh that
+// AllImportsFixes would make, without computing their edits, which is
+// expensive. ImportFixEdits computes the edits of some of them.
+func ImportFixes(ctx context.Context, snapshot Snapshot, fh FileHandle) (fixes []*imports.ImportFix, err error) {
+	ctx, done := event.Start(ctx, "source.ImportFixes")
+	defer done()
+
+	pgf, err := snapshot.ParseGo(ctx, fh, ParseFull)
+	if err != nil {
+		return nil, err
+	}
+	if err := snapshot.RunProcessEnvFunc(ctx, func(opts *imports.Options) error {
+		fixes, err = imports.FixImports(pgf.URI.Filename(), pgf.Src, opts)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("ImportFixes: %v", err)
+	}
+	return fixes, nil
+}
+
+// ImportFixEdits returns the edits that perform the given fixes to the
+// imports of fh.
+func ImportFixEdits(ctx context.Context, snapshot Snapshot, fh FileHandle, fixes []*imports.ImportFix) (edits []protocol.TextEdit, err error) {
+	ctx, done := event.Start(ctx, "source.ImportFixEdits")
+	defer done()
+
+	pgf, err := snapshot.ParseGo(ctx, fh, ParseFull)
+	if err != nil {
+		return nil, err
+	}
+	if err := snapshot.RunProcessEnvFunc(ctx, func(opts *imports.Options) error {
+		edits, err = computeFixEdits(snapshot, pgf, opts, fixes)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("ImportFixEdits: %v", err)
+	}
+	return edits, nil
+}
+
 // computeImportEdits computes a set of edits that perform one or all of the
 // necessary import fixes.
 func computeImportEdits(snapshot Snapshot, pgf *ParsedGoFile, options *imports.Options) (allFixEdits []protocol.TextEdit, editsPerFix []*ImportFix, err error) {
@@ -0,0 +1,346 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/lsp/safetoken"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/event"
+)
+
+// FormatRange returns the edits that format the statements or
+// declarations of the file that overlap the range rng, leaving the rest
+// of the file unchanged.
+//
+// The edits are those that gofmt would make to the lines of these
+// statements when formatting the whole file, so that formatting a
+// selection of a file that is not gofmt-clean changes only the
+// selection.
+func FormatRange(ctx context.Context, snapshot Snapshot, fh FileHandle, rng protocol.Range) ([]protocol.TextEdit, error) {
+	ctx, done := event.Start(ctx, "source.FormatRange")
+	defer done()
+
+	// Generated files shouldn't be edited. So, don't format them
+	if IsGenerated(ctx, snapshot, fh.URI()) {
+		return nil, fmt.Errorf("can't format %q: file is generated", fh.URI().Filename())
+	}
+
+	pgf, err := snapshot.ParseGo(ctx, fh, ParseFull)
+	if err != nil {
+		return nil, err
+	}
+	if pgf.ParseErr != nil {
+		return nil, fmt.Errorf("can't format range of %q: %v", fh.URI().Filename(), pgf.ParseErr)
+	}
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	edits, err := formatStatements(ctx, snapshot, fh, pgf, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return ToProtocolEdits(pgf.Mapper, edits)
+}
+
+// FormatOnType returns the edits that tidy the code around position pp
+// of the file after the character ch was typed there.
+//
+// Typing a closing brace formats the statement or declaration that it
+// closes, and, at the end of a function, fixes the imports of the file.
+// Typing a newline formats the statement completed by the previous
+// line and indents the new line.
+func FormatOnType(ctx context.Context, snapshot Snapshot, fh FileHandle, pp protocol.Position, ch string) ([]protocol.TextEdit, error) {
+	ctx, done := event.Start(ctx, "source.FormatOnType")
+	defer done()
+
+	if IsGenerated(ctx, snapshot, fh.URI()) {
+		return nil, nil
+	}
+	pgf, err := snapshot.ParseGo(ctx, fh, ParseFull)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := pgf.PositionPos(pp)
+	if err != nil {
+		return nil, err
+	}
+	switch ch {
+	case "}":
+		return formatClosingBrace(ctx, snapshot, fh, pgf, pos)
+	case "\n":
+		return formatNewline(ctx, snapshot, fh, pgf, pos)
+	}
+	return nil, nil
+}
+
+// formatClosingBrace returns the edits that format the statement or
+// declaration closed by the brace just before pos.
+func formatClosingBrace(ctx context.Context, snapshot Snapshot, fh FileHandle, pgf *ParsedGoFile, pos token.Pos) ([]protocol.TextEdit, error) {
+	if pgf.ParseErr != nil {
+		return nil, nil // formatting an incomplete file would move code around
+	}
+	offset, err := safetoken.Offset(pgf.Tok, pos)
+	if err != nil {
+		return nil, err
+	}
+	if offset == 0 || pgf.Src[offset-1] != '}' {
+		return nil, nil
+	}
+	brace := pos - 1
+
+	// Find the innermost node closed by the brace.
+	path, _ := astutil.PathEnclosingInterval(pgf.File, brace, pos)
+	var closed ast.Node
+	var isFuncBody bool
+	for i, n := range path {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			if n.Rbrace == brace {
+				closed = n
+				if i+1 < len(path) {
+					_, isFuncBody = path[i+1].(*ast.FuncDecl)
+				}
+			}
+		case *ast.CompositeLit:
+			if n.Rbrace == brace {
+				closed = n
+			}
+		case *ast.FieldList:
+			if n.Closing == brace {
+				closed = n
+			}
+		}
+		if closed != nil {
+			break
+		}
+	}
+	if closed == nil {
+		return nil, nil
+	}
+
+	edits, err := formatStatements(ctx, snapshot, fh, pgf, closed.Pos(), closed.End())
+	if err != nil {
+		return nil, err
+	}
+	result, err := ToProtocolEdits(pgf.Mapper, edits)
+	if err != nil {
+		return nil, err
+	}
+	if isFuncBody {
+		// The imports precede the function, so the edits are disjoint.
+		importEdits, _, err := AllImportsFixes(ctx, snapshot, fh)
+		if err != nil {
+			return nil, err
+		}
+		result = append(importEdits, result...)
+	}
+	return result, nil
+}
+
+// formatNewline returns the edits that format the statement completed
+// by the line before pos, and that indent the line of pos.
+func formatNewline(ctx context.Context, snapshot Snapshot, fh FileHandle, pgf *ParsedGoFile, pos token.Pos) ([]protocol.TextEdit, error) {
+	tok := pgf.Tok
+	line := tok.Line(pos)
+	if line < 2 {
+		return nil, nil
+	}
+	lineStart, err := safetoken.Offset(tok, tok.LineStart(line))
+	if err != nil {
+		return nil, err
+	}
+	lineEnd := len(pgf.Src)
+	if line < tok.LineCount() {
+		if lineEnd, err = safetoken.Offset(tok, tok.LineStart(line+1)); err != nil {
+			return nil, err
+		}
+	}
+	text := strings.TrimRight(string(pgf.Src[lineStart:lineEnd]), "\r\n")
+	rest := strings.TrimLeft(text, " \t")
+	indentEnd := lineStart + len(text) - len(rest)
+
+	var edits []diff.Edit
+	if pgf.ParseErr == nil {
+		prevStart, err := safetoken.Offset(tok, tok.LineStart(line-1))
+		if err != nil {
+			return nil, err
+		}
+		prevEdits, err := formatStatements(ctx, snapshot, fh, pgf, tok.Pos(prevStart), tok.Pos(lineStart-1))
+		if err != nil {
+			return nil, err
+		}
+		// Leave the new line and those after it alone.
+		for _, edit := range prevEdits {
+			if edit.End < lineStart {
+				edits = append(edits, edit)
+			}
+		}
+	}
+	indent := strings.Repeat("\t", indentation(pgf, tok.Pos(indentEnd), rest))
+	if string(pgf.Src[lineStart:indentEnd]) != indent {
+		edits = append(edits, diff.Edit{Start: lineStart, End: indentEnd, New: indent})
+	}
+	return ToProtocolEdits(pgf.Mapper, edits)
+}
+
+// formatStatements returns the edits that gofmt would make to the
+// lines of the statements or declarations that overlap [start, end).
+func formatStatements(ctx context.Context, snapshot Snapshot, fh FileHandle, pgf *ParsedGoFile, start, end token.Pos) ([]diff.Edit, error) {
+	from, to, err := statementLines(pgf, start, end)
+	if err != nil {
+		return nil, err
+	}
+	formatted, err := formatFile(ctx, snapshot, fh, pgf)
+	if err != nil {
+		return nil, err
+	}
+	var edits []diff.Edit
+	for _, edit := range snapshot.View().Options().ComputeEdits(string(pgf.Src), formatted) {
+		if from <= edit.Start && edit.End <= to && !(edit.Start == edit.End && edit.Start == to) {
+			edits = append(edits, edit)
+		}
+	}
+	return edits, nil
+}
+
+// statementLines returns the offsets of the start and end of the lines
+// spanned by [start, end) and by the statements or declarations that
+// overlap it in the innermost enclosing block, case clause, or file.
+func statementLines(pgf *ParsedGoFile, start, end token.Pos) (int, int, error) {
+	tok := pgf.Tok
+
+	// A selection of whole lines ends at the start of the next line.
+	if end > start && safetoken.Position(tok, end).Column == 1 {
+		end--
+	}
+
+	// Find the innermost block or case clause whose body contains the
+	// range, or failing that, the file.
+	path, _ := astutil.PathEnclosingInterval(pgf.File, start, end)
+	var list []ast.Node
+outer:
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			if n.Lbrace < start && end <= n.Rbrace {
+				for _, stmt := range n.List {
+					list = append(list, stmt)
+				}
+				break outer
+			}
+		case *ast.CaseClause:
+			if n.Colon < start {
+				for _, stmt := range n.Body {
+					list = append(list, stmt)
+				}
+				break outer
+			}
+		case *ast.CommClause:
+			if n.Colon < start {
+				for _, stmt := range n.Body {
+					list = append(list, stmt)
+				}
+				break outer
+			}
+		case *ast.File:
+			for _, decl := range n.Decls {
+				list = append(list, decl)
+			}
+		}
+	}
+	lo, hi := start, end
+	for _, n := range list {
+		if n.Pos() <= end && start <= n.End() {
+			if n.Pos() < lo {
+				lo = n.Pos()
+			}
+			if n.End() > hi {
+				hi = n.End()
+			}
+		}
+	}
+
+	from, err := safetoken.Offset(tok, tok.LineStart(tok.Line(lo)))
+	if err != nil {
+		return 0, 0, err
+	}
+	to := len(pgf.Src)
+	if line := tok.Line(hi); line < tok.LineCount() {
+		if to, err = safetoken.Offset(tok, tok.LineStart(line+1)); err != nil {
+			return 0, 0, err
+		}
+	}
+	return from, to, nil
+}
+
+// indentation returns the number of tabs with which gofmt would indent
+// a line whose text, starting at pos, is rest.
+//
+// The line is indented once for each line that opens a bracket or a
+// case clause enclosing pos, so that, for example, the body of a
+// function literal passed as the last argument of a call is indented
+// only once.
+func indentation(pgf *ParsedGoFile, pos token.Pos, rest string) int {
+	lines := make(map[int]bool)
+	open := func(lbrack, rbrack token.Pos) {
+		if lbrack.IsValid() && lbrack < pos && (pos < rbrack || !rbrack.IsValid()) {
+			lines[pgf.Tok.Line(lbrack)] = true
+		}
+	}
+	path, _ := astutil.PathEnclosingInterval(pgf.File, pos, pos)
+	for i, n := range path {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			if i+1 < len(path) {
+				switch path[i+1].(type) {
+				case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+					// Case clauses are not indented, but their bodies are.
+					if !strings.HasPrefix(rest, "case") && !strings.HasPrefix(rest, "default") {
+						open(lastColon(n, pos), token.NoPos)
+					}
+					continue
+				}
+			}
+			open(n.Lbrace, n.Rbrace)
+		case *ast.CompositeLit:
+			open(n.Lbrace, n.Rbrace)
+		case *ast.FieldList:
+			open(n.Opening, n.Closing)
+		case *ast.CallExpr:
+			open(n.Lparen, n.Rparen)
+		case *ast.GenDecl:
+			open(n.Lparen, n.Rparen)
+		}
+	}
+	return len(lines)
+}
+
+// lastColon returns the position of the colon of the last case clause
+// of the switch or select body that begins before pos.
+func lastColon(body *ast.BlockStmt, pos token.Pos) token.Pos {
+	colon := token.NoPos
+	for _, stmt := range body.List {
+		var c token.Pos
+		switch stmt := stmt.(type) {
+		case *ast.CaseClause:
+			c = stmt.Colon
+		case *ast.CommClause:
+			c = stmt.Colon
+		}
+		if c.IsValid() && c < pos {
+			colon = c
+		}
+	}
+	return colon
+}
@@ -0,0 +1,1467 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+// This file defines the inline-call and inline-variable refactorings,
+// the inverses of extractFunction and extractVariable.
+//
+// Both refactorings substitute an expression into a new context, which
+// is safe only if doing so changes neither which objects its names
+// denote nor the number or order of its side effects. Where these
+// properties cannot be established, the refactorings either fall back
+// to a more conservative transformation or refuse.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/lsp/safetoken"
+	"golang.org/x/tools/gopls/internal/span"
+	"golang.org/x/tools/internal/bug"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// CanInlineCall reports whether the innermost call enclosing the
+// selection is a call to a function or method that may be inlined,
+// and if so returns the call and the called function.
+func CanInlineCall(start, end token.Pos, file *ast.File, info *types.Info) (*ast.CallExpr, *types.Func, error) {
+	path, _ := astutil.PathEnclosingInterval(file, start, end)
+	var call *ast.CallExpr
+	for _, n := range path {
+		if c, ok := n.(*ast.CallExpr); ok {
+			call = c
+			break
+		}
+	}
+	if call == nil {
+		return nil, nil, fmt.Errorf("no call expression selected")
+	}
+	fn := typeutil.StaticCallee(info, call)
+	if fn == nil {
+		return nil, nil, fmt.Errorf("not a static call to a Go function")
+	}
+	sig := fn.Type().(*types.Signature)
+	if typeparams.ForSignature(sig).Len() > 0 || typeparams.RecvTypeParams(sig).Len() > 0 {
+		return nil, nil, fmt.Errorf("cannot inline generic function %s", fn.Name())
+	}
+	if fn.Pkg() == nil || !fn.Pos().IsValid() {
+		return nil, nil, fmt.Errorf("no source for %s", fn.Name())
+	}
+	return call, fn, nil
+}
+
+// IllTypedCallStatement reports whether the call is the expression of
+// an expression statement at which the package has a type error. The
+// inlined statement would be no better typed, so it is not offered.
+func IllTypedCallStatement(ctx context.Context, snapshot Snapshot, pkg Package, pgf *ParsedGoFile, call *ast.CallExpr) bool {
+	path, _ := astutil.PathEnclosingInterval(pgf.File, call.Pos(), call.End())
+	if len(path) < 2 {
+		return false
+	}
+	stmt, ok := path[1].(*ast.ExprStmt)
+	if !ok || !pkg.HasTypeErrors() {
+		return false
+	}
+	diags, err := pkg.DiagnosticsForFile(ctx, snapshot, pgf.URI)
+	if err != nil {
+		return false
+	}
+	for _, diag := range diags {
+		if diag.Source != TypeError {
+			continue
+		}
+		start, end, err := pgf.RangePos(diag.Range)
+		if err == nil && start < stmt.End() && stmt.Pos() <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineCall returns a suggested fix that replaces the call at the
+// selected range by the body of the called function.
+//
+// If the body of the callee is a single return statement, the call is
+// replaced by the returned expression, with the arguments substituted
+// for the parameters. Otherwise, if the call is a statement, it is
+// replaced by a block that binds the parameters to the arguments and
+// then executes the body. In all other cases, such as a callee with
+// defer statements or multiple returns, the call is replaced by a call
+// of a function literal equivalent to the callee.
+func inlineCall(ctx context.Context, snapshot Snapshot, fh FileHandle, rng protocol.Range) (*token.FileSet, *analysis.SuggestedFix, error) {
+	pkg, pgf, err := PackageForFile(ctx, snapshot, fh.URI(), NarrowestPackage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pgf.Fixed {
+		return nil, nil, fmt.Errorf("file contains parse errors: %s", pgf.URI)
+	}
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, nil, err
+	}
+	call, fn, err := CanInlineCall(start, end, pgf.File, pkg.GetTypesInfo())
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot inline call: %v", err)
+	}
+	if IllTypedCallStatement(ctx, snapshot, pkg, pgf, call) {
+		return nil, nil, fmt.Errorf("cannot inline call: the statement is ill-typed")
+	}
+
+	// Find the declaration of the callee,
+	// type-checked in the context of its own package.
+	declFile := pkg.FileSet().File(fn.Pos())
+	if declFile == nil {
+		return nil, nil, bug.Errorf("no file for declaration of %s", fn.Name())
+	}
+	declOffset, err := safetoken.Offset(declFile, fn.Pos())
+	if err != nil {
+		return nil, nil, err
+	}
+	calleePkg, calleePGF, err := PackageForFile(ctx, snapshot, span.URIFromPath(declFile.Name()), NarrowestPackage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if calleePGF.Fixed {
+		return nil, nil, fmt.Errorf("file contains parse errors: %s", calleePGF.URI)
+	}
+	declPos, err := safetoken.Pos(calleePGF.Tok, declOffset)
+	if err != nil {
+		return nil, nil, err
+	}
+	var decl *ast.FuncDecl
+	for _, d := range calleePGF.File.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && d.Name.Pos() == declPos {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		return nil, nil, bug.Errorf("no declaration of %s in %s", fn.Name(), calleePGF.URI)
+	}
+	if decl.Body == nil {
+		return nil, nil, fmt.Errorf("cannot inline call: %s has no body", fn.Name())
+	}
+
+	in := &inliner{
+		pkg:         pkg,
+		pgf:         pgf,
+		info:        pkg.GetTypesInfo(),
+		call:        call,
+		fn:          fn,
+		calleePGF:   calleePGF,
+		calleeInfo:  calleePkg.GetTypesInfo(),
+		decl:        decl,
+		importNames: make(map[string]string),
+	}
+	in.path, _ = astutil.PathEnclosingInterval(pgf.File, call.Pos(), call.End())
+	if err := in.bind(); err != nil {
+		return nil, nil, fmt.Errorf("cannot inline call: %v", err)
+	}
+	start, end, text, err := in.inline()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot inline call: %v", err)
+	}
+	return in.apply(snapshot, start, end, text)
+}
+
+// An inliner holds the state of the inlining of a single call.
+type inliner struct {
+	// The caller.
+	pkg  Package
+	pgf  *ParsedGoFile
+	info *types.Info
+	call *ast.CallExpr
+	path []ast.Node // path from call to root of file
+
+	// The callee, type-checked in its own package.
+	fn         *types.Func // as seen by the caller
+	calleePGF  *ParsedGoFile
+	calleeInfo *types.Info
+	decl       *ast.FuncDecl
+
+	recv   *binding   // receiver, if any
+	params []*binding // parameters, if bound
+	bound  bool       // arguments correspond one-to-one to parameters
+
+	importNames map[string]string // local name of each imported package, by path
+	newImports  []inlineImport    // for AddNamedImport
+}
+
+type inlineImport struct{ name, path string }
+
+// A binding pairs a parameter of the callee with its argument.
+type binding struct {
+	obj   *types.Var // the parameter, or nil if it is unnamed
+	field *ast.Field // the declaration of the parameter
+	arg   ast.Expr   // the argument
+	text  string     // the text of the argument, adjusted for a receiver
+	index int        // the index of the parameter, or -1 for a receiver
+}
+
+// A replacement is the text substituted for a parameter.
+type replacement struct {
+	text    string
+	primary bool // text is a primary expression
+}
+
+// bind pairs the receiver and parameters of the callee with the
+// arguments of the call.
+func (in *inliner) bind() error {
+	sig := in.fn.Type().(*types.Signature)
+	args := in.call.Args
+	if in.decl.Recv != nil {
+		sel, ok := astutil.Unparen(in.call.Fun).(*ast.SelectorExpr)
+		seln := in.info.Selections[sel]
+		if !ok || seln == nil {
+			return bug.Errorf("method call without selection")
+		}
+		in.recv = &binding{field: in.decl.Recv.List[0], index: -1}
+		if names := in.recv.field.Names; len(names) > 0 {
+			in.recv.obj, _ = in.calleeInfo.Defs[names[0]].(*types.Var)
+		}
+		switch seln.Kind() {
+		case types.MethodVal:
+			if len(seln.Index()) > 1 {
+				return fmt.Errorf("%s is a promoted method", in.fn.Name())
+			}
+			// Make explicit any implicit & or * of the receiver.
+			in.recv.arg = sel.X
+			in.recv.text = in.callerText(sel.X)
+			_, wantPtr := sig.Recv().Type().(*types.Pointer)
+			_, isPtr := in.info.TypeOf(sel.X).Underlying().(*types.Pointer)
+			if wantPtr && !isPtr {
+				in.recv.text = "&" + in.operandText(sel.X)
+			} else if !wantPtr && isPtr {
+				in.recv.text = "*" + in.operandText(sel.X)
+			}
+		case types.MethodExpr:
+			in.recv.arg = args[0]
+			in.recv.text = in.callerText(args[0])
+			args = args[1:]
+		}
+	}
+
+	// Only a call whose arguments correspond one-to-one to
+	// the parameters can bind each parameter to its argument.
+	if sig.Variadic() || len(args) != sig.Params().Len() {
+		return nil
+	}
+	in.bound = true
+	i := 0
+	for _, field := range in.decl.Type.Params.List {
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{nil}
+		}
+		for _, name := range names {
+			b := &binding{field: field, arg: args[i], text: in.callerText(args[i]), index: i}
+			if name != nil {
+				b.obj, _ = in.calleeInfo.Defs[name].(*types.Var)
+			}
+			in.params = append(in.params, b)
+			i++
+		}
+	}
+	return nil
+}
+
+// bindings returns the receiver and parameter bindings.
+func (in *inliner) bindings() []*binding {
+	if in.recv != nil {
+		return append([]*binding{in.recv}, in.params...)
+	}
+	return in.params
+}
+
+// inline returns the replacement text for the
+// portion of the caller file from start to end.
+func (in *inliner) inline() (start, end token.Pos, text string, err error) {
+	if in.bound {
+		if text, ok, err := in.inlineExpr(); err != nil || ok {
+			return in.call.Pos(), in.call.End(), text, err
+		}
+		if stmt, ok := in.path[1].(*ast.ExprStmt); ok {
+			if text, ok, err := in.inlineBlock(); err != nil || ok {
+				return stmt.Pos(), stmt.End(), text, err
+			}
+		}
+	}
+	text, err = in.inlineLiteral()
+	return in.call.Pos(), in.call.End(), text, err
+}
+
+// inlineExpr replaces the call by the expression returned by the
+// callee, if its body is a single return statement, and the arguments
+// may be substituted for the parameters without changing the effects
+// of the call.
+func (in *inliner) inlineExpr() (string, bool, error) {
+	sig := in.fn.Type().(*types.Signature)
+	if len(in.decl.Body.List) != 1 || sig.Results().Len() != 1 {
+		return "", false, nil
+	}
+	ret, ok := in.decl.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return "", false, nil
+	}
+	expr := ret.Results[0]
+	switch in.path[1].(type) {
+	case *ast.GoStmt, *ast.DeferStmt:
+		return "", false, nil
+	case *ast.ExprStmt:
+		// The call is a statement, and the expression must be too.
+		if !in.isStatement(expr) {
+			return "", false, nil
+		}
+	}
+	resultType := in.decl.Type.Results.List[0].Type
+	if names := in.decl.Type.Results.List[0].Names; len(names) > 0 {
+		if res, ok := in.calleeInfo.Defs[names[0]].(*types.Var); ok && len(in.calleeUses(expr, res)) > 0 {
+			return "", false, nil // refers to a named result
+		}
+	}
+
+	// Names declared within the expression, such as
+	// the parameters of a function literal, must not
+	// capture the names used by the arguments.
+	declared := make(map[string]bool)
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && in.calleeInfo.Defs[id] != nil {
+			declared[id.Name] = true
+		}
+		return true
+	})
+
+	// Each argument that is not trivial to evaluate must be used
+	// exactly once, unconditionally, and in the same order relative
+	// to the effects of the body; we require it to be the only one.
+	effects := hasEffects(in.calleeInfo, expr)
+	subst := make(map[*types.Var]replacement)
+	nontrivial := 0
+	for _, b := range in.bindings() {
+		for _, id := range freeIdents(in.info, b.arg) {
+			if declared[id.Name] {
+				return "", false, nil
+			}
+		}
+		var uses []*ast.Ident
+		if b.obj != nil {
+			uses = in.calleeUses(expr, b.obj)
+		}
+		conditional := false
+		for _, use := range uses {
+			path, _ := astutil.PathEnclosingInterval(in.calleePGF.File, use.Pos(), use.End())
+			if isMutated(in.calleeInfo, path) {
+				return "", false, nil
+			}
+			conditional = conditional || isConditional(path, expr)
+		}
+		if !in.isTrivial(b.arg, effects) {
+			if len(uses) != 1 || conditional || effects {
+				return "", false, nil
+			}
+			nontrivial++
+		}
+		if b.obj == nil {
+			continue
+		}
+		r := replacement{text: b.text, primary: b.index >= 0 && isPrimary(b.arg)}
+		if b.index >= 0 && needsConversion(in.info, b.arg, b.obj.Type()) {
+			conv, err := in.conversion(b.field.Type, r.text)
+			if err != nil {
+				return "", false, err
+			}
+			r = replacement{text: conv, primary: true}
+		} else if b.index < 0 && b.text != in.callerText(b.arg) {
+			// A receiver with an implicit & or * need not be
+			// made explicit where it is used only in selections.
+			r.text = in.operandText(b.arg)
+			r.primary = true
+			for _, use := range uses {
+				path, _ := astutil.PathEnclosingInterval(in.calleePGF.File, use.Pos(), use.End())
+				if sel, ok := path[1].(*ast.SelectorExpr); !ok || sel.X != use {
+					r.text = "(" + b.text + ")"
+					break
+				}
+			}
+		}
+		subst[b.obj] = r
+	}
+	if nontrivial > 1 {
+		return "", false, nil
+	}
+
+	text, err := in.rewrite(expr.Pos(), expr.End(), subst)
+	if err != nil {
+		return "", false, err
+	}
+	primary := isPrimary(expr)
+	if id, ok := expr.(*ast.Ident); ok {
+		if v, ok := in.calleeInfo.Uses[id].(*types.Var); ok {
+			if r, ok := subst[v]; ok {
+				primary = r.primary
+			}
+		}
+	}
+	if needsConversion(in.calleeInfo, expr, in.calleeInfo.TypeOf(resultType)) {
+		if text, err = in.conversion(resultType, text); err != nil {
+			return "", false, err
+		}
+		primary = true
+	}
+	if !primary && needsParens(in.path[1], in.call) {
+		text = "(" + text + ")"
+	}
+	return text, true, nil
+}
+
+// isStatement reports whether the expression of the callee may be used
+// as a statement: it must be a function call, other than a conversion
+// or a call of a builtin function that only computes a value, or a
+// receive operation.
+func (in *inliner) isStatement(expr ast.Expr) bool {
+	switch expr := astutil.Unparen(expr).(type) {
+	case *ast.CallExpr:
+		tv := in.calleeInfo.Types[expr.Fun]
+		if tv.IsType() {
+			return false
+		}
+		if tv.IsBuiltin() {
+			id, _ := astutil.Unparen(expr.Fun).(*ast.Ident)
+			if id == nil {
+				return false // unsafe.Sizeof, etc.
+			}
+			switch id.Name {
+			case "clear", "close", "copy", "delete", "panic", "print", "println", "recover":
+				return true
+			}
+			return false
+		}
+		return true
+	case *ast.UnaryExpr:
+		return expr.Op == token.ARROW
+	}
+	return false
+}
+
+// inlineBlock replaces a call statement by a block that binds the
+// parameters to the arguments and then executes the body of the
+// callee, if the body has no return or defer statements.
+func (in *inliner) inlineBlock() (string, bool, error) {
+	if res := in.decl.Type.Results; res != nil && len(res.List) > 0 && len(res.List[0].Names) > 0 {
+		return "", false, nil // named results
+	}
+	ok := true
+	ast.Inspect(in.decl.Body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt, *ast.DeferStmt, *ast.LabeledStmt:
+			ok = false
+		}
+		return ok
+	})
+	if !ok {
+		return "", false, nil
+	}
+
+	// Bind each used parameter to its argument, and
+	// evaluate any other argument with side effects.
+	var names, values []string
+	define := false
+	for _, b := range in.bindings() {
+		if b.obj == nil || b.obj.Name() == "_" || len(in.calleeUses(in.decl.Body, b.obj)) == 0 {
+			if hasEffects(in.info, b.arg) {
+				names = append(names, "_")
+				values = append(values, b.text)
+			}
+			continue
+		}
+		value := b.text
+		if b.index >= 0 && needsConversion(in.info, b.arg, b.obj.Type()) {
+			conv, err := in.conversion(b.field.Type, value)
+			if err != nil {
+				return "", false, err
+			}
+			value = conv
+		}
+		names = append(names, b.obj.Name())
+		values = append(values, value)
+		define = true
+	}
+
+	var buf strings.Builder
+	buf.WriteString("{\n")
+	if len(names) > 0 {
+		tok := " = "
+		if define {
+			tok = " := "
+		}
+		fmt.Fprintf(&buf, "%s%s%s\n", strings.Join(names, ", "), tok, strings.Join(values, ", "))
+	}
+	body, err := in.rewrite(in.decl.Body.Lbrace+1, in.decl.Body.Rbrace, nil)
+	if err != nil {
+		return "", false, err
+	}
+	buf.WriteString(strings.TrimSpace(body))
+	buf.WriteString("\n}")
+	return buf.String(), true, nil
+}
+
+// inlineLiteral replaces the callee by an equivalent function literal,
+// preserving the semantics of returns, defers, and named results.
+func (in *inliner) inlineLiteral() (string, error) {
+	// The receiver becomes the first parameter,
+	// and all parameters are named, as they must be
+	// if any of them is.
+	var params []string
+	field := func(f *ast.Field) error {
+		typ, err := in.rewrite(f.Type.Pos(), f.Type.End(), nil)
+		if err != nil {
+			return err
+		}
+		names := "_"
+		if len(f.Names) > 0 {
+			var ids []string
+			for _, id := range f.Names {
+				ids = append(ids, id.Name)
+			}
+			names = strings.Join(ids, ", ")
+		}
+		params = append(params, names+" "+typ)
+		return nil
+	}
+	if in.decl.Recv != nil {
+		if err := field(in.decl.Recv.List[0]); err != nil {
+			return "", err
+		}
+	}
+	for _, f := range in.decl.Type.Params.List {
+		if err := field(f); err != nil {
+			return "", err
+		}
+	}
+	var results string
+	if res := in.decl.Type.Results; res != nil && len(res.List) > 0 {
+		text, err := in.rewrite(res.Pos(), res.End(), nil)
+		if err != nil {
+			return "", err
+		}
+		results = " " + text
+	}
+	body, err := in.rewrite(in.decl.Body.Pos(), in.decl.Body.End(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	var args []string
+	argsStart := in.call.Lparen + 1
+	if in.recv != nil {
+		args = append(args, in.recv.text)
+		if in.recv.arg != astutil.Unparen(in.call.Fun).(*ast.SelectorExpr).X {
+			// A method expression: skip the receiver argument.
+			argsStart = in.call.Rparen
+			if len(in.call.Args) > 1 {
+				argsStart = in.call.Args[1].Pos()
+			}
+		}
+	}
+	if text := strings.TrimSpace(in.callerSpan(argsStart, in.call.Rparen)); text != "" {
+		args = append(args, text)
+	}
+	return fmt.Sprintf("func(%s)%s %s(%s)", strings.Join(params, ", "), results, body, strings.Join(args, ", ")), nil
+}
+
+// rewrite returns the text of the callee from start to end, with each
+// reference to a parameter in subst replaced by its argument, and each
+// reference to a package-level object qualified as appropriate in the
+// caller. It reports an error if a referenced object is inaccessible
+// from the caller or would be shadowed at the call.
+func (in *inliner) rewrite(start, end token.Pos, subst map[*types.Var]replacement) (string, error) {
+	startOffset, endOffset, err := safetoken.Offsets(in.calleePGF.Tok, start, end)
+	if err != nil {
+		return "", err
+	}
+	callerPath := in.pkg.GetTypes().Path()
+	var edits []diff.Edit
+	replace := func(id *ast.Ident, text string) {
+		offset, _ := safetoken.Offset(in.calleePGF.Tok, id.Pos())
+		edits = append(edits, diff.Edit{Start: offset - startOffset, End: offset - startOffset + len(id.Name), New: text})
+	}
+	var stack []ast.Node
+	ast.Inspect(in.decl, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if err != nil || n.End() <= start || n.Pos() >= end {
+			return false
+		}
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			stack = append(stack, n)
+			return true
+		}
+		parent := stack[len(stack)-1]
+		obj := in.calleeInfo.Uses[id]
+		if obj == nil {
+			return false
+		}
+		if v, ok := obj.(*types.Var); ok {
+			if r, ok := subst[v]; ok {
+				if !r.primary && needsParens(parent, id) {
+					replace(id, "("+r.text+")")
+				} else {
+					replace(id, r.text)
+				}
+				return false
+			}
+		}
+		if obj.Pkg() != nil && in.decl.Pos() <= obj.Pos() && obj.Pos() < in.decl.End() {
+			return false // local to the callee
+		}
+		if pkgname, ok := obj.(*types.PkgName); ok {
+			var name string
+			if name, err = in.importName(pkgname.Imported()); err == nil && name != id.Name {
+				replace(id, name)
+			}
+			return false
+		}
+		accessible := obj.Exported() || obj.Pkg() == nil || obj.Pkg().Path() == callerPath
+		if sel, ok := parent.(*ast.SelectorExpr); ok && sel.Sel == id {
+			// A qualified identifier, field, or method.
+			if !accessible {
+				err = fmt.Errorf("%s refers to inaccessible %s", in.fn.Name(), obj.Name())
+			}
+			return false
+		}
+		if v, ok := obj.(*types.Var); ok && v.IsField() {
+			// A field name in a composite literal.
+			if !accessible {
+				err = fmt.Errorf("%s refers to inaccessible field %s", in.fn.Name(), obj.Name())
+			}
+			return false
+		}
+		switch {
+		case obj.Pkg() == nil: // universe
+			if in.lookup(id.Name) != obj {
+				err = fmt.Errorf("%s is shadowed at the call", id.Name)
+			}
+		case obj.Pkg().Path() == callerPath:
+			if found := in.lookup(id.Name); found == nil || found.Parent() != in.pkg.GetTypes().Scope() {
+				err = fmt.Errorf("%s is shadowed at the call", id.Name)
+			}
+		case !obj.Exported():
+			err = fmt.Errorf("%s refers to unexported %s.%s", in.fn.Name(), obj.Pkg().Name(), obj.Name())
+		default:
+			var name string
+			if name, err = in.importName(obj.Pkg()); err == nil {
+				replace(id, name+"."+id.Name)
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return "", err
+	}
+	return diff.Apply(string(in.calleePGF.Src[startOffset:endOffset]), edits)
+}
+
+// lookup returns the object denoted by name at the call.
+func (in *inliner) lookup(name string) types.Object {
+	scope := in.pkg.GetTypes().Scope().Innermost(in.call.Pos())
+	if scope == nil {
+		scope = in.pkg.GetTypes().Scope()
+	}
+	_, obj := scope.LookupParent(name, in.call.Pos())
+	return obj
+}
+
+// importName returns the name by which the caller file refers to the
+// specified package, adding an import of the package if necessary.
+func (in *inliner) importName(pkg *types.Package) (string, error) {
+	path := pkg.Path()
+	if name, ok := in.importNames[path]; ok {
+		return name, nil
+	}
+	for _, imp := range in.pgf.File.Imports {
+		if string(UnquoteImportPath(imp)) != path {
+			continue
+		}
+		var obj types.Object
+		if imp.Name != nil {
+			obj = in.info.Defs[imp.Name]
+		} else {
+			obj = in.info.Implicits[imp]
+		}
+		if pkgname, ok := obj.(*types.PkgName); ok && pkgname.Name() != "_" {
+			if pkgname.Name() == "." {
+				return "", fmt.Errorf("%q is dot-imported", path)
+			}
+			in.importNames[path] = pkgname.Name()
+			return pkgname.Name(), nil
+		}
+	}
+
+	// Add a new import, using the package's declared name.
+	name := pkg.Name()
+	if in.lookup(name) != nil {
+		return "", fmt.Errorf("cannot import %q as %s: name is already in use", path, name)
+	}
+	in.importNames[path] = name
+	in.newImports = append(in.newImports, inlineImport{name: name, path: path})
+	return name, nil
+}
+
+// conversion returns the text of the conversion of x
+// to the type of a parameter or result declared by typ.
+func (in *inliner) conversion(typ ast.Expr, x string) (string, error) {
+	text, err := in.rewrite(typ.Pos(), typ.End(), nil)
+	if err != nil {
+		return "", err
+	}
+	switch typ.(type) {
+	case *ast.StarExpr, *ast.FuncType, *ast.ChanType:
+		text = "(" + text + ")"
+	}
+	return text + "(" + x + ")", nil
+}
+
+// calleeUses returns the references to v within the callee node n.
+func (in *inliner) calleeUses(n ast.Node, v *types.Var) []*ast.Ident {
+	var uses []*ast.Ident
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && in.calleeInfo.Uses[id] == v {
+			uses = append(uses, id)
+		}
+		return true
+	})
+	return uses
+}
+
+// isTrivial reports whether the argument e may be evaluated any number
+// of times, including none, in place of the parameter: it is a
+// constant, or a variable that the body, having no effects, cannot
+// modify.
+func (in *inliner) isTrivial(e ast.Expr, bodyEffects bool) bool {
+	tv := in.info.Types[e]
+	if tv.Value != nil || tv.IsNil() {
+		return true
+	}
+	switch e := astutil.Unparen(e).(type) {
+	case *ast.Ident:
+		return !bodyEffects
+	case *ast.UnaryExpr:
+		// The address of a variable, as for a receiver.
+		_, ok := astutil.Unparen(e.X).(*ast.Ident)
+		return ok && e.Op == token.AND && !bodyEffects
+	}
+	return false
+}
+
+// callerText returns the text of the caller node n.
+func (in *inliner) callerText(n ast.Node) string {
+	return in.callerSpan(n.Pos(), n.End())
+}
+
+func (in *inliner) callerSpan(start, end token.Pos) string {
+	startOffset, endOffset, err := safetoken.Offsets(in.pgf.Tok, start, end)
+	if err != nil {
+		return ""
+	}
+	return string(in.pgf.Src[startOffset:endOffset])
+}
+
+// operandText returns the text of the caller expression e,
+// parenthesized if it is not a primary expression.
+func (in *inliner) operandText(e ast.Expr) string {
+	if isPrimary(e) {
+		return in.callerText(e)
+	}
+	return "(" + in.callerText(e) + ")"
+}
+
+// apply returns a suggested fix that replaces the caller file from
+// start to end by text, and adds any new imports.
+func (in *inliner) apply(snapshot Snapshot, start, end token.Pos, text string) (*token.FileSet, *analysis.SuggestedFix, error) {
+	startOffset, endOffset, err := safetoken.Offsets(in.pgf.Tok, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	input := in.pgf.Src
+	var buf bytes.Buffer
+	buf.Write(input[:startOffset])
+	buf.WriteString(text)
+	buf.Write(input[endOffset:])
+
+	// Re-parse the file.
+	fset := token.NewFileSet()
+	newF, err := parser.ParseFile(fset, in.pgf.URI.Filename(), buf.Bytes(), parser.ParseComments)
+	if err != nil {
+		return nil, nil, bug.Errorf("could not reparse file after inlining: %v", err)
+	}
+
+	// Splice the new imports into the syntax tree.
+	for _, imp := range in.newImports {
+		name := imp.name
+		if name == imp.path[strings.LastIndex(imp.path, "/")+1:] {
+			name = ""
+		}
+		astutil.AddNamedImport(fset, newF, name, imp.path)
+	}
+
+	// Pretty-print.
+	var output strings.Builder
+	if err := format.Node(&output, fset, newF); err != nil {
+		return nil, nil, fmt.Errorf("format.Node: %w", err)
+	}
+
+	// Report the diff.
+	diffs := snapshot.View().Options().ComputeEdits(string(input), output.String())
+	var edits []analysis.TextEdit
+	for _, edit := range diffs {
+		edits = append(edits, analysis.TextEdit{
+			Pos:     in.pgf.Tok.Pos(edit.Start),
+			End:     in.pgf.Tok.Pos(edit.End),
+			NewText: []byte(edit.New),
+		})
+	}
+	return FileSetFor(in.pgf.Tok), &analysis.SuggestedFix{TextEdits: edits}, nil
+}
+
+// CanInlineVariable reports whether the identifier at the selection
+// denotes a local variable that may be inlined, that is, one declared
+// by a statement with an initializer, and if so returns the variable.
+func CanInlineVariable(start, end token.Pos, file *ast.File, info *types.Info) (*types.Var, error) {
+	path, _ := astutil.PathEnclosingInterval(file, start, end)
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("no identifier selected")
+	}
+	obj := info.Uses[id]
+	if obj == nil {
+		obj = info.Defs[id]
+	}
+	v, ok := obj.(*types.Var)
+	if !ok || v.IsField() || v.Pkg() == nil || v.Parent() == v.Pkg().Scope() {
+		return nil, fmt.Errorf("%s is not a local variable", id.Name)
+	}
+	if _, _, _, err := varDecl(file, info, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// varDecl returns the statement declaring the local variable v, the
+// assignment or specification within it that declares v, and its
+// initializer.
+func varDecl(file *ast.File, info *types.Info, v *types.Var) (ast.Stmt, ast.Node, ast.Expr, error) {
+	path, _ := astutil.PathEnclosingInterval(file, v.Pos(), v.Pos())
+	if len(path) < 3 {
+		return nil, nil, nil, bug.Errorf("no declaration of %s", v.Name())
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok || info.Defs[id] != v {
+		return nil, nil, nil, fmt.Errorf("%s is not declared in this file", v.Name())
+	}
+	var stmt ast.Stmt
+	var init ast.Expr
+	switch decl := path[1].(type) {
+	case *ast.AssignStmt:
+		if len(decl.Lhs) != len(decl.Rhs) {
+			return nil, nil, nil, fmt.Errorf("%s is initialized by a multi-valued expression", v.Name())
+		}
+		for i, lhs := range decl.Lhs {
+			if lhs == id {
+				init = decl.Rhs[i]
+			}
+		}
+		stmt = decl
+	case *ast.ValueSpec:
+		if len(decl.Values) != len(decl.Names) {
+			if len(decl.Values) == 0 {
+				return nil, nil, nil, fmt.Errorf("%s has no initializer", v.Name())
+			}
+			return nil, nil, nil, fmt.Errorf("%s is initialized by a multi-valued expression", v.Name())
+		}
+		for i, name := range decl.Names {
+			if name == id {
+				init = decl.Values[i]
+			}
+		}
+		if len(path) < 4 {
+			return nil, nil, nil, bug.Errorf("no statement declaring %s", v.Name())
+		}
+		stmt, _ = path[3].(*ast.DeclStmt)
+	}
+	if init == nil || stmt == nil {
+		return nil, nil, nil, fmt.Errorf("%s is not declared by an assignment or var declaration", v.Name())
+	}
+	// Only a statement within a list may be deleted.
+	for _, n := range path[2:] {
+		if list := stmtList(n); list != nil {
+			for _, s := range list {
+				if s == stmt {
+					return stmt, path[1], init, nil
+				}
+			}
+			break
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("%s is declared within the header of a statement", v.Name())
+}
+
+// stmtList returns the list of statements of a block or clause.
+func stmtList(n ast.Node) []ast.Stmt {
+	switch n := n.(type) {
+	case *ast.BlockStmt:
+		return n.List
+	case *ast.CaseClause:
+		return n.Body
+	case *ast.CommClause:
+		return n.Body
+	}
+	return nil
+}
+
+// inlineVariable returns a suggested fix that replaces each use of
+// the local variable at the selected range by its initializer, and
+// deletes its declaration.
+//
+// An initializer that may have side effects, or whose value depends on
+// mutable state, is inlined only into a single unconditional use in
+// the statement that follows the declaration, and only if nothing is
+// evaluated before that use in the statement.
+func inlineVariable(fset *token.FileSet, start, end token.Pos, src []byte, file *ast.File, pkg *types.Package, info *types.Info) (*analysis.SuggestedFix, error) {
+	tokFile := fset.File(file.Pos())
+	v, err := CanInlineVariable(start, end, file, info)
+	if err != nil {
+		return nil, fmt.Errorf("cannot inline variable: %v", err)
+	}
+	stmt, decl, init, err := varDecl(file, info, v)
+	if err != nil {
+		return nil, fmt.Errorf("cannot inline variable: %v", err)
+	}
+	declPath, _ := astutil.PathEnclosingInterval(file, v.Pos(), v.Pos())
+
+	// Find the body of the enclosing function.
+	var body *ast.BlockStmt
+	for _, n := range declPath {
+		if fn, ok := n.(*ast.FuncLit); ok {
+			body = fn.Body
+			break
+		} else if fn, ok := n.(*ast.FuncDecl); ok {
+			body = fn.Body
+			break
+		}
+	}
+	if body == nil {
+		return nil, bug.Errorf("no function enclosing %s", v.Name())
+	}
+
+	// Find the uses of v, which must not modify it.
+	var uses []*ast.Ident
+	ast.Inspect(body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && info.Uses[id] == v {
+			uses = append(uses, id)
+		}
+		return true
+	})
+	if len(uses) == 0 {
+		return nil, fmt.Errorf("cannot inline variable: %s is not used", v.Name())
+	}
+	isModified := func(v *types.Var) bool {
+		modified := false
+		ast.Inspect(body, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && info.Uses[id] == v {
+				path, _ := astutil.PathEnclosingInterval(file, id.Pos(), id.End())
+				modified = modified || isMutated(info, path)
+			}
+			return !modified
+		})
+		return modified
+	}
+	if isModified(v) {
+		return nil, fmt.Errorf("cannot inline variable: %s is modified or its address is taken", v.Name())
+	}
+
+	// The names used by the initializer must denote
+	// the same objects at each use of the variable.
+	impure := hasEffects(info, init) || readsMemory(info, init)
+	var checkErr error
+	for _, id := range freeIdents(info, init) {
+		obj := info.Uses[id]
+		for _, use := range uses {
+			scope := pkg.Scope().Innermost(use.Pos())
+			if scope == nil {
+				scope = pkg.Scope()
+			}
+			if _, found := scope.LookupParent(id.Name, use.Pos()); found != obj {
+				checkErr = fmt.Errorf("%s would refer to a different object at %s", id.Name, safetoken.StartPosition(fset, use.Pos()))
+				break
+			}
+		}
+		if u, ok := obj.(*types.Var); ok {
+			if u.Parent() == pkg.Scope() {
+				impure = true // package-level variables may change at any time
+			} else if u.Pkg() == pkg && isModified(u) {
+				impure = true
+			}
+		}
+	}
+	if checkErr != nil {
+		return nil, fmt.Errorf("cannot inline variable: %v", checkErr)
+	}
+	if impure {
+		if err := checkNextStatement(file, declPath, uses, info); err != nil {
+			return nil, fmt.Errorf("cannot inline variable: %s's initializer has effects or reads mutable state, and %v", v.Name(), err)
+		}
+	}
+
+	// Compute the replacement text.
+	startOffset, endOffset, err := safetoken.Offsets(tokFile, init.Pos(), init.End())
+	if err != nil {
+		return nil, err
+	}
+	text := string(src[startOffset:endOffset])
+	primary := isPrimary(init)
+	if needsConversion(info, init, v.Type()) {
+		typ := types.TypeString(v.Type(), Qualifier(file, pkg, info))
+		switch v.Type().(type) {
+		case *types.Pointer, *types.Signature, *types.Chan:
+			typ = "(" + typ + ")"
+		}
+		text = typ + "(" + text + ")"
+		primary = true
+	}
+
+	var edits []analysis.TextEdit
+	for _, use := range uses {
+		path, _ := astutil.PathEnclosingInterval(file, use.Pos(), use.End())
+		newText := text
+		if !primary && needsParens(path[1], use) {
+			newText = "(" + text + ")"
+		}
+		edits = append(edits, analysis.TextEdit{Pos: use.Pos(), End: use.End(), NewText: []byte(newText)})
+	}
+	del, err := deleteVarDecl(tokFile, src, info, stmt, decl, v)
+	if err != nil {
+		return nil, err
+	}
+	return &analysis.SuggestedFix{TextEdits: append(edits, del)}, nil
+}
+
+// checkNextStatement returns an error unless the only use of a variable
+// is evaluated unconditionally, and exactly once, in the statement
+// following its declaration, before any other effects of the statement.
+func checkNextStatement(file *ast.File, declPath []ast.Node, uses []*ast.Ident, info *types.Info) error {
+	if len(uses) != 1 {
+		return fmt.Errorf("is used more than once")
+	}
+	var stmt, next ast.Stmt
+	for i, n := range declPath {
+		if list := stmtList(n); list != nil {
+			stmt, _ = declPath[i-1].(ast.Stmt)
+			for j, s := range list {
+				if s == stmt && j+1 < len(list) {
+					next = list[j+1]
+				}
+			}
+			break
+		}
+	}
+	use := uses[0]
+	if next == nil || !(next.Pos() <= use.Pos() && use.End() <= next.End()) {
+		return fmt.Errorf("is not used in the next statement")
+	}
+	path, _ := astutil.PathEnclosingInterval(file, use.Pos(), use.End())
+	if isConditional(path, next) {
+		return fmt.Errorf("is used conditionally")
+	}
+	var child ast.Node = use
+	for _, n := range path[1:] {
+		switch n := n.(type) {
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			return fmt.Errorf("is used conditionally")
+		case *ast.ForStmt:
+			if child != n.Init {
+				return fmt.Errorf("is used within a loop")
+			}
+		}
+		if n == next {
+			break
+		}
+		child = n
+	}
+	effects := false
+	ast.Inspect(next, func(n ast.Node) bool {
+		if n == nil || n.Pos() >= use.Pos() {
+			return false
+		}
+		if n.End() <= use.Pos() && hasEffects(info, n) {
+			effects = true
+		}
+		return !effects
+	})
+	if effects {
+		return fmt.Errorf("other effects precede its use")
+	}
+	return nil
+}
+
+// deleteVarDecl returns an edit that deletes the declaration of v by
+// decl, an assignment statement or a value specification within stmt.
+func deleteVarDecl(tokFile *token.File, src []byte, info *types.Info, stmt ast.Stmt, decl ast.Node, v *types.Var) (analysis.TextEdit, error) {
+	text := func(n ast.Node) string {
+		start, end, _ := safetoken.Offsets(tokFile, n.Pos(), n.End())
+		return string(src[start:end])
+	}
+	join := func(exprs []ast.Expr) string {
+		var texts []string
+		for _, e := range exprs {
+			texts = append(texts, text(e))
+		}
+		return strings.Join(texts, ", ")
+	}
+
+	switch decl := decl.(type) {
+	case *ast.AssignStmt:
+		if len(decl.Lhs) == 1 {
+			return deleteLines(tokFile, src, decl)
+		}
+		var lhs, rhs []ast.Expr
+		tok := token.ASSIGN
+		for i, e := range decl.Lhs {
+			if id, ok := e.(*ast.Ident); ok && info.Defs[id] == v {
+				continue
+			}
+			lhs = append(lhs, e)
+			rhs = append(rhs, decl.Rhs[i])
+			// := must declare at least one new variable.
+			if id, ok := e.(*ast.Ident); ok && decl.Tok == token.DEFINE && info.Defs[id] != nil {
+				tok = token.DEFINE
+			}
+		}
+		return analysis.TextEdit{
+			Pos:     decl.Pos(),
+			End:     decl.End(),
+			NewText: []byte(fmt.Sprintf("%s %s %s", join(lhs), tok, join(rhs))),
+		}, nil
+
+	case *ast.ValueSpec:
+		if len(decl.Names) == 1 {
+			if len(stmt.(*ast.DeclStmt).Decl.(*ast.GenDecl).Specs) == 1 {
+				return deleteLines(tokFile, src, stmt)
+			}
+			return deleteLines(tokFile, src, decl)
+		}
+		var names []string
+		var values []ast.Expr
+		for i, id := range decl.Names {
+			if info.Defs[id] != v {
+				names = append(names, id.Name)
+				values = append(values, decl.Values[i])
+			}
+		}
+		newText := strings.Join(names, ", ")
+		if decl.Type != nil {
+			newText += " " + text(decl.Type)
+		}
+		newText += " = " + join(values)
+		return analysis.TextEdit{Pos: decl.Pos(), End: decl.End(), NewText: []byte(newText)}, nil
+	}
+	return analysis.TextEdit{}, bug.Errorf("unexpected declaration %T", decl)
+}
+
+// deleteLines returns an edit that deletes the node n, along with the
+// lines it occupies if nothing else appears on them.
+func deleteLines(tokFile *token.File, src []byte, n ast.Node) (analysis.TextEdit, error) {
+	start, end, err := safetoken.Offsets(tokFile, n.Pos(), n.End())
+	if err != nil {
+		return analysis.TextEdit{}, err
+	}
+	lineStart := bytes.LastIndexByte(src[:start], '\n') + 1
+	lineEnd := len(src)
+	if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	if len(bytes.TrimSpace(src[lineStart:start])) == 0 && len(bytes.TrimSpace(src[end:lineEnd])) == 0 {
+		start, end = lineStart, lineEnd
+	}
+	return analysis.TextEdit{Pos: tokFile.Pos(start), End: tokFile.Pos(end)}, nil
+}
+
+// freeIdents returns the identifiers within e that refer to objects
+// declared outside it by lexical scoping, that is, excluding field
+// and method names, and qualified identifiers other than the package
+// name.
+func freeIdents(info *types.Info, e ast.Expr) []*ast.Ident {
+	var ids []*ast.Ident
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(n.X, visit)
+			return false
+		case *ast.KeyValueExpr:
+			if id, ok := n.Key.(*ast.Ident); ok {
+				if v, ok := info.Uses[id].(*types.Var); ok && v.IsField() {
+					ast.Inspect(n.Value, visit)
+					return false
+				}
+			}
+		case *ast.Ident:
+			if obj := info.Uses[n]; obj != nil && !(e.Pos() <= obj.Pos() && obj.Pos() < e.End()) {
+				ids = append(ids, n)
+			}
+		}
+		return true
+	}
+	ast.Inspect(e, visit)
+	return ids
+}
+
+// hasEffects reports whether the evaluation of n may have side
+// effects, that is, whether it contains a call of a function other
+// than a conversion or a pure built-in, a receive, or a statement that
+// assigns. Function literals are not considered, as they are not
+// evaluated where they appear.
+func hasEffects(info *types.Info, n ast.Node) bool {
+	effects := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if info.Types[n.Fun].IsType() {
+				break // conversion
+			}
+			if id, ok := astutil.Unparen(n.Fun).(*ast.Ident); ok {
+				if b, ok := info.Uses[id].(*types.Builtin); ok {
+					switch b.Name() {
+					case "len", "cap", "complex", "real", "imag", "min", "max":
+						return true
+					}
+				}
+			}
+			effects = true
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				effects = true
+			}
+		case *ast.AssignStmt, *ast.IncDecStmt, *ast.SendStmt:
+			effects = true
+		}
+		return !effects
+	})
+	return effects
+}
+
+// readsMemory reports whether the value of e depends on memory that
+// could be changed by any assignment, such as the contents of a slice
+// or map, or whether e allocates a new variable, whose identity would
+// change if e were evaluated more than once.
+func readsMemory(info *types.Info, e ast.Expr) bool {
+	reads := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.StarExpr:
+			reads = !info.Types[n].IsType()
+		case *ast.IndexExpr:
+			if tv, ok := info.Types[n.X]; ok && !tv.IsType() {
+				_, isArray := tv.Type.Underlying().(*types.Array)
+				_, isString := tv.Type.Underlying().(*types.Basic)
+				reads = !isArray && !isString
+			}
+		case *ast.SelectorExpr:
+			if seln, ok := info.Selections[n]; ok && seln.Kind() == types.FieldVal && seln.Indirect() {
+				reads = true
+			}
+		case *ast.CompositeLit:
+			switch info.TypeOf(n).Underlying().(type) {
+			case *types.Slice, *types.Map:
+				reads = true
+			}
+		case *ast.UnaryExpr:
+			reads = n.Op == token.AND
+		}
+		return !reads
+	})
+	return reads
+}
+
+// isMutated reports whether the variable referenced by the identifier
+// path[0] is assigned, incremented, or has its address taken, directly
+// or through one of its fields or array elements.
+func isMutated(info *types.Info, path []ast.Node) bool {
+	child := path[0]
+	for _, n := range path[1:] {
+		switch n := n.(type) {
+		case *ast.ParenExpr:
+			// keep going
+		case *ast.SelectorExpr:
+			seln, ok := info.Selections[n]
+			if !ok || n.X != child {
+				return false
+			}
+			if seln.Kind() == types.MethodVal {
+				// A call of a pointer method takes the address of its receiver.
+				_, ptrRecv := seln.Obj().Type().(*types.Signature).Recv().Type().(*types.Pointer)
+				_, isPtr := seln.Recv().Underlying().(*types.Pointer)
+				return ptrRecv && !isPtr && !seln.Indirect()
+			}
+			if seln.Indirect() {
+				return false
+			}
+		case *ast.IndexExpr:
+			if n.X != child {
+				return false
+			}
+			if _, ok := info.TypeOf(n.X).Underlying().(*types.Array); !ok {
+				return false
+			}
+		case *ast.SliceExpr:
+			_, isArray := info.TypeOf(n.X).Underlying().(*types.Array)
+			return n.X == child && isArray
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if lhs == child {
+					return true
+				}
+			}
+			return false
+		case *ast.IncDecStmt:
+			return true
+		case *ast.UnaryExpr:
+			return n.Op == token.AND
+		case *ast.RangeStmt:
+			return child == n.Key || child == n.Value
+		default:
+			return false
+		}
+		child = n
+	}
+	return false
+}
+
+// isConditional reports whether the node path[0] within root may not
+// be evaluated every time root is, because it is the right operand of
+// a logical operator, within a function literal, or within the else
+// branch of an if statement.
+func isConditional(path []ast.Node, root ast.Node) bool {
+	child := path[0]
+	for _, n := range path[1:] {
+		switch n := n.(type) {
+		case *ast.BinaryExpr:
+			if (n.Op == token.LAND || n.Op == token.LOR) && child == n.Y {
+				return true
+			}
+		case *ast.FuncLit:
+			return true
+		case *ast.IfStmt:
+			if child == n.Else {
+				return true
+			}
+		}
+		if n == root {
+			break
+		}
+		child = n
+	}
+	return false
+}
+
+// needsConversion reports whether substituting the expression e for a
+// variable of type T requires an explicit conversion to preserve the
+// type of the variable. The value nil is always converted, as is a
+// constant unless it is a literal or named constant of type T: the
+// type recorded for an untyped constant is that of its context,
+// which changes when it is substituted.
+func needsConversion(info *types.Info, e ast.Expr, T types.Type) bool {
+	tv := info.Types[e]
+	if tv.IsNil() || tv.Type == nil {
+		return true
+	}
+	if tv.Value != nil {
+		var typ types.Type
+		switch e := astutil.Unparen(e).(type) {
+		case *ast.BasicLit:
+			switch e.Kind {
+			case token.INT:
+				typ = types.Typ[types.Int]
+			case token.FLOAT:
+				typ = types.Typ[types.Float64]
+			case token.IMAG:
+				typ = types.Typ[types.Complex128]
+			case token.CHAR:
+				typ = types.Universe.Lookup("rune").Type()
+			case token.STRING:
+				typ = types.Typ[types.String]
+			}
+		case *ast.Ident, *ast.SelectorExpr:
+			var id *ast.Ident
+			if sel, ok := e.(*ast.SelectorExpr); ok {
+				id = sel.Sel
+			} else {
+				id = e.(*ast.Ident)
+			}
+			if c, ok := info.Uses[id].(*types.Const); ok {
+				typ = types.Default(c.Type())
+			}
+		default:
+			// Only string and boolean constants have
+			// a default type determined by their value.
+			switch tv.Value.Kind() {
+			case constant.String:
+				typ = types.Typ[types.String]
+			case constant.Bool:
+				typ = types.Typ[types.Bool]
+			}
+		}
+		return typ == nil || !types.Identical(typ, T)
+	}
+	return !types.Identical(types.Default(tv.Type), T)
+}
+
+// needsParens reports whether a non-primary expression substituted
+// for the operand child of parent must be parenthesized.
+func needsParens(parent ast.Node, child ast.Expr) bool {
+	switch parent := parent.(type) {
+	case *ast.SelectorExpr, *ast.TypeAssertExpr, *ast.StarExpr, *ast.UnaryExpr, *ast.BinaryExpr:
+		return true
+	case *ast.IndexExpr:
+		return parent.X == child
+	case *ast.SliceExpr:
+		return parent.X == child
+	case *ast.CallExpr:
+		return parent.Fun == child
+	}
+	return false
+}
+
+// isPrimary reports whether e is a primary expression,
+// which may be used as an operand without parentheses.
+func isPrimary(e ast.Expr) bool {
+	switch e.(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr, *ast.StarExpr, *ast.KeyValueExpr:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/lsp/safetoken"
+	"golang.org/x/tools/internal/event"
+)
+
+// LinkedEditingRange returns the ranges of the occurrences of the
+// identifier at position that the client may edit together, renaming
+// it as the user types.
+//
+// Only identifiers whose every occurrence is in the file qualify, so
+// that editing them locally is a complete rename: function-local
+// variables, labels, and unexported struct fields, such as the keys of
+// struct literals, that are declared and used only in the file.
+// For other identifiers, the result is empty.
+func LinkedEditingRange(ctx context.Context, snapshot Snapshot, fh FileHandle, position protocol.Position) ([]protocol.Range, error) {
+	ctx, done := event.Start(ctx, "source.LinkedEditingRange")
+	defer done()
+
+	// Fields may be used by the test files of the package.
+	pkg, pgf, err := PackageForFile(ctx, snapshot, fh.URI(), WidestPackage)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := pgf.PositionPos(position)
+	if err != nil {
+		return nil, err
+	}
+	targets, leaf, err := objectsAt(pkg.GetTypesInfo(), pgf.File, pos)
+	if err != nil {
+		if errors.Is(err, ErrNoIdentFound) || errors.Is(err, errNoObjectFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if _, ok := leaf.(*ast.Ident); !ok {
+		return nil, nil
+	}
+	for obj := range targets {
+		if !linkable(pkg.GetTypes(), pgf, obj) {
+			return nil, nil
+		}
+	}
+
+	// Collect the start of each occurrence. The declarations of the
+	// implicit objects of a type switch share the position of its
+	// symbolic variable, which is not itself defined.
+	starts := make(map[token.Pos]bool)
+	var name string
+	for obj := range targets {
+		starts[obj.Pos()] = true
+		name = obj.Name()
+	}
+	info := pkg.GetTypesInfo()
+	for _, f := range pkg.CompiledGoFiles() {
+		if f == pgf {
+			ast.Inspect(f.File, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok {
+					if obj := info.ObjectOf(id); obj != nil && targets[obj] != nil {
+						starts[id.Pos()] = true
+					}
+				}
+				return true
+			})
+		} else if usedIn(info, f.File, targets) {
+			return nil, nil // an occurrence is in another file
+		}
+	}
+
+	var ranges []protocol.Range
+	for start := range starts {
+		rng, err := pgf.PosRange(start, start+token.Pos(len(name)))
+		if err != nil {
+			return nil, fmt.Errorf("occurrence of %s: %v", name, err)
+		}
+		ranges = append(ranges, rng)
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return protocol.CompareRange(ranges[i], ranges[j]) < 0
+	})
+	return ranges, nil
+}
+
+// linkable reports whether obj may be renamed by editing its
+// occurrences in the file pgf, provided that none are in the other
+// files of package pkg.
+func linkable(pkg *types.Package, pgf *ParsedGoFile, obj types.Object) bool {
+	if obj.Pkg() != pkg || obj.Name() == "_" {
+		return false
+	}
+	if _, err := safetoken.Offset(pgf.Tok, obj.Pos()); err != nil {
+		return false // declared in another file
+	}
+	switch obj := obj.(type) {
+	case *types.Label:
+		return true
+	case *types.Var:
+		if obj.IsField() {
+			// Exported fields may be used by other packages, and
+			// the names of embedded fields are those of types.
+			return !obj.Exported() && !obj.Embedded()
+		}
+		return obj.Parent() != pkg.Scope()
+	}
+	return false
+}
+
+// usedIn reports whether any of the objects is used in the file f.
+func usedIn(info *types.Info, f *ast.File, objs map[types.Object]ast.Node) bool {
+	used := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && !used {
+			if obj := info.ObjectOf(id); obj != nil && objs[obj] != nil {
+				used = true
+			}
+		}
+		return !used
+	})
+	return used
+}
@@ -0,0 +1,1028 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+// This file defines the move-declaration refactoring, which moves a
+// top-level declaration (and, for a type, its methods) to another
+// file of the same package or of another package in the workspace.
+//
+// A move to another package rewrites the moved declaration so that it
+// qualifies the names of its former package, and rewrites every
+// reference to the declaration so that it qualifies the name of the
+// new package, adding and removing imports as needed. Before making
+// any change, it reports an error if the move would create an import
+// cycle or a reference across packages to an unexported name.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/lsp/safetoken"
+	"golang.org/x/tools/gopls/internal/span"
+	"golang.org/x/tools/internal/bug"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/imports"
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// MovableDecl returns the name of the top-level declaration of a
+// function, type, variable, or constant whose declared name encloses
+// pos, or nil if there is none.
+func MovableDecl(file *ast.File, pos token.Pos) *ast.Ident {
+	within := func(id *ast.Ident) bool { return id.Pos() <= pos && pos <= id.End() }
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && within(decl.Name) {
+				return decl.Name
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if within(spec.Name) {
+						return spec.Name
+					}
+				case *ast.ValueSpec:
+					for _, id := range spec.Names {
+						if within(id) && id.Name != "_" {
+							return id
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// MoveDeclaration returns the edits that move the top-level
+// declaration at position pp of the file fh to the file dest, which
+// may belong to another package of the workspace. The methods of a
+// type move with it. A dest file that does not exist is created in
+// the package of its directory, in which case its edits insert the
+// complete content of the new file.
+func MoveDeclaration(ctx context.Context, snapshot Snapshot, fh FileHandle, pp protocol.Position, dest span.URI) (map[span.URI][]protocol.TextEdit, error) {
+	ctx, done := event.Start(ctx, "source.MoveDeclaration")
+	defer done()
+
+	pkg, pgf, err := PackageForFile(ctx, snapshot, fh.URI(), NarrowestPackage)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := pgf.PositionPos(pp)
+	if err != nil {
+		return nil, err
+	}
+	if dest == pgf.URI {
+		return nil, fmt.Errorf("the declaration is already in %s", filepath.Base(dest.Filename()))
+	}
+	if strings.HasSuffix(dest.Filename(), "_test.go") {
+		return nil, fmt.Errorf("cannot move a declaration to a test file")
+	}
+
+	m := &mover{
+		snapshot: snapshot,
+		pkg:      pkg,
+		names:    make(map[string]bool),
+		dest:     dest,
+		changes:  make(map[span.URI]*fileChange),
+	}
+	if err := m.findParts(pgf, pos); err != nil {
+		return nil, err
+	}
+	if err := m.findDestination(ctx); err != nil {
+		return nil, err
+	}
+
+	// Check for conflicts with the names of the destination package.
+	if !m.samePkg {
+		for name := range m.names {
+			if m.destPkg.GetTypes().Scope().Lookup(name) != nil {
+				return nil, fmt.Errorf("%s is already declared in package %s", name, m.destPkg.GetTypes().Name())
+			}
+			if m.destPGF != nil && m.fileImportName(m.destPGF, m.destPkg.GetTypesInfo(), "", name) != "" {
+				return nil, fmt.Errorf("%s conflicts with an import of %s", name, filepath.Base(dest.Filename()))
+			}
+		}
+	}
+
+	// Move the declarations.
+	destChange := m.change(dest, m.destPGF)
+	text, err := m.movedText(destChange)
+	if err != nil {
+		return nil, err
+	}
+	// Format the moved text, whose lines may have been indented
+	// within a group; the rest of the destination is left as is.
+	if formatted, err := format.Source([]byte(text)); err == nil {
+		text = string(formatted)
+	}
+	if m.destPGF == nil {
+		text = fmt.Sprintf("package %s\n\n%s", m.destPkg.Metadata().Name, text)
+	} else {
+		text = "\n" + text
+	}
+	destChange.edits = append(destChange.edits, diff.Edit{Start: len(destChange.src()), End: len(destChange.src()), New: text})
+	for _, part := range m.parts {
+		c := m.change(part.pgf.URI, part.pgf)
+		c.edits = append(c.edits, diff.Edit{Start: part.delStart, End: part.delEnd})
+	}
+
+	// Update the references, which must import the destination package.
+	if !m.samePkg {
+		if err := m.updateReferences(ctx); err != nil {
+			return nil, err
+		}
+		if err := m.checkCycles(); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make(map[span.URI][]protocol.TextEdit)
+	for uri, c := range m.changes {
+		edits, err := c.textEdits(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		result[uri] = edits
+	}
+	return result, nil
+}
+
+// A mover holds the state of a single move.
+type mover struct {
+	snapshot Snapshot
+	pkg      Package         // the source package
+	parts    []*movedPart    // the moved declarations
+	names    map[string]bool // names of the moved package-level objects
+
+	dest    span.URI
+	destPkg Package
+	destPGF *ParsedGoFile // nil if the destination file is new
+	samePkg bool
+
+	destImportsSrc bool                 // moved code refers to the source package
+	importers      map[PackagePath]bool // packages that will import the destination
+	destKeepsSrc   bool                 // destination package has other references to the source
+
+	changes map[span.URI]*fileChange
+}
+
+// A movedPart is a declaration, or a specification within a group,
+// that is moved, along with its doc comment.
+type movedPart struct {
+	pgf     *ParsedGoFile
+	node    ast.Node          // *ast.FuncDecl, *ast.GenDecl, or ast.Spec
+	doc     *ast.CommentGroup // doc comment, if any
+	keyword string            // for a spec within a group, the keyword of the group
+
+	start, end       int // offsets of the moved text of node, including its line comment
+	delStart, delEnd int // offsets of the deleted lines
+}
+
+// contains reports whether the offset within the file uri is moved.
+func (p *movedPart) contains(uri span.URI, offset int) bool {
+	return p.pgf.URI == uri && p.delStart <= offset && offset < p.delEnd
+}
+
+// findParts finds the declaration at pos, and the methods of
+// a declared type, and records the parts of the files to move.
+func (m *mover) findParts(pgf *ParsedGoFile, pos token.Pos) error {
+	var decl ast.Decl
+	for _, d := range pgf.File.Decls {
+		if d.Pos() <= pos && pos <= d.End() {
+			decl = d
+		}
+	}
+	if decl == nil {
+		return fmt.Errorf("no top-level declaration at the selected position")
+	}
+	if pgf.Fixed {
+		return fmt.Errorf("file contains parse errors: %s", pgf.URI)
+	}
+
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv != nil {
+			return fmt.Errorf("cannot move method %s: move its receiver type instead", decl.Name.Name)
+		}
+		m.names[decl.Name.Name] = true
+		return m.addPart(pgf, decl, decl.Doc, "")
+
+	case *ast.GenDecl:
+		if decl.Tok == token.IMPORT {
+			return fmt.Errorf("cannot move an import declaration")
+		}
+		var spec ast.Spec
+		for _, s := range decl.Specs {
+			if s.Pos() <= pos && pos <= s.End() {
+				spec = s
+			}
+		}
+		if spec == nil {
+			if len(decl.Specs) != 1 {
+				return fmt.Errorf("select a single declaration within the group")
+			}
+			spec = decl.Specs[0]
+		}
+		if len(decl.Specs) == 1 {
+			if err := m.addPart(pgf, decl, decl.Doc, ""); err != nil {
+				return err
+			}
+		} else {
+			if decl.Tok == token.CONST && constGroupDependsOnOrder(decl) {
+				return fmt.Errorf("cannot move a constant from a group that uses iota or implicit values")
+			}
+			var doc *ast.CommentGroup
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				doc = spec.Doc
+			case *ast.ValueSpec:
+				doc = spec.Doc
+			}
+			if err := m.addPart(pgf, spec, doc, decl.Tok.String()+" "); err != nil {
+				return err
+			}
+		}
+
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			m.names[spec.Name.Name] = true
+			if tname, ok := m.pkg.GetTypesInfo().Defs[spec.Name].(*types.TypeName); ok {
+				if err := m.addMethods(tname); err != nil {
+					return err
+				}
+			}
+		case *ast.ValueSpec:
+			for _, id := range spec.Names {
+				if id.Name != "_" {
+					m.names[id.Name] = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addMethods adds the declarations of the methods of the named type.
+func (m *mover) addMethods(tname *types.TypeName) error {
+	info := m.pkg.GetTypesInfo()
+	for _, pgf := range m.pkg.CompiledGoFiles() {
+		for _, decl := range pgf.File.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || len(fd.Recv.List) == 0 {
+				continue
+			}
+			recv := fd.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if x, _, _, _ := typeparams.UnpackIndexExpr(recv); x != nil {
+				recv = x
+			}
+			if id, ok := recv.(*ast.Ident); ok && info.Uses[id] == tname {
+				if pgf.Fixed {
+					return fmt.Errorf("file contains parse errors: %s", pgf.URI)
+				}
+				if err := m.addPart(pgf, fd, fd.Doc, ""); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addPart records the extent of the declaration node in pgf.
+func (m *mover) addPart(pgf *ParsedGoFile, node ast.Node, doc *ast.CommentGroup, keyword string) error {
+	src := pgf.Src
+	start, end, err := safetoken.Offsets(pgf.Tok, node.Pos(), node.End())
+	if err != nil {
+		return err
+	}
+	delStart := start
+	if doc != nil {
+		if delStart, err = safetoken.Offset(pgf.Tok, doc.Pos()); err != nil {
+			return err
+		}
+	}
+
+	// Include a comment that follows on the same line,
+	// and delete whole lines.
+	lineEnd := len(src)
+	if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+		lineEnd = end + i
+	}
+	rest := bytes.TrimSpace(src[end:lineEnd])
+	before := bytes.TrimSpace(src[bytes.LastIndexByte(src[:delStart], '\n')+1 : delStart])
+	if len(before) > 0 || len(rest) > 0 && !bytes.HasPrefix(rest, []byte("//")) && !bytes.HasPrefix(rest, []byte("/*")) {
+		return fmt.Errorf("cannot move a declaration that shares a line with other code")
+	}
+	end = lineEnd
+	delStart = bytes.LastIndexByte(src[:delStart], '\n') + 1
+	delEnd := lineEnd
+	if delEnd < len(src) {
+		delEnd++ // newline
+	}
+
+	// Delete a blank line that would otherwise be
+	// adjacent to another, or end the file.
+	blankBefore := delStart > 0 && (delStart == 1 || src[delStart-2] == '\n')
+	if blankBefore && (delEnd == len(src) || src[delEnd] == '\n') {
+		if delEnd < len(src) {
+			delEnd++
+		} else {
+			delStart--
+		}
+	}
+
+	m.parts = append(m.parts, &movedPart{
+		pgf:      pgf,
+		node:     node,
+		doc:      doc,
+		keyword:  keyword,
+		start:    start,
+		end:      end,
+		delStart: delStart,
+		delEnd:   delEnd,
+	})
+	return nil
+}
+
+// constGroupDependsOnOrder reports whether the value of a constant in
+// the group depends on its position within the group, because the
+// group uses iota or omits the values of some of its constants.
+func constGroupDependsOnOrder(decl *ast.GenDecl) bool {
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.ValueSpec)
+		if len(spec.Values) == 0 {
+			return true
+		}
+		usesIota := false
+		for _, v := range spec.Values {
+			ast.Inspect(v, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+					usesIota = true
+				}
+				return !usesIota
+			})
+		}
+		if usesIota {
+			return true
+		}
+	}
+	return false
+}
+
+// findDestination finds and type-checks the package of the destination
+// file, which, if the file does not exist, is the package of its
+// directory.
+func (m *mover) findDestination(ctx context.Context) error {
+	exists := false
+	if fh, err := m.snapshot.GetFile(ctx, m.dest); err == nil {
+		if _, err := fh.Read(); err == nil {
+			exists = true
+		}
+	}
+
+	var destMeta *Metadata
+	if exists {
+		metas, err := m.snapshot.MetadataForFile(ctx, m.dest)
+		if err != nil {
+			return err
+		}
+		metas = RemoveIntermediateTestVariants(metas)
+		if len(metas) == 0 {
+			return fmt.Errorf("no package for file %s", m.dest)
+		}
+		destMeta = metas[0]
+	} else {
+		dir := filepath.Dir(m.dest.Filename())
+		metas, err := m.snapshot.AllMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		for _, meta := range metas {
+			if meta.ForTest != "" {
+				continue
+			}
+			for _, uri := range meta.CompiledGoFiles {
+				if filepath.Dir(uri.Filename()) == dir {
+					destMeta = meta
+					break
+				}
+			}
+		}
+		if destMeta == nil {
+			return fmt.Errorf("no package in directory %s", dir)
+		}
+	}
+	if destMeta.Name == "main" && destMeta.PkgPath != m.pkg.Metadata().PkgPath {
+		return fmt.Errorf("cannot move a declaration to package main")
+	}
+	if strings.HasSuffix(string(destMeta.Name), "_test") {
+		return fmt.Errorf("cannot move a declaration to a test package")
+	}
+
+	pkgs, err := m.snapshot.TypeCheck(ctx, destMeta.ID)
+	if err != nil {
+		return err
+	}
+	m.destPkg = pkgs[0]
+	if exists {
+		if m.destPGF, err = m.destPkg.File(m.dest); err != nil {
+			return err
+		}
+		if m.destPGF.Fixed {
+			return fmt.Errorf("file contains parse errors: %s", m.dest)
+		}
+	}
+	m.samePkg = destMeta.PkgPath == m.pkg.Metadata().PkgPath
+	return nil
+}
+
+// movedText returns the text of the moved declarations, with
+// references qualified as needed in the destination file, whose
+// imports are updated by c.
+func (m *mover) movedText(c *fileChange) (string, error) {
+	info := m.pkg.GetTypesInfo()
+	srcPkg := m.pkg.GetTypes()
+	destPath := m.destPkg.GetTypes().Path()
+
+	// moved reports whether the object is declared by the moved code.
+	moved := func(obj types.Object) bool {
+		tf := m.pkg.FileSet().File(obj.Pos())
+		if tf == nil {
+			return false
+		}
+		offset, err := safetoken.Offset(tf, obj.Pos())
+		if err != nil {
+			return false
+		}
+		uri := span.URIFromPath(tf.Name())
+		for _, part := range m.parts {
+			if part.contains(uri, offset) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var texts []string
+	for _, part := range m.parts {
+		var edits []diff.Edit
+		replace := func(n ast.Node, text string) {
+			start, end, _ := safetoken.Offsets(part.pgf.Tok, n.Pos(), n.End())
+			edits = append(edits, diff.Edit{Start: start - part.start, End: end - part.start, New: text})
+		}
+		var err error
+		// checkMember checks a reference to a field or method.
+		checkMember := func(id *ast.Ident) {
+			obj := info.Uses[id]
+			if obj != nil && !m.samePkg && !obj.Exported() && obj.Pkg() == srcPkg && !moved(obj) {
+				err = fmt.Errorf("%s refers to unexported field or method %s of package %s", part.name(), obj.Name(), srcPkg.Name())
+			}
+		}
+		var visit func(n ast.Node) bool
+		visit = func(n ast.Node) bool {
+			if err != nil {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if id, ok := n.X.(*ast.Ident); ok {
+					if pkgname, ok := info.Uses[id].(*types.PkgName); ok {
+						imported := pkgname.Imported()
+						m.change(part.pgf.URI, part.pgf).unused[imported.Path()] = id.Name
+						if !m.samePkg && imported.Path() == destPath {
+							replace(n, n.Sel.Name)
+							return false
+						}
+						var name string
+						if name, err = m.destImportName(c, imported, id.Name); err == nil && name != id.Name {
+							replace(id, name)
+						}
+						return false
+					}
+				}
+				checkMember(n.Sel)
+				ast.Inspect(n.X, visit)
+				return false
+
+			case *ast.KeyValueExpr:
+				if id, ok := n.Key.(*ast.Ident); ok {
+					if v, ok := info.Uses[id].(*types.Var); ok && v.IsField() {
+						checkMember(id)
+						ast.Inspect(n.Value, visit)
+						return false
+					}
+				}
+
+			case *ast.Ident:
+				obj := info.Uses[n]
+				switch {
+				case obj == nil || m.samePkg:
+				case obj.Parent() == types.Universe:
+					if m.destPkg.GetTypes().Scope().Lookup(n.Name) != nil {
+						err = fmt.Errorf("%s would be shadowed by a declaration of package %s", n.Name, m.destPkg.GetTypes().Name())
+					}
+				case obj.Pkg() != srcPkg:
+					if obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope() {
+						err = fmt.Errorf("%s refers to dot-imported %s", part.name(), n.Name)
+					}
+				case obj.Parent() == srcPkg.Scope() && !m.names[n.Name]:
+					if !obj.Exported() {
+						err = fmt.Errorf("%s refers to unexported %s of package %s", part.name(), n.Name, srcPkg.Name())
+						break
+					}
+					var name string
+					if name, err = m.destImportName(c, srcPkg, srcPkg.Name()); err == nil {
+						replace(n, name+"."+n.Name)
+						m.destImportsSrc = true
+					}
+				}
+			}
+			return true
+		}
+		ast.Inspect(part.node, visit)
+		if err != nil {
+			return "", err
+		}
+
+		text, err := diff.Apply(string(part.pgf.Src[part.start:part.end]), edits)
+		if err != nil {
+			return "", err
+		}
+		if part.doc != nil {
+			start, end, err := safetoken.Offsets(part.pgf.Tok, part.doc.Pos(), part.doc.End())
+			if err != nil {
+				return "", err
+			}
+			text = string(part.pgf.Src[start:end]) + "\n" + part.keyword + text
+		} else {
+			text = part.keyword + text
+		}
+		texts = append(texts, text+"\n")
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// name returns the name of the moved declaration, for use in messages.
+func (p *movedPart) name() string {
+	switch n := p.node.(type) {
+	case *ast.FuncDecl:
+		return n.Name.Name
+	case *ast.TypeSpec:
+		return n.Name.Name
+	case *ast.ValueSpec:
+		return n.Names[0].Name
+	case *ast.GenDecl:
+		switch spec := n.Specs[0].(type) {
+		case *ast.TypeSpec:
+			return spec.Name.Name
+		case *ast.ValueSpec:
+			return spec.Names[0].Name
+		}
+	}
+	return "declaration"
+}
+
+// destImportName returns the name by which the destination file refers
+// to the imported package, adding an import with the preferred name if
+// necessary.
+func (m *mover) destImportName(c *fileChange, imported *types.Package, preferred string) (string, error) {
+	if name, ok := c.imports[imported.Path()]; ok {
+		return name, nil
+	}
+	if m.destPGF != nil {
+		if name := m.fileImportName(m.destPGF, m.destPkg.GetTypesInfo(), imported.Path(), ""); name != "" {
+			if name == "." {
+				return "", fmt.Errorf("%s dot-imports %q", filepath.Base(m.dest.Filename()), imported.Path())
+			}
+			return name, nil
+		}
+		if m.fileImportName(m.destPGF, m.destPkg.GetTypesInfo(), "", preferred) != "" {
+			return "", fmt.Errorf("cannot import %q as %s in %s: name is already in use", imported.Path(), preferred, filepath.Base(m.dest.Filename()))
+		}
+	}
+	if m.destPkg.GetTypes().Scope().Lookup(preferred) != nil {
+		return "", fmt.Errorf("cannot import %q as %s: name is declared in package %s", imported.Path(), preferred, m.destPkg.GetTypes().Name())
+	}
+	c.addImport(imported, preferred)
+	return preferred, nil
+}
+
+// fileImportName returns the local name of the import of the specified
+// path, or of the import with the specified name, in the file pgf of a
+// package with the given type information, or "" if there is none.
+func (m *mover) fileImportName(pgf *ParsedGoFile, info *types.Info, path, name string) string {
+	for _, imp := range pgf.File.Imports {
+		var obj types.Object
+		if imp.Name != nil {
+			obj = info.Defs[imp.Name]
+		} else {
+			obj = info.Implicits[imp]
+		}
+		pkgname, ok := obj.(*types.PkgName)
+		if imp.Name != nil && imp.Name.Name == "." {
+			if string(UnquoteImportPath(imp)) == path {
+				return "."
+			}
+			continue
+		}
+		if !ok || pkgname.Name() == "_" {
+			continue
+		}
+		if path != "" && pkgname.Imported().Path() == path || name != "" && pkgname.Name() == name {
+			return pkgname.Name()
+		}
+	}
+	return ""
+}
+
+// updateReferences rewrites the references to the moved declarations
+// in the packages that directly import the source package, and in the
+// source package itself.
+func (m *mover) updateReferences(ctx context.Context) error {
+	srcPath := m.pkg.GetTypes().Path()
+	destPath := m.destPkg.GetTypes().Path()
+	destName := m.destPkg.GetTypes().Name()
+	m.importers = make(map[PackagePath]bool)
+
+	pkgs, err := typeCheckReverseDependencies(ctx, m.snapshot, m.parts[0].pgf.URI, false)
+	if err != nil {
+		return err
+	}
+	seen := make(map[span.URI]bool)
+	for _, pkg := range pkgs {
+		info := pkg.GetTypesInfo()
+		pkgPath := pkg.Metadata().PkgPath
+		isSrc := string(pkgPath) == srcPath
+		isDest := string(pkgPath) == destPath
+
+		// isMoved reports whether obj is a moved package-level object.
+		isMoved := func(obj types.Object) bool {
+			return obj != nil && obj.Pkg() != nil && obj.Pkg().Path() == srcPath &&
+				obj.Parent() == obj.Pkg().Scope() && m.names[obj.Name()]
+		}
+		// declaredInPart reports whether obj is declared by the moved code.
+		declaredInPart := func(obj types.Object) bool {
+			tf := pkg.FileSet().File(obj.Pos())
+			if tf == nil {
+				return false
+			}
+			offset, err := safetoken.Offset(tf, obj.Pos())
+			if err != nil {
+				return false
+			}
+			for _, part := range m.parts {
+				if part.contains(span.URIFromPath(tf.Name()), offset) {
+					return true
+				}
+			}
+			return false
+		}
+
+		for _, pgf := range pkg.CompiledGoFiles() {
+			if seen[pgf.URI] {
+				continue
+			}
+			seen[pgf.URI] = true
+			if pgf.Fixed {
+				return fmt.Errorf("file contains parse errors: %s", pgf.URI)
+			}
+
+			var c *fileChange
+			getChange := func() *fileChange {
+				if c == nil {
+					c = m.change(pgf.URI, pgf)
+				}
+				return c
+			}
+			inPart := func(n ast.Node) bool {
+				offset, err := safetoken.Offset(pgf.Tok, n.Pos())
+				if err != nil {
+					return false
+				}
+				for _, part := range m.parts {
+					if part.contains(pgf.URI, offset) {
+						return true
+					}
+				}
+				return false
+			}
+			// qualifier returns the name by which the
+			// file refers to the destination package.
+			qualifier := func(pos token.Pos) (string, error) {
+				if name := m.fileImportName(pgf, info, destPath, ""); name != "" {
+					if name == "." {
+						return "", fmt.Errorf("%s dot-imports %q", filepath.Base(pgf.URI.Filename()), destPath)
+					}
+					return name, nil
+				}
+				scope := pkg.GetTypes().Scope().Innermost(pos)
+				if scope == nil {
+					scope = pkg.GetTypes().Scope()
+				}
+				if _, obj := scope.LookupParent(destName, pos); obj != nil {
+					return "", fmt.Errorf("cannot refer to package %s at %s: name is already in use", destName, safetoken.StartPosition(pkg.FileSet(), pos))
+				}
+				getChange().addImport(m.destPkg.GetTypes(), destName)
+				return destName, nil
+			}
+			replace := func(n ast.Node, text string) {
+				start, end, _ := safetoken.Offsets(pgf.Tok, n.Pos(), n.End())
+				c := getChange()
+				c.edits = append(c.edits, diff.Edit{Start: start, End: end, New: text})
+			}
+
+			var err error
+			var visit func(n ast.Node) bool
+			visit = func(n ast.Node) bool {
+				if n == nil || err != nil || inPart(n) {
+					return false
+				}
+				switch n := n.(type) {
+				case *ast.SelectorExpr:
+					if id, ok := n.X.(*ast.Ident); ok {
+						if pkgname, ok := info.Uses[id].(*types.PkgName); ok {
+							if pkgname.Imported().Path() != srcPath {
+								return false
+							}
+							if !isMoved(info.Uses[n.Sel]) {
+								if isDest {
+									m.destKeepsSrc = true
+								}
+								return false
+							}
+							getChange().unused[srcPath] = id.Name
+							if isDest {
+								replace(n, n.Sel.Name)
+								return false
+							}
+							var name string
+							if name, err = qualifier(n.Pos()); err == nil {
+								replace(id, name)
+								m.importers[pkgPath] = true
+							}
+							return false
+						}
+					}
+					if isSrc {
+						if obj := info.Uses[n.Sel]; obj != nil && !obj.Exported() && declaredInPart(obj) {
+							err = fmt.Errorf("unexported field or method %s of %s is referenced at %s", obj.Name(), m.parts[0].name(), safetoken.StartPosition(pkg.FileSet(), n.Sel.Pos()))
+							return false
+						}
+					}
+					ast.Inspect(n.X, visit)
+					return false
+
+				case *ast.Ident:
+					obj := info.Uses[n]
+					if !isSrc || !isMoved(obj) {
+						break
+					}
+					if !obj.Exported() {
+						err = fmt.Errorf("unexported %s is referenced at %s", n.Name, safetoken.StartPosition(pkg.FileSet(), n.Pos()))
+						return false
+					}
+					var name string
+					if name, err = qualifier(n.Pos()); err == nil {
+						replace(n, name+"."+n.Name)
+						m.importers[pkgPath] = true
+					}
+				}
+				return true
+			}
+			ast.Inspect(pgf.File, visit)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkCycles reports an error if the move would create an import
+// cycle: that is, if the destination package would depend, directly or
+// indirectly, on a package that imports it after the move.
+func (m *mover) checkCycles() error {
+	srcPath := PackagePath(m.pkg.GetTypes().Path())
+	destMeta := m.destPkg.Metadata()
+
+	// deps returns the dependencies of a package
+	// in the import graph as it will be after the move.
+	deps := func(meta *Metadata) map[PackagePath]PackageID {
+		if meta.ID != destMeta.ID && !m.importers[meta.PkgPath] {
+			return meta.DepsByPkgPath
+		}
+		res := make(map[PackagePath]PackageID)
+		for path, dep := range meta.DepsByPkgPath {
+			if meta.ID != destMeta.ID || path != srcPath || m.destKeepsSrc {
+				res[path] = dep
+			}
+		}
+		if meta.ID == destMeta.ID {
+			if m.destImportsSrc {
+				res[srcPath] = m.pkg.Metadata().ID
+			}
+		} else {
+			res[destMeta.PkgPath] = destMeta.ID
+		}
+		return res
+	}
+
+	// Search the import graph from the destination package.
+	seen := make(map[PackageID]bool)
+	var visit func(meta *Metadata) error
+	visit = func(meta *Metadata) error {
+		seen[meta.ID] = true
+		for path, id := range deps(meta) {
+			if path == destMeta.PkgPath {
+				return fmt.Errorf("moving %s to package %s would create an import cycle through package %s", m.parts[0].name(), destMeta.Name, meta.PkgPath)
+			}
+			if dep := m.snapshot.Metadata(id); dep != nil && !seen[id] {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return visit(destMeta)
+}
+
+// change returns the pending change to the file uri,
+// whose parsed content, if it exists, is pgf.
+func (m *mover) change(uri span.URI, pgf *ParsedGoFile) *fileChange {
+	c, ok := m.changes[uri]
+	if !ok {
+		c = &fileChange{
+			uri:     uri,
+			pgf:     pgf,
+			imports: make(map[string]string),
+			names:   make(map[string]string),
+			unused:  make(map[string]string),
+		}
+		m.changes[uri] = c
+	}
+	return c
+}
+
+// A fileChange accumulates the changes to a single file.
+type fileChange struct {
+	uri     span.URI
+	pgf     *ParsedGoFile     // nil for a new file
+	edits   []diff.Edit       // edits to the source
+	imports map[string]string // local name of each import to add, by path
+	names   map[string]string // declared package name of each import to add, by path
+	unused  map[string]string // local name of each import to delete if unused, by path
+}
+
+func (c *fileChange) src() []byte {
+	if c.pgf == nil {
+		return nil
+	}
+	return c.pgf.Src
+}
+
+// addImport records that the file must import pkg with the given name.
+func (c *fileChange) addImport(pkg *types.Package, name string) {
+	c.imports[pkg.Path()] = name
+	c.names[pkg.Path()] = pkg.Name()
+}
+
+// textEdits applies the changes to the file, updates its imports,
+// and returns the resulting edits. Only the import declarations are
+// reformatted, so that the rest of the file keeps its formatting.
+func (c *fileChange) textEdits(snapshot Snapshot) ([]protocol.TextEdit, error) {
+	src := c.src()
+	text, err := diff.ApplyBytes(src, c.edits)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, c.uri.Filename(), text, parser.ParseComments)
+	if err != nil {
+		return nil, bug.Errorf("moving declaration produced invalid code: %v", err)
+	}
+
+	// Delete the unused imports before adding the new ones,
+	// so that an import may replace another in a lone import.
+	var fixes []*imports.ImportFix
+	for path, name := range c.unused {
+		if _, ok := c.imports[path]; ok || usesName(file, name) {
+			continue
+		}
+		for _, imp := range file.Imports {
+			if string(UnquoteImportPath(imp)) == path {
+				var specName string
+				if imp.Name != nil {
+					specName = imp.Name.Name
+				}
+				fixes = append(fixes, &imports.ImportFix{
+					StmtInfo:  imports.ImportInfo{ImportPath: path, Name: specName},
+					IdentName: name,
+					FixType:   imports.DeleteImport,
+				})
+				break
+			}
+		}
+	}
+
+	var paths []string
+	for path := range c.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		name := c.imports[path]
+		if name == c.names[path] {
+			name = ""
+		}
+		fixes = append(fixes, &imports.ImportFix{
+			StmtInfo:  imports.ImportInfo{ImportPath: path, Name: name},
+			IdentName: c.imports[path],
+			FixType:   imports.AddImport,
+		})
+	}
+	if len(fixes) > 0 {
+		if text, err = applyImportFixes(snapshot, c.uri, text, fixes); err != nil {
+			return nil, err
+		}
+	}
+	if c.pgf == nil {
+		return []protocol.TextEdit{{NewText: string(text)}}, nil
+	}
+	edits := snapshot.View().Options().ComputeEdits(string(src), string(text))
+	return ToProtocolEdits(c.pgf.Mapper, edits)
+}
+
+// applyImportFixes applies the import fixes to src, the content of
+// the file uri, reformatting only the part of the file up to the end
+// of its imports, in the manner of computeFixEdits.
+func applyImportFixes(snapshot Snapshot, uri span.URI, src []byte, fixes []*imports.ImportFix) ([]byte, error) {
+	prefix, err := importPrefix(src)
+	if err != nil {
+		return nil, err
+	}
+	flags := parser.ImportsOnly
+	if !strings.Contains(prefix, "\n") {
+		// One line may have more than imports.
+		prefix = string(src)
+		flags = 0
+	}
+	options := &imports.Options{
+		LocalPrefix: snapshot.View().Options().Local,
+		// Defaults.
+		AllErrors:  true,
+		Comments:   true,
+		Fragment:   true,
+		FormatOnly: false,
+		TabIndent:  true,
+		TabWidth:   8,
+	}
+	fixed, err := imports.ApplyFixes(fixes, uri.Filename(), src, options, flags)
+	if err != nil {
+		return nil, err
+	}
+	// The formatted prefix ends with a newline, which replaces
+	// the one that follows the imports in src, if any.
+	rest := src[len(prefix):]
+	if len(fixed) == 0 || fixed[len(fixed)-1] != '\n' {
+		fixed = append(fixed, '\n')
+	}
+	if !strings.HasSuffix(prefix, "\n") {
+		rest = bytes.TrimPrefix(rest, []byte("\n"))
+	}
+	return append(fixed, rest...), nil
+}
+
+// usesName reports whether the file contains
+// a selector qualified by the specified name.
+func usesName(file *ast.File, name string) bool {
+	used := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Name == name {
+				used = true
+			}
+		}
+		return !used
+	})
+	return used
+}
@@ -103,6 +103,7 @@ func DefaultOptions() *Options {
 						protocol.QuickFix:              true,
 						protocol.RefactorRewrite:       true,
 						protocol.RefactorExtract:       true,
+						protocol.RefactorInline:        true,
 					},
 					Mod: {
 						protocol.SourceOrganizeImports: true,
@@ -210,6 +211,10 @@ type ClientOptions struct {
 	RelatedInformationSupported                bool
 	CompletionTags                             bool
 	CompletionDeprecated                       bool
+	CompletionResolveDocumentation             bool
+	CompletionResolveEdits                     bool
+	CodeActionResolveEdits                     bool
+	PullDiagnosticsSupported                   bool
 	SupportedResourceOperations                []protocol.ResourceOperationKind
 }
 
@@ -425,6 +430,13 @@ type DiagnosticOptions struct {
 	//
 	// This option must be set to a valid duration string, for example `"250ms"`.
 	DiagnosticsDelay time.Duration `status:"advanced"`
+
+	// PullDiagnostics stops gopls from publishing diagnostics to clients
+	// that pull them, using the textDocument/diagnostic and
+	// workspace/diagnostic requests. Such clients must then pull the
+	// diagnostics of every file they show, as gopls does not ask them to
+	// pull again when diagnostics change.
+	PullDiagnostics bool `status:"experimental"`
 }
 
 type InlayHintOptions struct {
@@ -582,9 +594,12 @@ type InternalOptions struct {
 	ShowBugReports bool
 
 	// NewDiff controls the choice of the new diff implementation. It can be
-	// 'new', 'old', or 'both', which is the default. 'both' computes diffs with
-	// both algorithms, checks that the new algorithm has worked, and write some
-	// summary statistics to a file in os.TmpDir().
+	// 'new', 'old', 'histogram', 'tokens' or 'both', which is the default.
+	// 'both' computes diffs with both algorithms, checks that the new
+	// algorithm has worked, and write some summary statistics to a file in
+	// os.TmpDir(). 'histogram' uses the new implementation with the
+	// line-based histogram algorithm. 'tokens' compares the tokens of Go
+	// source, so that the edits of formatting change only white space.
 	NewDiff string
 
 	// ChattyDiagnostics controls whether to report file diagnostics for each
@@ -754,12 +769,33 @@ func (o *Options) ForClientCapabilities(caps protocol.ClientCapabilities) {
 
 	// Check if the client supports diagnostic related information.
 	o.RelatedInformationSupported = caps.TextDocument.PublishDiagnostics.RelatedInformation
+	// Check if the client pulls diagnostics.
+	o.PullDiagnosticsSupported = caps.TextDocument.Diagnostic != nil
 	// Check if the client completion support includes tags (preferred) or deprecation
 	if caps.TextDocument.Completion.CompletionItem.TagSupport.ValueSet != nil {
 		o.CompletionTags = true
 	} else if caps.TextDocument.Completion.CompletionItem.DeprecatedSupport {
 		o.CompletionDeprecated = true
 	}
+	// Check which completion item and code action properties the client
+	// can resolve lazily, so that their computation can be deferred.
+	if rs := caps.TextDocument.Completion.CompletionItem.ResolveSupport; rs != nil {
+		for _, prop := range rs.Properties {
+			switch prop {
+			case "documentation":
+				o.CompletionResolveDocumentation = true
+			case "additionalTextEdits":
+				o.CompletionResolveEdits = true
+			}
+		}
+	}
+	if ca := caps.TextDocument.CodeAction; ca.DataSupport && ca.ResolveSupport != nil {
+		for _, prop := range ca.ResolveSupport.Properties {
+			if prop == "edit" {
+				o.CodeActionResolveEdits = true
+			}
+		}
+	}
 }
 
 func (o *Options) Clone() *Options {
@@ -1101,6 +1137,9 @@ func (o *Options) set(name string, value interface{}, seen map[string]struct{})
 	case "diagnosticsDelay":
 		result.setDuration(&o.DiagnosticsDelay)
 
+	case "pullDiagnostics":
+		result.setBool(&o.PullDiagnostics)
+
 	case "experimentalWatchedFileDelay":
 		result.deprecated("")
 
@@ -241,7 +241,16 @@ func Rename(ctx context.Context, snapshot Snapshot, f FileHandle, pp protocol.Po
 		return nil, false, err
 	}
 
-	// Convert edits to protocol form.
+	result, err := toProtocolEditMap(ctx, snapshot, editMap)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, inPackageName, nil
+}
+
+// toProtocolEditMap converts the edits of a renaming to protocol form,
+// sorting and de-duplicating the edits to each file.
+func toProtocolEditMap(ctx context.Context, snapshot Snapshot, editMap map[span.URI][]diff.Edit) (map[span.URI][]protocol.TextEdit, error) {
 	result := make(map[span.URI][]protocol.TextEdit)
 	for uri, edits := range editMap {
 		// Sort and de-duplicate edits.
@@ -273,21 +282,21 @@ func Rename(ctx context.Context, snapshot Snapshot, f FileHandle, pp protocol.Po
 		// vendor/k8s.io/kubectl -> ../../staging/src/k8s.io/kubectl.
 		fh, err := snapshot.GetFile(ctx, uri)
 		if err != nil {
-			return nil, false, err
+			return nil, err
 		}
 		data, err := fh.Read()
 		if err != nil {
-			return nil, false, err
+			return nil, err
 		}
 		m := protocol.NewMapper(uri, data)
 		protocolEdits, err := ToProtocolEdits(m, edits)
 		if err != nil {
-			return nil, false, err
+			return nil, err
 		}
 		result[uri] = protocolEdits
 	}
 
-	return result, inPackageName, nil
+	return result, nil
 }
 
 // renameOrdinary renames an ordinary (non-package) name throughout the workspace.
@@ -608,87 +617,171 @@ func renamePackageName(ctx context.Context, s Snapshot, f FileHandle, newName Pa
 	newPkgDir := filepath.Join(filepath.Dir(oldBase), string(newName))
 
 	// Update any affected replace directives in go.mod files.
-	// TODO(adonovan): extract into its own function.
-	//
+	if err := renameReplaceDirectives(ctx, s, oldBase, newPkgDir, renamingEdits); err != nil {
+		return nil, err
+	}
+
+	return renamingEdits, nil
+}
+
+// renameReplaceDirectives computes the edits to the replace directives
+// of the workspace's go.mod files that refer to oldDir or a directory
+// beneath it, as a result of moving oldDir to newDir. Relative
+// directives of go.mod files beneath oldDir are updated too.
+//
+// Edits are written into the edits map.
+func renameReplaceDirectives(ctx context.Context, s Snapshot, oldDir, newDir string, edits map[span.URI][]diff.Edit) error {
 	// TODO: should this operate on all go.mod files, irrespective of whether they are included in the workspace?
 	// Get all active mod files in the workspace
-	modFiles := s.ModFiles()
-	for _, m := range modFiles {
+	for _, m := range s.ModFiles() {
 		fh, err := s.GetFile(ctx, m)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		pm, err := s.ParseMod(ctx, fh)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
 		modFileDir := filepath.Dir(pm.URI.Filename())
-		affectedReplaces := []*modfile.Replace{}
 
-		// Check if any replace directives need to be fixed
+		var copied *modfile.File
 		for _, r := range pm.File.Replace {
-			if !strings.HasPrefix(r.New.Path, "/") && !strings.HasPrefix(r.New.Path, "./") && !strings.HasPrefix(r.New.Path, "../") {
-				continue
+			if !modfile.IsDirectoryPath(r.New.Path) {
+				continue // replacement is a module, not a directory
 			}
-
-			replacedPath := r.New.Path
-			if strings.HasPrefix(r.New.Path, "./") || strings.HasPrefix(r.New.Path, "../") {
-				replacedPath = filepath.Join(modFileDir, r.New.Path)
+			newPath, ok := relocatePath(r.New.Path, modFileDir, oldDir, newDir)
+			if !ok {
+				continue // not affected by the move
 			}
-
-			// TODO: Is there a risk of converting a '\' delimited replacement to a '/' delimited replacement?
-			if !strings.HasPrefix(filepath.ToSlash(replacedPath)+"/", filepath.ToSlash(oldBase)+"/") {
-				continue // not affected by the package renaming
+			if copied == nil {
+				copied, err = modfile.Parse("", pm.Mapper.Content, nil)
+				if err != nil {
+					return err
+				}
+			}
+			if err := copied.AddReplace(r.Old.Path, r.Old.Version, newPath, ""); err != nil {
+				return err
 			}
-
-			affectedReplaces = append(affectedReplaces, r)
 		}
-
-		if len(affectedReplaces) == 0 {
+		if copied == nil {
 			continue
 		}
-		copied, err := modfile.Parse("", pm.Mapper.Content, nil)
+
+		copied.Cleanup()
+		newContent, err := copied.Format()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		for _, r := range affectedReplaces {
-			replacedPath := r.New.Path
-			if strings.HasPrefix(r.New.Path, "./") || strings.HasPrefix(r.New.Path, "../") {
-				replacedPath = filepath.Join(modFileDir, r.New.Path)
-			}
-
-			suffix := strings.TrimPrefix(replacedPath, string(oldBase))
-
-			newReplacedPath, err := filepath.Rel(modFileDir, newPkgDir+suffix)
-			if err != nil {
-				return nil, err
-			}
-
-			newReplacedPath = filepath.ToSlash(newReplacedPath)
+		// Calculate the edits to be made due to the change.
+		modEdits := s.View().Options().ComputeEdits(string(pm.Mapper.Content), string(newContent))
+		edits[pm.URI] = append(edits[pm.URI], modEdits...)
+	}
+	return nil
+}
 
-			if !strings.HasPrefix(newReplacedPath, "/") && !strings.HasPrefix(newReplacedPath, "../") {
-				newReplacedPath = "./" + newReplacedPath
-			}
+// renameWorkUses computes the edits to the use and replace directives
+// of the workspace's go.work file, if any, that refer to oldDir or a
+// directory beneath it, as a result of moving oldDir to newDir.
+//
+// Edits are written into the edits map.
+func renameWorkUses(ctx context.Context, s Snapshot, oldDir, newDir string, edits map[span.URI][]diff.Edit) error {
+	uri := s.WorkFile()
+	if uri == "" {
+		return nil
+	}
+	fh, err := s.GetFile(ctx, uri)
+	if err != nil {
+		return err
+	}
+	pw, err := s.ParseWork(ctx, fh)
+	if err != nil {
+		return err
+	}
+	if pw.File == nil {
+		return nil // parse error
+	}
+	workDir := filepath.Dir(uri.Filename())
 
-			if err := copied.AddReplace(r.Old.Path, "", newReplacedPath, ""); err != nil {
-				return nil, err
+	// Directives are located in the copy by index, as both
+	// parses of the same content yield the same directives.
+	copied, err := modfile.ParseWork("", pw.Mapper.Content, nil)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i, u := range pw.File.Use {
+		newPath, ok := relocatePath(u.Path, workDir, oldDir, newDir)
+		if !ok {
+			continue
+		}
+		use := copied.Use[i]
+		use.Path = newPath
+		use.Syntax.Token[len(use.Syntax.Token)-1] = modfile.AutoQuote(newPath)
+		changed = true
+	}
+	for _, r := range pw.File.Replace {
+		if !modfile.IsDirectoryPath(r.New.Path) {
+			continue
+		}
+		if newPath, ok := relocatePath(r.New.Path, workDir, oldDir, newDir); ok {
+			if err := copied.AddReplace(r.Old.Path, r.Old.Version, newPath, ""); err != nil {
+				return err
 			}
+			changed = true
 		}
+	}
+	if !changed {
+		return nil
+	}
 
-		copied.Cleanup()
-		newContent, err := copied.Format()
-		if err != nil {
-			return nil, err
-		}
+	copied.Cleanup()
+	newContent := modfile.Format(copied.Syntax)
+	workEdits := s.View().Options().ComputeEdits(string(pw.Mapper.Content), string(newContent))
+	edits[pw.URI] = append(edits[pw.URI], workEdits...)
+	return nil
+}
 
-		// Calculate the edits to be made due to the change.
-		edits := s.View().Options().ComputeEdits(string(pm.Mapper.Content), string(newContent))
-		renamingEdits[pm.URI] = append(renamingEdits[pm.URI], edits...)
+// relocatePath returns the new form of the directory path p, which
+// appears in a go.mod or go.work file in directory fileDir, after
+// oldDir is moved to newDir. A relative path remains relative, and is
+// updated if either the file or the directory it refers to is moved.
+// The result is false if the path is unaffected by the move.
+func relocatePath(p, fileDir, oldDir, newDir string) (string, bool) {
+	isAbs := filepath.IsAbs(filepath.FromSlash(p))
+	target := filepath.FromSlash(p)
+	if !isAbs {
+		target = filepath.Join(fileDir, target)
+	}
+	newTarget, targetMoved := movedPath(target, oldDir, newDir)
+	if isAbs {
+		return newTarget, targetMoved
+	}
+	newFileDir, fileMoved := movedPath(fileDir, oldDir, newDir)
+	if !targetMoved && !fileMoved {
+		return "", false
+	}
+	rel, err := filepath.Rel(newFileDir, newTarget)
+	if err != nil {
+		return "", false
 	}
+	rel = filepath.ToSlash(rel)
+	if rel != "." && !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	if path.Clean(rel) == path.Clean(p) {
+		return "", false
+	}
+	return rel, true
+}
 
-	return renamingEdits, nil
+// movedPath returns the new form of the file name p after oldDir is
+// moved to newDir, and whether p lies within oldDir.
+func movedPath(p, oldDir, newDir string) (string, bool) {
+	if !InDir(oldDir, p) {
+		return p, false
+	}
+	return filepath.Join(newDir, strings.TrimPrefix(p, oldDir)), true
 }
 
 // renamePackage computes all workspace edits required to rename the package
@@ -0,0 +1,145 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/span"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/event"
+)
+
+// RenameFiles returns the edits that keep the workspace consistent when
+// the client renames files or directories, as in a
+// workspace/willRenameFiles request. The edits must be applied before
+// the files are moved, and so refer to their original locations.
+//
+// Moving a directory changes the import path of each package beneath
+// it, so every import of such a package is updated, along with the
+// replace directives of go.mod and go.work files and the use
+// directives of the go.work file that refer to the moved directory.
+// Renaming an individual file requires no edits.
+func RenameFiles(ctx context.Context, snapshot Snapshot, renames []protocol.FileRename) (map[span.URI][]protocol.TextEdit, error) {
+	ctx, done := event.Start(ctx, "source.RenameFiles")
+	defer done()
+
+	edits := make(map[span.URI][]diff.Edit)
+	for _, r := range renames {
+		oldURI, newURI := span.URIFromURI(r.OldURI), span.URIFromURI(r.NewURI)
+		if !oldURI.IsFile() || !newURI.IsFile() {
+			continue
+		}
+		oldDir, newDir := filepath.Clean(oldURI.Filename()), filepath.Clean(newURI.Filename())
+		if fi, err := os.Stat(oldDir); err != nil || !fi.IsDir() {
+			continue // not a directory (or already moved)
+		}
+		if err := renamePackageDirs(ctx, snapshot, oldDir, newDir, edits); err != nil {
+			return nil, err
+		}
+		if err := renameReplaceDirectives(ctx, snapshot, oldDir, newDir, edits); err != nil {
+			return nil, err
+		}
+		if err := renameWorkUses(ctx, snapshot, oldDir, newDir, edits); err != nil {
+			return nil, err
+		}
+	}
+	return toProtocolEditMap(ctx, snapshot, edits)
+}
+
+// renamePackageDirs computes the edits to the imports of each package
+// whose directory lies within oldDir, as a result of moving oldDir to
+// newDir. Packages keep their names, so only import paths change.
+//
+// Packages of a module whose root directory is moved along with them
+// keep their import paths. A package moved into another module of the
+// workspace takes its import path from that module.
+//
+// Edits are written into the edits map.
+func renamePackageDirs(ctx context.Context, snapshot Snapshot, oldDir, newDir string, edits map[span.URI][]diff.Edit) error {
+	allMetadata, err := snapshot.AllMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	// modulePaths maps the directory of each go.mod
+	// file of the workspace to its module path.
+	var modulePaths map[string]string
+
+	for _, m := range allMetadata {
+		if len(m.GoFiles) == 0 || m.IsIntermediateTestVariant() {
+			continue
+		}
+		pkgDir := filepath.Dir(m.GoFiles[0].Filename())
+		newPkgDir, moved := movedPath(pkgDir, oldDir, newDir)
+		if !moved {
+			continue // not affected by the move
+		}
+		if m.Module == nil {
+			return fmt.Errorf("cannot move package: missing module information for package %q", m.PkgPath)
+		}
+		if InDir(oldDir, filepath.Clean(m.Module.Dir)) {
+			continue // the package moves along with its module
+		}
+
+		// Find the module containing the new directory.
+		moduleDir, modulePath := filepath.Clean(m.Module.Dir), m.Module.Path
+		if !InDir(moduleDir, newPkgDir) {
+			if modulePaths == nil {
+				modulePaths, err = workspaceModulePaths(ctx, snapshot)
+				if err != nil {
+					return err
+				}
+			}
+			moduleDir, modulePath = "", ""
+			for dir, path := range modulePaths {
+				if InDir(dir, newPkgDir) && len(dir) > len(moduleDir) {
+					moduleDir, modulePath = dir, path
+				}
+			}
+			if moduleDir == "" {
+				return fmt.Errorf("cannot move package %q outside of the workspace modules", m.PkgPath)
+			}
+		}
+		rel, err := filepath.Rel(moduleDir, newPkgDir)
+		if err != nil {
+			return err
+		}
+		newPath := path.Join(modulePath, filepath.ToSlash(rel))
+		if PackagePath(newPath) == m.PkgPath {
+			continue
+		}
+		if err := renameImports(ctx, snapshot, m, ImportPath(newPath), m.Name, edits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workspaceModulePaths returns a map from the directory of each go.mod
+// file of the workspace to its module path.
+func workspaceModulePaths(ctx context.Context, snapshot Snapshot) (map[string]string, error) {
+	paths := make(map[string]string)
+	for _, uri := range snapshot.ModFiles() {
+		fh, err := snapshot.GetFile(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fh.Read()
+		if err != nil {
+			return nil, err
+		}
+		if path := modfile.ModulePath(data); path != "" {
+			paths[filepath.Dir(uri.Filename())] = path
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,340 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/span"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// This file defines the type hierarchy operations. The supertypes of
+// a named type are the types it embeds and, for a concrete type, the
+// interfaces it implements. The subtypes are the types that embed it
+// and, for an interface type, the concrete types that implement it.
+//
+// Each item identifies a type by the position of its declaration,
+// from which subsequent requests recompute the type.
+
+// PrepareTypeHierarchy returns the type hierarchy item for the named
+// type referred to at the given position, or nil if there is none.
+func PrepareTypeHierarchy(ctx context.Context, snapshot Snapshot, fh FileHandle, pp protocol.Position) ([]protocol.TypeHierarchyItem, error) {
+	ctx, done := event.Start(ctx, "source.PrepareTypeHierarchy")
+	defer done()
+
+	pkg, tname, err := typeNameAt(ctx, snapshot, fh.URI(), pp)
+	if err != nil || tname == nil {
+		return nil, err
+	}
+	item, err := typeNameItem(ctx, snapshot, pkg, tname)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	return []protocol.TypeHierarchyItem{*item}, nil
+}
+
+// Supertypes returns the items for the types embedded by, or
+// implemented by, the type declared at the given position.
+func Supertypes(ctx context.Context, snapshot Snapshot, fh FileHandle, pp protocol.Position) ([]protocol.TypeHierarchyItem, error) {
+	ctx, done := event.Start(ctx, "source.Supertypes")
+	defer done()
+
+	pkg, tname, err := typeNameAt(ctx, snapshot, fh.URI(), pp)
+	if err != nil || tname == nil {
+		return nil, err
+	}
+
+	var items []protocol.TypeHierarchyItem
+	for _, embedded := range embeddedTypes(tname) {
+		item, err := typeNameItem(ctx, snapshot, pkg, embedded)
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			items = append(items, *item)
+		}
+	}
+
+	// For a concrete type, the implementation
+	// search reports the interfaces it implements.
+	if !types.IsInterface(tname.Type()) {
+		more, err := implementationItems(ctx, snapshot, fh, pp)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, more...)
+	}
+	return sortTypeHierarchyItems(items), nil
+}
+
+// Subtypes returns the items for the types that embed, or implement,
+// the type declared at the given position.
+func Subtypes(ctx context.Context, snapshot Snapshot, fh FileHandle, pp protocol.Position) ([]protocol.TypeHierarchyItem, error) {
+	ctx, done := event.Start(ctx, "source.Subtypes")
+	defer done()
+
+	_, tname, err := typeNameAt(ctx, snapshot, fh.URI(), pp)
+	if err != nil || tname == nil {
+		return nil, err
+	}
+
+	items, err := embeddingItems(ctx, snapshot, fh.URI(), tname)
+	if err != nil {
+		return nil, err
+	}
+
+	// For an interface type, the implementation
+	// search reports the concrete types that implement it.
+	if types.IsInterface(tname.Type()) {
+		more, err := implementationItems(ctx, snapshot, fh, pp)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, more...)
+	}
+	return sortTypeHierarchyItems(items), nil
+}
+
+// typeNameAt returns the declaration of the named type referred to
+// at the given position, or nil if there is none. For an instance of
+// a generic type, it returns the declaration of the generic type.
+func typeNameAt(ctx context.Context, snapshot Snapshot, uri span.URI, pp protocol.Position) (Package, *types.TypeName, error) {
+	pkg, pgf, err := PackageForFile(ctx, snapshot, uri, NarrowestPackage)
+	if err != nil {
+		return nil, nil, err
+	}
+	pos, err := pgf.PositionPos(pp)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, obj, _ := referencedObject(pkg, pgf, pos)
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil, nil, nil
+	}
+	return pkg, namedTypeName(obj.Type()), nil
+}
+
+// namedTypeName returns the declaration of the named type T,
+// or of its origin if T is an instance, or nil if T is not named.
+// It also returns nil for predeclared types such as error.
+func namedTypeName(T types.Type) *types.TypeName {
+	named, ok := T.(*types.Named)
+	if !ok {
+		return nil
+	}
+	tname := typeparams.NamedTypeOrigin(named).(*types.Named).Obj()
+	if tname.Pkg() == nil {
+		return nil // predeclared
+	}
+	return tname
+}
+
+// embeddedTypes returns the named types embedded
+// in the struct or interface type declared by tname.
+func embeddedTypes(tname *types.TypeName) []*types.TypeName {
+	var res []*types.TypeName
+	add := func(T types.Type) {
+		if ptr, ok := T.(*types.Pointer); ok {
+			T = ptr.Elem()
+		}
+		if embedded := namedTypeName(T); embedded != nil {
+			res = append(res, embedded)
+		}
+	}
+	switch T := tname.Type().Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < T.NumFields(); i++ {
+			if f := T.Field(i); f.Embedded() {
+				add(f.Type())
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < T.NumEmbeddeds(); i++ {
+			add(T.EmbeddedType(i))
+		}
+	}
+	return res
+}
+
+// embeddingItems returns the items for the types that embed the
+// package-level type tname, which is declared in the specified file.
+// It searches the declaring package and its reverse dependencies.
+func embeddingItems(ctx context.Context, snapshot Snapshot, declURI span.URI, tname *types.TypeName) ([]protocol.TypeHierarchyItem, error) {
+	if tname.Parent() != tname.Pkg().Scope() {
+		return nil, nil // local types can be embedded only locally; not supported
+	}
+	pkgs, err := typeCheckReverseDependencies(ctx, snapshot, declURI, true)
+	if err != nil {
+		return nil, err
+	}
+	// Each type is identified by its package and name,
+	// as the packages may have been type-checked separately.
+	matches := func(embedded *types.TypeName) bool {
+		return embedded.Name() == tname.Name() && embedded.Pkg().Path() == tname.Pkg().Path()
+	}
+	var items []protocol.TypeHierarchyItem
+	for _, pkg := range pkgs {
+		for _, pgf := range pkg.CompiledGoFiles() {
+			var err error
+			ast.Inspect(pgf.File, func(n ast.Node) bool {
+				spec, ok := n.(*ast.TypeSpec)
+				if !ok || err != nil {
+					return err == nil
+				}
+				def, ok := pkg.GetTypesInfo().Defs[spec.Name].(*types.TypeName)
+				if !ok || def.IsAlias() {
+					return true
+				}
+				for _, embedded := range embeddedTypes(def) {
+					if matches(embedded) {
+						var item *protocol.TypeHierarchyItem
+						item, err = specItem(pgf, spec, string(pkg.Metadata().PkgPath))
+						if err == nil {
+							items = append(items, *item)
+						}
+						break
+					}
+				}
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return items, nil
+}
+
+// implementationItems returns the items for the types reported by
+// the implementation search for the type declared at the given
+// position: the interfaces implemented by a concrete type, or the
+// concrete types that implement an interface.
+func implementationItems(ctx context.Context, snapshot Snapshot, fh FileHandle, pp protocol.Position) ([]protocol.TypeHierarchyItem, error) {
+	locs, err := implementations2(ctx, snapshot, fh, pp)
+	if err != nil {
+		return nil, err
+	}
+	var items []protocol.TypeHierarchyItem
+	for _, loc := range locs {
+		item, err := locationItem(ctx, snapshot, loc)
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			items = append(items, *item)
+		}
+	}
+	return items, nil
+}
+
+// typeNameItem returns the item for the type declared by tname,
+// which belongs to pkg or one of its dependencies.
+func typeNameItem(ctx context.Context, snapshot Snapshot, pkg Package, tname *types.TypeName) (*protocol.TypeHierarchyItem, error) {
+	if !tname.Pos().IsValid() {
+		return nil, nil
+	}
+	loc, err := mapPosition(ctx, pkg.FileSet(), snapshot, tname.Pos(), tname.Pos()+token.Pos(len(tname.Name())))
+	if err != nil {
+		return nil, err
+	}
+	return locationItem(ctx, snapshot, loc)
+}
+
+// locationItem returns the item for the type
+// whose declared name is at loc, or nil if none.
+func locationItem(ctx context.Context, snapshot Snapshot, loc protocol.Location) (*protocol.TypeHierarchyItem, error) {
+	uri := loc.URI.SpanURI()
+	fh, err := snapshot.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	pgf, err := snapshot.ParseGo(ctx, fh, ParseFull)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := pgf.PositionPos(loc.Range.Start)
+	if err != nil {
+		return nil, err
+	}
+	path, _ := astutil.PathEnclosingInterval(pgf.File, pos, pos)
+	for _, n := range path {
+		if spec, ok := n.(*ast.TypeSpec); ok && spec.Name.Pos() <= pos && pos <= spec.Name.End() {
+			var pkgPath string
+			if metas, err := snapshot.MetadataForFile(ctx, uri); err == nil && len(metas) > 0 {
+				pkgPath = string(metas[0].PkgPath)
+			}
+			return specItem(pgf, spec, pkgPath)
+		}
+	}
+	return nil, nil
+}
+
+// specItem returns the item for the type declared by spec.
+func specItem(pgf *ParsedGoFile, spec *ast.TypeSpec, pkgPath string) (*protocol.TypeHierarchyItem, error) {
+	rng, err := pgf.NodeRange(spec)
+	if err != nil {
+		return nil, err
+	}
+	nameRng, err := pgf.NodeRange(spec.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Show the type parameters of a generic type, e.g. "List[T]".
+	name := spec.Name.Name
+	if tparams := typeparams.ForTypeSpec(spec); tparams.NumFields() > 0 {
+		var names []string
+		for _, field := range tparams.List {
+			for _, id := range field.Names {
+				names = append(names, id.Name)
+			}
+		}
+		name += "[" + strings.Join(names, ", ") + "]"
+	}
+
+	kind := protocol.Class
+	switch spec.Type.(type) {
+	case *ast.InterfaceType:
+		kind = protocol.Interface
+	case *ast.StructType:
+		kind = protocol.Struct
+	}
+
+	return &protocol.TypeHierarchyItem{
+		Name:           name,
+		Kind:           kind,
+		Detail:         pkgPath,
+		URI:            protocol.URIFromSpanURI(pgf.URI),
+		Range:          rng,
+		SelectionRange: nameRng,
+	}, nil
+}
+
+// sortTypeHierarchyItems sorts the items by location
+// and removes duplicates.
+func sortTypeHierarchyItems(items []protocol.TypeHierarchyItem) []protocol.TypeHierarchyItem {
+	loc := func(item protocol.TypeHierarchyItem) protocol.Location {
+		return protocol.Location{URI: item.URI, Range: item.SelectionRange}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return protocol.CompareLocation(loc(items[i]), loc(items[j])) < 0
+	})
+	out := items[:0]
+	for _, item := range items {
+		if len(out) == 0 || loc(out[len(out)-1]) != loc(item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
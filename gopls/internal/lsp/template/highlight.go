@@ -94,3 +94,56 @@ func findWordAt(p *Parsed, pos int) string {
 	got := wordRe.Find(p.buf[:pos+len(after)])
 	return string(got)
 }
+
+// LinkedEditingRange returns the ranges of the names of the template
+// definitions and invocations in the file whose name is that at loc, so
+// that they can be edited together. The result is empty if the name is
+// also used by other template files, as editing the file alone would
+// then break them.
+func LinkedEditingRange(ctx context.Context, snapshot source.Snapshot, fh source.FileHandle, loc protocol.Position) ([]protocol.Range, error) {
+	buf, err := fh.Read()
+	if err != nil {
+		return nil, err
+	}
+	p := parseBuffer(buf)
+	if p.ParseErr != nil {
+		return nil, nil
+	}
+	pos := p.FromPosition(loc)
+	var name string
+	for _, s := range p.symbols {
+		if isTemplateName(s) && s.start <= pos && pos <= s.start+s.length {
+			name = s.name
+			break
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+	for k, other := range New(snapshot.Templates()).files {
+		if k == fh.URI() {
+			continue
+		}
+		for _, s := range other.symbols {
+			if isTemplateName(s) && s.name == name {
+				return nil, nil
+			}
+		}
+	}
+
+	var ans []protocol.Range
+	seen := make(map[int]bool) // a {{block}} both defines and invokes
+	for _, s := range p.symbols {
+		if isTemplateName(s) && s.name == name && !seen[s.start] {
+			seen[s.start] = true
+			ans = append(ans, p.Range(s.start, s.length))
+		}
+	}
+	return ans, nil
+}
+
+// isTemplateName reports whether s is the name of a template in a
+// {{define}}, {{block}} or {{template}} action.
+func isTemplateName(s symbol) bool {
+	return s.kind == protocol.Namespace || s.kind == protocol.Package
+}
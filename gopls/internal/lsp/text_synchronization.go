@@ -9,12 +9,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"golang.org/x/tools/gopls/internal/lsp/protocol"
 	"golang.org/x/tools/gopls/internal/lsp/source"
 	"golang.org/x/tools/gopls/internal/span"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/event/tag"
 	"golang.org/x/tools/internal/jsonrpc2"
 )
 
@@ -38,6 +42,10 @@ const (
 	// FromDidClose is a file modification caused by closing a file.
 	FromDidClose
 
+	// FromDidRenameFiles is a file modification caused by the client
+	// renaming files or directories.
+	FromDidRenameFiles
+
 	// TODO: add FromDidChangeConfiguration, once configuration changes cause a
 	// new snapshot to be created.
 
@@ -62,6 +70,8 @@ func (m ModificationSource) String() string {
 		return "saved files"
 	case FromDidClose:
 		return "close files"
+	case FromDidRenameFiles:
+		return "renamed files"
 	case FromRegenerateCgo:
 		return "regenerate cgo"
 	case FromInitialWorkspaceLoad:
@@ -178,6 +188,45 @@ func (s *Server) didChangeWatchedFiles(ctx context.Context, params *protocol.Did
 	return s.didModifyFiles(ctx, modifications, FromDidChangeWatchedFiles)
 }
 
+func (s *Server) didRenameFiles(ctx context.Context, params *protocol.RenameFilesParams) error {
+	var modifications []source.FileModification
+	for _, r := range params.Files {
+		oldURI, newURI := span.URIFromURI(r.OldURI), span.URIFromURI(r.NewURI)
+		if !oldURI.IsFile() || !newURI.IsFile() {
+			continue
+		}
+		// A deleted directory is expanded to its known files
+		// by didModifyFiles, but the files of the new directory
+		// are not yet known, so we must find them on disk.
+		modifications = append(modifications, source.FileModification{
+			URI:    oldURI,
+			Action: source.Delete,
+			OnDisk: true,
+		})
+		err := filepath.Walk(newURI.Filename(), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != newURI.Filename() && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			modifications = append(modifications, source.FileModification{
+				URI:    span.URIFromPath(path),
+				Action: source.Create,
+				OnDisk: true,
+			})
+			return nil
+		})
+		if err != nil {
+			event.Error(ctx, "walking renamed files", err, tag.URI.Of(newURI))
+		}
+	}
+	return s.didModifyFiles(ctx, modifications, FromDidRenameFiles)
+}
+
 func (s *Server) didSave(ctx context.Context, params *protocol.DidSaveTextDocumentParams) error {
 	uri := params.TextDocument.URI.SpanURI()
 	if !uri.IsFile() {
@@ -230,7 +279,7 @@ func (s *Server) didModifyFiles(ctx context.Context, modifications []source.File
 		}()
 	}
 
-	onDisk := cause == FromDidChangeWatchedFiles
+	onDisk := cause == FromDidChangeWatchedFiles || cause == FromDidRenameFiles
 
 	s.stateMu.Lock()
 	if s.state >= serverShutDown {
@@ -310,6 +359,12 @@ func (s *Server) applyIncrementalChanges(ctx context.Context, uri span.URI, chan
 	if err != nil {
 		return nil, fmt.Errorf("%w: file not found (%v)", jsonrpc2.ErrInternal, err)
 	}
+	return applyChanges(uri, content, changes)
+}
+
+// applyChanges returns the content of the document uri after the
+// incremental changes.
+func applyChanges(uri span.URI, content []byte, changes []protocol.TextDocumentContentChangeEvent) ([]byte, error) {
 	for _, change := range changes {
 		// TODO(adonovan): refactor to use diff.Apply, which is robust w.r.t.
 		// out-of-order or overlapping changes---and much more efficient.
@@ -16,6 +16,7 @@ import (
 	"golang.org/x/tools/internal/testenv"
 
 	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	"golang.org/x/tools/gopls/internal/lsp/tests/compare"
 )
 
 func TestMain(m *testing.M) {
@@ -713,3 +714,98 @@ use ./dir/foobar/
 		}
 	})
 }
+
+func TestResolveCompletionItem(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.12
+-- lib/lib.go --
+package lib
+
+func Hello() {}
+-- main.go --
+package main
+
+// Greet says hello.
+func Greet() {}
+
+func main() {
+	Gree
+	lib.Hel
+}
+`
+	WithOptions(
+		ResolveLazily(),
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("main.go")
+		env.Await(env.DoneWithOpen())
+
+		// The documentation is computed when the item is resolved.
+		item := firstCompletion(env, env.RegexpSearch("main.go", `\tGree()`), "Greet")
+		if doc := completionDoc(item); doc != "" {
+			t.Errorf("item has documentation %q before it is resolved", doc)
+		}
+		resolved := env.ResolveCompletionItem(item)
+		if doc := completionDoc(resolved); !strings.Contains(doc, "Greet says hello.") {
+			t.Errorf("resolved documentation %q does not contain the doc comment", doc)
+		}
+
+		// So are the edits that import the package of the item.
+		loc := env.RegexpSearch("main.go", `lib.Hel()`)
+		item = firstCompletion(env, loc, "Hello")
+		if len(item.AdditionalTextEdits) > 0 {
+			t.Errorf("item has additional text edits before it is resolved: %v", item.AdditionalTextEdits)
+		}
+		resolved = env.ResolveCompletionItem(item)
+		if len(resolved.AdditionalTextEdits) == 0 {
+			t.Errorf("resolved item has no additional text edits")
+		}
+		env.AcceptCompletion(loc, item)
+		const want = `package main
+
+import "mod.com/lib"
+
+// Greet says hello.
+func Greet() {}
+
+func main() {
+	Gree
+	lib.Hello
+}
+`
+		if got := env.BufferText("main.go"); got != want {
+			t.Errorf("after accepting completion:\n%s", compare.Text(want, got))
+		}
+	})
+}
+
+// firstCompletion returns the first completion item at loc, which must
+// have the given label.
+func firstCompletion(env *Env, loc protocol.Location, label string) protocol.CompletionItem {
+	env.T.Helper()
+	completions := env.Completion(loc)
+	if len(completions.Items) == 0 {
+		env.T.Fatalf("no completion items at %v", loc)
+	}
+	item := completions.Items[0]
+	if item.Label != label {
+		env.T.Fatalf("got completion item %q, want %q", item.Label, label)
+	}
+	return item
+}
+
+// completionDoc returns the text of the documentation of item.
+func completionDoc(item protocol.CompletionItem) string {
+	if item.Documentation == nil {
+		return ""
+	}
+	switch v := item.Documentation.Value.(type) {
+	case string:
+		return v
+	case protocol.MarkupContent:
+		return v.Value
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diagnostics
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	. "golang.org/x/tools/gopls/internal/lsp/regtest"
+)
+
+func TestPullDiagnostics(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func _() {
+	x := 1
+	x = x
+}
+-- b/b.go --
+package b
+
+func _() {
+	_ = y
+}
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		// pull requests the diagnostics of the named file, returning
+		// the kind and result ID of the report and its messages.
+		pull := func(name, previousResultID string) (kind, resultID string, messages []string) {
+			t.Helper()
+			params := &protocol.DocumentDiagnosticParams{
+				TextDocument:     protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI(name)},
+				PreviousResultID: previousResultID,
+			}
+			report, err := env.Editor.Server.Diagnostic(env.Ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The unchanged report also decodes as a full report.
+			full := report.Value.(protocol.RelatedFullDocumentDiagnosticReport)
+			for _, d := range full.Items {
+				messages = append(messages, d.Message)
+			}
+			sort.Strings(messages)
+			return full.Kind, full.ResultID, messages
+		}
+
+		// Analysis diagnostics are reported even for unopened files.
+		kind, id1, got := pull("a/a.go", "")
+		want := []string{"self-assignment of x to x"}
+		if kind != "full" || id1 == "" || !cmp.Equal(got, want) {
+			t.Fatalf("first pull = %s %q %q, want full report of %q", kind, id1, got, want)
+		}
+
+		if kind, id, _ := pull("a/a.go", id1); kind != "unchanged" || id != id1 {
+			t.Errorf("second pull = %s %q, want unchanged %q", kind, id, id1)
+		}
+
+		env.OpenFile("a/a.go")
+		env.RegexpReplace("a/a.go", `x = x`, `_ = x`)
+		if kind, id2, got := pull("a/a.go", id1); kind != "full" || id2 == id1 || len(got) > 0 {
+			t.Errorf("pull after edit = %s %q %q, want empty full report with new ID", kind, id2, got)
+		}
+
+		_, idB, got := pull("b/b.go", "")
+		if want := []string{"undefined: y"}; !cmp.Equal(got, want) {
+			t.Errorf("pull b/b.go = %q, want %q", got, want)
+		}
+		report, err := env.Editor.Server.DiagnosticWorkspace(env.Ctx, &protocol.WorkspaceDiagnosticParams{
+			PreviousResultIds: []protocol.PreviousResultID{
+				{URI: env.Sandbox.Workdir.URI("a/a.go"), Value: id1}, // stale
+				{URI: env.Sandbox.Workdir.URI("b/b.go"), Value: idB},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		kinds := make(map[string]string)
+		for _, item := range report.Items {
+			// The unchanged report also decodes as a full report.
+			full := item.Value.(protocol.WorkspaceFullDocumentDiagnosticReport)
+			kinds[env.Sandbox.Workdir.URIToPath(full.URI)] = full.Kind
+		}
+		wantKinds := map[string]string{
+			"a/a.go": "full",
+			"b/b.go": "unchanged",
+			"go.mod": "full",
+		}
+		if diff := cmp.Diff(wantKinds, kinds); diff != "" {
+			t.Errorf("workspace report kinds mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestPullDiagnosticsPublished(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func _() {
+	_ = y
+}
+`
+	// By default, diagnostics are published even to clients that pull them.
+	WithOptions(
+		PullDiagnostics(),
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		env.AfterChange(Diagnostics(env.AtRegexp("a/a.go", "y")))
+	})
+}
+
+func TestPullDiagnosticsNotPublished(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func _() {
+	_ = y
+}
+`
+	WithOptions(
+		PullDiagnostics(),
+		Settings{"pullDiagnostics": true},
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		env.AfterChange(NoDiagnostics(ForFile("a/a.go")))
+
+		report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("a/a.go")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		full := report.Value.(protocol.RelatedFullDocumentDiagnosticReport)
+		if len(full.Items) != 1 || full.Items[0].Message != "undefined: y" {
+			t.Errorf("pulled diagnostics = %v, want one for undefined: y", full.Items)
+		}
+	})
+}
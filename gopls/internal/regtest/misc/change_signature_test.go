@@ -0,0 +1,282 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/lsp/command"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	. "golang.org/x/tools/gopls/internal/lsp/regtest"
+	"golang.org/x/tools/gopls/internal/lsp/tests/compare"
+)
+
+// changeSignature executes the ChangeSignature command on the function
+// whose name is matched by the first capture group of re.
+func changeSignature(env *Env, file, re string, params ...command.SignatureParam) error {
+	cmd, err := command.NewChangeSignatureCommand("", command.ChangeSignatureArgs{
+		Location: env.RegexpSearch(file, re),
+		Params:   params,
+	})
+	if err != nil {
+		env.T.Fatal(err)
+	}
+	_, err = env.Editor.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+		Command:   cmd.Command,
+		Arguments: cmd.Arguments,
+	})
+	return err
+}
+
+func TestRemoveUnusedParameter(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func F(x, unused int, y int) int { return x + y }
+
+func G() int { return F(F(1, 2, 3), 4, 5) }
+-- b/b.go --
+package b
+
+import "mod.com/a"
+
+var V = a.F(1, 2, 3)
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `(unused)`)
+		actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var action *protocol.CodeAction
+		for i := range actions {
+			if actions[i].Title == "Remove unused parameter unused" {
+				action = &actions[i]
+			}
+		}
+		if action == nil {
+			t.Fatalf("no remove action among %v", actions)
+		}
+		if err := env.Editor.ApplyCodeAction(env.Ctx, *action); err != nil {
+			t.Fatal(err)
+		}
+		want := `package a
+
+func F(x int, y int) int { return x + y }
+
+func G() int { return F(F(1, 3), 5) }
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("a/a.go after change:\n%s", compare.Text(want, got))
+		}
+		want = `package b
+
+import "mod.com/a"
+
+var V = a.F(1, 3)
+`
+		if got := env.BufferText("b/b.go"); got != want {
+			t.Errorf("b/b.go after change:\n%s", compare.Text(want, got))
+		}
+	})
+}
+
+func TestChangeSignature(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+const Default = 3
+
+func Sum(x, y int, rest ...int) int {
+	for _, z := range rest {
+		x += z
+	}
+	return x + y
+}
+
+var (
+	_ = Sum(1, 2)
+	_ = Sum(1, 2, 3, 4)
+	_ = Sum(1, 2, []int{3}...)
+)
+-- b/b.go --
+package b
+
+import "mod.com/a"
+
+var _ = a.Sum(5, 6, 7)
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+
+		// The default value refers to a name of package a.
+		err := changeSignature(env, "a/a.go", `func (Sum)`,
+			command.SignatureParam{Index: 1},
+			command.SignatureParam{Index: 0},
+			command.SignatureParam{Index: -1, Name: "scale", Type: "int", Default: "Default"},
+			command.SignatureParam{Index: 2},
+		)
+		if err == nil || !strings.Contains(err.Error(), "does not refer to the same declaration") {
+			t.Fatalf("changing Sum: got error %v, want default value error", err)
+		}
+
+		if err := changeSignature(env, "a/a.go", `func (Sum)`,
+			command.SignatureParam{Index: 1},
+			command.SignatureParam{Index: 0},
+			command.SignatureParam{Index: -1, Name: "scale", Type: "int", Default: "1"},
+			command.SignatureParam{Index: 2},
+		); err != nil {
+			t.Fatal(err)
+		}
+		want := `package a
+
+const Default = 3
+
+func Sum(y, x int, scale int, rest ...int) int {
+	for _, z := range rest {
+		x += z
+	}
+	return x + y
+}
+
+var (
+	_ = Sum(2, 1, 1)
+	_ = Sum(2, 1, 1, 3, 4)
+	_ = Sum(2, 1, 1, []int{3}...)
+)
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("a/a.go after change:\n%s", compare.Text(want, got))
+		}
+		want = `package b
+
+import "mod.com/a"
+
+var _ = a.Sum(6, 5, 1, 7)
+`
+		if got := env.BufferText("b/b.go"); got != want {
+			t.Errorf("b/b.go after change:\n%s", compare.Text(want, got))
+		}
+	})
+}
+
+func TestChangeSignature_Interface(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+type Shape interface {
+	Scale(x, y int) Shape
+}
+
+func Double(s Shape) Shape { return s.Scale(2, 2) }
+-- b/b.go --
+package b
+
+import "mod.com/a"
+
+type Square struct{ side int }
+
+func (s Square) Scale(x, y int) a.Shape { return Square{s.side * x} }
+
+var _ a.Shape = Square{}
+
+func Triple(s Square) a.Shape { return s.Scale(3, 3) }
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("b/b.go")
+		if err := changeSignature(env, "b/b.go", `\) (Scale)`,
+			command.SignatureParam{Index: 0},
+		); err != nil {
+			t.Fatal(err)
+		}
+		want := `package a
+
+type Shape interface {
+	Scale(x int) Shape
+}
+
+func Double(s Shape) Shape { return s.Scale(2) }
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("a/a.go after change:\n%s", compare.Text(want, got))
+		}
+		want = `package b
+
+import "mod.com/a"
+
+type Square struct{ side int }
+
+func (s Square) Scale(x int) a.Shape { return Square{s.side * x} }
+
+var _ a.Shape = Square{}
+
+func Triple(s Square) a.Shape { return s.Scale(3) }
+`
+		if got := env.BufferText("b/b.go"); got != want {
+			t.Errorf("b/b.go after change:\n%s", compare.Text(want, got))
+		}
+	})
+}
+
+func TestChangeSignature_Errors(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func F(x, y int) int { return x }
+
+var f = F
+
+func G(x, y int) int { return x + y }
+
+func H(x int) int { return 0 }
+
+var _ = H(G(1, 2))
+
+func two() (int, int) { return 1, 2 }
+
+var _ = G(two())
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		for _, test := range []struct {
+			re     string
+			params []command.SignatureParam
+			want   string
+		}{
+			{`func (F)`, []command.SignatureParam{{Index: 0}}, "used as a value"},
+			{`func (G)`, []command.SignatureParam{{Index: 0}}, "parameter y of G: it is used"},
+			{`func (H)`, []command.SignatureParam{}, "side effects"},
+			{`func (G)`, []command.SignatureParam{{Index: 1}, {Index: 0}}, "multi-valued"},
+			{`func (H)`, []command.SignatureParam{{Index: 0}, {Index: -1, Name: "x", Type: "int", Default: "0"}}, "conflicts"},
+		} {
+			err := changeSignature(env, "a/a.go", test.re, test.params...)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("changing %s to %v: got error %v, want %q", test.re, test.params, err, test.want)
+			}
+		}
+	})
+}
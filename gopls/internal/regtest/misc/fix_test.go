@@ -101,3 +101,53 @@ func Foo() error {
 		env.AfterChange(NoDiagnostics(ForFile("main.go")))
 	})
 }
+
+func TestFillReturns_ResolveLazily(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.12
+-- main.go --
+package main
+
+func Foo() error {
+	return
+}
+`
+	WithOptions(
+		ResolveLazily(),
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("main.go")
+		var d protocol.PublishDiagnosticsParams
+		env.AfterChange(
+			Diagnostics(env.AtRegexp("main.go", `return`), WithMessage("return values")),
+			ReadDiagnostics("main.go", &d),
+		)
+		var quickFix *protocol.CodeAction
+		for _, a := range env.CodeAction("main.go", d.Diagnostics) {
+			if a.Edit != nil {
+				t.Errorf("code action %q has an edit before it is resolved", a.Title)
+			}
+			if a.Kind == protocol.QuickFix {
+				a := a
+				quickFix = &a
+			}
+		}
+		if quickFix == nil {
+			t.Fatalf("expected quickfix code action, got none")
+		}
+		if err := env.Editor.ApplyCodeAction(env.Ctx, *quickFix); err != nil {
+			t.Fatal(err)
+		}
+		want := `package main
+
+func Foo() error {
+	return nil
+}
+`
+		if got := env.BufferText("main.go"); got != want {
+			t.Errorf("fill returns:\n%s", compare.Text(want, got))
+		}
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
 	. "golang.org/x/tools/gopls/internal/lsp/regtest"
 	"golang.org/x/tools/gopls/internal/lsp/tests/compare"
 	"golang.org/x/tools/internal/testenv"
@@ -366,3 +367,126 @@ const Bar = 42
 		}
 	})
 }
+
+func TestFormatRange(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a.go --
+package a
+
+func f(x int) int {
+	y  :=  x*2
+	if y>0 {
+	return y
+	}
+	return  -y
+}
+
+func g( ) {
+}
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a.go")
+		// Select from the middle of the if statement to its end.
+		env.FormatRange(env.RegexpSearch("a.go", `return y\n\t}`))
+		want := `package a
+
+func f(x int) int {
+	y  :=  x*2
+	if y > 0 {
+		return y
+	}
+	return  -y
+}
+
+func g( ) {
+}
+`
+		if got := env.BufferText("a.go"); got != want {
+			t.Errorf("unexpected formatting result:\n%s", compare.Text(want, got))
+		}
+	})
+}
+
+func TestFormatOnTypeBrace(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- b/b.go --
+package b
+
+func Hello() string { return "hello" }
+-- a/a.go --
+package a
+
+func f( ) {
+}
+
+func g() string {
+return  b.Hello()
+}
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `b.Hello\(\)\n}()`)
+		env.FormatOnType(loc, "}")
+		want := `package a
+
+import "mod.com/b"
+
+func f( ) {
+}
+
+func g() string {
+	return b.Hello()
+}
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("unexpected formatting result:\n%s", compare.Text(want, got))
+		}
+	})
+}
+
+func TestFormatOnTypeNewline(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a.go --
+package a
+
+func f(x int) int {
+	switch {
+	case x>0:
+x = x+1
+	}
+	return  x
+}
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a.go")
+		loc := env.RegexpSearch("a.go", `x = x\+1\n()`)
+		env.EditBuffer("a.go", protocol.TextEdit{Range: loc.Range, NewText: "\n"})
+		env.FormatOnType(env.RegexpSearch("a.go", `x = x\+1\n()`), "\n")
+		want := `package a
+
+func f(x int) int {
+	switch {
+	case x>0:
+		x = x + 1
+		
+	}
+	return  x
+}
+`
+		if got := env.BufferText("a.go"); got != want {
+			t.Errorf("unexpected formatting result:\n%s", compare.Text(want, got))
+		}
+	})
+}
@@ -256,3 +256,89 @@ func Test() {
 		env.AfterChange(NoDiagnostics(ForFile("caller/caller.go")))
 	})
 }
+
+func TestOrganizeImports_ResolveLazily(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.12
+-- lib/lib.go --
+package lib
+
+func Hello() {}
+-- main.go --
+package main
+
+func main() {
+	lib.Hello()
+}
+`
+	const want = `package main
+
+import "mod.com/lib"
+
+func main() {
+	lib.Hello()
+}
+`
+	WithOptions(
+		ResolveLazily(),
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("main.go")
+		env.OrganizeImports("main.go")
+		if got := env.BufferText("main.go"); got != want {
+			t.Errorf("got\n%q, wanted\n%q", got, want)
+		}
+	})
+}
+
+func TestOrganizeImports_SortLazily(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.12
+-- a/a.go --
+package a
+
+func A() {}
+-- b/b.go --
+package b
+
+func B() {}
+-- main.go --
+package main
+
+import (
+	"mod.com/b"
+	"mod.com/a"
+)
+
+func main() {
+	a.A()
+	b.B()
+}
+`
+	const want = `package main
+
+import (
+	"mod.com/a"
+	"mod.com/b"
+)
+
+func main() {
+	a.A()
+	b.B()
+}
+`
+	WithOptions(
+		ResolveLazily(),
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("main.go")
+		env.OrganizeImports("main.go")
+		if got := env.BufferText("main.go"); got != want {
+			t.Errorf("got\n%q, wanted\n%q", got, want)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	. "golang.org/x/tools/gopls/internal/lsp/regtest"
+	"golang.org/x/tools/gopls/internal/lsp/tests/compare"
+)
+
+func TestInline(t *testing.T) {
+	const decls = `
+func add(x, y int) int { return x + y }
+
+func half(x float64) float64 { return x / 2 }
+
+func square(x int) int { return x * x }
+
+func next() int { return 1 }
+
+func logged(x int) int { return report(x) }
+
+func report(x int) int {
+	println(x)
+	return x
+}
+
+func twice(s string) {
+	println(s)
+	println(s)
+}
+
+func abs(x int) (r int) {
+	defer func() { r = -r }()
+	if x < 0 {
+		return x
+	}
+	return -x
+}
+
+type counter struct{ n int }
+
+func (c *counter) inc() int {
+	c.n++
+	return c.n
+}
+
+func (c counter) get() int { return c.n }
+`
+	tests := []struct {
+		name       string
+		body, want string // the body of the caller, before and after
+		selection  string // regexp for the selection
+		title      string // title of the code action
+		wantErr    string // substring of the expected error, if any
+	}{
+		{
+			name:      "expression",
+			body:      "_ = add(1, 2) * 3",
+			want:      "_ = (1 + 2) * 3",
+			selection: "add",
+			title:     "Inline call",
+		},
+		{
+			name:      "conversion",
+			body:      "_ = half(1)",
+			want:      "_ = float64(1) / 2",
+			selection: "half",
+			title:     "Inline call",
+		},
+		{
+			name:      "duplicated argument",
+			body:      "_ = square(next())",
+			want:      "_ = func(x int) int { return x * x }(next())",
+			selection: "square",
+			title:     "Inline call",
+		},
+		{
+			name:      "deferred call",
+			body:      "defer square(2)",
+			want:      "defer func(x int) int { return x * x }(2)",
+			selection: "square",
+			title:     "Inline call",
+		},
+		{
+			name:      "call statement",
+			body:      "logged(3)",
+			want:      "report(3)",
+			selection: "logged",
+			title:     "Inline call",
+		},
+		{
+			name: "block",
+			body: "twice(\"hello\" + \"world\")",
+			want: `{
+		s := "hello" + "world"
+		println(s)
+		println(s)
+	}`,
+			selection: "twice",
+			title:     "Inline call",
+		},
+		{
+			name: "defer and multiple returns",
+			body: "_ = abs(-1)",
+			want: `_ = func(x int) (r int) {
+		defer func() { r = -r }()
+		if x < 0 {
+			return x
+		}
+		return -x
+	}(-1)`,
+			selection: "abs",
+			title:     "Inline call",
+		},
+		{
+			name: "pointer receiver",
+			body: "var k counter\n\t_ = k.inc()",
+			want: `var k counter
+	_ = func(c *counter) int {
+		c.n++
+		return c.n
+	}(&k)`,
+			selection: "inc",
+			title:     "Inline call",
+		},
+		{
+			name:      "value receiver",
+			body:      "k := &counter{}\n\t_ = k.get()",
+			want:      "k := &counter{}\n\t_ = k.n",
+			selection: "get",
+			title:     "Inline call",
+		},
+		{
+			name:      "variable",
+			body:      "a := 1\n\tx := a + 1\n\tprintln(x * 2)\n\tprintln(x)",
+			want:      "a := 1\n\tprintln((a + 1) * 2)\n\tprintln(a + 1)",
+			selection: "x :=",
+			title:     "Inline variable",
+		},
+		{
+			name:      "variable with effects",
+			body:      "x := next()\n\tprintln(x, x)",
+			selection: "x :=",
+			title:     "Inline variable",
+			wantErr:   "used more than once",
+		},
+		{
+			name:      "shadowed variable",
+			body:      "a := 1\n\tx := a\n\t{\n\t\ta := 2\n\t\tprintln(a, x)\n\t}",
+			selection: "x :=",
+			title:     "Inline variable",
+			wantErr:   "different object",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func _() {
+	` + test.body + `
+}
+` + decls
+			Run(t, files, func(t *testing.T, env *Env) {
+				env.OpenFile("a/a.go")
+				loc := env.RegexpSearch("a/a.go", test.selection)
+				// Select only the first character, as a cursor would.
+				loc.Range.End = loc.Range.Start
+				loc.Range.End.Character++
+				actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var action *protocol.CodeAction
+				for i := range actions {
+					if actions[i].Kind == protocol.RefactorInline && actions[i].Title == test.title {
+						action = &actions[i]
+					}
+				}
+				if action == nil {
+					t.Fatalf("no %q code action among %v", test.title, actions)
+				}
+				err = env.Editor.ApplyCodeAction(env.Ctx, *action)
+				if test.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+						t.Fatalf("ApplyCodeAction: got error %v, want %q", err, test.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := env.BufferText("a/a.go")
+				want := "package a\n\nfunc _() {\n\t" + test.want + "\n}\n" + decls
+				if got != want {
+					t.Errorf("inlining failed:\n%s", compare.Text(want, got))
+				}
+			})
+		})
+	}
+}
+
+// TestInlineIllTyped checks that inlining is not offered for a call
+// whose statement is ill-typed.
+func TestInlineIllTyped(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func _() {
+	square("two")
+}
+
+func square(x int) int { return x * x }
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		env.AfterChange(Diagnostics(env.AtRegexp("a/a.go", `"two"`)))
+		loc := env.RegexpSearch("a/a.go", `square\(`)
+		actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, action := range actions {
+			if action.Title == "Inline call" {
+				t.Errorf("got Inline call action for ill-typed statement")
+			}
+		}
+	})
+}
+
+func TestInlineCrossPackage(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+import "mod.com/b"
+
+func _() {
+	_ = b.Inc(1)
+	_ = b.Double(1)
+}
+-- b/b.go --
+package b
+
+import "mod.com/c"
+
+func Inc(x int) int { return x + c.One }
+
+func Double(x int) int { return double(x) }
+
+func double(x int) int { return 2 * x }
+-- c/c.go --
+package c
+
+const One = 1
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		inline := func(re string) error {
+			loc := env.RegexpSearch("a/a.go", re)
+			actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, action := range actions {
+				if action.Title == "Inline call" {
+					return env.Editor.ApplyCodeAction(env.Ctx, action)
+				}
+			}
+			t.Fatalf("no inline call action at %s", re)
+			return nil
+		}
+
+		// An unexported function of another package cannot be referenced.
+		if err := inline(`Double`); err == nil || !strings.Contains(err.Error(), "unexported b.double") {
+			t.Errorf("inlining Double: got error %v, want unexported reference error", err)
+		}
+
+		// The caller imports a package referenced by the callee.
+		if err := inline(`Inc`); err != nil {
+			t.Fatal(err)
+		}
+		want := `package a
+
+import (
+	"mod.com/b"
+	"mod.com/c"
+)
+
+func _() {
+	_ = 1 + c.One
+	_ = b.Double(1)
+}
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("inlining Inc failed:\n%s", compare.Text(want, got))
+		}
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	. "golang.org/x/tools/gopls/internal/lsp/regtest"
+)
+
+func TestLinkedEditingRange(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+type point struct {
+	x, y int
+	Name string
+}
+
+var global = 1
+
+func f(arg int) int {
+	local := arg + global
+	p := point{x: local, y: 2}
+outer:
+	for {
+		if p.x > 0 {
+			break outer
+		}
+		continue outer
+	}
+	var v interface{} = p
+	switch w := v.(type) {
+	case point:
+		return w.y
+	case int:
+		return w
+	}
+	return local + len(p.Name)
+}
+-- a/b.go --
+package a
+
+func g(p point) int { return p.y }
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		for _, test := range []struct {
+			re   string
+			want int // number of linked ranges
+		}{
+			{`(local) :=`, 3},
+			{`return (local)`, 3},
+			{`f\((arg) int`, 2},
+			{`(outer):`, 3},
+			{`break (outer)`, 3},
+			{`point{(x):`, 3},    // field, key and selector
+			{`(y) int`, 0},       // used in b.go
+			{`(Name) string`, 0}, // exported
+			{`var (global)`, 0},
+			{`switch (w) :=`, 3},
+			{`return (w)\n`, 3},
+			{`(point) struct`, 0},
+		} {
+			loc := env.RegexpSearch("a/a.go", test.re)
+			ranges := env.LinkedEditingRange(loc)
+			var got int
+			if ranges != nil {
+				got = len(ranges.Ranges)
+			}
+			if got != test.want {
+				t.Errorf("linked editing ranges at %q: got %d ranges (%v), want %d", test.re, got, ranges, test.want)
+			}
+			if ranges == nil {
+				continue
+			}
+			// Every range has the text of the identifier at loc.
+			mapper := protocol.NewMapper(loc.URI.SpanURI(), []byte(env.BufferText("a/a.go")))
+			text := func(rng protocol.Range) string {
+				start, end, err := mapper.RangeOffsets(rng)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return string(mapper.Content[start:end])
+			}
+			for _, rng := range ranges.Ranges {
+				if got, want := text(rng), text(loc.Range); got != want {
+					t.Errorf("linked editing range at %q: range %v has text %q, want %q", test.re, rng, got, want)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,318 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/lsp/command"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	. "golang.org/x/tools/gopls/internal/lsp/regtest"
+	"golang.org/x/tools/gopls/internal/lsp/tests/compare"
+)
+
+func TestMoveToNewFile(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+// T is a type.
+type T struct{ n int }
+
+func (t T) Get() int { return t.n }
+
+func F() int { return T{}.Get() }
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `type (T)`)
+		actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var action *protocol.CodeAction
+		for i := range actions {
+			if actions[i].Title == "Move T to file t.go" {
+				action = &actions[i]
+			}
+		}
+		if action == nil {
+			t.Fatalf("no move action among %v", actions)
+		}
+		if err := env.Editor.ApplyCodeAction(env.Ctx, *action); err != nil {
+			t.Fatal(err)
+		}
+		want := `package a
+
+func F() int { return T{}.Get() }
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("a/a.go after move:\n%s", compare.Text(want, got))
+		}
+		want = `package a
+
+// T is a type.
+type T struct{ n int }
+
+func (t T) Get() int { return t.n }
+`
+		if got := env.BufferText("a/t.go"); got != want {
+			t.Errorf("a/t.go after move:\n%s", compare.Text(want, got))
+		}
+	})
+}
+
+func TestMoveToFileNames(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+type Data_test int
+
+type Data_linux int
+
+type Data_amd64 int
+
+type Data int
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		for name, want := range map[string]string{
+			"Data_test":  "", // a test file
+			"Data_linux": "", // built only on linux
+			"Data_amd64": "", // built only on amd64
+			"Data":       "Move Data to file data.go",
+		} {
+			loc := env.RegexpSearch("a/a.go", `type (`+name+`) `)
+			actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			for _, action := range actions {
+				if strings.HasPrefix(action.Title, "Move "+name+" to file") {
+					got = action.Title
+				}
+			}
+			if got != want {
+				t.Errorf("move action for %s = %q, want %q", name, got, want)
+			}
+		}
+	})
+}
+
+func TestMoveToPackage(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+import "mod.com/c"
+
+const K = 2
+
+func Double(x int) int { return K * x * c.One }
+
+func Unexported(x int) int { return x * k }
+
+var k = 1
+-- b/b.go --
+package b
+
+func B() int { return 0 }
+-- c/c.go --
+package c
+
+const One = 1
+-- d/d.go --
+package d
+
+import "mod.com/a"
+
+var _ = a.Double(a.K)
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		move := func(re, dest string) error {
+			loc := env.RegexpSearch("a/a.go", re)
+			cmd, err := command.NewMoveDeclarationCommand("", command.MoveDeclarationArgs{
+				Location:    loc,
+				Destination: env.Sandbox.Workdir.URI(dest),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = env.Editor.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+				Command:   cmd.Command,
+				Arguments: cmd.Arguments,
+			})
+			return err
+		}
+
+		// The moved function refers to an unexported variable.
+		if err := move(`func (Unexported)`, "b/b.go"); err == nil || !strings.Contains(err.Error(), "unexported") {
+			t.Errorf("moving Unexported: got error %v, want unexported reference error", err)
+		}
+
+		// References in the importers of the source package
+		// are qualified by the destination package.
+		if err := move(`func (Double)`, "b/b.go"); err != nil {
+			t.Fatal(err)
+		}
+		want := `package a
+
+const K = 2
+
+func Unexported(x int) int { return x * k }
+
+var k = 1
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("a/a.go after move:\n%s", compare.Text(want, got))
+		}
+		want = `package b
+
+import (
+	"mod.com/a"
+	"mod.com/c"
+)
+
+func B() int { return 0 }
+
+func Double(x int) int { return a.K * x * c.One }
+`
+		if got := env.BufferText("b/b.go"); got != want {
+			t.Errorf("b/b.go after move:\n%s", compare.Text(want, got))
+		}
+		want = `package d
+
+import (
+	"mod.com/a"
+	"mod.com/b"
+)
+
+var _ = b.Double(a.K)
+`
+		if got := env.BufferText("d/d.go"); got != want {
+			t.Errorf("d/d.go after move:\n%s", compare.Text(want, got))
+		}
+	})
+}
+
+func TestMoveImportCycle(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+import "mod.com/b"
+
+func F() int { return b.G() }
+
+func H() int { return 1 }
+
+func X() int { return H() }
+-- b/b.go --
+package b
+
+func G() int { return 0 }
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `func (X)`)
+		cmd, err := command.NewMoveDeclarationCommand("", command.MoveDeclarationArgs{
+			Location:    loc,
+			Destination: env.Sandbox.Workdir.URI("b/b.go"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// b.X would refer to a.H, yet a still imports b.
+		_, err = env.Editor.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+			Command:   cmd.Command,
+			Arguments: cmd.Arguments,
+		})
+		if err == nil || !strings.Contains(err.Error(), "import cycle") {
+			t.Errorf("moving X: got error %v, want import cycle error", err)
+		}
+	})
+}
+
+// TestMoveKeepsFormatting checks that a move does not reformat
+// the code it does not change.
+func TestMoveKeepsFormatting(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func F() int { return 1 }
+
+func  G()  int {return 2}
+-- b/b.go --
+package b
+-- d/d.go --
+package d
+
+import "mod.com/a"
+
+var _ =   a.F()
+
+var   y = 2
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `func (F)`)
+		cmd, err := command.NewMoveDeclarationCommand("", command.MoveDeclarationArgs{
+			Location:    loc,
+			Destination: env.Sandbox.Workdir.URI("b/b.go"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := env.Editor.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+			Command:   cmd.Command,
+			Arguments: cmd.Arguments,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		want := `package a
+
+func  G()  int {return 2}
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("a/a.go after move:\n%s", compare.Text(want, got))
+		}
+		want = `package d
+
+import "mod.com/b"
+
+var _ =   b.F()
+
+var   y = 2
+`
+		if got := env.BufferText("d/d.go"); got != want {
+			t.Errorf("d/d.go after move:\n%s", compare.Text(want, got))
+		}
+	})
+}
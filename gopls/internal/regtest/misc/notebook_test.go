@@ -0,0 +1,108 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	. "golang.org/x/tools/gopls/internal/lsp/regtest"
+)
+
+func TestNotebook(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+`
+	const (
+		decls = `type T struct{ X int }
+
+func (t T) Double() int { return 2 * t.X }
+`
+		body = `%%
+t := T{X: 1}
+_ = t.Double()
+_ = undefined
+`
+	)
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenNotebook("nb.ipynb", decls, body)
+		cell0 := env.Editor.NotebookCellURI("nb.ipynb", 0)
+		cell1 := env.Editor.NotebookCellURI("nb.ipynb", 1)
+		env.AfterChange(
+			NoDiagnostics(ForFile(string(cell0))),
+			Diagnostics(AtPosition(string(cell1), 3, 4), WithMessage("undefined")),
+		)
+
+		at := func(cell protocol.DocumentURI, line, col uint32) protocol.Location {
+			pos := protocol.Position{Line: line, Character: col}
+			return protocol.Location{URI: cell, Range: protocol.Range{Start: pos, End: pos}}
+		}
+
+		// Hover over t.Double in the body.
+		content, loc := env.Hover(at(cell1, 2, 7))
+		if content == nil || !strings.Contains(content.Value, "func (T).Double() int") {
+			t.Errorf("Hover = %v, want the declaration of Double", content)
+		}
+		if want := (protocol.Range{Start: protocol.Position{Line: 2, Character: 6}, End: protocol.Position{Line: 2, Character: 12}}); loc.Range != want {
+			t.Errorf("Hover range = %v, want %v", loc.Range, want)
+		}
+
+		// The definition of T is in the other cell.
+		if got, want := env.GoToDefinition(at(cell1, 1, 5)), at(cell0, 0, 5); got.URI != want.URI || got.Range.Start != want.Range.Start {
+			t.Errorf("GoToDefinition = %v, want %v", got, want)
+		}
+
+		// Complete the selector t.D.
+		env.SetNotebookCellContent("nb.ipynb", 1, "%%\nt := T{X: 1}\n_ = t.D\n")
+		env.AfterChange(NoDiagnostics(ForFile(string(cell0))))
+		completions := env.Completion(at(cell1, 2, 7))
+		if len(completions.Items) == 0 || completions.Items[0].Label != "Double" {
+			t.Fatalf("Completion = %v, want Double", completions.Items)
+		}
+		if got, want := completions.Items[0].TextEdit.Range.Start, (protocol.Position{Line: 2, Character: 6}); got != want {
+			t.Errorf("Completion edit start = %v, want %v", got, want)
+		}
+
+		env.SetNotebookCellContent("nb.ipynb", 1, "%%\nt := T{X: 1}\n_ = t.Double()\n")
+		env.AfterChange(NoDiagnostics(ForFile(string(cell1))))
+
+		env.CloseNotebook("nb.ipynb")
+		env.AfterChange(NoDiagnostics(ForFile(string(cell1))))
+	})
+}
+
+func TestNotebookBodies(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+`
+	const (
+		decls = `func double(x int) int { return 2 * x }
+`
+		body1 = `%%
+_ = double(1)
+`
+		body2 = `%%
+_ = double(2)
+_ = undefined
+`
+	)
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenNotebook("nb.ipynb", decls, body1, body2)
+		cell1 := env.Editor.NotebookCellURI("nb.ipynb", 1)
+		cell2 := env.Editor.NotebookCellURI("nb.ipynb", 2)
+		env.AfterChange(
+			NoDiagnostics(ForFile(string(cell1))),
+			Diagnostics(AtPosition(string(cell2), 2, 4), WithMessage("undefined")),
+			NoDiagnostics(ForFile(string(cell2)), WithMessage("redeclared")),
+		)
+	})
+}
@@ -916,6 +916,112 @@ func main() {
 	})
 }
 
+func TestRenameDirectory_Imports(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/b/b.go --
+package b
+
+import "mod.com/a/b/sub"
+
+const B = sub.S
+-- a/b/sub/sub.go --
+package sub
+
+const S = 1
+-- main.go --
+package main
+
+import (
+	"mod.com/a/b"
+	x "mod.com/a/b/sub"
+)
+
+const _, _ = b.B, x.S
+-- testdata/a/c/b.go --
+package b
+
+import "mod.com/a/c/sub"
+
+const B = sub.S
+-- testdata/main.go --
+package main
+
+import (
+	"mod.com/a/c"
+	x "mod.com/a/c/sub"
+)
+
+const _, _ = b.B, x.S
+`
+	// The packages keep their names, so references are unchanged.
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.RenameFile("a/b", "a/c")
+		env.AfterChange(NoDiagnostics())
+		checkTestdata(t, env)
+	})
+}
+
+func TestRenameDirectory_Modules(t *testing.T) {
+	const files = `
+-- go.work --
+go 1.18
+
+use (
+	.
+	./lib
+)
+-- go.mod --
+module mod.com
+
+go 1.18
+
+require mod.com/lib v0.0.0
+
+replace mod.com/lib => ./lib
+-- main.go --
+package main
+
+import "mod.com/lib"
+
+const _ = lib.L
+-- lib/go.mod --
+module mod.com/lib
+
+go 1.18
+
+replace mod.com/other => ../other
+-- lib/lib.go --
+package lib
+
+const L = 1
+-- testdata/go.work --
+go 1.18
+
+use (
+	.
+	./library
+)
+-- testdata/go.mod --
+module mod.com
+
+go 1.18
+
+require mod.com/lib v0.0.0
+
+replace mod.com/lib => ./library
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.RenameFile("lib", "library")
+		// The import path of the moved module is unchanged.
+		env.RegexpSearch("main.go", `"mod.com/lib"`)
+		checkTestdata(t, env)
+	})
+}
+
 // checkTestdata checks that current buffer contents match their corresponding
 // expected content in the testdata directory.
 func checkTestdata(t *testing.T, env *Env) {
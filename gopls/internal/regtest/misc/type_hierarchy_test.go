@@ -0,0 +1,132 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/gopls/internal/lsp/protocol"
+	. "golang.org/x/tools/gopls/internal/lsp/regtest"
+)
+
+func TestTypeHierarchy(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+type Shape interface {
+	Area() int
+}
+
+type Named interface {
+	Shape
+	Name() string
+}
+
+type Square struct{ side int }
+
+func (s Square) Area() int { return s.side * s.side }
+-- b/b.go --
+package b
+
+import "mod.com/a"
+
+type Colored struct {
+	a.Square
+	color int
+}
+
+type Wrapper[T any] struct {
+	*a.Square
+	v T
+}
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		env.OpenFile("b/b.go")
+
+		prepare := func(loc protocol.Location) protocol.TypeHierarchyItem {
+			t.Helper()
+			var params protocol.TypeHierarchyPrepareParams
+			params.TextDocument.URI = loc.URI
+			params.Position = loc.Range.Start
+			items, err := env.Editor.Server.PrepareTypeHierarchy(env.Ctx, &params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("PrepareTypeHierarchy returned %d items, want 1", len(items))
+			}
+			return items[0]
+		}
+		summarize := func(items []protocol.TypeHierarchyItem, err error) []string {
+			t.Helper()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var res []string
+			for _, item := range items {
+				res = append(res, fmt.Sprintf("%s.%s (%v)", item.Detail, item.Name, item.Kind))
+			}
+			return res
+		}
+		supertypes := func(item protocol.TypeHierarchyItem) []string {
+			t.Helper()
+			return summarize(env.Editor.Server.Supertypes(env.Ctx, &protocol.TypeHierarchySupertypesParams{Item: item}))
+		}
+		subtypes := func(item protocol.TypeHierarchyItem) []string {
+			t.Helper()
+			return summarize(env.Editor.Server.Subtypes(env.Ctx, &protocol.TypeHierarchySubtypesParams{Item: item}))
+		}
+
+		shape := prepare(env.RegexpSearch("a/a.go", "Shape interface"))
+		named := prepare(env.RegexpSearch("a/a.go", "Named interface"))
+		// Prepare on a reference yields the item for the declaration.
+		square := prepare(env.RegexpSearch("b/b.go", `a\.(Square)`))
+		colored := prepare(env.RegexpSearch("b/b.go", "Colored struct"))
+
+		if got, want := summarize([]protocol.TypeHierarchyItem{square}, nil), []string{"mod.com/a.Square (Struct)"}; !cmp.Equal(got, want) {
+			t.Errorf("PrepareTypeHierarchy(Square) = %v, want %v", got, want)
+		}
+
+		for _, test := range []struct {
+			name string
+			got  []string
+			want []string
+		}{
+			{"Supertypes(Shape)", supertypes(shape), nil},
+			{"Subtypes(Shape)", subtypes(shape), []string{
+				"mod.com/a.Named (Interface)",
+				"mod.com/a.Square (Struct)",
+				"mod.com/b.Colored (Struct)",
+				"mod.com/b.Wrapper[T] (Struct)",
+			}},
+			{"Supertypes(Named)", supertypes(named), []string{
+				"mod.com/a.Shape (Interface)",
+			}},
+			{"Supertypes(Square)", supertypes(square), []string{
+				"mod.com/a.Shape (Interface)",
+			}},
+			{"Subtypes(Square)", subtypes(square), []string{
+				"mod.com/b.Colored (Struct)",
+				"mod.com/b.Wrapper[T] (Struct)",
+			}},
+			{"Supertypes(Colored)", supertypes(colored), []string{
+				"mod.com/a.Shape (Interface)",
+				"mod.com/a.Square (Struct)",
+			}},
+		} {
+			if diff := cmp.Diff(test.want, test.got); diff != "" {
+				t.Errorf("%s: unexpected result (-want +got):\n%s", test.name, diff)
+			}
+		}
+	})
+}
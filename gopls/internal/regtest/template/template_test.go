@@ -229,3 +229,36 @@ func shorten(fn protocol.DocumentURI) string {
 }
 
 // Hover needs tests
+
+func TestLinkedEditingRange(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.12
+-- a.tmpl --
+{{define "zzz"}}z{{end}}
+{{template "zzz"}}
+{{template "yyy"}}
+-- b.tmpl --
+{{define "yyy"}}y{{end}}
+`
+	WithOptions(
+		Settings{
+			"templateExtensions": []string{"tmpl"},
+		},
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a.tmpl")
+		ranges := env.LinkedEditingRange(env.RegexpSearch("a.tmpl", `template "(zzz)"`))
+		if ranges == nil || len(ranges.Ranges) != 2 {
+			t.Fatalf("got linked editing ranges %v for zzz, want the definition and the invocation", ranges)
+		}
+		if got, want := ranges.Ranges[0], env.RegexpSearch("a.tmpl", `define "(zzz)"`).Range; got != want {
+			t.Errorf("got first range %v, want %v", got, want)
+		}
+		// yyy is defined in another file.
+		if ranges := env.LinkedEditingRange(env.RegexpSearch("a.tmpl", `(yyy)`)); ranges != nil {
+			t.Errorf("got linked editing ranges %v for yyy, want none", ranges)
+		}
+	})
+}
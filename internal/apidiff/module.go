@@ -0,0 +1,318 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package apidiff
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// A Module is a version of a module, as compared by ModuleChanges.
+type Module struct {
+	Path     string           // module path
+	Go       string           // version of the go directive, or "" if none
+	Require  []module.Version // requirements of the go.mod file
+	Packages []*types.Package // packages whose APIs to compare
+}
+
+// ModuleReport describes the changes detected by ModuleChanges.
+type ModuleReport struct {
+	// Changes holds the changes of the module as a whole, such as the
+	// removal of packages and changes of its go.mod file.
+	Changes []Change
+
+	// Packages holds the reports of the packages present in both
+	// versions of the module, by import path in the new version.
+	Packages []PackageReport
+}
+
+// A PackageReport describes the changes of a package of a module.
+type PackageReport struct {
+	Path string
+	Report
+}
+
+// ModuleChanges reports on the differences between the old and new
+// versions of a module.
+//
+// It pairs the packages of the two versions by their import paths
+// relative to the module paths, so that the packages of a module whose
+// path changes, as at a new major version, correspond, and reports the
+// changes of each pair as Changes does. The removal of a package is an
+// incompatible change, and the addition of one a compatible change.
+//
+// It also reports the changes of the go.mod file that affect the users
+// of the module: raising the Go version of the go directive is
+// incompatible, since the module no longer supports the earlier Go
+// releases, and lowering it is compatible. A requirement of a new major
+// version of a dependency is reported as a compatible change, since the
+// major versions of a module may coexist in a build; any resulting
+// change of the API of a package is reported among its changes.
+func ModuleChanges(old, new *Module) ModuleReport {
+	var r ModuleReport
+	change := func(compatible bool, format string, args ...interface{}) {
+		r.Changes = append(r.Changes, Change{Message: fmt.Sprintf(format, args...), Compatible: compatible})
+	}
+
+	if old.Path != new.Path {
+		change(false, "module path: changed from %s to %s", old.Path, new.Path)
+	}
+
+	// Packages.
+	oldpkgs := packagesByRelPath(old)
+	newpkgs := packagesByRelPath(new)
+	for _, rel := range sortedPaths(oldpkgs) {
+		oldpkg := oldpkgs[rel]
+		newpkg, ok := newpkgs[rel]
+		if !ok {
+			change(false, "package %s: removed", oldpkg.Path())
+			continue
+		}
+		if report := Changes(oldpkg, newpkg); len(report.Changes) > 0 {
+			r.Packages = append(r.Packages, PackageReport{Path: newpkg.Path(), Report: report})
+		}
+	}
+	for _, rel := range sortedPaths(newpkgs) {
+		if _, ok := oldpkgs[rel]; !ok {
+			change(true, "package %s: added", newpkgs[rel].Path())
+		}
+	}
+
+	// The go directive.
+	oldgo, newgo := goVersion(old.Go), goVersion(new.Go)
+	if cmp := compareGo(oldgo, newgo); cmp != 0 {
+		change(cmp > 0, "go directive: changed from %s to %s", oldgo, newgo)
+	}
+
+	// Requirements.
+	oldmajors := requiredMajors(old.Require)
+	newmajors := requiredMajors(new.Require)
+	var prefixes []string
+	for prefix := range oldmajors {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		if newmajor, ok := newmajors[prefix]; ok && newmajor != oldmajors[prefix] {
+			change(true, "requirement %s: major version changed from %s to %s", prefix, oldmajors[prefix], newmajor)
+		}
+	}
+	return r
+}
+
+// packagesByRelPath returns the packages of the module by import path
+// relative to the module path.
+func packagesByRelPath(m *Module) map[string]*types.Package {
+	pkgs := make(map[string]*types.Package)
+	for _, pkg := range m.Packages {
+		rel := "."
+		if pkg.Path() != m.Path {
+			rel = strings.TrimPrefix(pkg.Path(), m.Path+"/")
+		}
+		pkgs[rel] = pkg
+	}
+	return pkgs
+}
+
+// goVersion returns the Go version of a go directive. The go command
+// assumes Go 1.16 for a go.mod file without a go directive.
+func goVersion(v string) string {
+	if v == "" {
+		return "1.16"
+	}
+	return v
+}
+
+// compareGo returns -1, 0, or +1 according as the Go version x is
+// less than, equal to, or greater than y. A version without a patch
+// number, such as 1.21, denotes the same release as 1.21.0, which
+// follows its prereleases, such as 1.21rc1 and 1.21beta1. Malformed
+// versions compare as strings, after all well-formed ones.
+func compareGo(x, y string) int {
+	vx, okx := parseGo(x)
+	vy, oky := parseGo(y)
+	switch {
+	case !okx && !oky:
+		return strings.Compare(x, y)
+	case !okx:
+		return +1
+	case !oky:
+		return -1
+	}
+	for i := range vx {
+		if vx[i] != vy[i] {
+			if vx[i] < vy[i] {
+				return -1
+			}
+			return +1
+		}
+	}
+	return 0
+}
+
+// Kinds of Go release, in order.
+const (
+	goBeta = iota
+	goRC
+	goRelease
+)
+
+// parseGo parses a Go version such as 1, 1.21, 1.21.3, 1.21rc1, or
+// 1.21beta1, returning its major and minor numbers, kind of release,
+// and prerelease or patch number, in order of precedence.
+func parseGo(v string) (parts [4]int, ok bool) {
+	major, rest, hasMinor := strings.Cut(v, ".")
+	if parts[0], ok = parseGoNum(major); !ok {
+		return parts, false
+	}
+	parts[2] = goRelease
+	if !hasMinor {
+		return parts, true
+	}
+	minor, patch, hasPatch := strings.Cut(rest, ".")
+	if hasPatch {
+		if parts[3], ok = parseGoNum(patch); !ok {
+			return parts, false
+		}
+	} else if i := strings.IndexAny(minor, "br"); i >= 0 {
+		switch pre := minor[i:]; {
+		case strings.HasPrefix(pre, "beta"):
+			parts[2] = goBeta
+			parts[3], ok = parseGoNum(pre[len("beta"):])
+		case strings.HasPrefix(pre, "rc"):
+			parts[2] = goRC
+			parts[3], ok = parseGoNum(pre[len("rc"):])
+		default:
+			ok = false
+		}
+		if !ok {
+			return parts, false
+		}
+		minor = minor[:i]
+	}
+	parts[1], ok = parseGoNum(minor)
+	return parts, ok
+}
+
+// parseGoNum parses a decimal number of a Go version.
+func parseGoNum(s string) (int, bool) {
+	if s == "" || len(s) > 1 && s[0] == '0' || len(s) > 9 {
+		return 0, false
+	}
+	n := 0
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
+// requiredMajors returns the highest major version of each required
+// module, by module path without major version suffix.
+func requiredMajors(reqs []module.Version) map[string]string {
+	majors := make(map[string]string)
+	for _, req := range reqs {
+		prefix, _, ok := module.SplitPathVersion(req.Path)
+		if !ok {
+			continue
+		}
+		major := semver.Major(req.Version)
+		if major == "" {
+			continue
+		}
+		if cur, ok := majors[prefix]; !ok || semver.Compare(cur, major) < 0 {
+			majors[prefix] = major
+		}
+	}
+	return majors
+}
+
+// sortedPaths returns the keys of pkgs in order.
+func sortedPaths(pkgs map[string]*types.Package) []string {
+	paths := make([]string, 0, len(pkgs))
+	for path := range pkgs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Compatible reports whether all the changes of the module are
+// compatible.
+func (r ModuleReport) Compatible() bool {
+	if len(Report{Changes: r.Changes}.messages(false)) > 0 {
+		return false
+	}
+	for _, p := range r.Packages {
+		if len(p.messages(false)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (r ModuleReport) String() string {
+	var buf bytes.Buffer
+	if err := r.Text(&buf); err != nil {
+		return fmt.Sprintf("!!%v", err)
+	}
+	return buf.String()
+}
+
+// Text writes the changes of the module as a whole, followed by those
+// of each package.
+func (r ModuleReport) Text(w io.Writer) error {
+	return r.text(w, false)
+}
+
+// TextIncompatible writes the incompatible changes of the module as a
+// whole, followed by those of each package.
+func (r ModuleReport) TextIncompatible(w io.Writer) error {
+	return r.text(w, true)
+}
+
+func (r ModuleReport) text(w io.Writer, incompatibleOnly bool) error {
+	write := func(header string, report Report) error {
+		msgs := report.messages(false)
+		if !incompatibleOnly {
+			msgs = append(msgs, report.messages(true)...)
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+		if _, err := fmt.Fprintf(w, "%s:\n", header); err != nil {
+			return err
+		}
+		var err error
+		if incompatibleOnly {
+			err = report.TextIncompatible(w, true)
+		} else {
+			err = report.Text(w)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w)
+		return err
+	}
+	if err := write("Module", Report{Changes: r.Changes}); err != nil {
+		return err
+	}
+	for _, p := range r.Packages {
+		if err := write("Package "+p.Path, p.Report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
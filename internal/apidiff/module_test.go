@@ -0,0 +1,157 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package apidiff
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/module"
+)
+
+// checkPackage type-checks a package, which must not import others,
+// from the source.
+func checkPackage(t *testing.T, path, src string) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := new(types.Config).Check(path, fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func TestModuleChanges(t *testing.T) {
+	old := &Module{
+		Path: "example.com/m",
+		Go:   "1.18",
+		Require: []module.Version{
+			{Path: "example.com/a", Version: "v1.2.0"},
+			{Path: "example.com/b", Version: "v1.0.0"},
+			{Path: "example.com/c/v2", Version: "v2.1.0"},
+		},
+		Packages: []*types.Package{
+			checkPackage(t, "example.com/m", "package m; func F() {}"),
+			checkPackage(t, "example.com/m/p", "package p; const C = 1"),
+			checkPackage(t, "example.com/m/removed", "package removed"),
+		},
+	}
+	new := &Module{
+		Path: "example.com/m/v2",
+		Go:   "1.20",
+		Require: []module.Version{
+			{Path: "example.com/a/v3", Version: "v3.0.0"},
+			{Path: "example.com/b", Version: "v1.5.0"},
+			{Path: "example.com/c", Version: "v1.0.0"},
+		},
+		Packages: []*types.Package{
+			checkPackage(t, "example.com/m/v2", "package m; func F(int) {}"),
+			checkPackage(t, "example.com/m/v2/p", "package p; const C = 1"),
+			checkPackage(t, "example.com/m/v2/added", "package added"),
+		},
+	}
+
+	report := ModuleChanges(old, new)
+	if report.Compatible() {
+		t.Errorf("Compatible() = true, want false")
+	}
+	const want = `Module:
+Incompatible changes:
+- module path: changed from example.com/m to example.com/m/v2
+- package example.com/m/removed: removed
+- go directive: changed from 1.18 to 1.20
+Compatible changes:
+- package example.com/m/v2/added: added
+- requirement example.com/a: major version changed from v1 to v3
+- requirement example.com/c: major version changed from v2 to v1
+
+Package example.com/m/v2:
+Incompatible changes:
+- F: changed from func() to func(int)
+
+`
+	if got := report.String(); got != want {
+		t.Errorf("got report:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Lowering the go directive is compatible.
+	old.Path, old.Go, new.Go = new.Path, "1.20", ""
+	old.Packages, old.Require, new.Require = new.Packages, nil, nil
+	report = ModuleChanges(old, new)
+	if !report.Compatible() {
+		t.Errorf("Compatible() = false, want true")
+	}
+	if got, want := report.String(), "go directive: changed from 1.20 to 1.16"; !strings.Contains(got, want) {
+		t.Errorf("got report:\n%s\nwant %q", got, want)
+	}
+
+	var buf strings.Builder
+	if err := ModuleChanges(old, new).TextIncompatible(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("TextIncompatible wrote %q, want nothing", buf.String())
+	}
+}
+
+func TestModuleChangesGoDirective(t *testing.T) {
+	for _, test := range []struct {
+		old, new string
+		want     string // change, or "" if none
+		compat   bool
+	}{
+		{"1.21", "1.21.0", "", true},
+		{"1.21.0", "1.21", "", true},
+		{"1.16", "", "", true},
+		{"1.21rc1", "1.21.0", "go directive: changed from 1.21rc1 to 1.21.0", false},
+		{"1.21rc2", "1.21rc1", "go directive: changed from 1.21rc2 to 1.21rc1", true},
+		{"1.21beta1", "1.21rc1", "go directive: changed from 1.21beta1 to 1.21rc1", false},
+		{"1.9", "1.10", "go directive: changed from 1.9 to 1.10", false},
+	} {
+		old := &Module{Path: "example.com/m", Go: test.old}
+		new := &Module{Path: "example.com/m", Go: test.new}
+		report := ModuleChanges(old, new)
+		if got := report.String(); !strings.Contains(got, test.want) || test.want == "" && len(report.Changes) > 0 {
+			t.Errorf("go %s -> go %s: got report:\n%s\nwant %q", test.old, test.new, got, test.want)
+		}
+		if got := report.Compatible(); got != test.compat {
+			t.Errorf("go %s -> go %s: Compatible() = %t, want %t", test.old, test.new, got, test.compat)
+		}
+	}
+}
+
+func TestCompareGo(t *testing.T) {
+	for _, test := range []struct {
+		x, y string
+		want int
+	}{
+		{"1.21", "1.21.0", 0},
+		{"1.21", "1.21.1", -1},
+		{"1.21rc1", "1.21", -1},
+		{"1.21rc1", "1.21rc2", -1},
+		{"1.21beta1", "1.21rc1", -1},
+		{"1.20.9", "1.21rc1", -1},
+		{"1.9", "1.10", -1},
+		{"1", "1.0", 0},
+		{"2", "1.21", +1},
+		{"1.21", "1.21x", -1}, // malformed versions sort last
+		{"1.21x", "1.21x", 0},
+	} {
+		if got := compareGo(test.x, test.y); got != test.want {
+			t.Errorf("compareGo(%q, %q) = %d, want %d", test.x, test.y, got, test.want)
+		}
+		if got := compareGo(test.y, test.x); got != -test.want {
+			t.Errorf("compareGo(%q, %q) = %d, want %d", test.y, test.x, got, -test.want)
+		}
+	}
+}
@@ -75,6 +75,39 @@ func TestNRandom(t *testing.T) {
 	}
 }
 
+func TestHistogram(t *testing.T) {
+	for _, tc := range difftest.TestCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			checkHistogram(t, tc.In, tc.Out)
+		})
+	}
+}
+
+// checkHistogram checks that the edits computed by the histogram
+// algorithm transform a into b and replace whole lines.
+func checkHistogram(t *testing.T, a, b string) {
+	t.Helper()
+	edits := diff.Strings(a, b, diff.Histogram)
+	got, err := diff.Apply(a, edits)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got != b {
+		t.Fatalf("applying histogram diff(%q, %q) gives %q; edits=%v", a, b, got, edits)
+	}
+	lineStart := func(offset int) bool {
+		return offset == 0 || offset == len(a) || a[offset-1] == '\n'
+	}
+	for _, edit := range edits {
+		if !lineStart(edit.Start) || !lineStart(edit.End) {
+			t.Errorf("histogram diff(%q, %q) = %v, not whole lines", a, b, edits)
+		}
+	}
+	if bytesEdits := diff.Bytes([]byte(a), []byte(b), diff.Histogram); !reflect.DeepEqual(bytesEdits, edits) {
+		t.Errorf("Bytes = %v, Strings = %v", bytesEdits, edits)
+	}
+}
+
 // $ go test -fuzz=FuzzRoundTrip ./internal/diff
 func FuzzRoundTrip(f *testing.F) {
 	f.Fuzz(func(t *testing.T, a, b string) {
@@ -89,6 +122,7 @@ func FuzzRoundTrip(f *testing.F) {
 		if got != b {
 			t.Fatalf("applying diff(%q, %q) gives %q; edits=%v", a, b, got, edits)
 		}
+		checkHistogram(t, a, b)
 	})
 }
 
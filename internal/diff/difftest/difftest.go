@@ -262,6 +262,17 @@ var TestCases = []struct {
  A
 `,
 	},
+	{
+		Name:  "delete_all",
+		In:    "A\nB\n",
+		Out:   "",
+		Edits: []diff.Edit{{Start: 0, End: 4, New: ""}},
+		Unified: UnifiedPrefix + `
+@@ -1,2 +0,0 @@
+-A
+-B
+`[1:],
+	},
 }
 
 func DiffTest(t *testing.T, compute func(before, after string) []diff.Edit) {
@@ -0,0 +1,169 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"go/scanner"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/internal/diff/lcs"
+)
+
+// GoTokens computes the differences between two Go source files by
+// comparing their tokens, including comments, rather than their runes.
+// The resulting edits transform before into after exactly.
+//
+// Each edit either replaces a sequence of whole tokens, together with
+// any changed white space around them, or replaces only the white space
+// between two tokens common to both files. So the edits for reformatted
+// source change only white space, and those for other changes align on
+// token boundaries instead of sharing fragments of unrelated tokens.
+//
+// If either file cannot be tokenized, GoTokens returns Strings(before,
+// after).
+func GoTokens(before, after string) []Edit {
+	return goTokens(before, after, false)
+}
+
+// GoTokensIgnoringSpace is like GoTokens, but omits the edits that
+// change only the white space between two tokens. The edits transform
+// before into a file that has the same tokens as after, but the layout
+// of before where the two differ only in white space.
+func GoTokensIgnoringSpace(before, after string) []Edit {
+	return goTokens(before, after, true)
+}
+
+// A goToken is a token of a Go source file, denoted by its byte offsets.
+type goToken struct{ start, end int }
+
+func goTokens(before, after string, ignoreSpace bool) []Edit {
+	if before == after {
+		return nil // common case
+	}
+	a, aok := scanGoTokens(before)
+	b, bok := scanGoTokens(after)
+	if !aok || !bok {
+		return Strings(before, after)
+	}
+
+	// Diff the sequences of tokens, each represented by a rune
+	// that identifies its text.
+	ids := make(map[string]rune)
+	toRunes := func(src string, toks []goToken) []rune {
+		runes := make([]rune, len(toks))
+		for i, tok := range toks {
+			text := src[tok.start:tok.end]
+			if tok.start == tok.end {
+				text = "\n" // an automatic semicolon
+			}
+			id, ok := ids[text]
+			if !ok {
+				id = rune(len(ids))
+				ids[text] = id
+			}
+			runes[i] = id
+		}
+		return runes
+	}
+	diffs := lcs.DiffRunes(toRunes(before, a), toRunes(after, b))
+
+	// The tokens of before between the diffs match those of after.
+	// The region from the end of token i-1 to the start of token j is
+	// spanned by a "gap" if i == j and otherwise contains tokens.
+	regionStart := func(toks []goToken, i int) int {
+		if i == 0 {
+			return 0
+		}
+		return toks[i-1].end
+	}
+	regionEnd := func(src string, toks []goToken, j int) int {
+		if j == len(toks) {
+			return len(src)
+		}
+		return toks[j].start
+	}
+	var edits []Edit
+	edit := func(ai, aj, bi, bj int) {
+		if ignoreSpace && ai == aj && bi == bj {
+			return
+		}
+		start, end := regionStart(a, ai), regionEnd(before, a, aj)
+		new := after[regionStart(b, bi):regionEnd(after, b, bj)]
+		// Trim the white space common to the start or end of both.
+		for start < end && new != "" && before[start] == new[0] && isSpace(new[0]) {
+			start++
+			new = new[1:]
+		}
+		for start < end && new != "" && before[end-1] == new[len(new)-1] && isSpace(new[len(new)-1]) {
+			end--
+			new = new[:len(new)-1]
+		}
+		if before[start:end] != new {
+			edits = append(edits, Edit{Start: start, End: end, New: new})
+		}
+	}
+	i, j := 0, 0 // index of the next token of before and after
+	for _, d := range diffs {
+		for ; i < d.Start; i, j = i+1, j+1 {
+			edit(i, i, j, j) // the gap before matching tokens
+		}
+		edit(d.Start, d.End, d.ReplStart, d.ReplEnd)
+		i, j = d.End+1, d.ReplEnd+1
+	}
+	for ; i <= len(a); i, j = i+1, j+1 {
+		edit(i, i, j, j)
+	}
+	return edits
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// scanGoTokens returns the tokens of the Go source file, or false if it
+// contains invalid tokens. An automatic semicolon is an empty token at
+// the end of its line.
+func scanGoTokens(src string) ([]goToken, bool) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+
+	var toks []goToken
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		start := file.Offset(pos)
+		var end int
+		switch {
+		case tok == token.SEMICOLON && lit == "\n":
+			end = start // automatic
+		case tok == token.COMMENT && strings.HasPrefix(lit, "//"):
+			// The literal of a comment omits carriage returns.
+			end = strings.IndexByte(src[start:], '\n')
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += start
+			}
+			if end > start && src[end-1] == '\r' {
+				end--
+			}
+		case tok == token.COMMENT:
+			end = start + strings.Index(src[start:], "*/") + len("*/")
+		case tok == token.STRING && strings.HasPrefix(lit, "`"):
+			end = start + 1 + strings.IndexByte(src[start+1:], '`') + 1
+		case lit != "":
+			end = start + len(lit)
+		default:
+			end = start + len(tok.String())
+		}
+		toks = append(toks, goToken{start, end})
+	}
+	return toks, s.ErrorCount == 0
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/diff/difftest"
+)
+
+func TestGoTokens(t *testing.T) {
+	for _, test := range []struct {
+		name, before, after string
+		want, ignoringSpace []diff.Edit
+	}{
+		{
+			name:   "reindent",
+			before: "func f() {\n  x := 1 // one\n    return\n}\n",
+			after:  "func f() {\n\tx := 1 // one\n\treturn\n}\n",
+			want: []diff.Edit{
+				{Start: 11, End: 13, New: "\t"},
+				{Start: 27, End: 31, New: "\t"},
+			},
+		},
+		{
+			name:          "rename",
+			before:        "var gord = 1\n",
+			after:         "var gourd  =  1\n",
+			want:          []diff.Edit{{Start: 4, End: 8, New: "gourd "}, {Start: 11, End: 11, New: " "}},
+			ignoringSpace: []diff.Edit{{Start: 4, End: 8, New: "gourd "}},
+		},
+		{
+			name:          "insert",
+			before:        "f(a, b)\n",
+			after:         "f(a, x, b)\n",
+			want:          []diff.Edit{{Start: 3, End: 3, New: ", x"}},
+			ignoringSpace: []diff.Edit{{Start: 3, End: 3, New: ", x"}},
+		},
+		{
+			name:          "semicolon",
+			before:        "a := 1; b := 2\n",
+			after:         "a := 1\nb := 2\n",
+			want:          []diff.Edit{{Start: 6, End: 8, New: "\n"}},
+			ignoringSpace: []diff.Edit{{Start: 6, End: 8, New: "\n"}},
+		},
+		{
+			name:          "comment",
+			before:        "x /* one */ + y // two\r\n",
+			after:         "x /* One */ + y\t// two\r\n",
+			want:          []diff.Edit{{Start: 2, End: 11, New: "/* One */"}, {Start: 15, End: 16, New: "\t"}},
+			ignoringSpace: []diff.Edit{{Start: 2, End: 11, New: "/* One */"}},
+		},
+		{
+			name:          "invalid",
+			before:        "x := `unterminated\n",
+			after:         "x := `unterminated!\n",
+			want:          []diff.Edit{{Start: 18, End: 18, New: "!"}},
+			ignoringSpace: []diff.Edit{{Start: 18, End: 18, New: "!"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := diff.GoTokens(test.before, test.after)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GoTokens = %v, want %v", got, test.want)
+			}
+			checkApply(t, test.before, test.after, got)
+
+			got = diff.GoTokensIgnoringSpace(test.before, test.after)
+			if !reflect.DeepEqual(got, test.ignoringSpace) {
+				t.Errorf("GoTokensIgnoringSpace = %v, want %v", got, test.ignoringSpace)
+			}
+		})
+	}
+}
+
+func TestGoTokensDiffTest(t *testing.T) {
+	for _, tc := range difftest.TestCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			checkApply(t, tc.In, tc.Out, diff.GoTokens(tc.In, tc.Out))
+		})
+	}
+}
+
+// checkApply checks that the edits transform before into after.
+func checkApply(t *testing.T, before, after string, edits []diff.Edit) {
+	t.Helper()
+	got, err := diff.Apply(before, edits)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got != after {
+		t.Fatalf("applying %v to %q gives %q, want %q", edits, before, got, after)
+	}
+}
+
+// $ go test -fuzz=FuzzGoTokens ./internal/diff
+func FuzzGoTokens(f *testing.F) {
+	f.Add("package p\n\nfunc f() {\n  return\n}\n", "package p\n\nfunc f() {\n\treturn // x\n}\n")
+	f.Fuzz(func(t *testing.T, a, b string) {
+		if !utf8.ValidString(a) || !utf8.ValidString(b) {
+			return // inputs must be text
+		}
+		checkApply(t, a, b, diff.GoTokens(a, b))
+		diff.GoTokensIgnoringSpace(a, b)
+	})
+}
+
+// reformatted returns a Go source file of this package, and a version
+// of it with different indentation and one renamed identifier.
+func reformatted(b *testing.B) (string, string) {
+	data, err := ioutil.ReadFile("unified.go")
+	if err != nil {
+		b.Fatal(err)
+	}
+	before := string(data)
+	after := strings.ReplaceAll(before, "\t", "    ")
+	after = strings.ReplaceAll(after, "addEqualLines", "addEqual")
+	return before, after
+}
+
+// reportEdits reports the number of edits and of the bytes they delete
+// and insert, since a diff that gives up on aligning the texts reports
+// few but large edits.
+func reportEdits(b *testing.B, edits []diff.Edit) {
+	n := 0
+	for _, edit := range edits {
+		n += edit.End - edit.Start + len(edit.New)
+	}
+	b.ReportMetric(float64(len(edits)), "edits")
+	b.ReportMetric(float64(n), "edited-bytes")
+}
+
+func BenchmarkGoTokens(b *testing.B) {
+	before, after := reformatted(b)
+	var edits []diff.Edit
+	for i := 0; i < b.N; i++ {
+		edits = diff.GoTokens(before, after)
+	}
+	reportEdits(b, edits)
+}
+
+func BenchmarkGoTokensStrings(b *testing.B) {
+	before, after := reformatted(b)
+	var edits []diff.Edit
+	for i := 0; i < b.N; i++ {
+		edits = diff.Strings(before, after)
+	}
+	reportEdits(b, edits)
+}
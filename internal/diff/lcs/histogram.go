@@ -0,0 +1,179 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lcs
+
+// DiffLinesHistogram returns the differences between two sequences of
+// lines, computed by the histogram diff algorithm of git.
+//
+// The histogram algorithm extends patience diff: it aligns the
+// sequences on the longest common region that contains a line with the
+// fewest occurrences in A, and recurs on the regions before and after
+// it. Lines that occur more than a few dozen times in A, such as blank
+// lines and closing braces, are never chosen as anchors, so the
+// differences of files with many repeated lines, such as generated
+// code, follow the unique lines that a reader would match. A region
+// without such a line is diffed by the bounded algorithm of DiffStrings.
+//
+// The search for anchors may take time quadratic in the number of
+// lines, as when many lines occur a few times each, so it is limited
+// to a budget of work proportional to the lengths of the sequences.
+// Once the budget is spent, the remaining regions are diffed by the
+// bounded algorithm.
+func DiffLinesHistogram(a, b []string) []Diff {
+	h := newHistogram(a, b)
+	h.diff(0, len(a), 0, len(b))
+	return h.diffs
+}
+
+// newHistogram returns the initial state of DiffLinesHistogram.
+func newHistogram(a, b []string) *histogram {
+	// Represent each line by a small integer.
+	ids := make(map[string]int)
+	toIDs := func(lines []string) []int {
+		res := make([]int, len(lines))
+		for i, l := range lines {
+			id, ok := ids[l]
+			if !ok {
+				id = len(ids)
+				ids[l] = id
+			}
+			res[i] = id
+		}
+		return res
+	}
+	h := &histogram{
+		lines: linesSeqs{a, b},
+		a:     toIDs(a),
+		b:     toIDs(b),
+		next:  make([]int, len(a)),
+		work:  workPerLine * (len(a) + len(b)),
+	}
+	h.count = make([]int, len(ids))
+	h.head = make([]int, len(ids))
+	for i := range h.head {
+		h.head[i] = -1
+	}
+	return h
+}
+
+// maxChain is the largest number of occurrences in A of a line that
+// may anchor a region, as in git.
+const maxChain = 64
+
+// workPerLine is the budget of the search for anchors, in steps per
+// line of the two sequences.
+const workPerLine = 1024
+
+// histogram holds the state of DiffLinesHistogram.
+type histogram struct {
+	lines linesSeqs
+	a, b  []int // the lines, by id
+	diffs []Diff
+
+	// The occurrences of each line in the current region of a, by id:
+	// their number, and a list of their indexes linked by next.
+	count, head, next []int
+
+	work int // remaining budget of steps of the search for anchors
+}
+
+// diff appends the differences between a[alo:ahi] and b[blo:bhi].
+func (h *histogram) diff(alo, ahi, blo, bhi int) {
+	a, b := h.a, h.b
+	for alo < ahi && blo < bhi && a[alo] == b[blo] {
+		alo++
+		blo++
+	}
+	for alo < ahi && blo < bhi && a[ahi-1] == b[bhi-1] {
+		ahi--
+		bhi--
+	}
+	if alo == ahi || blo == bhi {
+		if alo < ahi || blo < bhi {
+			h.diffs = append(h.diffs, Diff{alo, ahi, blo, bhi})
+		}
+		return
+	}
+	if h.work <= 0 {
+		h.fallback(alo, ahi, blo, bhi)
+		return
+	}
+
+	// Index the occurrences of each line of a[alo:ahi].
+	count, head, next := h.count, h.head, h.next
+	h.work -= ahi - alo + bhi - blo
+	for i := ahi - 1; i >= alo; i-- {
+		next[i] = head[a[i]]
+		head[a[i]] = i
+		count[a[i]]++
+	}
+
+	// Find the longest common region that contains a line with the
+	// fewest occurrences.
+	var (
+		bestA, bestB, bestLen int
+		bestCount             = maxChain
+	)
+	for j := blo; j < bhi && h.work > 0; {
+		jnext := j + 1
+		if count[b[j]] > bestCount {
+			j = jnext
+			continue
+		}
+		for i := head[b[j]]; i >= 0; i = next[i] {
+			// Extend the match of a[i] and b[j] to a region,
+			// whose count is the least of its lines.
+			n := count[a[i]]
+			as, bs := i, j
+			for as > alo && bs > blo && a[as-1] == b[bs-1] {
+				as--
+				bs--
+				if count[a[as]] < n {
+					n = count[a[as]]
+				}
+			}
+			ae, be := i+1, j+1
+			for ae < ahi && be < bhi && a[ae] == b[be] {
+				if count[a[ae]] < n {
+					n = count[a[ae]]
+				}
+				ae++
+				be++
+			}
+			h.work -= ae - as
+			if be > jnext {
+				jnext = be // the lines of b up to be are in this region
+			}
+			if size := ae - as; n < bestCount || n == bestCount && size > bestLen {
+				bestA, bestB, bestLen, bestCount = as, bs, size, n
+			}
+		}
+		j = jnext
+	}
+
+	for i := alo; i < ahi; i++ {
+		head[a[i]] = -1
+		count[a[i]] = 0
+	}
+
+	if bestLen == 0 || h.work <= 0 {
+		// No line of a that occurs few times is in b,
+		// or the search ran out of budget.
+		h.fallback(alo, ahi, blo, bhi)
+		return
+	}
+	h.diff(alo, bestA, blo, bestB)
+	h.diff(bestA+bestLen, ahi, bestB+bestLen, bhi)
+}
+
+// fallback appends the differences between a[alo:ahi] and b[blo:bhi]
+// computed by the bounded algorithm of DiffStrings.
+func (h *histogram) fallback(alo, ahi, blo, bhi int) {
+	seqs := linesSeqs{h.lines.a[alo:ahi], h.lines.b[blo:bhi]}
+	diffs, _ := compute(seqs, twosided, maxDiffs/2)
+	for _, d := range diffs {
+		h.diffs = append(h.diffs, Diff{d.Start + alo, d.End + alo, d.ReplStart + blo, d.ReplEnd + blo})
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lcs
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lines splits s into lines, keeping their newlines.
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.SplitAfter(s, "\n")[:strings.Count(s, "\n")]
+}
+
+func checkLineDiffs(t *testing.T, a []string, diffs []Diff, b []string) {
+	t.Helper()
+	checkDiffs(t, strings.Join(a, ""), toByteDiffs(a, b, diffs), strings.Join(b, ""))
+}
+
+// toByteDiffs converts diffs of lines to diffs of the bytes of their
+// concatenation.
+func toByteDiffs(a, b []string, diffs []Diff) []Diff {
+	offset := func(lines []string, i int) int { return len(strings.Join(lines[:i], "")) }
+	res := make([]Diff, len(diffs))
+	for i, d := range diffs {
+		res[i] = Diff{offset(a, d.Start), offset(a, d.End), offset(b, d.ReplStart), offset(b, d.ReplEnd)}
+	}
+	return res
+}
+
+func TestDiffLinesHistogram(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want []Diff
+	}{
+		{"", "", nil},
+		{"a\n", "a\n", nil},
+		{"a\nb\nc\n", "a\nB\nc\n", []Diff{{1, 2, 1, 2}}},
+		{"a\nb\n", "", []Diff{{0, 2, 0, 0}}},
+		{"", "a\nb\n", []Diff{{0, 0, 0, 2}}},
+		// Moved lines: the unique line anchors the diff.
+		{"x\ny\nz\n", "z\nx\ny\n", []Diff{{0, 0, 0, 1}, {2, 3, 3, 3}}},
+		// The new function is inserted whole, rather than
+		// interleaved with the repeated lines of the old one.
+		{
+			"func f() {\n}\n\nfunc g() {\n}\n",
+			"func f() {\n}\n\nfunc h() {\n}\n\nfunc g() {\n}\n",
+			[]Diff{{3, 3, 3, 6}},
+		},
+		// No line occurs few times: the fallback aligns the lines.
+		{"}\n}\n}\n", "}\n}\n", []Diff{{2, 3, 2, 2}}},
+	} {
+		a, b := lines(test.a), lines(test.b)
+		diffs := DiffLinesHistogram(a, b)
+		if !reflect.DeepEqual(diffs, test.want) {
+			t.Errorf("DiffLinesHistogram(%q, %q) = %v, want %v", test.a, test.b, diffs, test.want)
+		}
+		checkLineDiffs(t, a, diffs, b)
+	}
+}
+
+func TestRandHistogram(t *testing.T) {
+	rand.Seed(1)
+	for i := 0; i < 1000; i++ {
+		// Lines from a small set, so that many repeat more than
+		// maxChain times.
+		a := lines(strings.Join(strings.Split(randstr("abcde", 200), ""), "\n") + "\n")
+		b := lines(strings.Join(strings.Split(randstr("abcdef", 200), ""), "\n") + "\n")
+		checkLineDiffs(t, a, DiffLinesHistogram(a, b), b)
+		checkLineDiffs(t, b, DiffLinesHistogram(b, a), a)
+	}
+}
+
+// generated returns the lines of a generated file with many repeated
+// lines, and a version of it in which every tenth declaration is
+// replaced by two others.
+func generated(n int) ([]string, []string) {
+	decl := func(name string) []string {
+		return []string{
+			fmt.Sprintf("func (x *%s) Reset() {\n", name),
+			"\t*x = T{}\n",
+			"}\n",
+			"\n",
+		}
+	}
+	var a, b []string
+	for i := 0; i < n; i++ {
+		a = append(a, decl(fmt.Sprintf("T%d", i))...)
+		if i%10 == 5 {
+			b = append(b, decl(fmt.Sprintf("U%d", i))...)
+			b = append(b, decl(fmt.Sprintf("V%d", i))...)
+		} else {
+			b = append(b, decl(fmt.Sprintf("T%d", i))...)
+		}
+	}
+	return a, b
+}
+
+func BenchmarkHistogram(b *testing.B) {
+	before, after := generated(1000)
+	// The number of deleted and inserted lines measures the quality
+	// of the diffs: that of the lcs algorithm is bounded.
+	report := func(b *testing.B, diffs []Diff) {
+		n := 0
+		for _, d := range diffs {
+			n += d.End - d.Start + d.ReplEnd - d.ReplStart
+		}
+		b.ReportMetric(float64(n), "edited-lines")
+	}
+	b.Run("histogram", func(b *testing.B) {
+		var diffs []Diff
+		for i := 0; i < b.N; i++ {
+			diffs = DiffLinesHistogram(before, after)
+		}
+		report(b, diffs)
+	})
+	b.Run("unanchored", func(b *testing.B) {
+		before, after := unanchored(10000)
+		var diffs []Diff
+		for i := 0; i < b.N; i++ {
+			diffs = DiffLinesHistogram(before, after)
+		}
+		report(b, diffs)
+	})
+	b.Run("lcs", func(b *testing.B) {
+		var diffs []Diff
+		for i := 0; i < b.N; i++ {
+			diffs, _ = compute(linesSeqs{before, after}, twosided, maxDiffs/2)
+		}
+		report(b, diffs)
+	})
+}
+
+// unanchored returns n unique lines, and a version of them in which a
+// line is inserted after every other one. Every common line is a
+// region of its own, so each step of the search for anchors splits off
+// a single line, and an unbounded search takes time quadratic in n.
+func unanchored(n int) ([]string, []string) {
+	var a, b []string
+	for i := 0; i < n; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		a = append(a, line)
+		b = append(b, line)
+		if i%2 == 0 {
+			b = append(b, "inserted\n")
+		}
+	}
+	return a, b
+}
+
+func TestHistogramBudget(t *testing.T) {
+	a, b := unanchored(8000)
+	h := newHistogram(a, b)
+	budget := h.work
+	h.diff(0, len(a), 0, len(b))
+	// The search may overrun its budget by at most a region.
+	if spent, max := budget-h.work, budget+2*(len(a)+len(b)); spent > max {
+		t.Errorf("search took %d steps, want at most %d", spent, max)
+	}
+
+	// Apply the diffs to a, which must yield b.
+	var got []string
+	pa := 0
+	for _, d := range h.diffs {
+		got = append(got, a[pa:d.Start]...)
+		got = append(got, b[d.ReplStart:d.ReplEnd]...)
+		pa = d.End
+	}
+	got = append(got, a[pa:]...)
+	if !reflect.DeepEqual(got, b) {
+		t.Errorf("applying the diffs does not yield b")
+	}
+}
@@ -27,9 +27,10 @@ func DiffBytes(a, b []byte) []Diff { return diff(bytesSeqs{a, b}) }
 // DiffRunes returns the differences between two rune sequences.
 func DiffRunes(a, b []rune) []Diff { return diff(runesSeqs{a, b}) }
 
+// A limit on how deeply the LCS algorithm should search. The value is just a guess.
+const maxDiffs = 30
+
 func diff(seqs sequences) []Diff {
-	// A limit on how deeply the LCS algorithm should search. The value is just a guess.
-	const maxDiffs = 30
 	diff, _ := compute(seqs, twosided, maxDiffs/2)
 	return diff
 }
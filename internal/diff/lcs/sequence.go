@@ -23,6 +23,16 @@ func (s stringSeqs) commonSuffixLen(ai, aj, bi, bj int) int {
 	return commonSuffixLenString(s.a[ai:aj], s.b[bi:bj])
 }
 
+type linesSeqs struct{ a, b []string }
+
+func (s linesSeqs) lengths() (int, int) { return len(s.a), len(s.b) }
+func (s linesSeqs) commonPrefixLen(ai, aj, bi, bj int) int {
+	return commonPrefixLenLines(s.a[ai:aj:aj], s.b[bi:bj:bj])
+}
+func (s linesSeqs) commonSuffixLen(ai, aj, bi, bj int) int {
+	return commonSuffixLenLines(s.a[ai:aj:aj], s.b[bi:bj:bj])
+}
+
 // The explicit capacity in s[i:j:j] leads to more efficient code.
 
 type bytesSeqs struct{ a, b []byte }
@@ -69,6 +79,14 @@ func commonPrefixLenRunes(a, b []rune) int {
 	}
 	return i
 }
+func commonPrefixLenLines(a, b []string) int {
+	n := min(len(a), len(b))
+	i := 0
+	for i < n && a[i] == b[i] {
+		i++
+	}
+	return i
+}
 func commonPrefixLenString(a, b string) int {
 	n := min(len(a), len(b))
 	i := 0
@@ -95,6 +113,14 @@ func commonSuffixLenRunes(a, b []rune) int {
 	}
 	return i
 }
+func commonSuffixLenLines(a, b []string) int {
+	n := min(len(a), len(b))
+	i := 0
+	for i < n && a[len(a)-1-i] == b[len(b)-1-i] {
+		i++
+	}
+	return i
+}
 func commonSuffixLenString(a, b string) int {
 	n := min(len(a), len(b))
 	i := 0
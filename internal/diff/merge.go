@@ -0,0 +1,134 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import "strings"
+
+// A Conflict is a region of the result of Merge in which the two sets
+// of changes to the base text could not be reconciled.
+type Conflict struct {
+	Start, End int // byte offsets of the region, including its markers
+
+	// Base, Ours and Theirs are the lines of the region in the
+	// base text and in each of the changed texts.
+	Base, Ours, Theirs string
+}
+
+// Conflict markers, as used by git.
+const (
+	oursMarker   = "<<<<<<< ours\n"
+	sepMarker    = "=======\n"
+	theirsMarker = ">>>>>>> theirs\n"
+)
+
+// Merge performs a three-way merge, line by line, of the changes from
+// base to ours and from base to theirs, and returns the merged text.
+//
+// Changes to distinct lines of base are combined. Changes to the same
+// or adjacent lines are combined only if they are identical; otherwise
+// the merged text contains the lines of each side between conflict
+// markers, as git does:
+//
+//	<<<<<<< ours
+//	(lines of ours)
+//	=======
+//	(lines of theirs)
+//	>>>>>>> theirs
+//
+// and the region is also reported among the conflicts, in order.
+func Merge(base, ours, theirs string) (string, []Conflict) {
+	oursEdits := mergeEdits(base, ours)
+	theirsEdits := mergeEdits(base, theirs)
+
+	var (
+		out       strings.Builder
+		conflicts []Conflict
+		last      int // end of the previous region of base
+	)
+	for len(oursEdits) > 0 || len(theirsEdits) > 0 {
+		// Find the region of base spanned by a maximal cluster of
+		// overlapping or adjacent edits of either side.
+		var start, end int
+		if len(theirsEdits) == 0 || len(oursEdits) > 0 && oursEdits[0].Start <= theirsEdits[0].Start {
+			start, end = oursEdits[0].Start, oursEdits[0].End
+		} else {
+			start, end = theirsEdits[0].Start, theirsEdits[0].End
+		}
+		var o, t int // number of edits of each side in the cluster
+		for {
+			if o < len(oursEdits) && oursEdits[o].Start <= end {
+				if oursEdits[o].End > end {
+					end = oursEdits[o].End
+				}
+				o++
+			} else if t < len(theirsEdits) && theirsEdits[t].Start <= end {
+				if theirsEdits[t].End > end {
+					end = theirsEdits[t].End
+				}
+				t++
+			} else {
+				break
+			}
+		}
+
+		out.WriteString(base[last:start])
+		oursText := applyRegion(base, start, end, oursEdits[:o])
+		theirsText := applyRegion(base, start, end, theirsEdits[:t])
+		switch {
+		case t == 0:
+			out.WriteString(oursText)
+		case o == 0:
+			out.WriteString(theirsText)
+		case oursText == theirsText:
+			out.WriteString(oursText)
+		default:
+			c := Conflict{Start: out.Len(), Base: base[start:end], Ours: oursText, Theirs: theirsText}
+			out.WriteString(oursMarker)
+			writeLines(&out, oursText)
+			out.WriteString(sepMarker)
+			writeLines(&out, theirsText)
+			out.WriteString(theirsMarker)
+			c.End = out.Len()
+			conflicts = append(conflicts, c)
+		}
+		last = end
+		oursEdits, theirsEdits = oursEdits[o:], theirsEdits[t:]
+	}
+	out.WriteString(base[last:])
+	return out.String(), conflicts
+}
+
+// mergeEdits returns the line edits that transform base into text.
+func mergeEdits(base, text string) []Edit {
+	edits, err := lineEdits(base, Strings(base, text))
+	if err != nil {
+		// Can't happen: edits are consistent.
+		panic(err)
+	}
+	return edits
+}
+
+// applyRegion applies the edits, which lie within the region
+// [start, end) of base, to that region and returns the result.
+func applyRegion(base string, start, end int, edits []Edit) string {
+	var buf strings.Builder
+	last := start
+	for _, edit := range edits {
+		buf.WriteString(base[last:edit.Start])
+		buf.WriteString(edit.New)
+		last = edit.End
+	}
+	buf.WriteString(base[last:end])
+	return buf.String()
+}
+
+// writeLines writes the lines of s to out, terminating the last line
+// so that a conflict marker may follow it.
+func writeLines(out *strings.Builder, s string) {
+	out.WriteString(s)
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		out.WriteString("\n")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/tools/internal/diff"
+)
+
+func TestMerge(t *testing.T) {
+	const base = "a\nb\nc\nd\ne\nf\n"
+	for _, test := range []struct {
+		name, ours, theirs string
+		want               string
+		conflicts          []diff.Conflict
+	}{
+		{
+			name:   "unchanged",
+			ours:   base,
+			theirs: base,
+			want:   base,
+		},
+		{
+			name:   "ours",
+			ours:   "a\nB\nc\nd\ne\nf\n",
+			theirs: base,
+			want:   "a\nB\nc\nd\ne\nf\n",
+		},
+		{
+			name:   "theirs",
+			ours:   base,
+			theirs: "a\nb\nc\nd\nf\n",
+			want:   "a\nb\nc\nd\nf\n",
+		},
+		{
+			name:   "distinct",
+			ours:   "A\nb\nc\nd\ne\nf\ng\n",
+			theirs: "a\nb\nc\nD\ne\nf\n",
+			want:   "A\nb\nc\nD\ne\nf\ng\n",
+		},
+		{
+			name:   "identical",
+			ours:   "a\nB\nc\nd\ne\nf\n",
+			theirs: "a\nB\nc\nd\ne\nF\n",
+			want:   "a\nB\nc\nd\ne\nF\n",
+		},
+		{
+			name:   "conflict",
+			ours:   "a\nb\nX\nd\ne\nf\n",
+			theirs: "a\nb\nY\nY\nd\ne\nf\n",
+			want:   "a\nb\n<<<<<<< ours\nX\n=======\nY\nY\n>>>>>>> theirs\nd\ne\nf\n",
+			conflicts: []diff.Conflict{
+				{Start: 4, End: 46, Base: "c\n", Ours: "X\n", Theirs: "Y\nY\n"},
+			},
+		},
+		{
+			name:   "adjacent",
+			ours:   "a\nb\nC\nd\ne\nf\n",
+			theirs: "a\nb\nc\nD\ne\nf\n",
+			want:   "a\nb\n<<<<<<< ours\nC\nd\n=======\nc\nD\n>>>>>>> theirs\ne\nf\n",
+			conflicts: []diff.Conflict{
+				{Start: 4, End: 48, Base: "c\nd\n", Ours: "C\nd\n", Theirs: "c\nD\n"},
+			},
+		},
+		{
+			name:   "no_newline",
+			ours:   "a\nb\nc\nd\ne\nF",
+			theirs: "a\nb\nc\nd\ne\nG\n",
+			want:   "a\nb\nc\nd\ne\n<<<<<<< ours\nF\n=======\nG\n>>>>>>> theirs\n",
+			conflicts: []diff.Conflict{
+				{Start: 10, End: 50, Base: "f\n", Ours: "F", Theirs: "G\n"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, conflicts := diff.Merge(base, test.ours, test.theirs)
+			if got != test.want {
+				t.Errorf("Merge = %q, want %q", got, test.want)
+			}
+			if !reflect.DeepEqual(conflicts, test.conflicts) {
+				t.Errorf("Merge conflicts = %+v, want %+v", conflicts, test.conflicts)
+			}
+			for _, c := range conflicts {
+				if c.Start < 0 || c.End > len(got) || c.Start > c.End {
+					t.Errorf("conflict %+v out of bounds", c)
+				}
+			}
+		})
+	}
+}
+
+// $ go test -fuzz=FuzzMerge ./internal/diff
+func FuzzMerge(f *testing.F) {
+	f.Add("a\nb\nc\n", "a\nB\nc\n", "a\nb\nC\n")
+	f.Fuzz(func(t *testing.T, base, ours, theirs string) {
+		if !utf8.ValidString(base) || !utf8.ValidString(ours) || !utf8.ValidString(theirs) {
+			return // inputs must be text
+		}
+		// Merging with an unchanged side gives the other side.
+		for _, pair := range [][2]string{{ours, base}, {base, theirs}, {ours, ours}} {
+			got, conflicts := diff.Merge(base, pair[0], pair[1])
+			want := pair[0]
+			if pair[0] == base {
+				want = pair[1]
+			}
+			if got != want || len(conflicts) > 0 {
+				t.Fatalf("Merge(%q, %q, %q) = %q, %v; want %q", base, pair[0], pair[1], got, conflicts, want)
+			}
+		}
+		// Conflicts lie within the result, in order.
+		got, conflicts := diff.Merge(base, ours, theirs)
+		last := 0
+		for _, c := range conflicts {
+			if c.Start < last || c.End > len(got) || c.Start > c.End {
+				t.Fatalf("Merge(%q, %q, %q) = %q has invalid conflict %+v", base, ours, theirs, got, c)
+			}
+			last = c.End
+		}
+	})
+}
@@ -6,18 +6,38 @@ package diff
 
 import (
 	"bytes"
+	"strings"
 	"unicode/utf8"
 
 	"golang.org/x/tools/internal/diff/lcs"
 )
 
+// An Option modifies how Strings and Bytes compute differences.
+type Option int
+
+const (
+	// Histogram computes the differences between the lines of the
+	// texts using the histogram diff algorithm of git, which aligns
+	// them on their least frequent lines. It gives more readable
+	// differences than the default algorithm for large files with many
+	// repeated lines, such as generated code. Its search for aligned
+	// lines is limited to work proportional to the sizes of the
+	// texts, beyond which the lines are aligned by the default
+	// algorithm. The resulting edits replace whole lines.
+	Histogram Option = iota + 1
+)
+
 // Strings computes the differences between two strings.
 // The resulting edits respect rune boundaries.
-func Strings(before, after string) []Edit {
+func Strings(before, after string, opts ...Option) []Edit {
 	if before == after {
 		return nil // common case
 	}
 
+	if hasOption(opts, Histogram) {
+		return diffLines(before, after)
+	}
+
 	if stringIsASCII(before) && stringIsASCII(after) {
 		// TODO(adonovan): opt: specialize diffASCII for strings.
 		return diffASCII([]byte(before), []byte(after))
@@ -27,11 +47,15 @@ func Strings(before, after string) []Edit {
 
 // Bytes computes the differences between two byte slices.
 // The resulting edits respect rune boundaries.
-func Bytes(before, after []byte) []Edit {
+func Bytes(before, after []byte, opts ...Option) []Edit {
 	if bytes.Equal(before, after) {
 		return nil // common case
 	}
 
+	if hasOption(opts, Histogram) {
+		return diffLines(string(before), string(after))
+	}
+
 	if bytesIsASCII(before) && bytesIsASCII(after) {
 		return diffASCII(before, after)
 	}
@@ -68,6 +92,41 @@ func diffRunes(before, after []rune) []Edit {
 	return res
 }
 
+func diffLines(before, after string) []Edit {
+	a, b := splitLines(before), splitLines(after)
+	diffs := lcs.DiffLinesHistogram(a, b)
+
+	// Convert line indexes to byte offsets.
+	res := make([]Edit, len(diffs))
+	lastEnd := 0
+	offset := 0
+	for i, d := range diffs {
+		offset += linesLen(a[lastEnd:d.Start]) // text between edits
+		start := offset
+		offset += linesLen(a[d.Start:d.End]) // text deleted by this edit
+		res[i] = Edit{start, offset, strings.Join(b[d.ReplStart:d.ReplEnd], "")}
+		lastEnd = d.End
+	}
+	return res
+}
+
+// linesLen returns the length in bytes of lines.
+func linesLen(lines []string) (n int) {
+	for _, l := range lines {
+		n += len(l)
+	}
+	return n
+}
+
+func hasOption(opts []Option, opt Option) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
 // runes is like []rune(string(bytes)) without the duplicate allocation.
 func runes(bytes []byte) []rune {
 	n := utf8.RuneCount(bytes)
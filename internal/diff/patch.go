@@ -0,0 +1,206 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A FileDiff is the unified diff of a single file, as parsed by
+// ParseUnified.
+type FileDiff struct {
+	From, To string // names of the original and modified files
+	hunks    []*hunk
+}
+
+// String returns the unified diff of the file.
+func (d *FileDiff) String() string {
+	return unified{From: d.From, To: d.To, Hunks: d.hunks}.String()
+}
+
+// ParseUnified parses a unified diff, such as the output of ToUnified,
+// "diff -u" or "git diff", and returns the diffs of its files in order.
+// Lines outside the diffs of files, such as the extended headers of
+// git, are ignored.
+func ParseUnified(patch string) ([]*FileDiff, error) {
+	var (
+		diffs []*FileDiff
+		lines = splitLines(patch)
+	)
+	for i := 0; i < len(lines); {
+		l := lines[i]
+		if !strings.HasPrefix(l, "--- ") || i+1 == len(lines) || !strings.HasPrefix(lines[i+1], "+++ ") {
+			i++ // not a file header
+			continue
+		}
+		d := &FileDiff{
+			From: fileLabel(l[len("--- "):]),
+			To:   fileLabel(lines[i+1][len("+++ "):]),
+		}
+		i += 2
+		for i < len(lines) && strings.HasPrefix(lines[i], "@@ ") {
+			h, n, err := parseHunk(lines[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%s: line %d: %v", d.From, i+1, err)
+			}
+			d.hunks = append(d.hunks, h)
+			i += n
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// fileLabel returns the name of the file of a header line, without the
+// timestamp that may follow a tab.
+func fileLabel(s string) string {
+	s = strings.TrimRight(s, "\r\n")
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// parseHunk parses the hunk at the start of lines, and returns it and
+// the number of lines it occupies.
+func parseHunk(lines []string) (*hunk, int, error) {
+	// @@ -from[,count] +to[,count] @@ [section heading]
+	fields := strings.Fields(lines[0])
+	if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return nil, 0, fmt.Errorf("invalid hunk header %q", strings.TrimSpace(lines[0]))
+	}
+	fromLine, fromCount, err := parseRange(fields[1][1:])
+	if err != nil {
+		return nil, 0, err
+	}
+	toLine, toCount, err := parseRange(fields[2][1:])
+	if err != nil {
+		return nil, 0, err
+	}
+	// An empty range is denoted by the line before it.
+	if fromCount == 0 {
+		fromLine++
+	}
+	if toCount == 0 {
+		toLine++
+	}
+	h := &hunk{FromLine: fromLine, ToLine: toLine}
+
+	n := 1
+	for fromCount > 0 || toCount > 0 {
+		if n == len(lines) {
+			return nil, 0, fmt.Errorf("unexpected end of hunk")
+		}
+		l := lines[n]
+		n++
+		var kind OpKind
+		switch {
+		case l == "\n":
+			// Some tools strip the space of empty context lines.
+			kind, l = Equal, " \n"
+		case strings.HasPrefix(l, " "):
+			kind = Equal
+		case strings.HasPrefix(l, "-"):
+			kind = Delete
+		case strings.HasPrefix(l, "+"):
+			kind = Insert
+		case strings.HasPrefix(l, `\`):
+			continue // "\ No newline at end of file", handled below
+		default:
+			return nil, 0, fmt.Errorf("invalid line in hunk: %q", l)
+		}
+		if kind != Insert {
+			fromCount--
+		}
+		if kind != Delete {
+			toCount--
+		}
+		if fromCount < 0 || toCount < 0 {
+			return nil, 0, fmt.Errorf("hunk has more lines than its header")
+		}
+		// A line without newline is followed by a marker.
+		content := l[1:]
+		if n < len(lines) && strings.HasPrefix(lines[n], `\`) {
+			content = strings.TrimSuffix(content, "\n")
+			n++
+		}
+		h.Lines = append(h.Lines, line{Kind: kind, Content: content})
+	}
+	return h, n, nil
+}
+
+// parseRange parses the line and count of a range of a hunk header.
+func parseRange(s string) (line, count int, err error) {
+	count = 1
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		if count, err = strconv.Atoi(s[i+1:]); err != nil || count < 0 {
+			return 0, 0, fmt.Errorf("invalid range %q", s)
+		}
+		s = s[:i]
+	}
+	if line, err = strconv.Atoi(s); err != nil || line < 0 {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	return line, count, nil
+}
+
+// Edits returns the edits of the diff to the content of the original
+// file. It returns an error if the content does not match the context
+// and deleted lines of the diff.
+func (d *FileDiff) Edits(content string) ([]Edit, error) {
+	// offsets[i] is the offset of the start of the line i.
+	lines := splitLines(content)
+	offsets := make([]int, len(lines)+1)
+	for i, l := range lines {
+		offsets[i+1] = offsets[i] + len(l)
+	}
+
+	var edits []Edit
+	next := 0 // index of the first line after the previous hunk
+	for _, h := range d.hunks {
+		i := h.FromLine - 1
+		if i < next || i > len(lines) {
+			return nil, fmt.Errorf("%s: hunk at line %d is out of order or out of bounds", d.From, h.FromLine)
+		}
+		inRun := false // whether the last edit is that of the current run of changed lines
+		for _, l := range h.Lines {
+			start := offsets[i]
+			if l.Kind != Insert {
+				if i == len(lines) || lines[i] != l.Content {
+					return nil, fmt.Errorf("%s: hunk at line %d does not match line %d", d.From, h.FromLine, i+1)
+				}
+				i++
+			}
+			if l.Kind == Equal {
+				inRun = false
+				continue
+			}
+			if !inRun {
+				edits = append(edits, Edit{Start: start, End: start})
+				inRun = true
+			}
+			edit := &edits[len(edits)-1]
+			if l.Kind == Insert {
+				edit.New += l.Content
+			} else {
+				edit.End = offsets[i]
+			}
+		}
+		next = i
+	}
+	return edits, nil
+}
+
+// Apply applies the diff to the content of the original file and
+// returns the content of the modified file.
+func (d *FileDiff) Apply(content string) (string, error) {
+	edits, err := d.Edits(content)
+	if err != nil {
+		return "", err
+	}
+	return Apply(content, edits)
+}
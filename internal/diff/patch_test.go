@@ -0,0 +1,142 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diff_test
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/diff/difftest"
+)
+
+func TestParseUnified(t *testing.T) {
+	const patch = `diff --git a/a.txt b/a.txt
+index 3b18e51..e69de29 100644
+--- a/a.txt	2023-01-01 00:00:00
++++ b/a.txt	2023-01-02 00:00:00
+@@ -1,3 +1,3 @@ section
+ one
+-two
++TWO
+ three
+@@ -7,2 +7,0 @@
+-seven
+-eight
+diff --git a/b.txt b/b.txt
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1,2 @@
+-x
+\ No newline at end of file
++x
++y
+\ No newline at end of file
+`
+	diffs, err := diff.ParseUnified(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("ParseUnified returned %d files, want 2", len(diffs))
+	}
+	if got := diffs[0].From + " " + diffs[0].To; got != "a/a.txt b/a.txt" {
+		t.Errorf("got labels %q, want %q", got, "a/a.txt b/a.txt")
+	}
+
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n"
+	edits, err := diffs[0].Edits(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []diff.Edit{
+		{Start: 4, End: 8, New: "TWO\n"},
+		{Start: 28, End: 40, New: ""},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("Edits = %v, want %v", edits, want)
+	}
+	got, err := diffs[1].Apply("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "x\ny" {
+		t.Errorf("Apply = %q, want %q", got, "x\ny")
+	}
+
+	// The content must match the diff.
+	if _, err := diffs[0].Edits("one\n2\nthree\n"); err == nil {
+		t.Errorf("Edits succeeded on mismatched content")
+	}
+
+	// Malformed hunks.
+	for _, bad := range []string{
+		"--- a\n+++ b\n@@ -1 +1 @@\n",                // too few lines
+		"--- a\n+++ b\n@@ -x +1 @@\n-x\n+y\n",        // bad range
+		"--- a\n+++ b\n@@ -1 +1 @@\n-x\n*y\n",        // bad line
+		"--- a\n+++ b\n@@ -1,2 +1 @@\n-x\n+y\n",      // too few deletions
+		"--- a\n+++ b\n@@ -1 +1 -@@\n-x\n+y\n",       // bad header
+		"--- a\n+++ b\n@@ -1,-1 +1 @@\n-x\n+y\n+z\n", // negative count
+	} {
+		if _, err := diff.ParseUnified(bad); err == nil {
+			t.Errorf("ParseUnified(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseUnifiedRoundTrip(t *testing.T) {
+	for _, tc := range difftest.TestCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			checkParseUnified(t, tc.In, tc.Out, tc.Edits)
+		})
+	}
+}
+
+// checkParseUnified checks that the unified diff of the edits to a
+// parses and applies to a, giving b.
+func checkParseUnified(t *testing.T, a, b string, edits []diff.Edit) {
+	t.Helper()
+	unified, err := diff.ToUnified(difftest.FileA, difftest.FileB, a, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := diff.ParseUnified(unified)
+	if err != nil {
+		t.Fatalf("ParseUnified failed: %v\n%s", err, unified)
+	}
+	if unified == "" {
+		if len(diffs) != 0 {
+			t.Fatalf("ParseUnified of empty diff returned %d files", len(diffs))
+		}
+		return
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("ParseUnified returned %d files, want 1:\n%s", len(diffs), unified)
+	}
+	if got := diffs[0].String(); got != unified {
+		t.Errorf("String = %q, want %q", got, unified)
+	}
+	got, err := diffs[0].Apply(a)
+	if err != nil {
+		t.Fatalf("Apply failed: %v\n%s", err, unified)
+	}
+	if got != b {
+		t.Errorf("applying parsed diff gives %q, want %q\n%s", got, b, unified)
+	}
+}
+
+// $ go test -fuzz=FuzzParseUnified ./internal/diff
+func FuzzParseUnified(f *testing.F) {
+	for _, tc := range difftest.TestCases {
+		f.Add(tc.In, tc.Out)
+	}
+	f.Fuzz(func(t *testing.T, a, b string) {
+		if !utf8.ValidString(a) || !utf8.ValidString(b) {
+			return // inputs must be text
+		}
+		checkParseUnified(t, a, b, diff.Strings(a, b))
+	})
+}
@@ -215,17 +215,20 @@ func (u unified) String() string {
 				toCount++
 			}
 		}
+		// An empty range is denoted by the line before it,
+		// as in GNU diff -u.
 		fmt.Fprint(b, "@@")
 		if fromCount > 1 {
 			fmt.Fprintf(b, " -%d,%d", hunk.FromLine, fromCount)
-		} else if hunk.FromLine == 1 && fromCount == 0 {
-			// Match odd GNU diff -u behavior adding to empty file.
-			fmt.Fprintf(b, " -0,0")
+		} else if fromCount == 0 {
+			fmt.Fprintf(b, " -%d,0", hunk.FromLine-1)
 		} else {
 			fmt.Fprintf(b, " -%d", hunk.FromLine)
 		}
 		if toCount > 1 {
 			fmt.Fprintf(b, " +%d,%d", hunk.ToLine, toCount)
+		} else if toCount == 0 {
+			fmt.Fprintf(b, " +%d,0", hunk.ToLine-1)
 		} else {
 			fmt.Fprintf(b, " +%d", hunk.ToLine)
 		}
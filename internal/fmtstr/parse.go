@@ -0,0 +1,229 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fmtstr parses the format strings of the printf family of
+// functions, following the syntax accepted by package fmt, so that
+// the analyzers that inspect them agree on their directives.
+package fmtstr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Directive is a single formatting directive, such as "%3.*[4]d",
+// within a format string.
+type Directive struct {
+	Start, End int    // byte offsets of the directive within the format
+	Text       string // the directive, from % through the verb
+	Verb       rune   // the verb: 'd' for "%d", or '%' for "%%"
+	Flags      []byte // the flags, such as '#' and '+', and '.' if there is a precision
+	HasIndex   bool   // whether an explicit argument index is present
+
+	// ArgNums holds the indexes of the operands consumed by the
+	// directive, in order: those of any * width and precision, and
+	// that of the verb. Operands are numbered from zero, starting
+	// after the format.
+	ArgNums []int
+
+	// ArgNum is the index of the operand of the verb, or, for "%%",
+	// of the operand that a verb in its place would format.
+	ArgNum int
+}
+
+// An ErrorKind describes what is wrong with a malformed directive.
+type ErrorKind int
+
+const (
+	MissingBracket ErrorKind = iota // an argument index lacks its closing ]
+	InvalidIndex                    // an argument index is not a valid operand number
+	MissingVerb                     // the format ends before the verb
+)
+
+// An Error describes a malformed directive.
+type Error struct {
+	Kind ErrorKind
+
+	// Text is the invalid index, for InvalidIndex, and otherwise the
+	// remainder of the format, starting at the directive's %.
+	Text string
+}
+
+func (e *Error) Error() string {
+	switch e.Kind {
+	case MissingBracket:
+		return fmt.Sprintf("format %s is missing closing ]", e.Text)
+	case InvalidIndex:
+		return fmt.Sprintf("format has invalid argument index [%s]", e.Text)
+	default:
+		return fmt.Sprintf("format %s is missing verb at end of string", e.Text)
+	}
+}
+
+// Parse returns the directives of the format string, whose operands
+// are numbered from zero. If numArgs is not negative, it is the number
+// of operands, and an index of a later operand is invalid.
+//
+// It returns an *Error for the first malformed directive.
+func Parse(format string, numArgs int) ([]*Directive, error) {
+	var dirs []*Directive
+	argNum := 0
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			i++
+			continue
+		}
+		d, err := ParseDirective(format, i, argNum, numArgs)
+		if err != nil {
+			return nil, err
+		}
+		if len(d.ArgNums) > 0 {
+			// Continue with the next sequential operand.
+			argNum = d.ArgNums[len(d.ArgNums)-1] + 1
+		}
+		dirs = append(dirs, d)
+		i = d.End
+	}
+	return dirs, nil
+}
+
+// ParseDirective parses the directive at byte offset start of the
+// format string, which must hold a '%'. The directive consumes
+// operands starting at argNum, unless it has an explicit index.
+// numArgs is as for Parse.
+func ParseDirective(format string, start, argNum, numArgs int) (*Directive, error) {
+	p := &parser{
+		format:  format[start:],
+		numArgs: numArgs,
+		argNum:  argNum,
+		nbytes:  1, // skip the percent sign
+		dir: &Directive{
+			Start:   start,
+			Flags:   make([]byte, 0, 5),
+			ArgNums: make([]int, 0, 1),
+		},
+	}
+	// There may be flags.
+	p.parseFlags()
+	// There may be an index.
+	if err := p.parseIndex(); err != nil {
+		return nil, err
+	}
+	// There may be a width.
+	p.parseNum()
+	// There may be a precision.
+	if err := p.parsePrecision(); err != nil {
+		return nil, err
+	}
+	// Now a verb, possibly prefixed by an index (which we may already have).
+	if !p.indexPending {
+		if err := p.parseIndex(); err != nil {
+			return nil, err
+		}
+	}
+	if p.nbytes == len(p.format) {
+		return nil, &Error{MissingVerb, p.format}
+	}
+	verb, w := utf8.DecodeRuneInString(p.format[p.nbytes:])
+	p.nbytes += w
+	d := p.dir
+	d.Verb = verb
+	if verb != '%' {
+		d.ArgNums = append(d.ArgNums, p.argNum)
+	}
+	d.ArgNum = p.argNum
+	d.End = start + p.nbytes
+	d.Text = p.format[:p.nbytes]
+	return d, nil
+}
+
+// parser holds the state of ParseDirective.
+type parser struct {
+	format       string // the format, from the directive's % to the end
+	numArgs      int    // the number of operands, or -1 if unknown
+	argNum       int    // the operand to format next
+	indexPending bool   // whether an index has not yet been consumed
+	nbytes       int    // number of bytes of the format consumed
+	dir          *Directive
+}
+
+// parseFlags accepts any printf flags.
+func (p *parser) parseFlags() {
+	for p.nbytes < len(p.format) {
+		switch c := p.format[p.nbytes]; c {
+		case '#', '0', '+', '-', ' ':
+			p.dir.Flags = append(p.dir.Flags, c)
+			p.nbytes++
+		default:
+			return
+		}
+	}
+}
+
+// scanNum advances through a decimal number if present.
+func (p *parser) scanNum() {
+	for ; p.nbytes < len(p.format); p.nbytes++ {
+		c := p.format[p.nbytes]
+		if c < '0' || '9' < c {
+			return
+		}
+	}
+}
+
+// parseIndex scans an index expression, if present.
+func (p *parser) parseIndex() error {
+	if p.nbytes == len(p.format) || p.format[p.nbytes] != '[' {
+		return nil
+	}
+	// Argument index present.
+	p.nbytes++ // skip '['
+	start := p.nbytes
+	p.scanNum()
+	ok := true
+	if p.nbytes == len(p.format) || p.nbytes == start || p.format[p.nbytes] != ']' {
+		ok = false // syntax error is either missing "]" or invalid index.
+		p.nbytes = strings.Index(p.format[start:], "]")
+		if p.nbytes < 0 {
+			return &Error{MissingBracket, p.format}
+		}
+		p.nbytes = p.nbytes + start
+	}
+	arg32, err := strconv.ParseInt(p.format[start:p.nbytes], 10, 32)
+	if err != nil || !ok || arg32 <= 0 || p.numArgs >= 0 && arg32 > int64(p.numArgs) {
+		return &Error{InvalidIndex, p.format[start:p.nbytes]}
+	}
+	p.nbytes++ // skip ']'
+	p.argNum = int(arg32) - 1 // zero-index the operands
+	p.dir.HasIndex = true
+	p.indexPending = true
+	return nil
+}
+
+// parseNum scans a width or precision (or *).
+func (p *parser) parseNum() {
+	if p.nbytes < len(p.format) && p.format[p.nbytes] == '*' {
+		p.indexPending = false // absorb any index
+		p.nbytes++
+		p.dir.ArgNums = append(p.dir.ArgNums, p.argNum)
+		p.argNum++
+	} else {
+		p.scanNum()
+	}
+}
+
+// parsePrecision scans for a precision.
+func (p *parser) parsePrecision() error {
+	// If there's a period, there may be a precision.
+	if p.nbytes < len(p.format) && p.format[p.nbytes] == '.' {
+		p.dir.Flags = append(p.dir.Flags, '.') // Treat precision as a flag.
+		p.nbytes++
+		if err := p.parseIndex(); err != nil {
+			return err
+		}
+		p.parseNum()
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fmtstr_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/fmtstr"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		format  string
+		numArgs int
+		want    string // directives as text:argnums, or the error
+	}{
+		{"", 2, ""},
+		{"%d %s", 2, "%d:[0] %s:[1]"},
+		{"100%% %v", 1, "%%:[] %v:[0]"},
+		{"%[2]d %[1]d %d", 2, "%[2]d:[1] %[1]d:[0] %d:[1]"},
+		{"%*.*f %x", 4, "%*.*f:[0 1 2] %x:[3]"},
+		{"%[3]*.[2]*[1]f", 3, "%[3]*.[2]*[1]f:[2 1 0]"},
+		{"%-+# 08.3w", 1, "%-+# 08.3w:[0]"},
+		{"%[3]d", 2, "format has invalid argument index [3]"},
+		{"%[3]d", -1, "%[3]d:[2]"},
+		{"%[0]d", -1, "format has invalid argument index [0]"},
+		{"%[x]d", -1, "format has invalid argument index [x]"},
+		{"%[xd", -1, "format %[xd is missing closing ]"},
+		{"x %", -1, "format % is missing verb at end of string"},
+	} {
+		var got string
+		dirs, err := fmtstr.Parse(test.format, test.numArgs)
+		if err != nil {
+			got = err.Error()
+		} else {
+			var parts []string
+			for _, d := range dirs {
+				if d.Text != test.format[d.Start:d.End] {
+					t.Errorf("Parse(%q): directive %q at [%d:%d]", test.format, d.Text, d.Start, d.End)
+				}
+				parts = append(parts, fmt.Sprintf("%s:%v", d.Text, d.ArgNums))
+			}
+			got = strings.Join(parts, " ")
+		}
+		if got != test.want {
+			t.Errorf("Parse(%q, %d) = %s, want %s", test.format, test.numArgs, got, test.want)
+		}
+	}
+}